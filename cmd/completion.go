@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"jsfinder/pkg/discovery"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a completion script for bash, zsh or fish. Besides commands and
+flags it completes values such as --format and the built-in wordlists
+of --wordlist builtin:<name>.`,
+	Example: `  # bash, current session
+  source <(jsfinder completion bash)
+
+  # bash, permanently
+  jsfinder completion bash > /etc/bash_completion.d/jsfinder
+
+  # zsh
+  jsfinder completion zsh > "${fpath[1]}/_jsfinder"
+
+  # fish
+  jsfinder completion fish > ~/.config/fish/completions/jsfinder.fish`,
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	default:
+		return fmt.Errorf("unsupported shell %q", args[0])
+	}
+}
+
+// completeValues completes a flag from a fixed set of values
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeWordlist offers the built-in wordlists alongside files on disk
+func completeWordlist(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for _, name := range discovery.BuiltinWordlists() {
+		names = append(names, discovery.BuiltinPrefix+name)
+	}
+	return names, cobra.ShellCompDirectiveDefault
+}
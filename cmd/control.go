@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	"jsfinder/pkg/utils"
+)
+
+// startControl lets an operator steer a long run from outside: pauseSignal
+// pauses or resumes new requests and statsSignal writes the statistics so
+// far to stderr. The returned function stops listening. Platforms without
+// these signals get no control.
+func startControl(pause *utils.PauseSwitch, stats *utils.RunStats) func() {
+	if pauseSignal == nil || statsSignal == nil {
+		return func() {}
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, pauseSignal, statsSignal)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				switch sig {
+				case pauseSignal:
+					if pause.Toggle() {
+						fmt.Fprintf(os.Stderr, "Paused: in-flight requests will finish, no new ones are sent (send %s again to resume)\n", signalName(pauseSignal))
+					} else {
+						fmt.Fprintln(os.Stderr, "Resumed")
+					}
+				case statsSignal:
+					stats.WriteSummary(os.Stderr)
+				}
+			}
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Send %s to pause or resume, %s to print statistics (pid %d)\n", signalName(pauseSignal), signalName(statsSignal), os.Getpid())
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals handled by startControl
+var (
+	pauseSignal os.Signal = syscall.SIGUSR1
+	statsSignal os.Signal = syscall.SIGUSR2
+)
+
+func signalName(sig os.Signal) string {
+	switch sig {
+	case syscall.SIGUSR1:
+		return "SIGUSR1"
+	case syscall.SIGUSR2:
+		return "SIGUSR2"
+	}
+	return sig.String()
+}
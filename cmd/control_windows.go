@@ -0,0 +1,15 @@
+//go:build windows
+
+package cmd
+
+import "os"
+
+// Windows has no user-defined signals, so runs cannot be controlled from outside
+var (
+	pauseSignal os.Signal
+	statsSignal os.Signal
+)
+
+func signalName(sig os.Signal) string {
+	return sig.String()
+}
@@ -1,8 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
 	"github.com/spf13/cobra"
 	"jsfinder/pkg/crawler"
+	"jsfinder/pkg/input"
+	"jsfinder/pkg/scanner"
+	"jsfinder/pkg/scope"
+	"jsfinder/pkg/utils"
 )
 
 var crawlCmd = &cobra.Command{
@@ -11,7 +20,9 @@ var crawlCmd = &cobra.Command{
 	Long: `Crawl target domains to discover and extract JavaScript files.
 Supports both single domain crawling and batch processing from stdin.`,
 	Example: `  jsfinder crawl --domain https://example.com --output jsfiles.txt
-  cat domains.txt | jsfinder crawl --output all-js.txt`,
+  cat domains.txt | jsfinder crawl --output all-js.txt
+  wget --mirror --adjust-extension https://example.com
+  jsfinder crawl --snapshot example.com --output jsfiles.txt`,
 	RunE: runCrawl,
 }
 
@@ -23,6 +34,17 @@ var (
 	timeout    int
 	ignoreRobots bool
 	verbose    bool
+	auditHeaders bool
+	auditOutput  string
+	fingerprint       bool
+	fingerprintOutput string
+	crawlSort    string
+	subdomainsOutput string
+	jsHistory        int
+	dedupeMirrors    bool
+	mirrorsOutput    string
+	pageData         bool
+	pageDataOutput   string
 )
 
 func init() {
@@ -35,26 +57,168 @@ func init() {
 	crawlCmd.Flags().IntVarP(&timeout, "timeout", "", 30, "Request timeout in seconds")
 	crawlCmd.Flags().BoolVarP(&ignoreRobots, "ignore-robots", "r", false, "Ignore robots.txt")
 	crawlCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	crawlCmd.Flags().BoolVar(&auditHeaders, "audit-headers", false, "Audit security headers (CSP, HSTS, X-Frame-Options) of each crawled origin")
+	crawlCmd.Flags().StringVar(&crawlSort, "sort", "", "Write JS files sorted by url once the crawl ends (default: as found)")
+	crawlCmd.Flags().StringVar(&auditOutput, "audit-output", "", "JSON file for security header findings (default: log only)")
+	crawlCmd.Flags().BoolVar(&fingerprint, "fingerprint", false, "Detect technologies (headers, meta generator, framework markers) and hash the favicon of each crawled origin")
+	crawlCmd.Flags().StringVar(&fingerprintOutput, "fingerprint-output", "", "JSON file for origin fingerprints (default: log only)")
+	crawlCmd.Flags().IntVar(&jsHistory, "js-history", 0, "Try up to this many older builds per version number in a JS URL (app.v123.js, ?v=42) and read open directory listings next to JS files, adding older bundles still served (0 = off)")
+	crawlCmd.Flags().BoolVar(&dedupeMirrors, "dedupe-mirrors", false, "Crawl only the first of several input domains serving the same homepage (www/non-www, regional mirrors) and record the others as its aliases")
+	crawlCmd.Flags().BoolVar(&pageData, "page-data", false, "Scan the hidden inputs, JSON-LD and serialized state (window.__INITIAL_STATE__, __NEXT_DATA__) of each crawled page for secrets")
+	crawlCmd.Flags().StringVar(&pageDataOutput, "page-data-output", "", "JSON file for secrets found in page data (default: log only)")
+	crawlCmd.Flags().StringVar(&mirrorsOutput, "mirrors-output", "", "JSON file listing the mirror domains skipped for each crawled domain (default: log only)")
+	addSnapshotFlags(crawlCmd, "Read the pages of a saved copy of the site (e.g. a wget mirror) instead of crawling it, sending no requests")
+	crawlCmd.Flags().StringVar(&subdomainsOutput, "subdomains-output", "", "Write the hostnames of pages and JS files seen under the --domain's root domain to this file")
 }
 
 func runCrawl(cmd *cobra.Command, args []string) error {
+	if err := validateChoice("sort", crawlSort, crawler.SortURL); err != nil {
+		return err
+	}
+
+	snapshot, err := openSnapshot()
+	if err != nil {
+		return err
+	}
+	if snapshot != nil && (domain != "" || auditHeaders || fingerprint || jsHistory > 0 || dedupeMirrors) {
+		return fmt.Errorf("--snapshot cannot be combined with --domain, --audit-headers, --fingerprint, --js-history or --dedupe-mirrors")
+	}
+	if jsHistory < 0 {
+		return fmt.Errorf("--js-history must not be negative, got %d", jsHistory)
+	}
+	if domain != "" {
+		if _, err := input.ParseDomain(domain); err != nil {
+			return err
+		}
+	}
+
+	stats := utils.NewRunStats()
+	defer reportStats(stats)
+	if err := openErrorLog(cmd); err != nil {
+		return err
+	}
+
 	config := &crawler.Config{
-		Domain:       domain,
-		OutputFile:   outputFile,
-		MaxDepth:     maxDepth,
-		Threads:      threads,
-		Timeout:      timeout,
-		IgnoreRobots: ignoreRobots,
-		Verbose:      verbose,
+		Domain:            domain,
+		OutputFile:        projectOutput(outputFile, utils.ProjectJSFiles),
+		MaxDepth:          maxDepth,
+		Threads:           threads,
+		Timeout:           timeout,
+		IgnoreRobots:      ignoreRobots,
+		Verbose:           verbose,
+		Stats:             stats,
+		Budget:            runBudget,
+		Window:            runWindow,
+		Shard:             runShard,
+		Scope:             runScope,
+		Identity:          runIdentity,
+		Auth:              runAuth,
+		UAFallback:        runUAFallback,
+		Timeouts:          runTimeouts,
+		Dialer:            runDialer,
+		Retry:             runRetry,
+		Memory:            runMemory,
+		Errors:            runErrors,
+		Labels:            runLabels,
+		Pause:             utils.NewPauseSwitch(),
+		AuditHeaders:      auditHeaders,
+		AuditOutput:       auditOutput,
+		Fingerprint:       fingerprint,
+		FingerprintOutput: fingerprintOutput,
+		GlobalTimeout:     globalTimeout,
+		Sort:              crawlSort,
+		Snapshot:          snapshot,
+		JSHistory:         jsHistory,
+		DedupeMirrors:     dedupeMirrors,
+	}
+
+	if auditHeaders {
+		config.AuditOutput = projectOutput(auditOutput, utils.ProjectEvidence, "security-headers.json")
+	}
+	if fingerprint {
+		config.FingerprintOutput = projectOutput(fingerprintOutput, utils.ProjectEvidence, "fingerprints.json")
+	}
+	if pageData {
+		config.PageData = scanner.New(&scanner.Config{Stats: stats, Labels: runLabels, Verbose: verbose})
+		if err := loadInstalledPack(config.PageData); err != nil {
+			return err
+		}
+		config.PageDataOutput = projectOutput(pageDataOutput, utils.ProjectEvidence, "page-data.json")
+	}
+	if dedupeMirrors {
+		config.MirrorsOutput = projectOutput(mirrorsOutput, utils.ProjectEvidence, "mirrors.json")
 	}
 
 	c := crawler.New(config)
 
+	if snapshot != nil {
+		if dryRun {
+			return writePlan(c.PlanSnapshot())
+		}
+		if err := c.CrawlSnapshot(); err != nil {
+			return err
+		}
+		return writeSubdomains(subdomainsOutput, snapshot.Base.String(), c.Hosts())
+	}
+
+	if dryRun {
+		if domain != "" {
+			return writePlan(c.Plan(strings.NewReader(domain)))
+		}
+		return writePlan(c.Plan(os.Stdin))
+	}
+
+	stopControl := startControl(config.Pause, stats)
+	defer stopControl()
+
 	if domain != "" {
 		// Single domain crawling
-		return c.CrawlDomain(domain)
+		err = c.CrawlDomain(domain)
 	} else {
 		// Batch processing from stdin
-		return c.CrawlFromStdin()
+		err = c.CrawlFromStdin()
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeSubdomains(subdomainsOutput, domain, c.Hosts())
+}
+
+// writeSubdomains writes the crawled hosts on the registrable domain of
+// target, one per line. Without a target every host is written.
+func writeSubdomains(path, target string, hosts []string) error {
+	if path == "" {
+		return nil
+	}
+
+	root := ""
+	if target != "" {
+		root = scope.RegistrableDomain(hostname(target))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create subdomains output file: %w", err)
+	}
+	defer file.Close()
+
+	for _, host := range hosts {
+		if root == "" || scope.RegistrableDomain(host) == root {
+			fmt.Fprintln(file, host)
+		}
+	}
+	return nil
+}
+
+// hostname returns the lowercase host of a URL, or of a bare host name
+func hostname(target string) string {
+	if !strings.Contains(target, "://") {
+		target = "//" + target
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return ""
 	}
+	return strings.ToLower(parsed.Hostname())
 }
\ No newline at end of file
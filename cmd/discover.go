@@ -1,8 +1,16 @@
 package cmd
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
 	"jsfinder/pkg/discovery"
+	"jsfinder/pkg/match"
+	"jsfinder/pkg/utils"
 )
 
 var discoverCmd = &cobra.Command{
@@ -24,6 +32,35 @@ var (
 	statusFilter       string
 	maxRedirects       int
 	userAgent          string
+	soft404Mode        string
+	stopCrossOrigin    bool
+	confirmThreshold   int64
+	assumeYes          bool
+	discoverSort       string
+	sessionCookies     bool
+	matchExpr          string
+	filterExpr         string
+	urlTemplate        string
+	fuzzHeaders        []string
+	rawRequestFile     string
+	rawRequestScheme   string
+	vhostTarget        string
+	vhostDomain        string
+	discoverRetry      string
+	discoverAllHosts   bool
+	collapseRoutes     bool
+	discoverOOB        bool
+	oobServer          string
+	oobToken           string
+	oobWait            time.Duration
+	corsCheck          bool
+	authParams         bool
+	corsOutput         string
+	discoverExport     string
+	exportDir          string
+	discoverCheckpoint string
+	stageWordlist      string
+	stageMinHits       int
 )
 
 func init() {
@@ -31,32 +68,251 @@ func init() {
 
 	discoverCmd.Flags().StringVarP(&discoverInputFile, "input", "i", "", "Input file containing JS file URLs")
 	discoverCmd.Flags().StringVarP(&discoverOutputFile, "output", "o", "", "Output file for discovered endpoints")
-	discoverCmd.Flags().StringVarP(&wordlistFile, "wordlist", "w", "", "Wordlist file for endpoint discovery")
+	discoverCmd.Flags().StringVarP(&wordlistFile, "wordlist", "w", "", "Wordlist file for endpoint discovery, or builtin:<name> for a bundled one")
 	discoverCmd.Flags().IntVarP(&discoverThreads, "threads", "t", 20, "Number of concurrent threads")
 	discoverCmd.Flags().IntVarP(&discoverTimeout, "timeout", "", 10, "Request timeout in seconds")
 	discoverCmd.Flags().StringVarP(&statusFilter, "status", "s", "200,201,202,204,301,302,307,308,401,403", "HTTP status codes to report (comma-separated)")
+	discoverCmd.Flags().StringVar(&matchExpr, "match", "", "Report responses matching this expression instead of --status (e.g. 'status in (200,201) and length > 100')")
+	discoverCmd.Flags().StringVar(&filterExpr, "filter", "", "Drop responses matching this expression (e.g. 'body contains \"error\"')")
+	discoverCmd.Flags().StringVar(&urlTemplate, "url-template", "", "Probe this URL per word instead of the fixed path variations, with FUZZ replaced by the word and BASE by each base host (e.g. https://BASE/api/FUZZ.json)")
+	discoverCmd.Flags().StringArrayVar(&fuzzHeaders, "fuzz-header", nil, "Header sent with --url-template probes, FUZZ and BASE are replaced too (e.g. \"X-Api-Version: FUZZ\"), repeatable")
+	discoverCmd.Flags().StringVar(&rawRequestFile, "request", "", "Send this raw HTTP request file (e.g. saved from Burp) per word exactly as written, with FUZZ replaced by the word and BASE by each base host")
+	discoverCmd.Flags().StringVar(&rawRequestScheme, "request-scheme", "https", "Scheme used to connect for --request: http or https")
+	discoverCmd.Flags().StringVar(&vhostTarget, "vhost", "", "Discover virtual hosts on this IP or base URL by sending each word as the Host header (e.g. https://203.0.113.10)")
+	discoverCmd.Flags().StringVar(&vhostDomain, "vhost-domain", "", "Domain appended to each word in --vhost mode (e.g. example.com tries admin.example.com)")
 	discoverCmd.Flags().IntVarP(&maxRedirects, "redirects", "r", 3, "Maximum number of redirects to follow")
 	discoverCmd.Flags().StringVarP(&userAgent, "user-agent", "u", "jsfinder/1.0", "User-Agent header")
+	discoverCmd.Flags().BoolVar(&stopCrossOrigin, "stop-cross-origin", false, "Do not follow redirects that leave the original origin")
+	discoverCmd.Flags().Int64Var(&confirmThreshold, "confirm-threshold", 100000, "Ask for confirmation when more requests than this are estimated (0 = never ask)")
+	discoverCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Proceed without confirmation when the request estimate exceeds the threshold")
+	discoverCmd.Flags().BoolVar(&sessionCookies, "session-cookies", false, "Capture the cookies each host sets on its base URL and replay them on probes of that host")
+	discoverCmd.Flags().StringVar(&soft404Mode, "soft404", "filter", "Soft-404 handling: filter, flag or off")
+	discoverCmd.Flags().StringVar(&discoverSort, "sort", "", "Sort results before writing: url or status (default: order found)")
+	discoverCmd.Flags().StringVar(&discoverRetry, "retry-failed", "", "Process only the JS files a previous discover run wrote to this --errors-file, merging the results into the existing JSON output")
+	discoverCmd.Flags().BoolVar(&discoverAllHosts, "all-hosts", false, "Probe every host named in the JS files, not only those on each file's registrable domain or allowed by --scope")
+	discoverCmd.Flags().BoolVar(&collapseRoutes, "collapse-routes", false, "Report one endpoint per route, treating numeric IDs and UUIDs in the path as placeholders (/users/{id}), and probe endpoints rebuilt from JS constants once per route")
+	discoverCmd.Flags().BoolVar(&discoverOOB, "oob", false, "Probe each endpoint found once more with interactsh callback hosts in common URL parameters and headers, and tag endpoints that trigger out-of-band DNS/HTTP requests")
+	discoverCmd.Flags().StringVar(&oobServer, "oob-server", utils.DefaultInteractshServer, "Interactsh server for --oob")
+	discoverCmd.Flags().StringVar(&oobToken, "oob-token", "", "Authorization token for --oob-server, or a secret reference (env:NAME, vault:..., aws-sm:...)")
+	discoverCmd.Flags().DurationVar(&oobWait, "oob-wait", 10*time.Second, "How long to wait for out-of-band interactions after the --oob probes")
+	discoverCmd.Flags().BoolVar(&authParams, "auth-params", false, "Retry endpoints answering 401/403 with each credential parameter name found in the JS files (up to 8 requests each) and tag those the server reacts to")
+	discoverCmd.Flags().BoolVar(&corsCheck, "cors-check", false, "Request each endpoint found again with Origin: "+discovery.CORSTestOrigin+" and report those reflecting it with credentials allowed")
+	discoverCmd.Flags().StringVar(&corsOutput, "cors-output", "", "JSON file for CORS misconfiguration findings (default: log only)")
+	discoverCmd.Flags().StringVar(&discoverExport, "export", "", "Also export the endpoints found in this format: openapi writes a skeleton OpenAPI document per host with the routes, methods, parameters and response shapes seen")
+	discoverCmd.Flags().StringVar(&exportDir, "export-dir", "", "Directory for --export files (default: openapi)")
+	discoverCmd.Flags().StringVar(&discoverCheckpoint, "checkpoint", "", "File to save probe progress and endpoints found to; an interrupted run started again with the same file, base URLs and wordlist resumes where it stopped")
+	discoverCmd.Flags().StringVar(&stageWordlist, "stage-wordlist", "", "Small wordlist, or builtin:<name>, probed on every base URL first; the --wordlist entries then only go to base URLs it found endpoints on")
+	discoverCmd.Flags().IntVar(&stageMinHits, "stage-min-hits", 1, "Endpoints --stage-wordlist must find on a base URL before the full wordlist is probed there")
+	addCSVFlags(discoverCmd)
 
 	// Make wordlist required
 	discoverCmd.MarkFlagRequired("wordlist")
+
+	discoverCmd.RegisterFlagCompletionFunc("wordlist", completeWordlist)
+	discoverCmd.RegisterFlagCompletionFunc("stage-wordlist", completeWordlist)
+	discoverCmd.RegisterFlagCompletionFunc("soft404", completeValues("filter", "flag", "off"))
+	discoverCmd.RegisterFlagCompletionFunc("sort", completeValues(discovery.SortURL, discovery.SortStatus))
+	discoverCmd.RegisterFlagCompletionFunc("request-scheme", completeValues("http", "https"))
+	discoverCmd.RegisterFlagCompletionFunc("export", completeValues(discovery.ExportOpenAPI))
 }
 
 func runDiscover(cmd *cobra.Command, args []string) error {
+	if err := validateChoice("sort", discoverSort, discovery.SortURL, discovery.SortStatus); err != nil {
+		return err
+	}
+	if err := validateChoice("request-scheme", rawRequestScheme, "http", "https"); err != nil {
+		return err
+	}
+	if err := validateChoice("export", discoverExport, discovery.ExportOpenAPI); err != nil {
+		return err
+	}
+	if discoverExport != "" && vhostTarget != "" {
+		return fmt.Errorf("--export cannot be combined with --vhost")
+	}
+
+	matchResponses, err := parseExpr("match", matchExpr)
+	if err != nil {
+		return err
+	}
+	filterResponses, err := parseExpr("filter", filterExpr)
+	if err != nil {
+		return err
+	}
+	csv, err := csvOptions(discovery.CSVHeader)
+	if err != nil {
+		return err
+	}
+
+	var retryURLs []string
+	if discoverRetry != "" {
+		if discoverInputFile != "" || vhostTarget != "" {
+			return fmt.Errorf("--retry-failed cannot be combined with --input or --vhost")
+		}
+		urls, err := failedURLs(discoverRetry, cmd.Name())
+		if err != nil {
+			return err
+		}
+		if len(urls) == 0 {
+			fmt.Fprintf(os.Stderr, "No failed discover URLs to retry in %s\n", discoverRetry)
+			return nil
+		}
+		retryURLs = urls
+	}
+
+	if stageWordlist == "" && cmd.Flags().Changed("stage-min-hits") {
+		return fmt.Errorf("--stage-min-hits requires --stage-wordlist")
+	}
+	if stageMinHits < 1 {
+		return fmt.Errorf("--stage-min-hits must be at least 1")
+	}
+	if vhostTarget != "" && stageWordlist != "" {
+		return fmt.Errorf("--stage-wordlist cannot be combined with --vhost")
+	}
+	if vhostTarget != "" && (discoverOOB || corsCheck) {
+		return fmt.Errorf("--oob and --cors-check cannot be combined with --vhost")
+	}
+	var oob string
+	if discoverOOB {
+		oob = oobServer
+		if !dryRun {
+			oobToken, err = utils.ResolveSecret(oobToken)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var template *discovery.Template
+	if vhostTarget != "" {
+		if urlTemplate != "" {
+			return fmt.Errorf("--vhost and --url-template cannot be combined")
+		}
+		template, err = discovery.VHostTemplate(vhostTarget, vhostDomain)
+		if err != nil {
+			return err
+		}
+	} else if urlTemplate != "" {
+		template, err = discovery.ParseTemplate(urlTemplate, fuzzHeaders)
+		if err != nil {
+			return err
+		}
+	} else if len(fuzzHeaders) > 0 {
+		return fmt.Errorf("--fuzz-header requires --url-template")
+	}
+
+	var rawRequest *discovery.RawRequest
+	if rawRequestFile != "" {
+		if template != nil {
+			return fmt.Errorf("--request cannot be combined with --url-template or --vhost")
+		}
+		if authParams {
+			return fmt.Errorf("--auth-params cannot be combined with --request")
+		}
+		data, err := os.ReadFile(rawRequestFile)
+		if err != nil {
+			return fmt.Errorf("failed to read raw request: %w", err)
+		}
+		rawRequest, err = discovery.ParseRawRequest(data, rawRequestScheme)
+		if err != nil {
+			return err
+		}
+	}
+
+	stats := utils.NewRunStats()
+	defer reportStats(stats)
+	if err := openErrorLog(cmd); err != nil {
+		return err
+	}
+
 	config := &discovery.Config{
-		InputFile:    discoverInputFile,
-		OutputFile:   discoverOutputFile,
-		WordlistFile: wordlistFile,
-		Threads:      discoverThreads,
-		Timeout:      discoverTimeout,
-		StatusFilter: statusFilter,
-		MaxRedirects: maxRedirects,
-		UserAgent:    userAgent,
-		Verbose:      verbose,
+		InputFile:        discoverInputFile,
+		OutputFile:       projectOutput(discoverOutputFile, utils.ProjectEndpoints),
+		WordlistFile:     wordlistFile,
+		Threads:          discoverThreads,
+		Timeout:          discoverTimeout,
+		StatusFilter:     statusFilter,
+		MaxRedirects:     maxRedirects,
+		UserAgent:        userAgent,
+		Verbose:          verbose,
+		Stats:            stats,
+		Budget:           runBudget,
+		Window:           runWindow,
+		Shard:            runShard,
+		Scope:            runScope,
+		Identity:         runIdentity,
+		Auth:             runAuth,
+		UAFallback:       runUAFallback,
+		Timeouts:         runTimeouts,
+		Dialer:           runDialer,
+		Memory:           runMemory,
+		Errors:           runErrors,
+		Labels:           runLabels,
+		Soft404:          soft404Mode,
+		StopCrossOrigin:  stopCrossOrigin,
+		ConfirmThreshold: confirmThreshold,
+		Confirm:          confirmLargeRun,
+		GlobalTimeout:    globalTimeout,
+		Sort:             discoverSort,
+		SessionCookies:   sessionCookies,
+		Match:            matchResponses,
+		Filter:           filterResponses,
+		Template:         template,
+		Raw:              rawRequest,
+		VHost:            vhostTarget != "",
+		CSV:              csv,
+		AllHosts:         discoverAllHosts,
+		CollapseRoutes:   collapseRoutes,
+		OOBServer:        oob,
+		OOBToken:         oobToken,
+		OOBWait:          oobWait,
+		CORSCheck:        corsCheck,
+		AuthParams:       authParams,
+		Checkpoint:       discoverCheckpoint,
+		StageWordlist:    stageWordlist,
+		StageMinHits:     stageMinHits,
+	}
+	if corsCheck {
+		config.CORSOutput = projectOutput(corsOutput, utils.ProjectEvidence, "cors.json")
+	}
+	if discoverExport == discovery.ExportOpenAPI {
+		config.OpenAPIDir = projectOutput(exportDir, utils.ProjectEvidence, "openapi")
+		if config.OpenAPIDir == "" {
+			config.OpenAPIDir = "openapi"
+		}
 	}
 
 	d := discovery.New(config)
 
+	if vhostTarget != "" {
+		if dryRun {
+			return writePlan(d.Plan(strings.NewReader("")))
+		}
+		return d.DiscoverVHosts()
+	}
+
+	if retryURLs != nil {
+		if dryRun {
+			return writePlan(d.Plan(strings.NewReader(strings.Join(retryURLs, "\n"))))
+		}
+		mergeOutput, err := preserveOutput(config.OutputFile, strings.HasSuffix(config.OutputFile, ".json"))
+		if err != nil {
+			return err
+		}
+		if err := d.DiscoverURLs(retryURLs); err != nil {
+			return err
+		}
+		return mergeOutput()
+	}
+
+	if dryRun {
+		input, err := openPlanInput(discoverInputFile)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+		return writePlan(d.Plan(input))
+	}
+
 	if discoverInputFile != "" {
 		// Discover from input file
 		return d.DiscoverFromFile(discoverInputFile)
@@ -64,4 +320,34 @@ func runDiscover(cmd *cobra.Command, args []string) error {
 		// Discover from stdin
 		return d.DiscoverFromStdin()
 	}
-}
\ No newline at end of file
+}
+
+// confirmLargeRun asks on the terminal rather than stdin, which may carry the input list
+func confirmLargeRun(total int64) bool {
+	if assumeYes {
+		return true
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "Send about %d requests? [y/N] ", total)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// parseExpr compiles a --match or --filter expression; empty means no expression
+func parseExpr(flag, source string) (*match.Expr, error) {
+	if source == "" {
+		return nil, nil
+	}
+	expr, err := match.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %w", flag, err)
+	}
+	return expr, nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate documentation",
+}
+
+var docsManCmd = &cobra.Command{
+	Use:   "man",
+	Short: "Generate man pages",
+	Long: `Generate a section 1 man page for jsfinder and each of its commands
+(jsfinder.1, jsfinder-scan.1, ...) from the command and flag definitions.`,
+	Example: `  jsfinder docs man --dir /usr/local/share/man/man1
+  man jsfinder-scan`,
+	Args: cobra.NoArgs,
+	RunE: runDocsMan,
+}
+
+var manDir string
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+	docsCmd.AddCommand(docsManCmd)
+
+	docsManCmd.Flags().StringVar(&manDir, "dir", "man", "Directory to write the man pages to")
+}
+
+func runDocsMan(cmd *cobra.Command, args []string) error {
+	if err := os.MkdirAll(manDir, 0755); err != nil {
+		return fmt.Errorf("failed to create man directory: %w", err)
+	}
+
+	// The date comes from SOURCE_DATE_EPOCH when set, for reproducible packages
+	header := &doc.GenManHeader{
+		Section: "1",
+		Source:  "jsfinder " + version,
+		Manual:  "jsfinder Manual",
+	}
+	rootCmd.DisableAutoGenTag = true
+	if err := doc.GenManTree(rootCmd, header, manDir); err != nil {
+		return fmt.Errorf("failed to write man pages: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote man pages to %s\n", manDir)
+	return nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"jsfinder/pkg/utils"
+)
+
+// openPlanInput opens the input list for a dry run, falling back to stdin
+func openPlanInput(inputFile string) (io.ReadCloser, error) {
+	if inputFile == "" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(inputFile)
+}
+
+// writePlan adds the resolved global settings to a dry-run plan and prints it
+func writePlan(plan *utils.RequestPlan, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if scopeFile != "" {
+		plan.AddSetting("Scope", scopeFile)
+	}
+	if runShard != nil {
+		plan.AddSetting("Shard", runShard)
+	}
+	if runWindow != nil {
+		plan.AddSetting("Run window", runWindow)
+	}
+	if runIdentity != nil {
+		plan.AddSetting("Identification", runIdentity)
+	}
+	if runAuth != nil {
+		plan.AddSetting("Authentication", runAuth)
+	}
+	if max := runBudget.MaxRequests(); max > 0 {
+		plan.AddSetting("Max requests", max)
+		if plan.Total() > max {
+			plan.AddNote("plan exceeds --max-requests; the run would stop after %d requests", max)
+		}
+	}
+
+	return plan.Write(os.Stdout)
+}
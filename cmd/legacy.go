@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// legacyFlags are the options of the original Python JSFinder that name a
+// legacy command line when it starts with one of them
+var legacyFlags = map[string]bool{
+	"-u": true, "--url": true,
+	"-d": true, "--deep": true,
+	"-ou": true, "--outputurl": true,
+	"-os": true, "--outputsubdomain": true,
+	"-c": true, "--cookie": true,
+	"-f": true, "--file": true,
+	"-j": true, "--js": true,
+}
+
+// translateLegacyArgs rewrites a Python JSFinder command line such as
+// "-u https://example.com -d -ou urls.txt -os subdomains.txt" into the
+// equivalent crawl command, so scripts written for the old tool keep working.
+// Arguments without a legacy option first are returned unchanged. Options
+// this tool also has (--threads, --verbose and so on) pass through.
+func translateLegacyArgs(args []string) ([]string, bool, error) {
+	if len(args) == 0 || !legacyFlags[args[0]] {
+		return args, false, nil
+	}
+
+	translated := []string{"crawl"}
+	depth := "0" // The Python tool only read the given page unless --deep was set
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("legacy option %s needs a value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch arg {
+		case "-u", "--url":
+			target, err := value()
+			if err != nil {
+				return nil, true, err
+			}
+			translated = append(translated, "--domain", target)
+		case "-d", "--deep":
+			depth = "1"
+		case "-ou", "--outputurl":
+			path, err := value()
+			if err != nil {
+				return nil, true, err
+			}
+			translated = append(translated, "--output", path)
+		case "-os", "--outputsubdomain":
+			path, err := value()
+			if err != nil {
+				return nil, true, err
+			}
+			translated = append(translated, "--subdomains-output", path)
+		case "-c", "--cookie":
+			cookie, err := value()
+			if err != nil {
+				return nil, true, err
+			}
+			translated = append(translated, "--id-header", "Cookie: "+cookie)
+		case "-f", "--file", "-j", "--js":
+			return nil, true, fmt.Errorf("legacy option %s is not supported: pipe page URLs into \"jsfinder crawl\", or JS file URLs into \"jsfinder scan\"", arg)
+		default:
+			translated = append(translated, arg)
+		}
+	}
+	return append(translated, "--depth", depth), true, nil
+}
+
+// legacyCommandLine formats translated arguments for the migration notice
+func legacyCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \t\"'") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		quoted[i] = arg
+	}
+	return "jsfinder " + strings.Join(quoted, " ")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"jsfinder/pkg/merge"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <file>...",
+	Short: "Merge and deduplicate results from multiple runs or shards",
+	Long: `Merge JSON findings and endpoints from multiple scan or discover runs
+(or shards of one run) into a single canonical result set. Duplicates are
+collapsed, the newest copy wins on conflicts and occurrence counts are kept.`,
+	Example: `  jsfinder merge out/*.json --format json -o merged.json
+  jsfinder merge shard-*/findings.json --format csv`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMerge,
+}
+
+var (
+	mergeFormat string
+	mergeOutput string
+)
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.Flags().StringVarP(&mergeFormat, "format", "f", "json", "Output format (json, csv)")
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "", "Output file for merged results (default: stdout)")
+	addCSVFlags(mergeCmd)
+
+	mergeCmd.RegisterFlagCompletionFunc("format", completeValues("json", "csv"))
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	csv, err := csvOptions(nil)
+	if err != nil {
+		return err
+	}
+
+	m := merge.New()
+	m.CSV = csv
+	for _, path := range args {
+		if err := m.AddFile(path); err != nil {
+			return err
+		}
+	}
+
+	var output io.Writer = os.Stdout
+	if mergeOutput != "" {
+		file, err := os.Create(mergeOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	if err := m.Write(output, mergeFormat); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Merged %d files into %d findings and %d endpoints\n", len(args), len(m.Findings()), len(m.Endpoints()))
+	if references := m.CrossReferences(); len(references) > 0 {
+		fmt.Fprintf(os.Stderr, "Matched %d leaked endpoints to discover results (see cross_references)\n", len(references))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jsfinder/pkg/scanner"
+	"jsfinder/pkg/utils"
+)
+
+var patternsCmd = &cobra.Command{
+	Use:   "patterns",
+	Short: "Manage the secret pattern pack",
+}
+
+var patternsUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Install the latest signed pattern pack",
+	Long: `Download the pattern pack, a versioned pattern file published by the project,
+and install it to ~/.jsfinder/patterns.yaml, where every scan loads it on top
+of the built-in patterns. Detection improves without upgrading jsfinder.
+
+The pack's ed25519 signature, published next to it with a .sig suffix, must
+verify against --public-key or scanner.pack_public_key from the config file,
+and every pattern must be valid, or nothing is installed. Scans verify the
+installed pack against scanner.pack_public_key, so --public-key must be that
+same key unless the pack is installed elsewhere with --output. An older pack than the installed one is
+refused unless --force is given.`,
+	Example: `  jsfinder patterns update
+  jsfinder patterns update --url https://intel.example.com/pack.yaml --output intel.yaml --public-key "$(cat pack.pub)"`,
+	Args: cobra.NoArgs,
+	RunE: runPatternsUpdate,
+}
+
+var patternsKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Create a key pair for signing pattern packs",
+	Long: `Create an ed25519 key pair for publishing your own pattern packs. The private
+key is written to --output, readable only by you; keep it out of the
+repository. The public key is printed for pack users to pass to patterns
+update --public-key, or to set as scanner.pack_public_key in the config file
+so scans verify the installed pack against it.`,
+	Example: `  jsfinder patterns keygen -o ~/.secrets/pack.key`,
+	Args:    cobra.NoArgs,
+	RunE:    runPatternsKeygen,
+}
+
+var patternsSignCmd = &cobra.Command{
+	Use:   "sign PACK",
+	Short: "Sign a pattern pack",
+	Long: `Check a pattern pack and write its signature next to it as PACK.sig, which
+patterns update fetches from the pack URL with .sig appended. The project's
+own pack, config/pattern-pack.yaml, is signed this way; bump its version
+whenever it changes.`,
+	Example: `  jsfinder patterns sign --key ~/.secrets/pack.key config/pattern-pack.yaml`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runPatternsSign,
+}
+
+var (
+	packURL       string
+	packPublicKey string
+	packOutput    string
+	packForce     bool
+	packKeyFile   string
+)
+
+func init() {
+	rootCmd.AddCommand(patternsCmd)
+	patternsCmd.AddCommand(patternsUpdateCmd)
+	patternsCmd.AddCommand(patternsKeygenCmd)
+	patternsCmd.AddCommand(patternsSignCmd)
+
+	patternsUpdateCmd.Flags().StringVar(&packURL, "url", scanner.DefaultPackURL, "URL of the pattern pack; its signature is fetched from the same URL with .sig appended")
+	patternsUpdateCmd.Flags().StringVar(&packPublicKey, "public-key", "", "Base64 ed25519 public key the pack must be signed with (default scanner.pack_public_key from the config file)")
+	patternsUpdateCmd.Flags().StringVarP(&packOutput, "output", "o", "", "Install the pack here instead of ~/.jsfinder/patterns.yaml")
+	patternsUpdateCmd.Flags().BoolVar(&packForce, "force", false, "Install the pack even if it is older than the installed one")
+
+	patternsKeygenCmd.Flags().StringVarP(&packKeyFile, "output", "o", "", "File to write the private key to")
+	patternsKeygenCmd.MarkFlagRequired("output")
+	patternsSignCmd.Flags().StringVar(&packKeyFile, "key", "", "Private key file written by patterns keygen")
+	patternsSignCmd.MarkFlagRequired("key")
+}
+
+// packKey returns the public key pattern packs are verified with: flag when
+// set, else scanner.pack_public_key from the config file. There is no
+// built-in key, so one of them is required.
+func packKey(flag string) (ed25519.PublicKey, error) {
+	key := flag
+	if key == "" && appConfig != nil {
+		key = appConfig.Scanner.PackPublicKey
+	}
+	if key == "" {
+		return nil, fmt.Errorf("no key to verify pattern packs with: pass --public-key or set scanner.pack_public_key in the config file")
+	}
+	return scanner.ParsePublicKey(key)
+}
+
+func runPatternsUpdate(cmd *cobra.Command, args []string) error {
+	publicKey, err := packKey(packPublicKey)
+	if err != nil {
+		return err
+	}
+	path := packOutput
+	if path == "" {
+		// Scans verify the pack they load with the config file's key, so a
+		// pack signed with another key would be skipped by every scan
+		if scanKey, err := packKey(""); err != nil || !scanKey.Equal(publicKey) {
+			return fmt.Errorf("--public-key differs from scanner.pack_public_key, which scans verify the installed pack with; set that key in the config file first, or install elsewhere with --output")
+		}
+		if path, err = scanner.DefaultPackPath(); err != nil {
+			return err
+		}
+	}
+	installed, err := scanner.InstalledPack(path, publicKey)
+	if err != nil {
+		// A pack that no longer verifies is replaced, not trusted for its version
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+		installed = nil
+	}
+
+	client := utils.NewHTTPClient(&utils.ClientOptions{Timeout: 30 * time.Second, Identity: runIdentity})
+	pack, err := scanner.FetchPack(context.Background(), client, packURL, publicKey)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if installed != nil && !packForce {
+		switch {
+		case installed.Version == pack.Version:
+			fmt.Fprintf(out, "Pattern pack %s is already installed in %s\n", pack.Version, path)
+			return nil
+		case utils.NewerVersion(pack.Version, installed.Version):
+			return fmt.Errorf("pattern pack %s is older than the installed %s; use --force to install it anyway", pack.Version, installed.Version)
+		}
+	}
+
+	if err := pack.Install(path); err != nil {
+		return err
+	}
+	if installed != nil {
+		fmt.Fprintf(out, "Updated pattern pack %s -> %s in %s\n", installed.Version, pack.Version, path)
+	} else {
+		fmt.Fprintf(out, "Installed pattern pack %s in %s\n", pack.Version, path)
+	}
+	return nil
+}
+
+func runPatternsKeygen(cmd *cobra.Command, args []string) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(privateKey) + "\n"
+	if err := os.WriteFile(packKeyFile, []byte(encoded), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote private key to %s\nPublic key: %s\n", packKeyFile, base64.StdEncoding.EncodeToString(publicKey))
+	return nil
+}
+
+func runPatternsSign(cmd *cobra.Command, args []string) error {
+	encoded, err := os.ReadFile(packKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+	privateKey, err := scanner.ParsePrivateKey(string(encoded))
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read pattern pack: %w", err)
+	}
+	signature, err := scanner.SignPack(data, privateKey)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(args[0]+".sig", []byte(signature), 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Signed %s with the key for %s\n", args[0], base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)))
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime/trace"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pprofAddr string
+	traceFile string
+	traceOut  *os.File
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&pprofAddr, "pprof", "", "Serve net/http/pprof on this address for the run (e.g. :6060); without a host it listens on 127.0.0.1 only")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace", "", "Write a runtime execution trace of the run to this file (view with go tool trace)")
+
+	cobra.OnFinalize(stopProfiling)
+}
+
+// startProfiling starts the pprof server and execution trace requested on
+// the command line. The pprof listener is bound before returning so a busy
+// port fails the run instead of silently disabling profiling.
+func startProfiling() error {
+	if pprofAddr != "" {
+		listener, err := net.Listen("tcp", pprofListenAddr(pprofAddr))
+		if err != nil {
+			return fmt.Errorf("failed to start pprof server: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Serving pprof on http://%s/debug/pprof/\n", listener.Addr())
+		go http.Serve(listener, http.DefaultServeMux)
+	}
+
+	if traceFile != "" {
+		file, err := os.Create(traceFile)
+		if err != nil {
+			return fmt.Errorf("failed to create trace file: %w", err)
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to start trace: %w", err)
+		}
+		traceOut = file
+	}
+
+	return nil
+}
+
+// pprofListenAddr binds addresses without a host, such as ":6060" or "6060",
+// to the loopback interface: the profiles expose memory contents, so serving
+// them on other interfaces needs an explicit host such as 0.0.0.0
+func pprofListenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = "", addr
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// stopProfiling flushes the execution trace once the command has finished,
+// whether it succeeded or not
+func stopProfiling() {
+	if traceOut == nil {
+		return
+	}
+	trace.Stop()
+	traceOut.Close()
+	fmt.Fprintf(os.Stderr, "Execution trace written to %s\n", traceOut.Name())
+	traceOut = nil
+}
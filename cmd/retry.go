@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"jsfinder/pkg/merge"
+	"jsfinder/pkg/utils"
+)
+
+// failedURLs reads the URLs a previous run of command could not process from
+// its --errors-file. Out-of-scope URLs are left out, since a rerun would
+// refuse them again.
+func failedURLs(path, command string) ([]string, error) {
+	records, err := utils.ReadErrorLog(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, record := range records {
+		if record.Command != command || record.Kind == utils.ErrorKindScope || seen[record.URL] {
+			continue
+		}
+		seen[record.URL] = true
+		urls = append(urls, record.URL)
+	}
+	return urls, nil
+}
+
+// preserveOutput keeps the results of the run being retried. It reads the
+// existing output file and returns a function that, once the retry has
+// written its own results, merges both back into the file. Only JSON output
+// can be merged.
+func preserveOutput(path string, isJSON bool) (func() error, error) {
+	info, err := os.Stat(path)
+	if path == "" || os.IsNotExist(err) {
+		return func() error { return nil }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !isJSON {
+		return nil, fmt.Errorf("--retry-failed can only merge into JSON output, and %s exists in another format", path)
+	}
+
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return func() error {
+		current, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if bytes.Equal(current, previous) {
+			return nil
+		}
+
+		m := merge.New()
+		if err := m.Add(previous, info.ModTime()); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := m.Add(current, time.Now()); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+
+		if err := m.Write(file, "json"); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Merged retried results into %s (%d findings, %d endpoints)\n", path, len(m.Findings()), len(m.Endpoints()))
+		return nil
+	}, nil
+}
@@ -1,7 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"jsfinder/pkg/scope"
+	"jsfinder/pkg/utils"
 )
 
 var rootCmd = &cobra.Command{
@@ -14,15 +23,451 @@ It provides three main commands:
 - crawl: Crawl domains and extract JS files
 - scan: Scan JS files for secrets and API keys
 - discover: Brute-force endpoints using wordlists`,
+	PersistentPreRunE: setupGlobals,
 }
 
+var (
+	statsJSON     bool
+	maxRequests   int64
+	maxBandwidth  string
+	runBudget     *utils.Budget
+	runWindowStr  string
+	runWindow     *utils.RunWindow
+	shardSpec     string
+	runShard      *utils.Shard
+	scopeFile     string
+	runScope      *scope.Scope
+	idHeaders     []string
+	contact       string
+	appConfig     *utils.Config
+	runIdentity   *utils.Identity
+	authBasic     string
+	authBearer    string
+	runAuth       *utils.Auth
+	logFormat     string
+	logLevels     string
+	logSample     int
+	dryRun        bool
+	uaFallback    bool
+	runUAFallback *utils.UAFallback
+	runTimeouts   *utils.ClientTimeouts
+	dialTarget    string
+	unixSocket    string
+	runDialer     *utils.Dialer
+	runRetry      utils.RetryPolicies
+	projectName   string
+	runProject    *utils.Project
+	runStarted    time.Time
+	runFlags      map[string]string
+	runArgs       []string // Command line after legacy options are translated
+	runCommand    string
+	globalTimeout time.Duration
+	jsTimeout     time.Duration
+	maxMemory     string
+	runMemory     *utils.MemoryGuard
+	errorsFile    string
+	runErrors     *utils.ErrorLog
+	csvColumns    []string
+	csvEscape     bool
+	snapshotDir   string
+	snapshotBase  string
+	labelSpecs    []string
+	runLabels     utils.Labels
+)
+
+// Build information, set at build time with
+// -ldflags "-X jsfinder/cmd.version=v1.2.3 -X jsfinder/cmd.commit=abc1234 -X jsfinder/cmd.buildDate=2024-01-02T15:04:05Z"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
+	args, legacy, err := translateLegacyArgs(os.Args[1:])
+	if err != nil {
+		return err
+	}
+	// Legacy -c cookies become --id-header, so the run record masks them too
+	runArgs = args
+	if legacy {
+		fmt.Fprintf(os.Stderr, "Translated legacy JSFinder options to: %s\n", legacyCommandLine(redactArgs(args)))
+		rootCmd.SetArgs(args)
+	}
 	return rootCmd.Execute()
 }
 
 func init() {
 	// Global flags can be added here
-	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output: per-file progress logged at debug level (same as --log-level debug)")
 	rootCmd.PersistentFlags().StringP("config", "c", "", "Config file (default is ./config.yaml)")
-}
\ No newline at end of file
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the request plan and exit without sending any traffic")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logLevels, "log-level", "", "Log levels, globally and per module (e.g. warn,crawler=debug,scanner=warn)")
+	rootCmd.PersistentFlags().IntVar(&logSample, "log-sample", 0, "Only log every Nth repetition of identical messages (0 = log all)")
+	rootCmd.PersistentFlags().BoolVar(&statsJSON, "stats", false, "Emit the run summary as JSON")
+	rootCmd.PersistentFlags().Int64Var(&maxRequests, "max-requests", 0, "Stop after this many HTTP requests (0 = unlimited)")
+	rootCmd.PersistentFlags().StringVar(&maxBandwidth, "max-bandwidth", "", "Stop after downloading this much data (e.g. 500MB)")
+	rootCmd.PersistentFlags().StringVar(&scopeFile, "scope", "", "Scope file with allow/deny host and CIDR rules")
+	rootCmd.PersistentFlags().StringVar(&shardSpec, "shard", "", "Only process shard N of M of the input list (e.g. 2/5)")
+	rootCmd.PersistentFlags().StringArrayVar(&idHeaders, "id-header", nil, "Identification header sent with every request (e.g. \"X-Bug-Bounty: handle\"), repeatable")
+	rootCmd.PersistentFlags().BoolVar(&uaFallback, "ua-fallback", false, "Retry hosts answering 403/406 twice in a row once with browser User-Agent and Accept headers")
+	rootCmd.PersistentFlags().StringVar(&contact, "contact", "", "Researcher contact appended to the User-Agent")
+	rootCmd.PersistentFlags().StringVar(&authBasic, "auth-basic", "", "Send HTTP basic auth credentials with every request (user:password, or a secret reference such as env:NAME)")
+	rootCmd.PersistentFlags().StringVar(&authBearer, "auth-bearer", "", "Send this bearer token in the Authorization header of every request (or a secret reference such as env:NAME)")
+	rootCmd.PersistentFlags().StringVar(&projectName, "project", "", "Write outputs, logs and run metadata under <name>/<date>/")
+	rootCmd.PersistentFlags().DurationVar(&globalTimeout, "global-timeout", 0, "Stop the run after this long, cancelling stuck downloads and probes (e.g. 2h; crawl defaults to 10m, scan and discover to no limit)")
+	rootCmd.PersistentFlags().DurationVar(&jsTimeout, "js-timeout", 0, "Overall timeout for JavaScript downloads, which can be tens of MB, instead of --timeout (e.g. 5m; overrides timeouts.js)")
+	rootCmd.PersistentFlags().StringVar(&dialTarget, "dial", "", "Open every connection through this dialer instead of directly: ssh://[user@]bastion[:port] runs ssh -W through the bastion, unix:///path connects to a Unix socket")
+	rootCmd.PersistentFlags().StringVar(&unixSocket, "unix-socket", "", "Send every request over this Unix domain socket, keeping the target URL's Host (same as --dial unix:///path)")
+	rootCmd.PersistentFlags().StringVar(&maxMemory, "max-memory", "", "Hold new work and spill queues to disk as memory use nears this limit (e.g. 2GB)")
+	rootCmd.PersistentFlags().StringVar(&runWindowStr, "run-window", "", "Only send traffic inside this daily window (e.g. 22:00-06:00)")
+	rootCmd.PersistentFlags().StringVar(&errorsFile, "errors-file", "", "Write every URL that failed (DNS, timeout, TLS, HTTP errors) to this JSON Lines file")
+	rootCmd.PersistentFlags().StringArrayVar(&labelSpecs, "label", nil, "Attach key=value to every finding, endpoint and evidence record the run writes (e.g. team=payments), repeatable")
+
+	rootCmd.RegisterFlagCompletionFunc("log-format", completeValues("text", "json"))
+}
+
+// setupGlobals resolves the global flags shared by all commands
+func setupGlobals(cmd *cobra.Command, args []string) error {
+	format, err := utils.ParseLogFormat(logFormat)
+	if err != nil {
+		return err
+	}
+	utils.SetGlobalFormat(format)
+	levels := logLevels
+	if levels == "" && verbose {
+		levels = "debug"
+	}
+	if err := utils.SetModuleLevels(levels); err != nil {
+		return err
+	}
+	utils.SetGlobalSampling(logSample)
+
+	configPath, _ := cmd.Flags().GetString("config")
+	appConfig, err = utils.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	runIdentity, err = utils.NewIdentity(appConfig.Identity, idHeaders, contact)
+	if err != nil {
+		return err
+	}
+	if runIdentity != nil {
+		fmt.Fprintf(os.Stderr, "Identifying requests with %s\n", runIdentity)
+	}
+
+	runAuth, err = utils.NewAuth(authBasic, authBearer)
+	if err != nil {
+		return err
+	}
+	if runAuth != nil {
+		fmt.Fprintf(os.Stderr, "Authenticating requests with %s\n", runAuth)
+	}
+
+	runDialer, err = utils.NewDialer(dialTarget, unixSocket)
+	if err != nil {
+		return err
+	}
+	if runDialer != nil {
+		fmt.Fprintf(os.Stderr, "Connecting through %s\n", runDialer)
+	}
+
+	runLabels, err = utils.ParseLabels(labelSpecs)
+	if err != nil {
+		return err
+	}
+
+	if jsTimeout != 0 {
+		appConfig.Timeouts.JS = jsTimeout
+	}
+	if err := appConfig.Timeouts.Validate(); err != nil {
+		return err
+	}
+	runTimeouts = &appConfig.Timeouts
+	if err := appConfig.Retry.Validate(); err != nil {
+		return err
+	}
+	runRetry = appConfig.Retry
+
+	if uaFallback {
+		runUAFallback = utils.NewUAFallback()
+	}
+
+	maxBytes, err := utils.ParseByteSize(maxBandwidth)
+	if err != nil {
+		return err
+	}
+
+	if maxRequests > 0 || maxBytes > 0 {
+		runBudget = utils.NewBudget(maxRequests, maxBytes)
+	}
+
+	memoryLimit, err := utils.ParseByteSize(maxMemory)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		runMemory = utils.NewMemoryGuard(memoryLimit)
+		runMemory.Start()
+	}
+
+	runWindow, err = utils.ParseRunWindow(runWindowStr)
+	if err != nil {
+		return err
+	}
+
+	runShard, err = utils.ParseShard(shardSpec)
+	if err != nil {
+		return err
+	}
+
+	if scopeFile != "" {
+		runScope, err = scope.Load(scopeFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := startProfiling(); err != nil {
+		return err
+	}
+
+	runStarted = time.Now()
+	if !dryRun {
+		if err := setupProject(cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setupProject creates the --project run directory, tees logs into it and
+// snapshots the effective flags for the run record
+func setupProject(cmd *cobra.Command) error {
+	var err error
+	runProject, err = utils.NewProject(projectName, runStarted)
+	if err != nil || runProject == nil {
+		return err
+	}
+
+	logFile, err := runProject.OpenLog(commandName(cmd), runStarted)
+	if err != nil {
+		return fmt.Errorf("failed to open project log: %w", err)
+	}
+	utils.SetGlobalOutput(io.MultiWriter(os.Stderr, logFile))
+
+	runCommand = commandName(cmd)
+	runFlags = make(map[string]string)
+	snapshot := func(flag *pflag.Flag) {
+		if flag.Name != "help" {
+			runFlags[flag.Name] = snapshotFlag(flag)
+		}
+	}
+	cmd.Flags().VisitAll(snapshot)
+	cmd.InheritedFlags().VisitAll(snapshot)
+
+	fmt.Fprintf(os.Stderr, "Writing project files to %s\n", runProject.Dir)
+	return nil
+}
+
+// validateChoice checks a flag value against the values it accepts; empty is always allowed
+func validateChoice(flag, value string, choices ...string) error {
+	if value == "" {
+		return nil
+	}
+	for _, choice := range choices {
+		if value == choice {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --%s %q, expected one of: %s", flag, value, strings.Join(choices, ", "))
+}
+
+// projectOutput returns the output path for a command: the explicit flag
+// value if given, otherwise the standard file inside the --project directory
+func projectOutput(explicit string, elem ...string) string {
+	if explicit != "" || runProject == nil {
+		return explicit
+	}
+	return runProject.Path(elem...)
+}
+
+// addCSVFlags registers the CSV output flags shared by scan, discover, merge and stats
+func addCSVFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&csvColumns, "columns", nil, "Only write these CSV columns, in this order (e.g. url,type,match)")
+	cmd.Flags().BoolVar(&csvEscape, "escape-formulas", false, "Prefix CSV cells starting with =, +, -, @, tab or CR with ' so spreadsheets do not run them as formulas")
+}
+
+// csvOptions returns the CSV output options, checking --columns against the
+// header of the records being written. Merge passes a nil header, since it
+// only knows which records it writes once its inputs are read.
+func csvOptions(header []string) (*utils.CSVOptions, error) {
+	if len(csvColumns) == 0 && !csvEscape {
+		return nil, nil
+	}
+
+	options := &utils.CSVOptions{Columns: csvColumns, EscapeFormulas: csvEscape}
+	if header != nil {
+		if err := options.ValidateColumns(header); err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// addSnapshotFlags registers the offline snapshot flags shared by crawl and scan
+func addSnapshotFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVar(&snapshotDir, "snapshot", "", usage)
+	cmd.Flags().StringVar(&snapshotBase, "base-url", "", "URL the --snapshot was saved from (default: https:// plus the directory name, e.g. example.com)")
+}
+
+// openSnapshot returns the --snapshot directory, or nil when none was given
+func openSnapshot() (*utils.Snapshot, error) {
+	if snapshotDir == "" {
+		if snapshotBase != "" {
+			return nil, fmt.Errorf("--base-url requires --snapshot")
+		}
+		return nil, nil
+	}
+	return utils.NewSnapshot(snapshotDir, snapshotBase)
+}
+
+// commandName names a command in logs, run records and file names: its name,
+// prefixed by its parent for subcommands such as wordlist-gen
+func commandName(cmd *cobra.Command) string {
+	if cmd.HasParent() && cmd.Parent().HasParent() {
+		return cmd.Parent().Name() + "-" + cmd.Name()
+	}
+	return cmd.Name()
+}
+
+// openErrorLog creates the failed-URL log of a crawl, scan or discover run:
+// the --errors-file path, or <command>-errors.jsonl in the project directory
+func openErrorLog(cmd *cobra.Command) error {
+	if dryRun {
+		return nil
+	}
+
+	var err error
+	runErrors, err = utils.NewErrorLog(projectOutput(errorsFile, commandName(cmd)+"-errors.jsonl"), commandName(cmd))
+	runErrors.SetLabels(runLabels)
+	return err
+}
+
+// reportStats writes the run summary to stderr so it never mixes with results on stdout
+func reportStats(stats *utils.RunStats) {
+	if dryRun {
+		return
+	}
+	stats.Finish()
+	if runMemory != nil {
+		runMemory.Stop()
+		stats.SetPeakMemory(runMemory.Peak())
+	}
+	if runUAFallback != nil {
+		for host, agent := range runUAFallback.Results() {
+			stats.SetHostUserAgent(host, agent)
+		}
+	}
+	if statsJSON {
+		stats.WriteJSON(os.Stderr)
+	} else {
+		stats.WriteSummary(os.Stderr)
+	}
+	if runErrors != nil {
+		runErrors.Close()
+		if failed := runErrors.Count(); failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d failed URLs written to %s\n", failed, runErrors.Path())
+		}
+	}
+
+	if runProject != nil {
+		err := runProject.RecordRun(utils.RunRecord{
+			Command:  runCommand,
+			Version:  version,
+			Args:     redactArgs(runArgs),
+			Flags:    runFlags,
+			Labels:   runLabels,
+			Started:  runStarted,
+			Finished: time.Now(),
+			Stats:    stats.Snapshot(),
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// credentialFlags are the flags whose values are kept out of run records.
+// Header flags only have the values of credential headers masked, see
+// redactHeader.
+var credentialFlags = map[string]bool{"auth-basic": true, "auth-bearer": true, "oob-token": true, "monitor-webhook": true, "id-header": true}
+
+// credentialHeaderWords mark header names whose values are credentials, such
+// as Cookie, Authorization or X-Api-Key
+var credentialHeaderWords = []string{"auth", "cookie", "token", "key", "secret", "session", "password"}
+
+// redactFlag masks the value of a credential flag, unless it is a secret
+// reference naming where the credential is stored
+func redactFlag(name, value string) string {
+	if name == "id-header" {
+		return redactHeader(value)
+	}
+	if credentialFlags[name] && value != "" && !utils.IsSecretRef(value) {
+		return "<secret>"
+	}
+	return value
+}
+
+// redactHeader masks the value of a "Name: value" header when its name marks
+// a credential, keeping the name so the record still shows what was sent
+func redactHeader(header string) string {
+	name, value, found := strings.Cut(header, ":")
+	value = strings.TrimSpace(value)
+	if !found || value == "" || utils.IsSecretRef(value) {
+		return header
+	}
+	lower := strings.ToLower(name)
+	for _, word := range credentialHeaderWords {
+		if strings.Contains(lower, word) {
+			return name + ": <secret>"
+		}
+	}
+	return header
+}
+
+// snapshotFlag returns a flag's value for the run record, with credentials
+// masked
+func snapshotFlag(flag *pflag.Flag) string {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok && credentialFlags[flag.Name] {
+		values := slice.GetSlice()
+		for i, value := range values {
+			values[i] = redactFlag(flag.Name, value)
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+	return redactFlag(flag.Name, flag.Value.String())
+}
+
+// redactArgs masks the values of credential flags in a command line
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		redacted[i] = args[i]
+		name, value, inline := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "--") || !credentialFlags[name] {
+			continue
+		}
+		if inline {
+			redacted[i] = "--" + name + "=" + redactFlag(name, value)
+		} else if i+1 < len(args) {
+			i++
+			redacted[i] = redactFlag(name, args[i])
+		}
+	}
+	return redacted
+}
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
 	"jsfinder/pkg/scanner"
+	"jsfinder/pkg/utils"
 )
 
 var scanCmd = &cobra.Command{
@@ -11,42 +17,304 @@ var scanCmd = &cobra.Command{
 	Long: `Scan JavaScript files for secrets, API keys, tokens, and other sensitive information.
 Supports both file input and stdin for batch processing.`,
 	Example: `  jsfinder scan --input jsfiles.txt --output secrets.json
-  cat jsfiles.txt | jsfinder scan --output secrets.json`,
+  cat jsfiles.txt | jsfinder scan --output secrets.json
+  curl -s https://example.com/bundle.js | jsfinder scan --stdin-content -f txt
+  jsfinder scan --archive app.apk --output secrets.json
+  jsfinder scan --dir ./webapp --sort recent --output secrets.json
+  jsfinder scan --npm @acme/sdk@2.1.0 --output secrets.json
+  GITHUB_TOKEN=... jsfinder scan --github acme-corp --output secrets.json`,
 	RunE: runScan,
 }
 
 var (
-	scanInputFile  string
-	scanOutputFile string
-	scanThreads    int
-	scanTimeout    int
-	configFile     string
-	format         string
+	scanInputFile   string
+	scanArchive     string
+	scanDir         string
+	scanNpm         string
+	npmRegistry     string
+	scanGitHub      string
+	probeSockets    bool
+	remediation     bool
+	reportFragments string
+	scanMonitor     string
+	monitorWebhook  string
+	monitorDigest   string
+	scanNoSkip      bool
+	scanExcludeURLs []string
+	scanOutputFile  string
+	scanThreads     int
+	scanTimeout     int
+	configFile      string
+	format          string
+	scanSort        string
+	scanRetry       string
+	scanSplit       bool
+	scanSIEM        string
+	siemFormat      string
+	scanCacheSize   string
+	stdinContent    bool
+	stdinName       string
+	scanOnly        []string
+	scanDisable     []string
+	scanLiterals    bool
 )
 
 func init() {
 	rootCmd.AddCommand(scanCmd)
 
 	scanCmd.Flags().StringVarP(&scanInputFile, "input", "i", "", "Input file containing JS file URLs")
+	scanCmd.Flags().StringVarP(&scanArchive, "archive", "a", "", "Scan JS/HTML assets inside an .apk, .ipa or .zip archive instead of URLs")
+	scanCmd.Flags().StringVar(&scanDir, "dir", "", "Scan the JS/TS/HTML files under a local directory, annotating findings with git blame when it is a git work tree")
+	scanCmd.Flags().BoolVar(&stdinContent, "stdin-content", false, "Scan stdin as JavaScript/HTML content itself instead of a list of URLs")
+	scanCmd.Flags().StringVar(&stdinName, "stdin-name", "stdin", "Name findings from --stdin-content are reported under, e.g. the file's path")
+	scanCmd.Flags().StringVar(&scanNpm, "npm", "", "Download and scan an npm package (name[@version])")
+	scanCmd.Flags().StringVar(&npmRegistry, "npm-registry", scanner.DefaultNpmRegistry, "npm registry used by --npm")
+	scanCmd.Flags().StringVar(&scanGitHub, "github", "", "Scan the JS/TS sources of a GitHub repository (owner/repo) or every repository of an owner; uses GITHUB_TOKEN or the config's tokens.github if set")
 	scanCmd.Flags().StringVarP(&scanOutputFile, "output", "o", "", "Output file for scan results")
 	scanCmd.Flags().IntVarP(&scanThreads, "threads", "t", 10, "Number of concurrent threads")
 	scanCmd.Flags().IntVarP(&scanTimeout, "timeout", "", 30, "Request timeout in seconds")
-	scanCmd.Flags().StringVarP(&configFile, "config", "c", "", "Config file with regex patterns")
+	scanCmd.Flags().StringVarP(&configFile, "config", "c", "", "Pattern file adding to or overriding the built-in regex patterns")
+	scanCmd.Flags().StringSliceVar(&scanOnly, "only", nil, "Only scan with these pattern groups or pattern names (e.g. aws,github,jwt)")
+	scanCmd.Flags().StringSliceVar(&scanDisable, "disable", nil, "Leave out these pattern groups or pattern names (e.g. password,secret)")
+	scanCmd.Flags().BoolVar(&scanLiterals, "literals", false, "Match secret patterns against the string and template literals of JS files instead of raw lines, ignoring comments and variable names")
+	scanCmd.Flags().BoolVar(&probeSockets, "probe-websockets", false, "Attempt an unauthenticated handshake with each WebSocket URL found")
+	scanCmd.Flags().BoolVar(&remediation, "remediation", false, "Include remediation, rotation steps and documentation links with each finding")
+	scanCmd.Flags().StringVar(&reportFragments, "report-fragments", "", "Directory to write a Markdown ticket body to per credential finding, with the evidence (secret masked), affected URL and rotation steps")
+	scanCmd.Flags().BoolVar(&scanNoSkip, "no-skip", false, "Also scan binary files and known analytics/tag-manager bundles")
+	scanCmd.Flags().StringArrayVar(&scanExcludeURLs, "exclude-url", nil, "Neither download nor report URLs matching this glob, where * also matches slashes (e.g. '*/vendor/*', '*.min.js'), repeatable")
+	scanCmd.Flags().StringVar(&scanRetry, "retry-failed", "", "Rescan only the URLs a previous scan wrote to this --errors-file, merging the results into the existing JSON output")
 	scanCmd.Flags().StringVarP(&format, "format", "f", "json", "Output format (json, csv, txt)")
+	scanCmd.Flags().StringVar(&scanSort, "sort", "", "Sort results before writing: url, severity or recent (newest git blame first, with --dir) (default: order found)")
+	scanCmd.Flags().BoolVar(&scanSplit, "split-by-severity", false, "Write findings to high, medium and low files inside the --output directory")
+	scanCmd.Flags().StringVar(&scanCacheSize, "cache-size", "64MB", "Keep up to this much downloaded JS in memory so URLs listed more than once are fetched once (0 disables)")
+	scanCmd.Flags().StringVar(&scanMonitor, "monitor", "", "State file of the scanned JS files' hashes and strings; each file whose content changed since the previous run is reported with the strings added and removed and any new findings")
+	scanCmd.Flags().StringVar(&monitorWebhook, "monitor-webhook", "", "POST each change found with --monitor to this URL as JSON (with a text summary for chat webhooks), or a secret reference (env:NAME, vault:..., aws-sm:...)")
+	scanCmd.Flags().StringVar(&monitorDigest, "monitor-digest", "", "Instead of each change, POST one summary per target of the new JS files, endpoints and findings to --monitor-webhook once a period is over (daily or weekly)")
+	scanCmd.Flags().StringVar(&scanSIEM, "siem", "", "Also send each finding to a SIEM or syslog collector (udp://, tcp:// or tls://host[:port])")
+	scanCmd.Flags().StringVar(&siemFormat, "siem-format", scanner.SIEMFormatCEF, "Message format for --siem: cef or syslog (RFC 5424)")
+
+	addCSVFlags(scanCmd)
+	addSnapshotFlags(scanCmd, "Read each listed JS URL from a saved copy of the site (e.g. a wget mirror) instead of downloading it")
+
+	scanCmd.RegisterFlagCompletionFunc("format", completeValues("json", "csv", "txt"))
+	scanCmd.RegisterFlagCompletionFunc("sort", completeValues(scanner.SortURL, scanner.SortSeverity, scanner.SortRecent))
+	scanCmd.RegisterFlagCompletionFunc("only", completeValues(scanner.PatternGroupNames()...))
+	scanCmd.RegisterFlagCompletionFunc("disable", completeValues(scanner.PatternGroupNames()...))
+	scanCmd.RegisterFlagCompletionFunc("siem-format", completeValues(scanner.SIEMFormatCEF, scanner.SIEMFormatSyslog))
+	scanCmd.RegisterFlagCompletionFunc("monitor-digest", completeValues(scanner.DigestDaily, scanner.DigestWeekly))
 }
 
 func runScan(cmd *cobra.Command, args []string) error {
+	if err := validateChoice("sort", scanSort, scanner.SortURL, scanner.SortSeverity, scanner.SortRecent); err != nil {
+		return err
+	}
+	if err := validateChoice("siem-format", siemFormat, scanner.SIEMFormatCEF, scanner.SIEMFormatSyslog); err != nil {
+		return err
+	}
+	if monitorWebhook != "" && scanMonitor == "" {
+		return fmt.Errorf("--monitor-webhook requires --monitor")
+	}
+	if err := validateChoice("monitor-digest", monitorDigest, scanner.DigestDaily, scanner.DigestWeekly); err != nil {
+		return err
+	}
+	if monitorDigest != "" && monitorWebhook == "" {
+		return fmt.Errorf("--monitor-digest requires --monitor-webhook")
+	}
+
+	if scanSplit {
+		if scanOutputFile == "" && projectName == "" {
+			return fmt.Errorf("--split-by-severity requires --output (a directory) or --project")
+		}
+		if scanRetry != "" {
+			return fmt.Errorf("--split-by-severity cannot be combined with --retry-failed")
+		}
+	}
+
+	if stdinContent && (scanInputFile != "" || scanArchive != "" || scanDir != "" || scanNpm != "" || scanGitHub != "" || scanRetry != "" || snapshotDir != "") {
+		return fmt.Errorf("--stdin-content cannot be combined with --input, --archive, --dir, --npm, --github, --retry-failed or --snapshot")
+	}
+
+	var retryURLs []string
+	if scanRetry != "" {
+		if scanInputFile != "" || scanArchive != "" || scanDir != "" || scanNpm != "" || scanGitHub != "" {
+			return fmt.Errorf("--retry-failed cannot be combined with --input, --archive, --dir, --npm or --github")
+		}
+		urls, err := failedURLs(scanRetry, cmd.Name())
+		if err != nil {
+			return err
+		}
+		if len(urls) == 0 {
+			fmt.Fprintf(os.Stderr, "No failed scan URLs to retry in %s\n", scanRetry)
+			return nil
+		}
+		retryURLs = urls
+	}
+
+	stats := utils.NewRunStats()
+	defer reportStats(stats)
+	if err := openErrorLog(cmd); err != nil {
+		return err
+	}
+
+	csv, err := csvOptions(scanner.CSVHeader)
+	if err != nil {
+		return err
+	}
+
+	cacheSize, err := utils.ParseByteSize(scanCacheSize)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := openSnapshot()
+	if err != nil {
+		return err
+	}
+
+	var siem *scanner.SIEMSender
+	if scanSIEM != "" && !dryRun {
+		var err error
+		siem, err = scanner.NewSIEMSender(scanSIEM, siemFormat, version, time.Duration(scanTimeout)*time.Second)
+		if err != nil {
+			return err
+		}
+		defer siem.Close()
+	}
+
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	if githubToken == "" && scanGitHub != "" && !dryRun {
+		githubToken, err = utils.ResolveSecret(appConfig.Tokens.GitHub)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Chat webhook URLs carry their own credentials
+	webhook := monitorWebhook
+	if webhook != "" && !dryRun {
+		webhook, err = utils.ResolveSecret(webhook)
+		if err != nil {
+			return err
+		}
+	}
+
+	outputFile := projectOutput(scanOutputFile, utils.ProjectFindings+"."+strings.ToLower(format))
+	if scanSplit {
+		outputFile = projectOutput(scanOutputFile, utils.ProjectFindings)
+	}
+
 	config := &scanner.Config{
-		InputFile:  scanInputFile,
-		OutputFile: scanOutputFile,
-		Threads:    scanThreads,
-		Timeout:    scanTimeout,
-		ConfigFile: configFile,
-		Format:     format,
-		Verbose:    verbose,
+		InputFile:       scanInputFile,
+		OutputFile:      outputFile,
+		Threads:         scanThreads,
+		Timeout:         scanTimeout,
+		ConfigFile:      configFile,
+		Format:          format,
+		Verbose:         verbose,
+		Stats:           stats,
+		Budget:          runBudget,
+		Window:          runWindow,
+		Shard:           runShard,
+		Scope:           runScope,
+		Identity:        runIdentity,
+		Auth:            runAuth,
+		UAFallback:      runUAFallback,
+		Timeouts:        runTimeouts,
+		Dialer:          runDialer,
+		Memory:          runMemory,
+		Errors:          runErrors,
+		NpmRegistry:     npmRegistry,
+		GitHubToken:     githubToken,
+		ProbeWebSockets: probeSockets,
+		GlobalTimeout:   globalTimeout,
+		Sort:            scanSort,
+		NoSkip:          scanNoSkip,
+		ExcludeURLs:     scanExcludeURLs,
+		SplitBySeverity: scanSplit,
+		SIEM:            siem,
+		CSV:             csv,
+		Cache:           utils.NewContentCache(cacheSize),
+		Snapshot:        snapshot,
+		Remediation:     remediation,
+		ReportFragments: reportFragments,
+		Monitor:         scanMonitor,
+		MonitorWebhook:  webhook,
+		MonitorDigest:   monitorDigest,
+		Labels:          runLabels,
+		Literals:        scanLiterals,
+		Retry:           runRetry,
 	}
 
 	s := scanner.New(config)
+	if err := loadInstalledPack(s); err != nil {
+		return err
+	}
+	if configFile != "" {
+		if err := s.LoadPatterns(configFile); err != nil {
+			return err
+		}
+	}
+	if err := s.SelectPatterns(scanOnly, scanDisable); err != nil {
+		return err
+	}
+
+	if scanGitHub != "" {
+		if dryRun {
+			return writePlan(s.PlanGitHub(scanGitHub))
+		}
+		return s.ScanGitHub(scanGitHub)
+	}
+
+	if scanNpm != "" {
+		if dryRun {
+			return writePlan(s.PlanNpmPackage(scanNpm))
+		}
+		return s.ScanNpmPackage(scanNpm)
+	}
+
+	if scanArchive != "" {
+		if dryRun {
+			return writePlan(s.PlanArchive(scanArchive))
+		}
+		return s.ScanArchive(scanArchive)
+	}
+
+	if stdinContent {
+		if dryRun {
+			return writePlan(s.PlanContent(stdinName))
+		}
+		return s.ScanContent(os.Stdin, stdinName)
+	}
+
+	if scanDir != "" {
+		if dryRun {
+			return writePlan(s.PlanDirectory(scanDir))
+		}
+		return s.ScanDirectory(scanDir)
+	}
+
+	if retryURLs != nil {
+		if dryRun {
+			return writePlan(s.Plan(strings.NewReader(strings.Join(retryURLs, "\n"))))
+		}
+		mergeOutput, err := preserveOutput(config.OutputFile, strings.EqualFold(format, "json"))
+		if err != nil {
+			return err
+		}
+		if err := s.ScanURLs(retryURLs); err != nil {
+			return err
+		}
+		return mergeOutput()
+	}
+
+	if dryRun {
+		input, err := openPlanInput(scanInputFile)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+		return writePlan(s.Plan(input))
+	}
 
 	if scanInputFile != "" {
 		// Scan from input file
@@ -55,4 +323,28 @@ func runScan(cmd *cobra.Command, args []string) error {
 		// Scan from stdin
 		return s.ScanFromStdin()
 	}
-}
\ No newline at end of file
+}
+
+// loadInstalledPack adds the patterns of the pack `patterns update`
+// installed, if any, to s once its signature verifies. A pack that does not
+// verify is skipped with a warning; the run goes on with the other patterns.
+func loadInstalledPack(s *scanner.Scanner) error {
+	path, err := scanner.DefaultPackPath()
+	if err != nil {
+		return nil
+	}
+	// Without a key, a pack that is installed fails verification below
+	publicKey, _ := packKey("")
+	pack, err := scanner.InstalledPack(path, publicKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: skipping the installed pattern pack: %v (run jsfinder patterns update to reinstall it)\n", err)
+		return nil
+	}
+	if pack == nil {
+		return nil
+	}
+	if err := s.LoadPack(pack); err != nil {
+		return fmt.Errorf("installed pattern pack: %w", err)
+	}
+	return nil
+}
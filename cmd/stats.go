@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jsfinder/pkg/merge"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <project-dir>",
+	Short: "Report per-target trends across the runs of a project",
+	Long: `Compare the dated run directories written with --project and report, for
+each target host and day, the JS files crawled, findings and endpoints, with
+how many appeared or disappeared since the previous run: findings opened and
+closed, new endpoints and JS file churn. Export as JSON or CSV for dashboards.`,
+	Example: `  jsfinder stats acme
+  jsfinder stats acme --target app.acme.com
+  jsfinder stats acme --format csv -o trends.csv`,
+	Args: cobra.ExactArgs(1),
+	RunE: runStats,
+}
+
+var (
+	statsFormat  string
+	statsOutput  string
+	statsTargets []string
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVarP(&statsFormat, "format", "f", "txt", "Output format (txt, json, csv)")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "", "Output file for the trends (default: stdout)")
+	statsCmd.Flags().StringSliceVar(&statsTargets, "target", nil, "Only report these target hosts")
+	addCSVFlags(statsCmd)
+
+	statsCmd.RegisterFlagCompletionFunc("format", completeValues("txt", "json", "csv"))
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if err := validateChoice("format", statsFormat, "txt", "json", "csv"); err != nil {
+		return err
+	}
+	csv, err := csvOptions(merge.TrendCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	trends, err := merge.ProjectTrends(args[0])
+	if err != nil {
+		return err
+	}
+	if len(statsTargets) > 0 {
+		wanted := make(map[string]bool)
+		for _, target := range statsTargets {
+			wanted[strings.ToLower(target)] = true
+		}
+		filtered := trends[:0]
+		for _, trend := range trends {
+			if wanted[trend.Target] {
+				filtered = append(filtered, trend)
+			}
+		}
+		trends = filtered
+	}
+
+	var output io.Writer = os.Stdout
+	if statsOutput != "" {
+		file, err := os.Create(statsOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	return merge.WriteTrends(output, trends, statsFormat, csv)
+}
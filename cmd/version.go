@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jsfinder/pkg/scanner"
+	"jsfinder/pkg/utils"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Long: `Print the jsfinder version, the git commit and date it was built from and
+the version of the built-in secret pattern set, so bug reports and automation
+can pin an exact build. With --check-update the latest GitHub release is
+looked up and compared against this build.`,
+	Example: `  jsfinder version
+  jsfinder version --check-update`,
+	Args: cobra.NoArgs,
+	RunE: runVersion,
+}
+
+var checkUpdate bool
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().BoolVar(&checkUpdate, "check-update", false, "Check GitHub releases for a newer version")
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "jsfinder %s\n", version)
+	fmt.Fprintf(out, "  Commit:     %s\n", commit)
+	fmt.Fprintf(out, "  Built:      %s\n", buildDate)
+	fmt.Fprintf(out, "  Go:         %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(out, "  Patterns:   %s\n", scanner.PatternSetVersion)
+	if path, err := scanner.DefaultPackPath(); err == nil {
+		publicKey, _ := packKey("")
+		pack, err := scanner.InstalledPack(path, publicKey)
+		switch {
+		case err != nil:
+			fmt.Fprintf(out, "  Pack:       unverified, not loaded (%s)\n", path)
+		case pack != nil:
+			fmt.Fprintf(out, "  Pack:       %s (%s)\n", pack.Version, path)
+		}
+	}
+
+	if !checkUpdate {
+		return nil
+	}
+
+	client := utils.NewHTTPClient(&utils.ClientOptions{Timeout: 10 * time.Second, Identity: runIdentity})
+	release, err := utils.LatestRelease(client, utils.DefaultReleasesAPI)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case utils.NewerVersion(version, release.Version):
+		fmt.Fprintf(out, "\nUpdate available: %s (released %s)\n  %s\n", release.Version, release.Published.Format("2006-01-02"), release.URL)
+	case version == "dev":
+		fmt.Fprintf(out, "\nLatest release is %s; this is a development build\n", release.Version)
+	default:
+		fmt.Fprintf(out, "\njsfinder is up to date (latest release %s)\n", release.Version)
+	}
+	return nil
+}
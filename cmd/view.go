@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jsfinder/pkg/merge"
+	"jsfinder/pkg/view"
+)
+
+var viewCmd = &cobra.Command{
+	Use:   "view <file>...",
+	Short: "Browse saved findings and endpoints in the terminal",
+	Long: `Open JSON results from scan, discover or merge in a terminal browser. Results
+are listed a page at a time and can be filtered by type, severity, URL, status
+or kind, sorted, and opened to show every field with the source context of a
+finding. Several files are merged first, like jsfinder merge. Type help at the
+view> prompt for the commands.`,
+	Example: `  jsfinder view findings.json
+  jsfinder view findings.json endpoints.json --severity high --sort type
+  jsfinder view acme/2024-03-09/findings.json --url /static/`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runView,
+}
+
+var (
+	viewType     string
+	viewSeverity string
+	viewURL      string
+	viewStatus   string
+	viewSort     string
+	viewPageSize int
+)
+
+func init() {
+	rootCmd.AddCommand(viewCmd)
+
+	viewCmd.Flags().StringVar(&viewType, "type", "", "Start with findings of a type (or endpoints of a content type) containing this text")
+	viewCmd.Flags().StringVar(&viewSeverity, "severity", "", "Start with findings of this severity (high, medium, low)")
+	viewCmd.Flags().StringVar(&viewURL, "url", "", "Start with results whose URL contains this text")
+	viewCmd.Flags().StringVar(&viewStatus, "status", "", "Start with endpoints answering with this status code")
+	viewCmd.Flags().StringVar(&viewSort, "sort", "", "Initial sort order (url, type, severity, status; prefix - to reverse)")
+	viewCmd.Flags().IntVar(&viewPageSize, "page-size", view.DefaultPageSize, "Results listed per page")
+
+	viewCmd.RegisterFlagCompletionFunc("severity", completeValues("high", "medium", "low"))
+	viewCmd.RegisterFlagCompletionFunc("sort", completeValues(view.SortFields...))
+}
+
+func runView(cmd *cobra.Command, args []string) error {
+	if err := validateChoice("severity", strings.ToLower(viewSeverity), "high", "medium", "low"); err != nil {
+		return err
+	}
+	if viewPageSize < 1 {
+		return fmt.Errorf("--page-size must be at least 1")
+	}
+
+	m := merge.New()
+	for _, path := range args {
+		if err := m.AddFile(path); err != nil {
+			return err
+		}
+	}
+
+	browser := view.New(m.Findings(), m.Endpoints())
+	browser.PageSize = viewPageSize
+	for field, value := range map[string]string{"type": viewType, "severity": viewSeverity, "url": viewURL, "status": viewStatus} {
+		browser.Filter(field, value)
+	}
+	if viewSort != "" {
+		if err := browser.Sort(viewSort); err != nil {
+			return err
+		}
+	}
+	return browser.Run(os.Stdin, os.Stdout)
+}
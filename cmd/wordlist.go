@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"jsfinder/pkg/utils"
+	"jsfinder/pkg/wordlist"
+)
+
+var wordlistCmd = &cobra.Command{
+	Use:   "wordlist",
+	Short: "Build wordlists for endpoint discovery",
+}
+
+var wordlistGenCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate a target-specific wordlist from its pages and JS files",
+	Long: `Fetch the pages and JavaScript files of a target and build a wordlist from
+what they contain: the paths they reference, the keys of their objects and JSON,
+and the functions and methods they name, also split at camelCase, snake_case and
+kebab-case boundaries. Words are written most frequent first, ready for
+discover --wordlist. A list built from the target itself usually finds more of
+its endpoints than a generic one.`,
+	Example: `  jsfinder crawl --domain https://example.com -o jsfiles.txt
+  (echo https://example.com; cat jsfiles.txt) | jsfinder wordlist gen -o words.txt
+  jsfinder discover --input jsfiles.txt --wordlist words.txt`,
+	Args: cobra.NoArgs,
+	RunE: runWordlistGen,
+}
+
+var (
+	wordlistInputFile  string
+	wordlistOutputFile string
+	wordlistThreads    int
+	wordlistTimeout    int
+	wordlistMinLength  int
+	wordlistMaxWords   int
+)
+
+func init() {
+	rootCmd.AddCommand(wordlistCmd)
+	wordlistCmd.AddCommand(wordlistGenCmd)
+
+	wordlistGenCmd.Flags().StringVarP(&wordlistInputFile, "input", "i", "", "Input file containing page and JS file URLs (default: stdin)")
+	wordlistGenCmd.Flags().StringVarP(&wordlistOutputFile, "output", "o", "", "Output file for the wordlist (default: stdout)")
+	wordlistGenCmd.Flags().IntVarP(&wordlistThreads, "threads", "t", 10, "Number of concurrent threads")
+	wordlistGenCmd.Flags().IntVarP(&wordlistTimeout, "timeout", "", 30, "Request timeout in seconds")
+	wordlistGenCmd.Flags().IntVar(&wordlistMinLength, "min-length", wordlist.DefaultMinLength, "Shortest identifier or JSON key kept as a word (path segments are always kept)")
+	wordlistGenCmd.Flags().IntVar(&wordlistMaxWords, "max-words", 0, "Keep only the N most frequent words (0 = all)")
+}
+
+func runWordlistGen(cmd *cobra.Command, args []string) error {
+	stats := utils.NewRunStats()
+	defer reportStats(stats)
+	if err := openErrorLog(cmd); err != nil {
+		return err
+	}
+
+	config := &wordlist.Config{
+		InputFile:     wordlistInputFile,
+		OutputFile:    projectOutput(wordlistOutputFile, utils.ProjectWordlist),
+		Threads:       wordlistThreads,
+		Timeout:       wordlistTimeout,
+		MinLength:     wordlistMinLength,
+		MaxWords:      wordlistMaxWords,
+		Verbose:       verbose,
+		Stats:         stats,
+		Budget:        runBudget,
+		Window:        runWindow,
+		Shard:         runShard,
+		Scope:         runScope,
+		Identity:      runIdentity,
+		Auth:          runAuth,
+		UAFallback:    runUAFallback,
+		Timeouts:      runTimeouts,
+		Dialer:        runDialer,
+		Errors:        runErrors,
+		GlobalTimeout: globalTimeout,
+	}
+
+	g := wordlist.New(config)
+
+	if dryRun {
+		input, err := openPlanInput(wordlistInputFile)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+		return writePlan(g.Plan(input))
+	}
+
+	if wordlistInputFile != "" {
+		return g.GenerateFromFile(wordlistInputFile)
+	}
+	return g.GenerateFromStdin()
+}
@@ -0,0 +1,291 @@
+package crawler
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"jsfinder/pkg/input"
+	"jsfinder/pkg/utils"
+)
+
+// maxFaviconSize limits how much of a favicon is downloaded for hashing
+const maxFaviconSize = 1 << 20
+
+// Fingerprint describes the technology behind an origin, taken from the
+// first page crawled on it, to help prioritize targets
+type Fingerprint struct {
+	Origin       string       `json:"origin"`
+	URL          string       `json:"url"`
+	FaviconURL   string       `json:"favicon_url,omitempty"`
+	FaviconHash  int32        `json:"favicon_hash,omitempty"` // MurmurHash3 as searched with Shodan's http.favicon.hash
+	Technologies []string     `json:"technologies"`
+	Recon        *input.Recon `json:"recon,omitempty"` // Reported for the origin by the recon tool it was read from
+	Labels       utils.Labels `json:"labels,omitempty"`
+}
+
+// Technology signatures. Header and generator values are reported as sent,
+// since they usually carry the version.
+var (
+	versionHeaders = []string{"Server", "X-Powered-By", "X-AspNet-Version", "X-AspNetMvc-Version", "X-Generator", "X-Drupal-Cache", "X-Varnish"}
+
+	cookieTechnologies = map[string]string{
+		"PHPSESSID":          "PHP",
+		"JSESSIONID":         "Java",
+		"ASP.NET_SessionId":  "ASP.NET",
+		"laravel_session":    "Laravel",
+		"csrftoken":          "Django",
+		"_rails_session":     "Ruby on Rails",
+		"connect.sid":        "Express",
+		"wp-settings-time-1": "WordPress",
+		"__cf_bm":            "Cloudflare",
+	}
+
+	headerTechnologies = map[string]string{
+		"CF-Ray":               "Cloudflare",
+		"X-Amz-Cf-Id":          "Amazon CloudFront",
+		"X-Vercel-Id":          "Vercel",
+		"X-Nf-Request-Id":      "Netlify",
+		"X-Shopify-Stage":      "Shopify",
+		"X-Akamai-Transformed": "Akamai",
+	}
+
+	// bodyTechnologies match markers that frameworks leave in the page:
+	// globals they define, attributes they render and paths they serve from
+	bodyTechnologies = []struct {
+		name    string
+		pattern *regexp.Regexp
+	}{
+		{"Next.js", regexp.MustCompile(`__NEXT_DATA__|/_next/static/`)},
+		{"Nuxt.js", regexp.MustCompile(`window\.__NUXT__|/_nuxt/`)},
+		{"Gatsby", regexp.MustCompile(`___gatsby|window\.___loader`)},
+		{"SvelteKit", regexp.MustCompile(`__sveltekit_|data-sveltekit`)},
+		{"Angular", regexp.MustCompile(`\sng-version=["']|ng-app=`)},
+		{"React", regexp.MustCompile(`data-reactroot|__REACT_DEVTOOLS_GLOBAL_HOOK__|react(?:\.production)?\.min\.js`)},
+		{"Vue.js", regexp.MustCompile(`\sdata-v-[0-9a-f]{8}|__VUE__|vue(?:\.runtime)?(?:\.global)?(?:\.prod)?\.js`)},
+		{"Ember.js", regexp.MustCompile(`ember-application|window\.Ember\b`)},
+		{"jQuery", regexp.MustCompile(`jquery[.-][\w.-]*js|window\.jQuery`)},
+		{"WordPress", regexp.MustCompile(`/wp-content/|/wp-includes/`)},
+		{"Drupal", regexp.MustCompile(`drupal-settings-json|Drupal\.settings`)},
+		{"Shopify", regexp.MustCompile(`cdn\.shopify\.com|Shopify\.shop`)},
+		{"Google Tag Manager", regexp.MustCompile(`googletagmanager\.com/gtm\.js`)},
+	}
+
+	generatorPattern = regexp.MustCompile(`(?i)<meta\s[^>]*name=["']generator["'][^>]*content=["']([^"']+)["']|<meta\s[^>]*content=["']([^"']+)["'][^>]*name=["']generator["']`)
+	iconPattern      = regexp.MustCompile(`(?i)<link\s[^>]*rel=["'](?:shortcut )?icon["'][^>]*>`)
+	hrefPattern      = regexp.MustCompile(`(?i)\shref=["']?([^"'\s>]+)`)
+)
+
+// fingerprint detects the technologies and favicon hash of the first page
+// crawled on each origin
+func (c *Crawler) fingerprint(pageURL string, header http.Header, body string) {
+	origin := utils.Origin(pageURL)
+	if origin == "" {
+		return
+	}
+
+	c.fingerprintMux.Lock()
+	if c.fingerprinted[origin] {
+		c.fingerprintMux.Unlock()
+		return
+	}
+	c.fingerprinted[origin] = true
+	c.fingerprintMux.Unlock()
+
+	result := Fingerprint{
+		Origin:       origin,
+		URL:          pageURL,
+		Technologies: detectTechnologies(header, body),
+		Recon:        c.reconFor(origin),
+		Labels:       c.config.Labels,
+	}
+	result.FaviconURL = c.resolveURL(faviconHref(body), pageURL)
+	if hash, ok := c.faviconHash(result.FaviconURL); ok {
+		result.FaviconHash = hash
+	} else {
+		result.FaviconURL = ""
+	}
+
+	c.fingerprintMux.Lock()
+	c.fingerprints = append(c.fingerprints, result)
+	c.fingerprintMux.Unlock()
+
+	c.logger.WithField("target", origin).Infof("Technologies: %s; favicon hash %d", strings.Join(result.Technologies, ", "), result.FaviconHash)
+}
+
+// detectTechnologies lists the technologies a page's headers and HTML reveal
+func detectTechnologies(header http.Header, body string) []string {
+	found := make(map[string]bool)
+
+	for _, name := range versionHeaders {
+		if value := header.Get(name); value != "" {
+			found[value] = true
+		}
+	}
+	for name, technology := range headerTechnologies {
+		if header.Get(name) != "" {
+			found[technology] = true
+		}
+	}
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		if technology, exists := cookieTechnologies[cookie.Name]; exists {
+			found[technology] = true
+		}
+	}
+
+	for _, match := range generatorPattern.FindAllStringSubmatch(body, -1) {
+		found[strings.TrimSpace(match[1]+match[2])] = true
+	}
+	for _, technology := range bodyTechnologies {
+		if technology.pattern.MatchString(body) {
+			found[technology.name] = true
+		}
+	}
+
+	technologies := make([]string, 0, len(found))
+	for technology := range found {
+		technologies = append(technologies, technology)
+	}
+	sort.Strings(technologies)
+	return technologies
+}
+
+// faviconHref returns the icon a page declares, or the conventional /favicon.ico
+func faviconHref(body string) string {
+	if link := iconPattern.FindString(body); link != "" {
+		if match := hrefPattern.FindStringSubmatch(link); match != nil {
+			return match[1]
+		}
+	}
+	return "/favicon.ico"
+}
+
+// faviconHash downloads a favicon and hashes it the way Shodan does: MurmurHash3
+// of the base64 encoding, wrapped at 76 characters with a trailing newline
+func (c *Crawler) faviconHash(faviconURL string) (int32, bool) {
+	if faviconURL == "" || !c.config.Scope.AllowsURL(faviconURL) {
+		return 0, false
+	}
+
+	resp, err := c.client.Get(faviconURL)
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize))
+	if err != nil || len(data) == 0 {
+		return 0, false
+	}
+
+	return int32(murmur3(encodeBase64Lines(data), 0)), true
+}
+
+// encodeBase64Lines encodes data as MIME base64, as Python's base64.encodebytes does
+func encodeBase64Lines(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// murmur3 is the 32-bit x86 variant of MurmurHash3
+func murmur3(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+
+	h := seed
+	n := len(data)
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+		data = data[4:]
+	}
+
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(n)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// Fingerprints returns the origin fingerprints collected so far
+func (c *Crawler) Fingerprints() []Fingerprint {
+	c.fingerprintMux.Lock()
+	defer c.fingerprintMux.Unlock()
+
+	return append([]Fingerprint(nil), c.fingerprints...)
+}
+
+// technologies returns the technologies fingerprinted on origin, nil if it
+// was not fingerprinted
+func (c *Crawler) technologies(origin string) []string {
+	c.fingerprintMux.Lock()
+	defer c.fingerprintMux.Unlock()
+
+	for _, fingerprint := range c.fingerprints {
+		if fingerprint.Origin == origin {
+			return fingerprint.Technologies
+		}
+	}
+	return nil
+}
+
+// writeFingerprints saves the fingerprints as JSON when a fingerprint output file is set
+func (c *Crawler) writeFingerprints() error {
+	if !c.config.Fingerprint || c.config.FingerprintOutput == "" {
+		return nil
+	}
+
+	file, err := os.Create(c.config.FingerprintOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create fingerprint output file: %w", err)
+	}
+	defer file.Close()
+
+	fingerprints := c.Fingerprints()
+	sort.Slice(fingerprints, func(i, j int) bool { return fingerprints[i].Origin < fingerprints[j].Origin })
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(fingerprints)
+}
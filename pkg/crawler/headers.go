@@ -0,0 +1,212 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"jsfinder/pkg/input"
+	"jsfinder/pkg/utils"
+)
+
+// Security header issues reported by the header audit
+const (
+	IssueMissingCSP          = "missing-csp"
+	IssueWeakCSP             = "weak-csp"
+	IssueCSPReportURI        = "csp-report-uri"
+	IssueMissingHSTS         = "missing-hsts"
+	IssueWeakHSTS            = "weak-hsts"
+	IssueMissingFrameOptions = "missing-x-frame-options"
+	IssueWeakFrameOptions    = "weak-x-frame-options"
+)
+
+// SeverityInfo is the severity of every header audit finding
+const SeverityInfo = "INFO"
+
+// minHSTSMaxAge is the shortest HSTS max-age not reported as weak (180 days)
+const minHSTSMaxAge = 180 * 24 * 60 * 60
+
+// HeaderFinding is a security header observation for an origin
+type HeaderFinding struct {
+	Origin       string       `json:"origin"`
+	URL          string       `json:"url"`
+	Header       string       `json:"header"`
+	Issue        string       `json:"issue"`
+	Value        string       `json:"value,omitempty"`
+	Severity     string       `json:"severity"`
+	Description  string       `json:"description"`
+	Technologies []string     `json:"technologies,omitempty"` // Detected on the origin, with Fingerprint
+	Recon        *input.Recon `json:"recon,omitempty"`        // Reported for the origin by the recon tool it was read from
+	Labels       utils.Labels `json:"labels,omitempty"`
+}
+
+// auditHeaders checks the security headers of the first page crawled on each origin
+func (c *Crawler) auditHeaders(pageURL string, header http.Header) {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+	origin := parsed.Scheme + "://" + utils.CanonicalHost(parsed)
+
+	c.auditMux.Lock()
+	if c.audited[origin] {
+		c.auditMux.Unlock()
+		return
+	}
+	c.audited[origin] = true
+	c.auditMux.Unlock()
+
+	for _, finding := range checkSecurityHeaders(parsed.Scheme, header) {
+		finding.Origin = origin
+		finding.URL = pageURL
+		finding.Recon = c.reconFor(origin)
+		finding.Labels = c.config.Labels
+		c.addHeaderFinding(finding)
+	}
+}
+
+// checkSecurityHeaders reports missing or weak CSP, HSTS and X-Frame-Options headers
+func checkSecurityHeaders(scheme string, header http.Header) []HeaderFinding {
+	var findings []HeaderFinding
+	add := func(name, issue, value, description string) {
+		findings = append(findings, HeaderFinding{
+			Header:      name,
+			Issue:       issue,
+			Value:       value,
+			Severity:    SeverityInfo,
+			Description: description,
+		})
+	}
+
+	csp := header.Get("Content-Security-Policy")
+	directives := parseCSP(csp)
+	if csp == "" {
+		add("Content-Security-Policy", IssueMissingCSP, "", "No Content-Security-Policy header")
+	} else if weakness := cspWeakness(directives); weakness != "" {
+		add("Content-Security-Policy", IssueWeakCSP, csp, weakness)
+	}
+
+	// Report endpoints are worth noting even in report-only policies
+	for _, name := range []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"} {
+		policy := parseCSP(header.Get(name))
+		for _, directive := range []string{"report-uri", "report-to"} {
+			if value, exists := policy[directive]; exists {
+				add(name, IssueCSPReportURI, value, fmt.Sprintf("CSP violation reports are sent to %s", value))
+			}
+		}
+	}
+
+	if scheme == "https" {
+		hsts := header.Get("Strict-Transport-Security")
+		if hsts == "" {
+			add("Strict-Transport-Security", IssueMissingHSTS, "", "No Strict-Transport-Security header on HTTPS origin")
+		} else if maxAge := hstsMaxAge(hsts); maxAge < minHSTSMaxAge {
+			add("Strict-Transport-Security", IssueWeakHSTS, hsts, fmt.Sprintf("HSTS max-age %d is below 180 days", maxAge))
+		}
+	}
+
+	if _, framed := directives["frame-ancestors"]; !framed {
+		frameOptions := strings.ToUpper(strings.TrimSpace(header.Get("X-Frame-Options")))
+		switch frameOptions {
+		case "DENY", "SAMEORIGIN":
+		case "":
+			add("X-Frame-Options", IssueMissingFrameOptions, "", "No X-Frame-Options header or CSP frame-ancestors directive")
+		default:
+			add("X-Frame-Options", IssueWeakFrameOptions, frameOptions, "X-Frame-Options is neither DENY nor SAMEORIGIN")
+		}
+	}
+
+	return findings
+}
+
+// parseCSP splits a policy into directive name and value
+func parseCSP(policy string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(policy, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, exists := directives[name]; !exists {
+			directives[name] = strings.Join(fields[1:], " ")
+		}
+	}
+	return directives
+}
+
+// cspWeakness describes why a script policy is weak, or returns ""
+func cspWeakness(directives map[string]string) string {
+	scripts, exists := directives["script-src"]
+	if !exists {
+		scripts, exists = directives["default-src"]
+	}
+	if !exists {
+		return "No script-src or default-src directive"
+	}
+
+	for _, source := range strings.Fields(scripts) {
+		switch strings.ToLower(source) {
+		case "'unsafe-inline'", "'unsafe-eval'", "*", "data:", "http:", "https:":
+			return fmt.Sprintf("Script sources allow %s", source)
+		}
+	}
+	return ""
+}
+
+// hstsMaxAge extracts the max-age directive, returning 0 when absent
+func hstsMaxAge(value string) int {
+	for _, part := range strings.Split(value, ";") {
+		name, age, found := strings.Cut(strings.TrimSpace(part), "=")
+		if found && strings.EqualFold(name, "max-age") {
+			if seconds, err := strconv.Atoi(strings.Trim(age, `"`)); err == nil {
+				return seconds
+			}
+		}
+	}
+	return 0
+}
+
+// addHeaderFinding records a finding and logs it at INFO level
+func (c *Crawler) addHeaderFinding(finding HeaderFinding) {
+	c.auditMux.Lock()
+	c.headerFindings = append(c.headerFindings, finding)
+	c.auditMux.Unlock()
+
+	c.stats.AddFinding(finding.Severity)
+	c.logger.WithField("target", finding.Origin).Infof("%s: %s", finding.Issue, finding.Description)
+}
+
+// HeaderFindings returns the security header findings collected so far, with
+// the technologies fingerprinted on their origin
+func (c *Crawler) HeaderFindings() []HeaderFinding {
+	c.auditMux.Lock()
+	findings := append([]HeaderFinding(nil), c.headerFindings...)
+	c.auditMux.Unlock()
+
+	for i := range findings {
+		findings[i].Technologies = c.technologies(findings[i].Origin)
+	}
+	return findings
+}
+
+// writeHeaderFindings saves the audit findings as JSON when an audit output file is set
+func (c *Crawler) writeHeaderFindings() error {
+	if !c.config.AuditHeaders || c.config.AuditOutput == "" {
+		return nil
+	}
+
+	file, err := os.Create(c.config.AuditOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create audit output file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c.HeaderFindings())
+}
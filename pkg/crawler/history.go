@@ -0,0 +1,279 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"jsfinder/pkg/utils"
+)
+
+// maxHistorySample limits how much of a candidate bundle or directory
+// listing is read to tell whether it exists
+const maxHistorySample = 1 << 20
+
+var (
+	// A build number at the end of a file name: app.v123.js, main-42.min.js, lib.1.2.3.js
+	versionedFilePattern = regexp.MustCompile(`^(.*[.\-_~]v?)(\d{1,6})((?:\.min)?\.m?js)$`)
+	// A version directory: /v12/, /1.4.2/
+	versionedDirPattern = regexp.MustCompile(`^(v?(?:\d+\.)*)(\d{1,6})$`)
+	// Query parameters that carry a build number: ?v=123
+	versionParams = []string{"v", "ver", "version", "build", "rev", "release"}
+
+	// Open directory listings: Apache/nginx "Index of" pages and S3 bucket listings
+	indexOfPattern = regexp.MustCompile(`(?i)<title>\s*Index of /|<ListBucketResult`)
+	listedHref     = regexp.MustCompile(`(?i)href=["']([^"'?#]+\.m?js)["']`)
+	listedS3Key    = regexp.MustCompile(`<Key>([^<]+\.m?js)</Key>`)
+)
+
+// olderVersions returns the URLs of up to n builds before jsURL for each
+// version number it carries: in the file name, a version directory or a
+// version query parameter. Zero padding is kept, so app.007.js comes before
+// app.006.js.
+func olderVersions(jsURL string, n int) []string {
+	u, err := url.Parse(jsURL)
+	if err != nil || n <= 0 {
+		return nil
+	}
+
+	var candidates []string
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		pattern := versionedDirPattern
+		if i == len(segments)-1 {
+			pattern = versionedFilePattern
+		}
+		match := pattern.FindStringSubmatch(segment)
+		if match == nil {
+			continue
+		}
+		suffix := ""
+		if len(match) > 3 {
+			suffix = match[3]
+		}
+		for _, number := range decrement(match[2], n) {
+			older := *u
+			parts := append([]string(nil), segments...)
+			parts[i] = match[1] + number + suffix
+			older.Path = strings.Join(parts, "/")
+			older.RawPath = ""
+			candidates = append(candidates, older.String())
+		}
+	}
+
+	query := u.Query()
+	for _, param := range versionParams {
+		for _, number := range decrement(query.Get(param), n) {
+			older := *u
+			values := u.Query()
+			values.Set(param, number)
+			older.RawQuery = values.Encode()
+			candidates = append(candidates, older.String())
+		}
+	}
+	return candidates
+}
+
+// decrement returns up to n numbers below number, or nothing if it is not a
+// number. The width is kept only for a zero-padded number, so 007 leads to
+// 006 but 10 leads to 9.
+func decrement(number string, n int) []string {
+	value, err := strconv.Atoi(number)
+	if err != nil || len(number) > 6 {
+		return nil
+	}
+	width := 0
+	if len(number) > 1 && number[0] == '0' {
+		width = len(number)
+	}
+
+	var older []string
+	for i := 1; i <= n && value-i >= 0; i++ {
+		older = append(older, fmt.Sprintf("%0*d", width, value-i))
+	}
+	return older
+}
+
+// findHistoricalVersions looks for older builds of the versioned JS files
+// served from the crawled sites, and for bundles of the same name in open
+// directory listings, and adds those the server still serves. Old bundles
+// often keep secrets that were rotated out of the current build but never
+// revoked. Third-party scripts are left alone.
+func (c *Crawler) findHistoricalVersions() {
+	if c.config.JSHistory <= 0 {
+		return
+	}
+
+	var candidates []string
+	stems := make(map[string]map[string]bool) // Directory URL -> stems of the files found in it
+	var directories []string
+	for _, jsURL := range c.siteScripts() {
+		candidates = append(candidates, olderVersions(jsURL, c.config.JSHistory)...)
+		if u, err := url.Parse(jsURL); err == nil {
+			directory := *u
+			directory.Path, directory.RawPath, directory.RawQuery = path.Dir(u.Path)+"/", "", ""
+			key := directory.String()
+			if stems[key] == nil {
+				stems[key] = make(map[string]bool)
+				directories = append(directories, key)
+			}
+			stems[key][scriptStem(u.Path)] = true
+		}
+	}
+
+	var mutex sync.Mutex
+	c.probeEach(directories, func(directory string) {
+		listed := c.listDirectory(directory, stems[directory])
+		mutex.Lock()
+		candidates = append(candidates, listed...)
+		mutex.Unlock()
+	})
+
+	recovered := 0
+	c.probeEach(candidates, func(candidate string) {
+		c.jsFilesMux.RLock()
+		known := c.jsFiles[candidate]
+		c.jsFilesMux.RUnlock()
+		if known || !c.servesScript(candidate) {
+			return
+		}
+		c.addJSFile(candidate)
+		mutex.Lock()
+		recovered++
+		mutex.Unlock()
+	})
+	if recovered > 0 {
+		c.logger.Infof("Recovered %d older JS builds", recovered)
+	}
+}
+
+// siteScripts returns the JS files found on the sites crawled: those on the
+// host of a visited page, or that one could have linked to under the scope
+func (c *Crawler) siteScripts() []string {
+	hosts := make(map[string]string) // Host -> a visited page on it
+	c.visitedMux.RLock()
+	for pageURL := range c.visited {
+		hosts[hostname(pageURL)] = pageURL
+	}
+	c.visitedMux.RUnlock()
+
+	var scripts []string
+	c.jsFilesMux.RLock()
+	for jsURL := range c.jsFiles {
+		if _, crawled := hosts[hostname(jsURL)]; crawled && c.config.Scope.AllowsURL(jsURL) {
+			scripts = append(scripts, jsURL)
+			continue
+		}
+		for _, page := range hosts {
+			if c.config.Scope.AllowsLinked(jsURL, page) {
+				scripts = append(scripts, jsURL)
+				break
+			}
+		}
+	}
+	c.jsFilesMux.RUnlock()
+	sort.Strings(scripts)
+	return scripts
+}
+
+// probeEach runs fn for every distinct URL on up to Threads workers until
+// the budget or deadline runs out. Each probe waits for the run window, like
+// the pages of the crawl.
+func (c *Crawler) probeEach(urls []string, fn func(string)) {
+	pool := utils.NewWorkerPool(c.timeoutMgr.Context(), utils.PoolOptions{Workers: c.config.Threads, Logger: c.logger})
+	seen := make(map[string]bool)
+	for _, target := range urls {
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		if c.config.Budget.Exceeded() || c.timeoutMgr.Expired() {
+			break
+		}
+		pool.Submit(func(ctx context.Context, workerID int) {
+			if err := c.config.Window.Wait(ctx); err != nil || c.config.Budget.Exceeded() {
+				return
+			}
+			fn(target)
+		})
+	}
+	pool.Wait()
+	c.stats.AddWorkerPool(pool)
+}
+
+// listDirectory returns the JS files an open directory listing names whose
+// stem is one of stems, so app.4f2c.js leads to the other app.*.js builds
+// but not to every library next to it
+func (c *Crawler) listDirectory(directory string, stems map[string]bool) []string {
+	body, ok := c.fetchHistory(directory)
+	if !ok || !indexOfPattern.Match(body) {
+		return nil
+	}
+
+	var listed []string
+	add := func(reference string) {
+		resolved := c.resolveURL(reference, directory)
+		if u, err := url.Parse(resolved); err == nil && stems[scriptStem(u.Path)] {
+			listed = append(listed, resolved)
+		}
+	}
+	for _, match := range listedHref.FindAllSubmatch(body, -1) {
+		add(string(match[1]))
+	}
+	for _, match := range listedS3Key.FindAllSubmatch(body, -1) {
+		add("/" + string(match[1]))
+	}
+	if len(listed) > 0 {
+		c.logger.WithField("target", directory).Infof("Open directory listing names %d JS files", len(listed))
+	}
+	return listed
+}
+
+// scriptStem returns the part of a script's file name before the first
+// '.', '-' or '_': "app" for app.v12.min.js
+func scriptStem(scriptPath string) string {
+	name := path.Base(scriptPath)
+	if i := strings.IndexAny(name, ".-_"); i > 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+// servesScript reports whether a candidate bundle exists; single-page apps
+// answer any path with their HTML shell, which does not count
+func (c *Crawler) servesScript(candidate string) bool {
+	body, ok := c.fetchHistory(candidate)
+	if !ok {
+		return false
+	}
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] != '<'
+}
+
+// fetchHistory requests a candidate and returns the start of a 200 response
+func (c *Crawler) fetchHistory(target string) ([]byte, bool) {
+	req, err := http.NewRequestWithContext(c.timeoutMgr.Context(), "GET", target, nil)
+	if err != nil {
+		return nil, false
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHistorySample))
+	return body, err == nil
+}
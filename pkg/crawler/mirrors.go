@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mirror is an input domain that was crawled together with the domains
+// skipped because their homepage was the same
+type Mirror struct {
+	Domain      string   `json:"domain"`
+	Aliases     []string `json:"aliases"`
+	ContentHash string   `json:"content_hash"`
+}
+
+// volatilePattern matches per-response values that differ between two
+// requests for the same page: CSP nonces and CSRF tokens in meta tags
+var volatilePattern = regexp.MustCompile(`(?i)\bnonce=["'][^"']*["']|name=["']csrf[-_]?token["']\s+content=["'][^"']*["']`)
+
+// homepageHash hashes a homepage with its own host name and per-response
+// values blanked, so www/non-www variants and mirrors that only differ in
+// absolute links hash alike
+func homepageHash(pageURL string, body []byte) string {
+	content := volatilePattern.ReplaceAllString(string(body), "")
+	if parsed, err := url.Parse(pageURL); err == nil && parsed.Hostname() != "" {
+		bare := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+		content = strings.ReplaceAll(content, "www."+bare, "\x00")
+		content = strings.ReplaceAll(content, bare, "\x00")
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimHomepage records the homepage of an input domain. When an earlier
+// input domain served the same homepage it returns that domain and true, and
+// the caller skips the rest of the crawl.
+func (c *Crawler) claimHomepage(domain string, body []byte) (string, bool) {
+	hash := homepageHash(domain, body)
+
+	c.mirrorMux.Lock()
+	defer c.mirrorMux.Unlock()
+
+	if representative, seen := c.homepages[hash]; seen && representative != domain {
+		c.mirrors[representative] = append(c.mirrors[representative], domain)
+		return representative, true
+	}
+	c.homepages[hash] = domain
+	return "", false
+}
+
+// Mirrors returns the crawled domains that had mirrors, sorted by domain
+func (c *Crawler) Mirrors() []Mirror {
+	c.mirrorMux.Lock()
+	defer c.mirrorMux.Unlock()
+
+	mirrors := make([]Mirror, 0, len(c.mirrors))
+	for hash, domain := range c.homepages {
+		if aliases := c.mirrors[domain]; len(aliases) > 0 {
+			mirrors = append(mirrors, Mirror{Domain: domain, Aliases: append([]string(nil), aliases...), ContentHash: hash})
+		}
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].Domain < mirrors[j].Domain })
+	return mirrors
+}
+
+// writeMirrors logs the skipped mirror domains and saves them when an output
+// file is configured
+func (c *Crawler) writeMirrors() error {
+	if !c.config.DedupeMirrors {
+		return nil
+	}
+
+	mirrors := c.Mirrors()
+	skipped := 0
+	for _, mirror := range mirrors {
+		skipped += len(mirror.Aliases)
+	}
+	if skipped > 0 {
+		c.logger.Infof("Skipped %d mirror domains serving the same homepage as %d crawled domains", skipped, len(mirrors))
+	}
+	if c.config.MirrorsOutput == "" {
+		return nil
+	}
+
+	file, err := os.Create(c.config.MirrorsOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create mirrors output file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(mirrors)
+}
@@ -0,0 +1,33 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// writePageData reports the secrets found in the hidden inputs, JSON-LD and
+// state blobs of the crawled pages, and saves them to PageDataOutput
+func (c *Crawler) writePageData() error {
+	if c.config.PageData == nil {
+		return nil
+	}
+
+	findings := c.config.PageData.Findings()
+	for _, finding := range findings {
+		c.logger.WithField("target", finding.URL).Infof("%s in page data: %s", finding.Type, finding.Match)
+	}
+	if c.config.PageDataOutput == "" {
+		return nil
+	}
+
+	file, err := os.Create(c.config.PageDataOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create page data output file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(findings)
+}
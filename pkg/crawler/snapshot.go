@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/utils"
+)
+
+// isSnapshotPage reports whether a snapshot file is a saved HTML page; wget
+// keeps query strings in file names (page.html?id=2)
+func isSnapshotPage(name string) bool {
+	name, _, _ = strings.Cut(name, "?")
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".html", ".htm", ".xhtml":
+		return true
+	}
+	return false
+}
+
+// CrawlSnapshot extracts the JavaScript files referenced by every page of a
+// saved copy of a site (Config.Snapshot) without sending any requests.
+// References resolve against the URL each page was saved from, so the output
+// lists the same URLs an online crawl would.
+func (c *Crawler) CrawlSnapshot() error {
+	snapshot := c.config.Snapshot
+	if c.config.Verbose {
+		fmt.Printf("Reading snapshot %s as %s\n", snapshot.Root, snapshot.Base)
+	}
+
+	pages, err := snapshotPages(snapshot.Root)
+	if err != nil {
+		return err
+	}
+
+	if err := c.setupOutput(); err != nil {
+		return fmt.Errorf("failed to setup output: %w", err)
+	}
+	defer c.closeOutput()
+
+	c.stats.AddQueued(int64(len(pages)))
+	for _, name := range pages {
+		pageURL := snapshot.URL(name)
+		content, err := os.ReadFile(filepath.Join(snapshot.Root, filepath.FromSlash(name)))
+		if err != nil {
+			c.logger.WithField("target", name).Warnf("Error reading snapshot page: %v", err)
+			c.stats.AddProcessed()
+			continue
+		}
+
+		c.visitedMux.Lock()
+		c.visited[pageURL] = true
+		c.visitedMux.Unlock()
+
+		c.stats.AddPage()
+		c.extractJSFromHTML(string(content), pageURL)
+		if c.config.PageData != nil {
+			c.config.PageData.ScanPageData(pageURL, string(content))
+		}
+		c.stats.AddProcessed()
+	}
+
+	return c.writePageData()
+}
+
+// PlanSnapshot describes a snapshot crawl for a dry run; pages are read
+// locally, so the plan lists them but sends no requests
+func (c *Crawler) PlanSnapshot() (*utils.RequestPlan, error) {
+	plan := utils.NewRequestPlan("crawl", c.config.Threads, time.Duration(c.config.Timeout)*time.Second)
+
+	pages, err := snapshotPages(c.config.Snapshot.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.AddSetting("Snapshot", c.config.Snapshot.Root)
+	plan.AddSetting("Base URL", c.config.Snapshot.Base.String())
+	plan.AddSetting("Snapshot pages", len(pages))
+	if c.config.PageData != nil {
+		plan.AddSetting("Page data", c.config.PageDataOutput)
+	}
+	plan.AddNote("snapshot pages are read locally; no requests are sent")
+	return plan, nil
+}
+
+// snapshotPages lists the HTML pages under root as sorted slash-separated
+// relative paths
+func snapshotPages(root string) ([]string, error) {
+	var pages []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() || !isSnapshotPage(entry.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return pages, nil
+}
@@ -0,0 +1,93 @@
+package discovery
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Endpoint triage tags
+const (
+	TagAuthRequired   = "auth-required"
+	TagForbidden      = "forbidden"
+	TagCORSOpen       = "cors-open"
+	TagCORSCredential = "cors-credentials"
+	TagOptionsAllowed = "options-allowed"
+)
+
+// analyzeAuth records authentication and CORS hints from a probe response and
+// tags the endpoint accordingly. Endpoints answering 401/403 additionally get
+// an OPTIONS probe to see which methods the server advertises.
+func (d *Discovery) analyzeAuth(endpoint *Endpoint, resp *http.Response) {
+	endpoint.AuthScheme = authScheme(resp.Header.Get("WWW-Authenticate"))
+	endpoint.CORSOrigin = resp.Header.Get("Access-Control-Allow-Origin")
+
+	if resp.StatusCode == http.StatusUnauthorized || endpoint.AuthScheme != "" {
+		endpoint.addTag(TagAuthRequired)
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		endpoint.addTag(TagForbidden)
+	}
+	if endpoint.CORSOrigin == "*" {
+		endpoint.addTag(TagCORSOpen)
+	}
+	if strings.EqualFold(resp.Header.Get("Access-Control-Allow-Credentials"), "true") {
+		endpoint.addTag(TagCORSCredential)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		d.probeOptions(endpoint)
+	}
+}
+
+// probeOptions sends an OPTIONS request and records whether it is allowed
+func (d *Discovery) probeOptions(endpoint *Endpoint) {
+	req, err := http.NewRequest(http.MethodOptions, endpoint.URL, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", d.config.UserAgent)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	endpoint.AllowedMethods = resp.Header.Get("Allow")
+	if endpoint.AllowedMethods == "" {
+		endpoint.AllowedMethods = resp.Header.Get("Access-Control-Allow-Methods")
+	}
+	if endpoint.CORSOrigin == "" {
+		endpoint.CORSOrigin = resp.Header.Get("Access-Control-Allow-Origin")
+		if endpoint.CORSOrigin == "*" {
+			endpoint.addTag(TagCORSOpen)
+		}
+	}
+
+	if resp.StatusCode < 400 {
+		endpoint.OptionsAllowed = true
+		endpoint.addTag(TagOptionsAllowed)
+	}
+}
+
+// authScheme extracts the scheme name from a WWW-Authenticate header value
+func authScheme(header string) string {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return ""
+	}
+	if idx := strings.IndexAny(header, " ,"); idx != -1 {
+		header = header[:idx]
+	}
+	return header
+}
+
+// addTag adds a tag once
+func (e *Endpoint) addTag(tag string) {
+	for _, existing := range e.Tags {
+		if existing == tag {
+			return
+		}
+	}
+	e.Tags = append(e.Tags, tag)
+}
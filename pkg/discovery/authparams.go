@@ -0,0 +1,153 @@
+package discovery
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Tags for 401/403 endpoints retried with the auth parameter names found in
+// the JS files
+const (
+	TagKeyRequired    = "key-required"     // The endpoint reacted to one of the names: a valid key is what is missing
+	TagKeyNameUnknown = "key-name-unknown" // None of the names changed the response
+)
+
+// authPlaceholder is the value sent in each auth parameter
+const authPlaceholder = "jsfinder-placeholder"
+
+// maxAuthParamProbes caps the retries of one endpoint
+const maxAuthParamProbes = 8
+
+var (
+	// Query parameters in URLs and URLSearchParams calls
+	authQueryPattern  = regexp.MustCompile(`[?&]([A-Za-z_][\w-]{1,40})=`)
+	authSearchPattern = regexp.MustCompile(`\.(?:set|append)\(\s*["']([A-Za-z_][\w-]{1,40})["']`)
+	// Header names in setRequestHeader/headers.set calls and headers objects
+	authHeaderPattern = regexp.MustCompile(`["']((?i:authorization)|[Xx]-[A-Za-z0-9-]{1,40})["']\s*[:,]`)
+	// Names that carry credentials, as opposed to paging or search parameters
+	authNamePattern = regexp.MustCompile(`(?i)(api[_-]?key|apikey|access[_-]?(key|token)|auth|token|secret|signature|client[_-]?id|^key$|^sig$|^jwt$|session)`)
+	// Request IDs, timestamps and other values that change on every response
+	authNoisePattern = regexp.MustCompile(`[0-9a-fA-F-]{8,}|\d+`)
+)
+
+// extractAuthParams records the names of query parameters and headers a JS
+// file uses to send credentials
+func (d *Discovery) extractAuthParams(content string) {
+	d.baseURLsMutex.Lock()
+	defer d.baseURLsMutex.Unlock()
+
+	for _, pattern := range []*regexp.Regexp{authQueryPattern, authSearchPattern} {
+		for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+			if authNamePattern.MatchString(match[1]) {
+				d.authParams["query "+match[1]] = true
+			}
+		}
+	}
+	for _, match := range authHeaderPattern.FindAllStringSubmatch(content, -1) {
+		if authNamePattern.MatchString(match[1]) {
+			d.authParams["header "+http.CanonicalHeaderKey(match[1])] = true
+		}
+	}
+}
+
+// authParamNames returns the names found, as "query name" or "header Name"
+func (d *Discovery) authParamNames() []string {
+	d.baseURLsMutex.RLock()
+	defer d.baseURLsMutex.RUnlock()
+
+	names := make([]string, 0, len(d.authParams))
+	for name := range d.authParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > maxAuthParamProbes {
+		names = names[:maxAuthParamProbes]
+	}
+	return names
+}
+
+// probeAuthParams retries a 401/403 endpoint once per auth parameter name
+// found in the JS files, with a placeholder value added to the headers the
+// original probe sent, so only the placeholder differs. A response that
+// differs from the original beyond request IDs and timestamps means the
+// server knows the name, so the endpoint needs a valid key; otherwise the key
+// name is still unknown. The retries stop with the run window, the budget or
+// the global timeout, or when one fails, leaving the endpoint untagged.
+func (d *Discovery) probeAuthParams(endpoint *Endpoint, header http.Header, status int, body []byte) {
+	names := d.authParamNames()
+	if len(names) == 0 {
+		return
+	}
+
+	baseline := normalizeAuthBody(body)
+	for _, name := range names {
+		if err := d.config.Window.Wait(d.timeoutMgr.Context()); err != nil || d.config.Budget.Exceeded() || d.timeoutMgr.Expired() {
+			return
+		}
+		retryStatus, retried, err := d.retryAuthParam(endpoint, header, name)
+		if err != nil {
+			return
+		}
+
+		if retryStatus != status || !bytes.Equal(normalizeAuthBody(retried), baseline) {
+			endpoint.AuthParam = name
+			endpoint.addTag(TagKeyRequired)
+			d.logger.WithField("target", endpoint.URL).Debugf("Responds differently with %s set (HTTP %d, was %d)", name, retryStatus, status)
+			return
+		}
+	}
+	endpoint.addTag(TagKeyNameUnknown)
+}
+
+// retryAuthParam requests endpoint again with a placeholder for the auth
+// parameter name, returning the status and body sample. Like the soft-404
+// baseline, each retry runs within the probe as a nested operation with a
+// timeout of its own and takes no operation slot.
+func (d *Discovery) retryAuthParam(endpoint *Endpoint, header http.Header, name string) (int, []byte, error) {
+	op := d.timeoutMgr.StartNestedOperation("auth-param", endpoint.URL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	kind, param, _ := strings.Cut(name, " ")
+	req, err := http.NewRequestWithContext(op.Ctx, endpoint.Method, endpoint.URL, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("User-Agent", d.config.UserAgent)
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	if endpoint.VirtualHost != "" {
+		req.Host = endpoint.VirtualHost
+	}
+	setHeaders(req, header)
+	if kind == "query" {
+		query := req.URL.Query()
+		query.Set(param, authPlaceholder)
+		req.URL.RawQuery = query.Encode()
+	} else if param == "Authorization" {
+		req.Header.Set(param, "Bearer "+authPlaceholder)
+	} else {
+		req.Header.Set(param, authPlaceholder)
+	}
+	d.addSessionCookies(req)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(d.timeoutMgr.HeartbeatReader(op.ID, resp.Body), maxBodySample))
+	return resp.StatusCode, body, err
+}
+
+// normalizeAuthBody drops what differs between otherwise identical responses:
+// the placeholder echoed back, request IDs and timestamps
+func normalizeAuthBody(body []byte) []byte {
+	body = bytes.ReplaceAll(body, []byte(authPlaceholder), nil)
+	body = bytes.ReplaceAll(body, []byte(url.QueryEscape(authPlaceholder)), nil)
+	return authNoisePattern.ReplaceAll(body, []byte("0"))
+}
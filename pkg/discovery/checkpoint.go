@@ -0,0 +1,227 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkpointInterval is how many probes complete between checkpoint saves
+const checkpointInterval = 500
+
+// probeJob is one entry of the probe stream: a reconstructed endpoint probed
+// as-is, or a word tested against a base URL
+type probeJob struct {
+	endpoint string // Reconstructed endpoint URL, if this is one
+	source   string // JS file the reconstructed endpoint came from
+	base     string
+	word     string
+}
+
+// probeStream is the reconstructed endpoints followed by every base URL x
+// word pair, in a fixed order. Jobs are built on demand from their index, so
+// the stream is never materialized, and an index is enough to resume it.
+type probeStream struct {
+	endpoints []string
+	sources   map[string]string
+	bases     []string
+	words     []string
+}
+
+// newProbeStream orders the probes of the current base URLs and words
+func (d *Discovery) newProbeStream(words []string) *probeStream {
+	stream := &probeStream{sources: d.reconstructed, words: words}
+	for endpoint := range d.reconstructed {
+		stream.endpoints = append(stream.endpoints, endpoint)
+	}
+	sort.Strings(stream.endpoints)
+	for base := range d.probeBases() {
+		stream.bases = append(stream.bases, base)
+	}
+	sort.Strings(stream.bases)
+	return stream
+}
+
+// Len returns the number of jobs in the stream
+func (p *probeStream) Len() int {
+	return len(p.endpoints) + len(p.bases)*len(p.words)
+}
+
+// At returns job i of the stream
+func (p *probeStream) At(i int) probeJob {
+	if i < len(p.endpoints) {
+		return probeJob{endpoint: p.endpoints[i], source: p.sources[p.endpoints[i]]}
+	}
+	i -= len(p.endpoints)
+	return probeJob{base: p.bases[i/len(p.words)], word: p.words[i%len(p.words)]}
+}
+
+// fingerprint identifies the stream, so a checkpoint is only resumed by a
+// run that would send the same probes in the same order
+func (p *probeStream) fingerprint(probesPerWord int) string {
+	hash := sha256.New()
+	for _, part := range [][]string{p.endpoints, p.bases, p.words} {
+		hash.Write([]byte(strings.Join(part, "\n") + "\x00"))
+	}
+	fmt.Fprintf(hash, "%d", probesPerWord)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// key identifies an endpoint among those restored from a checkpoint
+func (e Endpoint) key() string {
+	return e.Method + " " + e.URL + " " + e.VirtualHost
+}
+
+// checkpointState is the layout of a checkpoint file
+type checkpointState struct {
+	Fingerprint string     `json:"fingerprint"`
+	Next        int        `json:"next"` // Every probe before this index of the stream has been sent
+	Total       int        `json:"total"`
+	Saved       time.Time  `json:"saved"`
+	Results     []Endpoint `json:"results"`
+}
+
+// checkpoint records how far the probe stream has got in its file.
+// Probes finish out of order, so it keeps the lowest index not yet done; a
+// resumed run repeats at most the probes that were in flight.
+type checkpoint struct {
+	d         *Discovery
+	path      string
+	state     checkpointState
+	mutex     sync.Mutex
+	done      map[int]bool // Finished probes past Next
+	lastSaved int
+}
+
+// openCheckpoint loads the checkpoint at path if it was saved for the same
+// probe stream, restoring the endpoints found so far, or starts a new one.
+// It returns nil when path is empty.
+func (d *Discovery) openCheckpoint(stream *probeStream, path string) (*checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	c := &checkpoint{
+		d:     d,
+		path:  path,
+		state: checkpointState{Fingerprint: stream.fingerprint(d.probesPerWord()), Total: stream.Len()},
+		done:  make(map[int]bool),
+	}
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var saved checkpointState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", c.path, err)
+	}
+	if saved.Fingerprint != c.state.Fingerprint {
+		d.logger.Warnf("Checkpoint %s is for different base URLs or wordlist; starting over", c.path)
+		return c, nil
+	}
+
+	c.state.Next = min(saved.Next, c.state.Total)
+	c.lastSaved = c.state.Next
+	d.restored = make(map[string]bool, len(saved.Results))
+	for _, endpoint := range saved.Results {
+		d.restored[endpoint.key()] = true
+	}
+	d.mutex.Lock()
+	// Endpoints found again by probes run before this stream, such as a
+	// repeated stage wordlist, are kept once
+	found := make(map[string]bool, len(d.results))
+	for _, endpoint := range d.results {
+		found[endpoint.key()] = true
+	}
+	d.results = append(slices.DeleteFunc(saved.Results, func(endpoint Endpoint) bool {
+		return found[endpoint.key()]
+	}), d.results...)
+	d.mutex.Unlock()
+	d.logger.Infof("Resuming from checkpoint %s at probe %d of %d with %d endpoints found", c.path, c.state.Next, c.state.Total, len(saved.Results))
+	return c, nil
+}
+
+// Next returns the index of the first probe to send
+func (c *checkpoint) Next() int {
+	if c == nil {
+		return 0
+	}
+	return c.state.Next
+}
+
+// Done marks probe i as sent, saving the checkpoint every checkpointInterval probes
+func (c *checkpoint) Done(i int) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.done[i] = true
+	for c.done[c.state.Next] {
+		delete(c.done, c.state.Next)
+		c.state.Next++
+	}
+	if c.state.Next-c.lastSaved >= checkpointInterval {
+		if err := c.save(); err != nil {
+			c.d.logger.Warnf("Failed to save checkpoint: %v", err)
+		}
+	}
+}
+
+// Finish removes the checkpoint once every probe was sent, or saves it so a
+// later run can pick up where this one stopped
+func (c *checkpoint) Finish() error {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.state.Next >= c.state.Total {
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove checkpoint: %w", err)
+		}
+		return nil
+	}
+	if err := c.save(); err != nil {
+		return err
+	}
+	c.d.logger.Infof("Stopped at probe %d of %d; run again with --checkpoint %s to resume", c.state.Next, c.state.Total, c.path)
+	return nil
+}
+
+// save writes the checkpoint with the endpoints found so far, replacing the
+// previous one atomically so an interrupted write cannot corrupt it
+func (c *checkpoint) save() error {
+	c.d.mutex.Lock()
+	c.state.Results = append([]Endpoint(nil), c.d.results...)
+	c.d.mutex.Unlock()
+	c.state.Saved = time.Now()
+
+	data, err := json.Marshal(c.state)
+	c.state.Results = nil
+	if err != nil {
+		return err
+	}
+	temp := c.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(temp, c.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	c.lastSaved = c.state.Next
+	return nil
+}
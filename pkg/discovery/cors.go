@@ -0,0 +1,152 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"jsfinder/pkg/utils"
+)
+
+// CORSTestOrigin is the Origin sent by the CORS check; a server that allows
+// it would allow any site
+const CORSTestOrigin = "https://evil.example"
+
+// TagCORSReflected marks endpoints that echo an arbitrary Origin back in
+// Access-Control-Allow-Origin
+const TagCORSReflected = "cors-reflected"
+
+// CORSMisconfiguration is the type of the findings the CORS check reports
+const CORSMisconfiguration = "CORS_MISCONFIGURATION"
+
+// corsHeaders are the response headers captured as evidence
+var corsHeaders = []string{
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Expose-Headers",
+	"Vary",
+}
+
+// CORSFinding is an endpoint that lets any origin read its responses with
+// the visitor's cookies
+type CORSFinding struct {
+	Type        string            `json:"type"`
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Origin      string            `json:"origin"` // Origin sent
+	StatusCode  int               `json:"status_code"`
+	Severity    string            `json:"severity"`
+	Description string            `json:"description"`
+	Headers     map[string]string `json:"headers"` // CORS response headers as received
+	Labels      utils.Labels      `json:"labels,omitempty"`
+}
+
+// checkCORS requests every endpoint found again with Origin: CORSTestOrigin.
+// Endpoints that reflect it are tagged, and those that also allow
+// credentials are reported as CORS_MISCONFIGURATION findings.
+func (d *Discovery) checkCORS() {
+	if !d.config.CORSCheck || len(d.results) == 0 {
+		return
+	}
+
+	d.followUp(func(i int) {
+		finding, reflected := d.probeCORS(d.results[i])
+		if !reflected {
+			return
+		}
+		d.results[i].addTag(TagCORSReflected)
+		if finding == nil {
+			return
+		}
+
+		d.mutex.Lock()
+		d.corsFindings = append(d.corsFindings, *finding)
+		d.mutex.Unlock()
+		d.stats.AddFinding(finding.Severity)
+		d.logger.WithField("target", finding.URL).Warnf("%s: %s", CORSMisconfiguration, finding.Description)
+	})
+}
+
+// probeCORS sends the CORS check request for an endpoint and reports whether
+// the test origin was reflected, with a finding if credentials are allowed too
+func (d *Discovery) probeCORS(endpoint Endpoint) (*CORSFinding, bool) {
+	op := d.timeoutMgr.StartOperation("cors", endpoint.URL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	req, err := http.NewRequestWithContext(op.Ctx, endpoint.Method, endpoint.URL, nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("User-Agent", d.config.UserAgent)
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("Origin", CORSTestOrigin)
+	if endpoint.VirtualHost != "" {
+		req.Host = endpoint.VirtualHost
+	}
+	d.addSessionCookies(req)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.logger.WithField("target", endpoint.URL).Debugf("CORS check failed: %v", err)
+		return nil, false
+	}
+	resp.Body.Close()
+
+	if resp.Header.Get("Access-Control-Allow-Origin") != CORSTestOrigin {
+		return nil, false
+	}
+	if !strings.EqualFold(resp.Header.Get("Access-Control-Allow-Credentials"), "true") {
+		return nil, true
+	}
+
+	headers := make(map[string]string)
+	for _, name := range corsHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	return &CORSFinding{
+		Type:        CORSMisconfiguration,
+		URL:         endpoint.URL,
+		Method:      endpoint.Method,
+		Origin:      CORSTestOrigin,
+		StatusCode:  resp.StatusCode,
+		Severity:    "HIGH",
+		Description: "Arbitrary origin reflected in Access-Control-Allow-Origin with credentials allowed; any site can read responses with the visitor's cookies",
+		Headers:     headers,
+		Labels:      d.config.Labels,
+	}, true
+}
+
+// CORSFindings returns the CORS misconfigurations found so far
+func (d *Discovery) CORSFindings() []CORSFinding {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([]CORSFinding(nil), d.corsFindings...)
+}
+
+// writeCORSFindings saves the CORS findings as JSON when a CORS output file is set
+func (d *Discovery) writeCORSFindings() error {
+	if !d.config.CORSCheck || d.config.CORSOutput == "" {
+		return nil
+	}
+
+	file, err := os.Create(d.config.CORSOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create CORS output file: %w", err)
+	}
+	defer file.Close()
+
+	findings := d.CORSFindings()
+	if findings == nil {
+		findings = []CORSFinding{}
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(findings)
+}
@@ -2,80 +2,207 @@ package discovery
 
 import (
 	"bufio"
-	"encoding/csv"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"jsfinder/pkg/input"
+	"jsfinder/pkg/literal"
+	"jsfinder/pkg/match"
+	"jsfinder/pkg/scope"
+	"jsfinder/pkg/utils"
 )
 
 // Config holds the configuration for endpoint discovery
 type Config struct {
-	InputFile    string
-	OutputFile   string
-	WordlistFile string
-	Threads      int
-	Timeout      int
-	StatusFilter string
-	MaxRedirects int
-	UserAgent    string
-	Verbose      bool
+	InputFile        string
+	OutputFile       string
+	WordlistFile     string
+	Threads          int
+	Timeout          int
+	StatusFilter     string
+	MaxRedirects     int
+	UserAgent        string
+	Verbose          bool
+	Stats            *utils.RunStats
+	Budget           *utils.Budget
+	Window           *utils.RunWindow
+	Shard            *utils.Shard
+	Soft404          string
+	StopCrossOrigin  bool
+	Scope            *scope.Scope
+	Identity         *utils.Identity
+	Auth             *utils.Auth
+	UAFallback       *utils.UAFallback
+	Timeouts         *utils.ClientTimeouts
+	Dialer           *utils.Dialer
+	Memory           *utils.MemoryGuard
+	Errors           *utils.ErrorLog
+	ConfirmThreshold int64                  // Estimated request count above which Confirm is asked (0 = never)
+	Confirm          func(total int64) bool // Approves large runs; nil approves every run
+	GlobalTimeout    time.Duration          // Stop starting new probes and cancel stuck ones after this long; 0 for no limit
+	Sort             string                 // SortURL or SortStatus orders results before writing; empty keeps discovery order
+	SessionCookies   bool                   // Capture the cookies each base URL sets and replay them on probes of its host
+	Match            *match.Expr            // Responses to report, replacing StatusFilter when set
+	Filter           *match.Expr            // Responses to drop even if they match
+	Template         *Template              // Where wordlist entries are placed; nil tests the fixed path variations
+	VHost            bool                   // Template fuzzes the Host header; drop responses matching the default virtual host
+	Raw              *RawRequest            // Request file sent as written per word instead of a Template or the path variations
+	CSV              *utils.CSVOptions      // Column selection and formula escaping for CSV output
+	AllHosts         bool                   // Probe every host named in JS files, not only those on the file's own domain or allowed by the scope
+	Labels           utils.Labels           // Attached to every endpoint
+	OOBServer        string                 // Interactsh server for a follow-up probe of each endpoint with callback hosts; empty disables it
+	OOBToken         string                 // Authorization for OOBServer, if it requires one
+	OOBWait          time.Duration          // How long to wait for out-of-band interactions after the follow-up probes
+	CORSCheck        bool                   // Request each endpoint found again with an arbitrary Origin and report credentialed reflection
+	AuthParams       bool                   // Retry 401/403 endpoints with the auth parameter names found in JS files
+	CORSOutput       string                 // JSON file for CORS findings; empty logs them only
+	CollapseRoutes   bool                   // Report one endpoint per route, with numeric IDs and UUIDs in the path collapsed, and probe reconstructed endpoints once per route
+	OpenAPIDir       string                 // Directory for one OpenAPI skeleton per host built from the endpoints found; empty disables the export
+	Checkpoint       string                 // File recording probe progress and endpoints found, resumed by a later run with the same base URLs and wordlist; empty disables it
+	StageWordlist    string                 // Small wordlist probed on every base URL first; the main wordlist then only goes to base URLs it found StageMinHits endpoints on. Empty probes the main wordlist everywhere
+	StageMinHits     int                    // Endpoints the stage wordlist must find on a base URL before the main wordlist is probed there (default 1)
+	OnEndpoint       func(Endpoint)         // Called with each endpoint as it is recorded, before follow-up probes, possibly from several goroutines at once
 }
 
 // Discovery represents the endpoint discovery engine
 type Discovery struct {
-	config        *Config
-	client        *http.Client
-	wordlist      []string
-	statusFilter  map[int]bool
-	results       []Endpoint
-	mutex         sync.Mutex
-	baseURLs      map[string]bool
-	baseURLsMutex sync.RWMutex
+	config         *Config
+	client         *http.Client
+	prober         *input.SchemeProber
+	raw            *utils.RawClient // Sends Config.Raw, nil without it
+	wordlist       []string
+	stageWords     []string // Config.StageWordlist, nil without it
+	statusFilter   map[int]bool
+	results        []Endpoint
+	mutex          sync.Mutex
+	baseURLs       map[string]bool
+	reconstructed  map[string]string // Endpoint URL rebuilt from JS constants -> JS file it came from
+	foreignHosts   map[string]bool   // Base URLs named in JS files but left out as third-party hosts
+	authParams     map[string]bool   // Credential parameters named in JS files, "query name" or "header Name"
+	routes         map[string]bool   // Origin and route of reconstructed endpoints, with CollapseRoutes
+	restored       map[string]bool   // Endpoints restored from a checkpoint, by key, so probes repeated on resume are not reported twice
+	baseURLsMutex  sync.RWMutex
+	stats          *utils.RunStats
+	baselineProbes map[string]*baselineProbe // Not-found and default vhost baselines per base URL
+	baselinesMutex sync.Mutex
+	logger         *utils.Logger
+	timeoutMgr     *utils.TimeoutManager
+	sessions       *cookiejar.Jar           // Cookies captured per host with SessionCookies, nil otherwise
+	primed         map[string]chan struct{} // Closed once the host's session request is done
+	oob            *utils.Interactsh        // Session with Config.OOBServer during a run
+	corsFindings   []CORSFinding
+	sessionsMutex  sync.Mutex
+	latency        map[string]*latencyBaseline // Response time baseline per base URL
+	latencyMutex   sync.Mutex
 }
 
 // Endpoint represents a discovered endpoint
 type Endpoint struct {
-	URL            string `json:"url" csv:"url"`
-	StatusCode     int    `json:"status_code" csv:"status_code"`
-	ContentLength  int64  `json:"content_length" csv:"content_length"`
-	ContentType    string `json:"content_type" csv:"content_type"`
-	ResponseTime   int64  `json:"response_time_ms" csv:"response_time_ms"`
-	Source         string `json:"source" csv:"source"`
-	Method         string `json:"method" csv:"method"`
-	RedirectChain  string `json:"redirect_chain,omitempty" csv:"redirect_chain"`
+	URL            string           `json:"url" csv:"url"`
+	Route          string           `json:"route,omitempty" csv:"route"`           // Path with IDs and UUIDs as placeholders, see routeTemplate
+	RouteHits      int              `json:"route_hits,omitempty" csv:"route_hits"` // Endpoints found for the route, with CollapseRoutes
+	StatusCode     int              `json:"status_code" csv:"status_code"`
+	ContentLength  int64            `json:"content_length" csv:"content_length"`
+	ContentType    string           `json:"content_type" csv:"content_type"`
+	Schema         string           `json:"schema,omitempty" csv:"schema"` // Shape of a JSON response body, see jsonSchema
+	ResponseTime   int64            `json:"response_time_ms" csv:"response_time_ms"`
+	LatencyAnomaly string           `json:"latency_anomaly,omitempty" csv:"latency_anomaly"` // LatencySlow or LatencyFast against the host's usual response time
+	Source         string           `json:"source" csv:"source"`
+	Method         string           `json:"method" csv:"method"`
+	RedirectChain  string           `json:"redirect_chain,omitempty" csv:"redirect_chain"`
+	Redirects      []RedirectHop    `json:"redirects,omitempty" csv:"-"`
+	AuthScheme     string           `json:"auth_scheme,omitempty" csv:"auth_scheme"`
+	AuthParam      string           `json:"auth_param,omitempty" csv:"auth_param"` // Auth parameter from the JS files a 401/403 endpoint reacted to
+	CORSOrigin     string           `json:"cors_origin,omitempty" csv:"cors_origin"`
+	VirtualHost    string           `json:"virtual_host,omitempty" csv:"virtual_host"` // Host header sent, when a template overrides it
+	OptionsAllowed bool             `json:"options_allowed,omitempty" csv:"options_allowed"`
+	AllowedMethods string           `json:"allowed_methods,omitempty" csv:"allowed_methods"`
+	Tags           []string         `json:"tags,omitempty" csv:"tags"`
+	OOB            []OOBInteraction `json:"oob,omitempty" csv:"oob"`       // Out-of-band requests the follow-up probe caused, with OOBServer
+	Labels         utils.Labels     `json:"labels,omitempty" csv:"labels"` // --label pairs of the run that found it
 }
 
 // New creates a new discovery instance
 func New(config *Config) *Discovery {
-	client := &http.Client{
-		Timeout: time.Duration(config.Timeout) * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= config.MaxRedirects {
-				return http.ErrUseLastResponse
-			}
-			return nil
-		},
+	stats := config.Stats
+	if stats == nil {
+		stats = utils.NewRunStats()
 	}
 
+	client := utils.NewHTTPClient(&utils.ClientOptions{
+		Timeout:    time.Duration(config.Timeout) * time.Second,
+		Stats:      stats,
+		Budget:     config.Budget,
+		Scope:      config.Scope,
+		Identity:   config.Identity,
+		Auth:       config.Auth,
+		UAFallback: config.UAFallback,
+		Timeouts:   config.Timeouts,
+		Dialer:     config.Dialer,
+		HostSNI:    config.VHost,
+	})
+
+	logger := utils.NewModuleLogger("discovery")
+	timeoutConfig := utils.DiscoveryTimeoutConfig().
+		WithRequestTimeout(time.Duration(config.Timeout)*time.Second, 1)
+	timeoutConfig.GlobalTimeout = config.GlobalTimeout
+	timeoutMgr := utils.NewTimeoutManager(timeoutConfig, logger)
+	stats.TrackOperations(timeoutMgr)
+
 	discovery := &Discovery{
-		config:   config,
-		client:   client,
-		results:  make([]Endpoint, 0),
-		baseURLs: make(map[string]bool),
+		config:         config,
+		client:         client,
+		prober:         input.NewSchemeProber(client),
+		results:        make([]Endpoint, 0),
+		baseURLs:       make(map[string]bool),
+		reconstructed:  make(map[string]string),
+		foreignHosts:   make(map[string]bool),
+		authParams:     make(map[string]bool),
+		routes:         make(map[string]bool),
+		stats:          stats,
+		baselineProbes: make(map[string]*baselineProbe),
+		logger:         logger,
+		timeoutMgr:     timeoutMgr,
+		sessions:       newSessionJar(config.SessionCookies),
+		primed:         make(map[string]chan struct{}),
+		latency:        make(map[string]*latencyBaseline),
+	}
+
+	client.CheckRedirect = discovery.checkRedirect
+	if config.Raw != nil {
+		discovery.raw = utils.NewRawClient(&utils.ClientOptions{
+			Timeout:  time.Duration(config.Timeout) * time.Second,
+			Stats:    stats,
+			Budget:   config.Budget,
+			Scope:    config.Scope,
+			Identity: config.Identity,
+			Auth:     config.Auth,
+			Timeouts: config.Timeouts,
+			Dialer:   config.Dialer,
+		})
 	}
 
 	discovery.parseStatusFilter()
 	return discovery
 }
 
+// Stats returns the run statistics collected by the discovery engine
+func (d *Discovery) Stats() *utils.RunStats {
+	return d.stats
+}
+
 // DiscoverFromFile discovers endpoints from JS files listed in input file
 func (d *Discovery) DiscoverFromFile(inputFile string) error {
 	file, err := os.Open(inputFile)
@@ -92,23 +219,62 @@ func (d *Discovery) DiscoverFromStdin() error {
 	return d.discoverFromReader(os.Stdin)
 }
 
+// DiscoverURLs discovers endpoints from the given JS file URLs, such as
+// those a previous run failed to fetch
+func (d *Discovery) DiscoverURLs(urls []string) error {
+	return d.discoverFromReader(strings.NewReader(strings.Join(urls, "\n")))
+}
+
 func (d *Discovery) discoverFromReader(reader io.Reader) error {
 	// Load wordlist
 	if err := d.loadWordlist(); err != nil {
 		return fmt.Errorf("failed to load wordlist: %w", err)
 	}
 
-	if d.config.Verbose {
-		fmt.Printf("Loaded %d words from wordlist\n", len(d.wordlist))
+	d.logger.Debugf("Loaded %d words from wordlist", len(d.wordlist))
+
+	if d.config.OOBServer != "" {
+		oob, err := utils.NewInteractsh(d.config.OOBServer, d.config.OOBToken, &utils.ClientOptions{
+			Timeout:  30 * time.Second,
+			Identity: d.config.Identity,
+			Timeouts: d.config.Timeouts,
+			Dialer:   d.config.Dialer,
+		})
+		if err != nil {
+			return err
+		}
+		d.oob = oob
+		defer func() {
+			if err := oob.Close(); err != nil {
+				d.logger.Warnf("Failed to deregister from interactsh server: %v", err)
+			}
+		}()
 	}
 
 	// Extract base URLs from JS files
-	scanner := bufio.NewScanner(reader)
+	scanner := input.NewScanner(reader)
 	for scanner.Scan() {
-		jsURL := strings.TrimSpace(scanner.Text())
-		if jsURL != "" {
-			if err := d.extractBaseURLs(jsURL); err != nil && d.config.Verbose {
-				fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", jsURL, err)
+		jsURL := scanner.Target().URL
+		if d.config.Shard.Includes(jsURL) {
+			if !d.config.Scope.AllowsURL(jsURL) {
+				d.logger.WithField("target", jsURL).Debug("Skipping out-of-scope URL")
+				continue
+			}
+			if d.config.Budget.Exceeded() {
+				d.stats.SetStopReason(d.config.Budget.Reason())
+				break
+			}
+			if err := d.config.Window.Wait(d.timeoutMgr.Context()); err != nil {
+				break
+			}
+			if d.timeoutMgr.Expired() {
+				d.stats.SetStopReason(d.timeoutMgr.ExpiredReason())
+				break
+			}
+			jsURL = d.prober.Resolve(d.timeoutMgr.Context(), scanner.Target()).URL
+			if err := d.extractBaseURLs(jsURL); err != nil {
+				d.config.Errors.Record(jsURL, err)
+				d.logger.WithField("target", jsURL).Warnf("Error processing: %v", err)
 			}
 		}
 	}
@@ -117,53 +283,231 @@ func (d *Discovery) discoverFromReader(reader io.Reader) error {
 		return err
 	}
 
-	if d.config.Verbose {
-		fmt.Printf("Extracted %d unique base URLs\n", len(d.baseURLs))
+	d.logger.Infof("Extracted %d unique base URLs", len(d.baseURLs))
+	if skipped := len(d.foreignHosts); skipped > 0 {
+		d.logger.Infof("Skipped %d base URLs on other domains than their JS files (use --all-hosts to probe them)", skipped)
+	}
+
+	if err := d.confirmRequestCount(); err != nil {
+		return err
 	}
 
 	// Discover endpoints
 	if err := d.discoverEndpoints(); err != nil {
 		return err
 	}
+	d.collapseRoutes()
+	d.checkCORS()
+	d.probeOOB()
+	if err := d.writeCORSFindings(); err != nil {
+		return err
+	}
 
-	return d.outputResults()
+	if err := d.outputResults(); err != nil {
+		return err
+	}
+	return d.writeOpenAPI()
+}
+
+// Plan builds the request plan for the JS files read from reader without sending
+// traffic. Only the origin of each JS file is known up front; base URLs found
+// inside the JS content add the same per-base request count each.
+func (d *Discovery) Plan(reader io.Reader) (*utils.RequestPlan, error) {
+	if err := d.loadWordlist(); err != nil {
+		return nil, fmt.Errorf("failed to load wordlist: %w", err)
+	}
+
+	perBase := int64(len(d.wordlist) * d.probesPerWord())
+	if d.stageWords != nil {
+		perBase = int64(len(d.stageWords) * d.probesPerWord())
+	}
+	if d.config.Soft404 != Soft404Off {
+		perBase++ // Not-found baseline probe
+	}
+	if d.config.SessionCookies {
+		perBase++ // Base URL request capturing the session
+	}
+
+	plan := utils.NewRequestPlan("discover", d.config.Threads, time.Duration(d.config.Timeout)*time.Second)
+	plan.AddSetting("Wordlist", fmt.Sprintf("%s (%d words)", d.config.WordlistFile, len(d.wordlist)))
+	if d.stageWords != nil {
+		plan.AddSetting("Stage wordlist", fmt.Sprintf("%s (%d words)", d.config.StageWordlist, len(d.stageWords)))
+		plan.AddNote("base URLs where the stage wordlist finds at least %d endpoints add up to %d requests each for the rest of the wordlist",
+			d.minStageHits(), len(d.escalationWords())*d.probesPerWord())
+	}
+	plan.AddSetting("Requests per base URL", perBase)
+	if d.config.Match != nil {
+		plan.AddSetting("Match", d.config.Match.String())
+	}
+	if d.config.Filter != nil {
+		plan.AddSetting("Filter", d.config.Filter.String())
+	}
+	if d.config.Template != nil {
+		plan.AddSetting("URL template", d.config.Template.String())
+	}
+	if d.config.Raw != nil {
+		plan.AddSetting("Raw request", d.config.Raw.String())
+	}
+	if d.config.Checkpoint != "" {
+		plan.AddSetting("Checkpoint", d.config.Checkpoint)
+		plan.AddNote("probes already recorded in %s are skipped when it matches the base URLs and wordlist", d.config.Checkpoint)
+	}
+	origin, fixed := d.fixedOrigin()
+	if fixed {
+		plan.Add(origin, perBase)
+	}
+
+	skipped := 0
+	bases := make(map[string]bool)
+	scanner := input.NewScanner(reader)
+	for scanner.Scan() {
+		jsURL := scanner.Target().URL
+		if !d.config.Shard.Includes(jsURL) {
+			continue
+		}
+		if !d.config.Scope.AllowsURL(jsURL) {
+			skipped++
+			continue
+		}
+
+		plan.Add(jsURL, 1)
+		if base := d.extractBaseURL(jsURL); base != "" && !bases[base] && !fixed {
+			bases[base] = true
+			plan.Add(base, perBase)
+		}
+	}
+
+	if skipped > 0 {
+		plan.AddNote("%d out-of-scope URLs skipped", skipped)
+	}
+	if !fixed {
+		plan.AddNote("base URLs extracted from JS content add %d requests each, plus OPTIONS probes for auth-protected hits", perBase)
+		if !d.config.AllHosts && !d.config.Scope.HasAllowRules() {
+			plan.AddNote("only base URLs on the registrable domain of their JS file are probed")
+		}
+	}
+	plan.AddNote("endpoints reconstructed from JS constants add one request each")
+	if d.config.AuthParams {
+		plan.AddNote("401/403 endpoints add up to %d requests each, retried with the auth parameter names found in the JS files", maxAuthParamProbes)
+	}
+	if d.config.CORSCheck {
+		plan.AddNote("each endpoint found gets one more request with Origin: %s", CORSTestOrigin)
+	}
+	if d.config.OOBServer != "" {
+		plan.AddSetting("OOB server", d.config.OOBServer)
+		plan.AddNote("each endpoint found gets one more request carrying callback hosts, then interactions are awaited for %s", d.config.OOBWait)
+	}
+	return plan, scanner.Err()
 }
 
 func (d *Discovery) loadWordlist() error {
-	file, err := os.Open(d.config.WordlistFile)
+	words, err := readWordlist(d.config.WordlistFile)
 	if err != nil {
 		return err
 	}
+	d.wordlist = words
+	if d.config.StageWordlist == "" {
+		return nil
+	}
+	if d.stageWords, err = readWordlist(d.config.StageWordlist); err != nil {
+		return fmt.Errorf("stage wordlist: %w", err)
+	}
+	return nil
+}
+
+// readWordlist returns the entries of a wordlist file or builtin list,
+// skipping blank lines and comments
+func readWordlist(name string) ([]string, error) {
+	file, err := openWordlist(name)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
+	var words []string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		word := strings.TrimSpace(scanner.Text())
 		if word != "" && !strings.HasPrefix(word, "#") {
-			d.wordlist = append(d.wordlist, word)
+			words = append(words, word)
+		}
+	}
+
+	return words, scanner.Err()
+}
+
+// escalationWords returns the main wordlist without the entries the stage
+// wordlist already probed
+func (d *Discovery) escalationWords() []string {
+	staged := make(map[string]bool, len(d.stageWords))
+	for _, word := range d.stageWords {
+		staged[word] = true
+	}
+	var words []string
+	for _, word := range d.wordlist {
+		if !staged[word] {
+			words = append(words, word)
 		}
 	}
+	return words
+}
 
-	return scanner.Err()
+// wordCount returns the most words probed on a base URL, which with a stage
+// wordlist is when it escalates to the main one
+func (d *Discovery) wordCount() int {
+	return len(d.stageWords) + len(d.escalationWords())
+}
+
+// minStageHits returns Config.StageMinHits, defaulting to 1
+func (d *Discovery) minStageHits() int {
+	if d.config.StageMinHits > 0 {
+		return d.config.StageMinHits
+	}
+	return 1
 }
 
 func (d *Discovery) extractBaseURLs(jsURL string) error {
-	resp, err := d.client.Get(jsURL)
+	op := d.timeoutMgr.StartOperation("extract", jsURL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	req, err := http.NewRequestWithContext(op.Ctx, "GET", jsURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.sessionClient().Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, jsURL)
+		return utils.NewHTTPError(fmt.Sprintf("HTTP %d: %s", resp.StatusCode, jsURL), resp.StatusCode, nil)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(d.timeoutMgr.HeartbeatReader(op.ID, resp.Body))
 	if err != nil {
 		return err
 	}
 
 	content := string(body)
+	d.extractAuthParams(content)
+
+	// Template literals and concatenations built from constants only name an
+	// endpoint once resolved, so append them as plain strings
+	for _, resolved := range literal.Resolve(content) {
+		content += "\n\"" + resolved.Value + "\""
+		d.addReconstructed(resolved.Value, jsURL)
+	}
+
+	// Framework idioms name endpoints through a client's base URL or the
+	// build ID, which the plain patterns below cannot join
+	frameworks, endpoints := frameworkEndpoints(content, jsURL)
+	if len(frameworks) > 0 {
+		d.logger.WithField("target", jsURL).Debugf("Detected %s: %d endpoints recovered", strings.Join(frameworks, ", "), len(endpoints))
+	}
+	for _, endpoint := range endpoints {
+		d.addReconstructed(endpoint, jsURL)
+	}
 
 	// Extract potential API endpoints from JS content
 	patterns := []*regexp.Regexp{
@@ -181,7 +525,7 @@ func (d *Discovery) extractBaseURLs(jsURL string) error {
 		for _, match := range matches {
 			if len(match) > 1 {
 				baseURL := d.extractBaseURL(match[1])
-				if baseURL != "" {
+				if baseURL != "" && d.keepHost(baseURL, jsURL) {
 					d.baseURLsMutex.Lock()
 					d.baseURLs[baseURL] = true
 					d.baseURLsMutex.Unlock()
@@ -191,9 +535,7 @@ func (d *Discovery) extractBaseURLs(jsURL string) error {
 	}
 
 	// Also add the base URL of the JS file itself
-	parsedURL, err := url.Parse(jsURL)
-	if err == nil {
-		baseURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
+	if baseURL := d.extractBaseURL(jsURL); baseURL != "" {
 		d.baseURLsMutex.Lock()
 		d.baseURLs[baseURL] = true
 		d.baseURLsMutex.Unlock()
@@ -202,43 +544,207 @@ func (d *Discovery) extractBaseURLs(jsURL string) error {
 	return nil
 }
 
-func (d *Discovery) extractBaseURL(urlStr string) string {
-	parsedURL, err := url.Parse(urlStr)
+// addReconstructed records an endpoint rebuilt from JS constants so it is
+// probed directly; root-relative paths resolve against the JS file's origin
+func (d *Discovery) addReconstructed(value, jsURL string) {
+	if !strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		return
+	}
+
+	base, err := url.Parse(jsURL)
+	if err != nil {
+		return
+	}
+	ref, err := url.Parse(value)
 	if err != nil {
-		return ""
+		return
 	}
+	endpoint := base.ResolveReference(ref)
+	if endpoint.Host == "" || !d.keepHost(endpoint.String(), jsURL) {
+		return
+	}
+
+	d.baseURLsMutex.Lock()
+	if !d.config.CollapseRoutes || d.addReconstructedRoute(endpoint.String()) {
+		d.reconstructed[endpoint.String()] = jsURL
+	}
+	d.baseURLsMutex.Unlock()
+}
 
-	if parsedURL.Scheme == "" || parsedURL.Host == "" {
-		return ""
+// keepHost reports whether a URL named in a JS file is worth probing. Besides
+// passing the scope, its host must be on the JS file's registrable domain, so
+// the CDNs and analytics services every site references are not brute-forced;
+// a scope with allow rules or AllHosts lifts that restriction.
+func (d *Discovery) keepHost(target, jsURL string) bool {
+	if d.config.AllHosts {
+		return d.config.Scope.AllowsURL(target)
+	}
+	if d.config.Scope.AllowsLinked(target, jsURL) {
+		return true
 	}
+	if !d.config.Scope.AllowsURL(target) {
+		return false
+	}
+
+	d.baseURLsMutex.Lock()
+	d.foreignHosts[d.extractBaseURL(target)] = true
+	d.baseURLsMutex.Unlock()
+	return false
+}
 
-	return fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
+// extractBaseURL returns the origin of a URL without its credentials, with
+// the host lowercased and default ports dropped so variants of one origin
+// are probed once
+func (d *Discovery) extractBaseURL(urlStr string) string {
+	return utils.Origin(urlStr)
 }
 
 func (d *Discovery) discoverEndpoints() error {
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, d.config.Threads)
-
-	for baseURL := range d.baseURLs {
-		for _, word := range d.wordlist {
-			wg.Add(1)
-			go func(base, endpoint string) {
-				defer wg.Done()
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
-
-				d.testEndpoint(base, endpoint)
-			}(baseURL, word)
+	if d.stageWords == nil {
+		return d.runProbes(d.newProbeStream(d.wordlist), d.config.Checkpoint)
+	}
+
+	// The stage wordlist goes to every base URL along with the reconstructed
+	// endpoints; the rest of the main wordlist only to base URLs it found
+	// enough endpoints on. Only the second stage is checkpointed, the first
+	// is small enough to repeat on resume.
+	if err := d.runProbes(d.newProbeStream(d.stageWords), ""); err != nil {
+		return err
+	}
+	if d.timeoutMgr.Expired() || d.config.Budget.Exceeded() {
+		return nil
+	}
+	stream := d.newProbeStream(d.escalationWords())
+	stream.endpoints = nil
+	hits := make(map[string]int)
+	d.mutex.Lock()
+	for _, endpoint := range d.results {
+		hits[endpoint.Source]++
+	}
+	d.mutex.Unlock()
+	all := len(stream.bases)
+	stream.bases = slices.DeleteFunc(stream.bases, func(base string) bool {
+		return hits[base] < d.minStageHits()
+	})
+	d.logger.Infof("Stage wordlist found at least %d endpoints on %d of %d base URLs; probing them with %d more words", d.minStageHits(), len(stream.bases), all, len(stream.words))
+	if len(stream.words) == 0 {
+		return nil
+	}
+	return d.runProbes(stream, d.config.Checkpoint)
+}
+
+// runProbes sends the probes of stream, resuming from and recording
+// progress in the checkpoint file at path unless it is empty
+func (d *Discovery) runProbes(stream *probeStream, path string) error {
+	checkpoint, err := d.openCheckpoint(stream, path)
+	if err != nil {
+		return err
+	}
+
+	pool := utils.NewWorkerPool(d.timeoutMgr.Context(), utils.PoolOptions{Workers: d.config.Threads, Logger: d.logger})
+	d.stats.AddQueued(int64(stream.Len() - checkpoint.Next()))
+
+	// Endpoints rebuilt from JS constants come first and are probed as-is,
+	// then every base URL x word pair. Jobs are built from their index as
+	// workers free up, so the probe list is never held in memory and a
+	// checkpoint only needs the index reached.
+	for i := checkpoint.Next(); i < stream.Len(); i++ {
+		if err := d.config.Memory.Wait(d.timeoutMgr.Context()); err != nil {
+			break
 		}
+		job := stream.At(i)
+		pool.Submit(func(ctx context.Context, workerID int) {
+			target := job.base
+			if job.endpoint != "" {
+				target = job.endpoint
+			}
+			logger := d.logger.WithFields(utils.WorkerFields(target, workerID))
+
+			if d.config.Budget.Exceeded() {
+				d.stats.SetStopReason(d.config.Budget.Reason())
+				logger.Debugf("Skipping %s: %s", target, d.config.Budget.Reason())
+				return
+			}
+			if job.endpoint != "" {
+				if err := d.config.Window.Wait(ctx); err != nil {
+					return
+				}
+			}
+			if d.timeoutMgr.Expired() {
+				d.stats.SetStopReason(d.timeoutMgr.ExpiredReason())
+				return
+			}
+
+			if job.endpoint != "" {
+				logger.Debugf("Testing reconstructed endpoint from %s", job.source)
+				d.makeRequest(job.endpoint, "GET", job.source)
+			} else {
+				logger.Debugf("Testing endpoint %s", job.word)
+				d.testEndpoint(job.base, job.word)
+			}
+			d.stats.AddProcessed()
+			checkpoint.Done(i)
+		})
+	}
+
+	pool.Wait()
+	d.stats.AddWorkerPool(pool)
+	return checkpoint.Finish()
+}
+
+// followUp runs probe for the index of every endpoint found, on up to
+// Threads workers, once discovery has finished. Probes stop when the budget,
+// run window or deadline says so.
+func (d *Discovery) followUp(probe func(i int)) {
+	pool := utils.NewWorkerPool(d.timeoutMgr.Context(), utils.PoolOptions{Workers: d.config.Threads, Logger: d.logger})
+	d.stats.AddQueued(int64(len(d.results)))
+	for i := range d.results {
+		pool.Submit(func(ctx context.Context, workerID int) {
+			defer d.stats.AddProcessed()
+
+			if d.config.Budget.Exceeded() {
+				d.stats.SetStopReason(d.config.Budget.Reason())
+				return
+			}
+			if err := d.config.Window.Wait(ctx); err != nil || d.timeoutMgr.Expired() {
+				return
+			}
+			probe(i)
+		})
+	}
+	pool.Wait()
+	d.stats.AddWorkerPool(pool)
+}
+
+// EstimatedRequests returns the number of probe requests for the extracted base URLs
+func (d *Discovery) EstimatedRequests() int64 {
+	d.baseURLsMutex.RLock()
+	defer d.baseURLsMutex.RUnlock()
+
+	return int64(len(d.probeBases()))*int64(d.wordCount())*int64(d.probesPerWord()) + int64(len(d.reconstructed))
+}
+
+// confirmRequestCount asks for confirmation before runs above the configured threshold
+func (d *Discovery) confirmRequestCount() error {
+	total := d.EstimatedRequests()
+	if d.config.ConfirmThreshold <= 0 || total <= d.config.ConfirmThreshold {
+		return nil
 	}
 
-	wg.Wait()
+	fmt.Fprintf(os.Stderr, "Discovery will probe %d base URLs x %d words x %d variations = %d requests\n",
+		len(d.probeBases()), d.wordCount(), d.probesPerWord(), total)
+
+	if d.config.Confirm != nil && !d.config.Confirm(total) {
+		d.stats.SetStopReason("request estimate not confirmed")
+		return fmt.Errorf("aborted: %d requests exceeds the confirmation threshold of %d (use --yes to proceed)",
+			total, d.config.ConfirmThreshold)
+	}
 	return nil
 }
 
-func (d *Discovery) testEndpoint(baseURL, endpoint string) {
-	// Test different endpoint variations
-	variations := []string{
+// endpointVariations returns the paths tested for each wordlist entry
+func endpointVariations(endpoint string) []string {
+	return []string{
 		endpoint,
 		"/" + endpoint,
 		"/api/" + endpoint,
@@ -246,71 +752,203 @@ func (d *Discovery) testEndpoint(baseURL, endpoint string) {
 		"/api/v2/" + endpoint,
 		"/admin/" + endpoint,
 	}
+}
+
+// probesPerWord returns the number of requests made for each wordlist entry
+func (d *Discovery) probesPerWord() int {
+	if d.config.Template != nil || d.config.Raw != nil {
+		return 1
+	}
+	return len(endpointVariations(""))
+}
 
-	for _, variation := range variations {
-		testURL := baseURL + variation
-		d.makeRequest(testURL, "GET", baseURL)
+// probeBases returns the base URLs the wordlist is tested against: those
+// extracted from the JS files, or the one host a template without BASE names
+func (d *Discovery) probeBases() map[string]bool {
+	if origin, fixed := d.fixedOrigin(); fixed {
+		return map[string]bool{origin: true}
+	}
+	return d.baseURLs
+}
+
+// fixedOrigin returns the one host probed when a template or raw request
+// without BASE names it
+func (d *Discovery) fixedOrigin() (string, bool) {
+	if d.config.Raw != nil {
+		return d.config.Raw.Origin(), !d.config.Raw.PerBase()
+	}
+	if d.config.Template != nil {
+		return d.config.Template.Origin(), !d.config.Template.PerBase()
+	}
+	return "", false
+}
+
+func (d *Discovery) testEndpoint(baseURL, endpoint string) {
+	if d.config.Raw != nil {
+		if d.config.Budget.Exceeded() || d.timeoutMgr.Expired() {
+			return
+		}
+		if err := d.config.Window.Wait(d.timeoutMgr.Context()); err != nil {
+			return
+		}
+		d.probeRaw(baseURL, endpoint)
+		return
+	}
+
+	var header http.Header
+	testURLs := make([]string, 0, d.probesPerWord())
+	if d.config.Template != nil {
+		var testURL string
+		testURL, header = d.config.Template.Expand(baseURL, endpoint)
+		testURLs = append(testURLs, testURL)
+	} else {
+		for _, variation := range endpointVariations(endpoint) {
+			testURLs = append(testURLs, baseURL+variation)
+		}
+	}
+
+	for _, testURL := range testURLs {
+		if d.config.Budget.Exceeded() || d.timeoutMgr.Expired() {
+			return
+		}
+		if err := d.config.Window.Wait(d.timeoutMgr.Context()); err != nil {
+			return
+		}
+		d.probe(testURL, "GET", baseURL, header)
 	}
 }
 
 func (d *Discovery) makeRequest(testURL, method, source string) {
+	d.probe(testURL, method, source, nil)
+}
+
+// probe requests testURL with any extra headers and records the response if
+// it passes the filters
+func (d *Discovery) probe(testURL, method, source string, header http.Header) {
+	if d.sessions != nil {
+		d.primeSession(d.extractBaseURL(testURL))
+	}
 	start := time.Now()
 
-	req, err := http.NewRequest(method, testURL, nil)
+	op := d.timeoutMgr.StartOperation("probe", testURL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	req, err := http.NewRequestWithContext(op.Ctx, method, testURL, nil)
 	if err != nil {
 		return
 	}
 
 	req.Header.Set("User-Agent", d.config.UserAgent)
 	req.Header.Set("Accept", "application/json, text/plain, */*")
+	setHeaders(req, header)
+	d.addSessionCookies(req)
 
 	resp, err := d.client.Do(req)
 	if err != nil {
+		d.stats.AddHostError(d.extractBaseURL(testURL))
 		return
 	}
 	defer resp.Body.Close()
+	d.stats.AddHostResponse(d.extractBaseURL(testURL), resp.StatusCode, time.Since(start))
 
+	d.record(op.ID, testURL, method, source, req.Host, header, start, resp)
+}
+
+// setHeaders adds the extra headers of a probe to req, a Host entry
+// replacing the request's Host
+func setHeaders(req *http.Request, header http.Header) {
+	for name, values := range header {
+		if name == "Host" {
+			req.Host = values[0]
+			continue
+		}
+		req.Header[name] = values
+	}
+}
+
+// record adds the endpoint a probe response stands for if it passes the
+// filters; host is the Host header sent when it overrides the URL's, and
+// header the extra headers the probe sent
+func (d *Discovery) record(opID, testURL, method, source, host string, header http.Header, start time.Time, resp *http.Response) {
 	responseTime := time.Since(start).Milliseconds()
+	latencyAnomaly := d.checkLatency(d.extractBaseURL(testURL), responseTime)
 
-	// Check if status code is in filter
-	if !d.statusFilter[resp.StatusCode] {
+	// Check if status code is in filter; a --match expression replaces it
+	if d.config.Match == nil && !d.statusFilter[resp.StatusCode] {
 		return
 	}
 
+	body, _ := io.ReadAll(io.LimitReader(d.timeoutMgr.HeartbeatReader(opID, resp.Body), maxBodySample))
+
 	contentLength := resp.ContentLength
 	if contentLength == -1 {
-		// Try to read body to get actual length
-		body, err := io.ReadAll(resp.Body)
-		if err == nil {
-			contentLength = int64(len(body))
+		contentLength = int64(len(body))
+	}
+
+	if d.config.Match != nil || d.config.Filter != nil {
+		response := &match.Response{
+			Status:      resp.StatusCode,
+			Length:      contentLength,
+			Time:        responseTime,
+			ContentType: resp.Header.Get("Content-Type"),
+			URL:         testURL,
+			Body:        body,
+		}
+		if d.config.Match != nil && !d.config.Match.Matches(response) || d.config.Filter.Matches(response) {
+			return
 		}
 	}
 
-	contentType := resp.Header.Get("Content-Type")
+	if d.config.VHost && d.isDefaultVHost(testURL, host, resp.StatusCode, body) {
+		return
+	}
 
-	// Build redirect chain if any
-	var redirectChain string
-	if resp.Request.URL.String() != testURL {
-		redirectChain = fmt.Sprintf("%s -> %s", testURL, resp.Request.URL.String())
+	soft404 := false
+	if d.soft404Mode() != Soft404Off && !d.config.VHost {
+		soft404 = d.isSoft404(d.extractBaseURL(testURL), resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		if soft404 && d.soft404Mode() == Soft404Filter {
+			return
+		}
 	}
 
+	contentType := resp.Header.Get("Content-Type")
+
 	endpoint := Endpoint{
-		URL:           testURL,
-		StatusCode:    resp.StatusCode,
-		ContentLength: contentLength,
-		ContentType:   contentType,
-		ResponseTime:  responseTime,
-		Source:        source,
-		Method:        method,
-		RedirectChain: redirectChain,
+		URL:            testURL,
+		Route:          routeTemplate(testURL),
+		StatusCode:     resp.StatusCode,
+		ContentLength:  contentLength,
+		ContentType:    contentType,
+		Schema:         jsonSchema(contentType, body),
+		ResponseTime:   responseTime,
+		LatencyAnomaly: latencyAnomaly,
+		Source:         source,
+		Method:         method,
+		VirtualHost:    host,
+		Labels:         d.config.Labels,
+	}
+
+	d.analyzeRedirects(&endpoint, resp)
+	d.analyzeAuth(&endpoint, resp)
+	if d.config.AuthParams && d.config.Raw == nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		d.probeAuthParams(&endpoint, header, resp.StatusCode, body)
+	}
+	if soft404 {
+		endpoint.addTag(TagSoft404)
 	}
 
 	d.mutex.Lock()
+	if d.restored[endpoint.key()] {
+		d.mutex.Unlock()
+		return
+	}
 	d.results = append(d.results, endpoint)
 	d.mutex.Unlock()
+	d.stats.AddEndpoint(endpoint.StatusCode)
 
-	if d.config.Verbose {
-		fmt.Printf("[%d] %s (%dms, %d bytes)\n", resp.StatusCode, testURL, responseTime, contentLength)
+	d.logger.WithField("target", testURL).Debugf("[%d] %dms, %d bytes", resp.StatusCode, responseTime, contentLength)
+	if d.config.OnEndpoint != nil {
+		d.config.OnEndpoint(endpoint)
 	}
 }
 
@@ -327,12 +965,12 @@ func (d *Discovery) parseStatusFilter() {
 
 func (d *Discovery) outputResults() error {
 	if len(d.results) == 0 {
-		if d.config.Verbose {
-			fmt.Println("No endpoints discovered.")
-		}
+		d.logger.Info("No endpoints discovered")
 		return nil
 	}
 
+	sortEndpoints(d.results, d.config.Sort)
+
 	var output io.Writer
 	if d.config.OutputFile != "" {
 		file, err := os.Create(d.config.OutputFile)
@@ -359,32 +997,52 @@ func (d *Discovery) outputJSON(output io.Writer) error {
 	return encoder.Encode(d.results)
 }
 
-func (d *Discovery) outputCSV(output io.Writer) error {
-	writer := csv.NewWriter(output)
-	defer writer.Flush()
+// CSVHeader is the header row of the discovery CSV output. The route
+// columns, RouteCSVHeader, come last so the columns before them keep their
+// positions for scripts written against earlier output.
+var CSVHeader = []string{"URL", "Status Code", "Content Length", "Content Type", "Response Time (ms)", "Source", "Method", "Redirect Chain", "Auth Scheme", "Auth Param", "CORS Origin", "Virtual Host", "Options Allowed", "Allowed Methods", "Tags", "OOB", "Labels", "Latency Anomaly", "Schema", "Route", "Route Hits"}
+
+// RouteCSVHeader names the trailing route columns of CSVHeader
+var RouteCSVHeader = []string{"Route", "Route Hits"}
+
+// CSVRecord returns the endpoint as a CSV row matching CSVHeader
+func (e Endpoint) CSVRecord() []string {
+	return []string{
+		e.URL,
+		fmt.Sprintf("%d", e.StatusCode),
+		fmt.Sprintf("%d", e.ContentLength),
+		e.ContentType,
+		fmt.Sprintf("%d", e.ResponseTime),
+		e.Source,
+		e.Method,
+		e.RedirectChain,
+		e.AuthScheme,
+		e.AuthParam,
+		e.CORSOrigin,
+		e.VirtualHost,
+		strconv.FormatBool(e.OptionsAllowed),
+		e.AllowedMethods,
+		strings.Join(e.Tags, ";"),
+		e.oobSummary(),
+		e.Labels.String(),
+		e.LatencyAnomaly,
+		e.Schema,
+		e.Route,
+		fmt.Sprintf("%d", e.RouteHits),
+	}
+}
 
-	// Write header
-	header := []string{"URL", "Status Code", "Content Length", "Content Type", "Response Time (ms)", "Source", "Method", "Redirect Chain"}
-	if err := writer.Write(header); err != nil {
+func (d *Discovery) outputCSV(output io.Writer) error {
+	writer, err := utils.NewCSVWriter(output, CSVHeader, d.config.CSV)
+	if err != nil {
 		return err
 	}
 
-	// Write data
 	for _, endpoint := range d.results {
-		record := []string{
-			endpoint.URL,
-			fmt.Sprintf("%d", endpoint.StatusCode),
-			fmt.Sprintf("%d", endpoint.ContentLength),
-			endpoint.ContentType,
-			fmt.Sprintf("%d", endpoint.ResponseTime),
-			endpoint.Source,
-			endpoint.Method,
-			endpoint.RedirectChain,
-		}
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(endpoint.CSVRecord()); err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
\ No newline at end of file
+	return writer.Flush()
+}
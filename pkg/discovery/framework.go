@@ -0,0 +1,193 @@
+package discovery
+
+import (
+	"regexp"
+	"strings"
+)
+
+// frameworkExtractor recovers the endpoints a framework's idioms name only
+// indirectly: through a client instance's base URL, a service's base URL
+// property or a build ID
+type frameworkExtractor struct {
+	name    string
+	detect  *regexp.Regexp // Matched against the JS content and URL
+	extract func(content, jsURL string) []string
+}
+
+// frameworkExtractors run on every JS file their framework is detected in
+var frameworkExtractors = []frameworkExtractor{
+	{
+		name:    "Angular",
+		detect:  regexp.MustCompile(`@angular/|ɵɵdefineInjectable|ɵfac\b|angular\.module\(|\$httpProvider|\bHttpClient\b`),
+		extract: angularEndpoints,
+	},
+	{
+		name:    "Vue.js/axios",
+		detect:  regexp.MustCompile(`__VUE__|\bcreateApp\(|\bVue\.(?:component|use|prototype|http)\b|\$axios|\.create\(\s*\{[^{}]*?baseURL\s*:`),
+		extract: axiosEndpoints,
+	},
+	{
+		name:    "Next.js",
+		detect:  regexp.MustCompile(`__NEXT_DATA__|/_next/|__BUILD_MANIFEST|__SSG_MANIFEST|next/router`),
+		extract: nextEndpoints,
+	},
+}
+
+// httpMethods are the client methods whose first argument is a URL
+const httpMethods = `get|post|put|delete|patch|head|options|jsonp|request`
+
+var (
+	// Angular HttpClient and AngularJS $http calls, with an optional
+	// generic type argument and the method name request() takes first
+	angularCall = regexp.MustCompile(`(?:\$http|[\w$]*[hH]ttp(?:Client)?)\s*\.\s*(?:` + httpMethods + `)\s*(?:<[^<>()]*>)?\s*\(\s*(?:["'][A-Z]+["']\s*,\s*)?["'` + "`" + `]([^"'` + "`" + `]+)`)
+	// The same calls on a base URL property plus a path
+	angularBaseCall = regexp.MustCompile(`\.\s*(?:` + httpMethods + `)\s*(?:<[^<>()]*>)?\s*\(\s*(?:["'][A-Z]+["']\s*,\s*)?(?:this\.)?([\w$.]+)\s*\+\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+	angularConfig   = regexp.MustCompile(`\$http\(\s*\{[^{}]*?\burl\s*:\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+	angularResource = regexp.MustCompile(`\$resource\(\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+	// Properties and variables holding a base URL: apiUrl: "...", this.baseUrl = "..."
+	baseAssignment = regexp.MustCompile(`(?:this\.)?([\w$]+)\s*[:=]\s*["'` + "`" + `](https?://[^"'` + "`" + `\s]+|/[^"'` + "`" + `\s]*)["'` + "`" + `]`)
+
+	// axios.create({baseURL: ...}) assigned to an instance, and the default
+	// base URL of axios itself or vue-resource
+	axiosInstance = regexp.MustCompile(`([\w$]+(?:\.[\w$]+)?)\s*=\s*[\w$.]*\.create\(\s*\{[^{}]*?baseURL\s*:\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+	axiosDefault  = regexp.MustCompile(`(?:axios\.defaults\.baseURL|\.http\.options\.root)\s*=\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+
+	nextBuildID      = regexp.MustCompile(`/_next/static/([\w-]+)/_(?:build|ssg)Manifest\.js|"buildId"\s*:\s*"([\w-]+)"`)
+	nextBuildPages   = regexp.MustCompile(`__BUILD_MANIFEST\s*=\s*\{([^;]*)`)
+	nextSSGPages     = regexp.MustCompile(`__SSG_MANIFEST\s*=\s*new Set\(\[([^\]]*)\]`)
+	nextPageKey      = regexp.MustCompile(`"(/[^"]*)"\s*:`)
+	quotedString     = regexp.MustCompile(`"([^"]*)"`)
+	nextAPIRoute     = regexp.MustCompile(`["'` + "`" + `](/api/[^"'` + "`" + `\s]*)["'` + "`" + `]`)
+	internalNextPage = regexp.MustCompile(`^/(?:_app|_error|_document|404|500)$`)
+)
+
+// frameworkEndpoints returns the frameworks detected in a JS file and the
+// endpoints their extractors recovered from it
+func frameworkEndpoints(content, jsURL string) (frameworks, endpoints []string) {
+	seen := make(map[string]bool)
+	for _, extractor := range frameworkExtractors {
+		if !extractor.detect.MatchString(content) && !extractor.detect.MatchString(jsURL) {
+			continue
+		}
+		frameworks = append(frameworks, extractor.name)
+		for _, endpoint := range extractor.extract(content, jsURL) {
+			if endpoint = cleanEndpoint(endpoint); endpoint != "" && !seen[endpoint] {
+				seen[endpoint] = true
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	return frameworks, endpoints
+}
+
+func angularEndpoints(content, jsURL string) []string {
+	var endpoints []string
+	for _, pattern := range []*regexp.Regexp{angularCall, angularConfig, angularResource} {
+		for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+			endpoints = append(endpoints, match[1])
+		}
+	}
+
+	bases := make(map[string]string)
+	for _, match := range baseAssignment.FindAllStringSubmatch(content, -1) {
+		bases[match[1]] = match[2]
+	}
+	for _, match := range angularBaseCall.FindAllStringSubmatch(content, -1) {
+		name := match[1][strings.LastIndex(match[1], ".")+1:]
+		if base, known := bases[name]; known {
+			endpoints = append(endpoints, joinEndpoint(base, match[2]))
+		}
+	}
+	return endpoints
+}
+
+func axiosEndpoints(content, jsURL string) []string {
+	var endpoints []string
+	for _, match := range axiosInstance.FindAllStringSubmatch(content, -1) {
+		name, base := match[1], match[2]
+		endpoints = append(endpoints, base)
+		endpoints = append(endpoints, clientCalls(content, regexp.QuoteMeta(name), base)...)
+	}
+	for _, match := range axiosDefault.FindAllStringSubmatch(content, -1) {
+		endpoints = append(endpoints, clientCalls(content, `(?:axios|\$axios|\$http|Vue\.http)`, match[1])...)
+	}
+	return endpoints
+}
+
+// clientCalls returns the paths passed to the methods of the client named by
+// the receiver pattern, joined to its base URL
+func clientCalls(content, receiver, base string) []string {
+	call := regexp.MustCompile(`(?:^|[^\w$.])(?:this\.)?` + receiver + `\s*\.\s*(?:` + httpMethods + `)\s*\(\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+	var endpoints []string
+	for _, match := range call.FindAllStringSubmatch(content, -1) {
+		endpoints = append(endpoints, joinEndpoint(base, match[1]))
+	}
+	return endpoints
+}
+
+func nextEndpoints(content, jsURL string) []string {
+	var buildID string
+	for _, source := range []string{jsURL, content} {
+		if match := nextBuildID.FindStringSubmatch(source); match != nil {
+			buildID = match[1] + match[2]
+			break
+		}
+	}
+
+	// Pages named in the build and SSG manifests, and the JSON data routes
+	// next/link prefetches them through
+	var pages []string
+	if match := nextBuildPages.FindStringSubmatch(content); match != nil {
+		for _, key := range nextPageKey.FindAllStringSubmatch(match[1], -1) {
+			pages = append(pages, key[1])
+		}
+	}
+	if match := nextSSGPages.FindStringSubmatch(content); match != nil {
+		for _, page := range quotedString.FindAllStringSubmatch(match[1], -1) {
+			pages = append(pages, page[1])
+		}
+	}
+
+	var endpoints []string
+	for _, page := range pages {
+		if strings.Contains(page, "[") || internalNextPage.MatchString(page) {
+			continue
+		}
+		endpoints = append(endpoints, page)
+		if buildID != "" {
+			if page == "/" {
+				page = "/index"
+			}
+			endpoints = append(endpoints, "/_next/data/"+buildID+page+".json")
+		}
+	}
+
+	// API routes live under /api by convention, so every such path is one
+	for _, match := range nextAPIRoute.FindAllStringSubmatch(content, -1) {
+		endpoints = append(endpoints, match[1])
+	}
+	return endpoints
+}
+
+// joinEndpoint appends a path to a base URL, unless it is a URL already
+func joinEndpoint(base, path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// cleanEndpoint drops the parts of a path that are filled in at runtime:
+// template placeholders and :param segments are cut off with what follows
+func cleanEndpoint(endpoint string) string {
+	if i := strings.Index(endpoint, "${"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	if i := strings.Index(endpoint, "/:"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	if endpoint == "/" || strings.ContainsAny(endpoint, " \n<>") {
+		return ""
+	}
+	return endpoint
+}
@@ -0,0 +1,69 @@
+package discovery
+
+import "math"
+
+// Latency anomalies reported in Endpoint.LatencyAnomaly
+const (
+	LatencySlow = "slow" // Much slower than the host usually answers: a heavy backend operation, or a time-based check
+	LatencyFast = "fast" // Much faster: often answered by a cache, WAF or filter in front of the application
+)
+
+// A response is only flagged once its host has a baseline, and only when it
+// deviates from it both statistically and by a margin that matters
+const (
+	latencyMinSamples = 20  // Responses needed before a host's baseline is trusted
+	latencyZScore     = 3.0 // Standard deviations from the mean
+	latencyMinRatio   = 2.0 // Times slower (or faster) than the mean
+	latencyMinDelta   = 100 // Milliseconds from the mean
+)
+
+// latencyBaseline is a running mean and variance of a host's response times
+// (Welford's algorithm), so it needs no per-response storage
+type latencyBaseline struct {
+	count int64
+	mean  float64
+	m2    float64
+}
+
+func (b *latencyBaseline) add(ms float64) {
+	b.count++
+	delta := ms - b.mean
+	b.mean += delta / float64(b.count)
+	b.m2 += delta * (ms - b.mean)
+}
+
+// anomaly classifies a response time against the baseline
+func (b *latencyBaseline) anomaly(ms float64) string {
+	if b.count < latencyMinSamples {
+		return ""
+	}
+	deviation := math.Sqrt(b.m2 / float64(b.count-1))
+
+	switch {
+	case ms > b.mean+latencyZScore*deviation && ms >= b.mean*latencyMinRatio && ms-b.mean >= latencyMinDelta:
+		return LatencySlow
+	case ms < b.mean-latencyZScore*deviation && ms <= b.mean/latencyMinRatio && b.mean-ms >= latencyMinDelta:
+		return LatencyFast
+	}
+	return ""
+}
+
+// checkLatency compares a response time with its host's baseline and returns
+// the anomaly, if any. Every probe response feeds the baseline, whatever its
+// status, except anomalies themselves, which would drag it towards them.
+func (d *Discovery) checkLatency(baseURL string, ms int64) string {
+	d.latencyMutex.Lock()
+	defer d.latencyMutex.Unlock()
+
+	baseline, exists := d.latency[baseURL]
+	if !exists {
+		baseline = &latencyBaseline{}
+		d.latency[baseURL] = baseline
+	}
+
+	anomaly := baseline.anomaly(float64(ms))
+	if anomaly == "" {
+		baseline.add(float64(ms))
+	}
+	return anomaly
+}
@@ -0,0 +1,173 @@
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"jsfinder/pkg/utils"
+)
+
+// TagOOB marks endpoints that made an out-of-band DNS or HTTP request to a
+// planted callback host, a sign of server-side fetching such as SSRF
+const TagOOB = "oob-interaction"
+
+// oobPollInterval is how often interactions are collected while waiting
+const oobPollInterval = 2 * time.Second
+
+// Where callback hosts are planted in the follow-up probe. The parameters are
+// the names servers commonly fetch from; the headers are ones proxies, logging
+// and analytics pipelines are known to resolve or fetch.
+var (
+	oobParams  = []string{"url", "uri", "callback", "redirect", "next", "dest", "target", "webhook", "feed", "image"}
+	oobHeaders = []string{"Referer", "X-Forwarded-Host", "X-Forwarded-For", "True-Client-IP", "X-Wap-Profile", "From"}
+)
+
+// OOBInteraction is an out-of-band request caused by a probe of an endpoint
+type OOBInteraction struct {
+	Protocol      string    `json:"protocol"`  // dns, http or smtp
+	Placement     string    `json:"placement"` // Where the callback host was planted, e.g. "param url" or "header Referer"
+	RemoteAddress string    `json:"remote_address,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// oobPayload ties a callback host back to the endpoint probe that carried it
+type oobPayload struct {
+	endpoint  int
+	placement string
+}
+
+// probeOOB sends one more request to every endpoint found, with a unique
+// callback host under the interactsh server in each common URL parameter and
+// in headers that are often fetched, then waits for the server to report
+// interactions and tags the endpoints that caused them.
+func (d *Discovery) probeOOB() {
+	if d.oob == nil || len(d.results) == 0 {
+		return
+	}
+
+	var payloadsMutex sync.Mutex
+	payloads := make(map[string]oobPayload)
+	plant := func(endpoint int, placement string) string {
+		host, nonce := d.oob.Payload()
+		payloadsMutex.Lock()
+		payloads[nonce] = oobPayload{endpoint: endpoint, placement: placement}
+		payloadsMutex.Unlock()
+		return host
+	}
+
+	d.followUp(func(i int) {
+		d.sendOOBProbe(d.results[i], func(placement string) string { return plant(i, placement) })
+	})
+
+	d.logger.Infof("Waiting %s for out-of-band interactions on %s", d.config.OOBWait, d.oob.Server())
+	triggered := 0
+	for _, interaction := range d.collectInteractions() {
+		payload, found := payloads[interaction.Nonce()]
+		if !found {
+			continue
+		}
+		endpoint := &d.results[payload.endpoint]
+		if len(endpoint.OOB) == 0 {
+			triggered++
+		}
+		endpoint.addOOB(OOBInteraction{
+			Protocol:      interaction.Protocol,
+			Placement:     payload.placement,
+			RemoteAddress: interaction.RemoteAddress,
+			Time:          interaction.Timestamp,
+		})
+	}
+	if triggered > 0 {
+		d.logger.Warnf("%d endpoints triggered out-of-band interactions (tagged %s)", triggered, TagOOB)
+	}
+}
+
+// sendOOBProbe requests an endpoint again with callback hosts from plant in
+// every oobParams parameter and oobHeaders header
+func (d *Discovery) sendOOBProbe(endpoint Endpoint, plant func(placement string) string) {
+	op := d.timeoutMgr.StartOperation("oob", endpoint.URL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	req, err := http.NewRequestWithContext(op.Ctx, endpoint.Method, endpoint.URL, nil)
+	if err != nil {
+		return
+	}
+	query := req.URL.Query()
+	for _, param := range oobParams {
+		query.Set(param, "http://"+plant("param "+param)+"/")
+	}
+	req.URL.RawQuery = query.Encode()
+
+	req.Header.Set("User-Agent", d.config.UserAgent)
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	for _, name := range oobHeaders {
+		host := plant("header " + name)
+		switch name {
+		case "Referer":
+			req.Header.Set(name, "http://"+host+"/")
+		case "X-Wap-Profile":
+			req.Header.Set(name, "http://"+host+"/wap.xml")
+		case "From":
+			req.Header.Set(name, "root@"+host)
+		default:
+			req.Header.Set(name, host)
+		}
+	}
+	if endpoint.VirtualHost != "" {
+		req.Host = endpoint.VirtualHost
+	}
+	d.addSessionCookies(req)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.logger.WithField("target", endpoint.URL).Debugf("Out-of-band probe failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// collectInteractions polls the interactsh server until OOBWait has passed
+// since the probes were sent, or the run's deadline
+func (d *Discovery) collectInteractions() []utils.Interaction {
+	var interactions []utils.Interaction
+	deadline := time.Now().Add(d.config.OOBWait)
+	for {
+		polled, err := d.oob.Poll()
+		if err != nil {
+			d.logger.Warnf("%v", err)
+		}
+		interactions = append(interactions, polled...)
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 || d.timeoutMgr.Expired() {
+			return interactions
+		}
+		select {
+		case <-time.After(min(remaining, oobPollInterval)):
+		case <-d.timeoutMgr.Context().Done():
+		}
+	}
+}
+
+// addOOB records an interaction once per protocol and placement
+func (e *Endpoint) addOOB(interaction OOBInteraction) {
+	for _, existing := range e.OOB {
+		if existing.Protocol == interaction.Protocol && existing.Placement == interaction.Placement {
+			return
+		}
+	}
+	e.OOB = append(e.OOB, interaction)
+	e.addTag(TagOOB)
+}
+
+// oobSummary lists the interactions as "http via param url" for CSV output
+func (e Endpoint) oobSummary() string {
+	parts := make([]string, len(e.OOB))
+	for i, interaction := range e.OOB {
+		parts[i] = fmt.Sprintf("%s via %s", interaction.Protocol, interaction.Placement)
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,358 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportOpenAPI is the --export format writing an OpenAPI skeleton per host
+const ExportOpenAPI = "openapi"
+
+// openAPIVersion is the OpenAPI release the skeletons follow
+const openAPIVersion = "3.0.3"
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components *openAPIComponents                      `json:"components,omitempty"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	Parameters []openAPIParameter          `json:"parameters,omitempty"`
+	Responses  map[string]*openAPIResponse `json:"responses"`
+	Security   []map[string][]string       `json:"security,omitempty"`
+	Example    string                      `json:"x-example-url,omitempty"` // An endpoint URL the operation was built from
+}
+
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required,omitempty"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema map[string]interface{} `json:"schema,omitempty"`
+}
+
+type openAPIComponents struct {
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	Name   string `json:"name,omitempty"`
+	In     string `json:"in,omitempty"`
+}
+
+// writeOpenAPI writes one OpenAPI skeleton per host of the endpoints found
+// into Config.OpenAPIDir: a path per route, an operation per method probed or
+// advertised in Allow, the responses seen with their JSON shape, and the
+// auth schemes and parameters the endpoint reacted to
+func (d *Discovery) writeOpenAPI() error {
+	if d.config.OpenAPIDir == "" || len(d.results) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(d.config.OpenAPIDir, 0755); err != nil {
+		return fmt.Errorf("failed to create OpenAPI export directory: %w", err)
+	}
+
+	documents := buildOpenAPI(d.results)
+	hosts := make([]string, 0, len(documents))
+	for host := range documents {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		path := filepath.Join(d.config.OpenAPIDir, strings.ReplaceAll(host, ":", "_")+".openapi.json")
+		data, err := json.MarshalIndent(documents[host], "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write OpenAPI export: %w", err)
+		}
+	}
+	d.logger.Infof("Wrote OpenAPI skeletons for %d hosts to %s", len(hosts), d.config.OpenAPIDir)
+	return nil
+}
+
+// buildOpenAPI groups endpoints by host name into OpenAPI documents
+func buildOpenAPI(endpoints []Endpoint) map[string]*openAPIDocument {
+	documents := make(map[string]*openAPIDocument)
+	servers := make(map[string]map[string]bool)
+
+	for _, endpoint := range endpoints {
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil || parsed.Hostname() == "" {
+			continue
+		}
+		host := parsed.Hostname()
+		document := documents[host]
+		if document == nil {
+			document = &openAPIDocument{
+				OpenAPI: openAPIVersion,
+				Info: openAPIInfo{
+					Title:       host,
+					Description: "Skeleton reconstructed by jsfinder from the endpoints found in JavaScript files; response schemas are inferred from single samples",
+					Version:     "unknown",
+				},
+				Paths: make(map[string]map[string]*openAPIOperation),
+			}
+			documents[host] = document
+			servers[host] = make(map[string]bool)
+		}
+		if origin := parsed.Scheme + "://" + parsed.Host; !servers[host][origin] {
+			servers[host][origin] = true
+			document.Servers = append(document.Servers, openAPIServer{URL: origin})
+		}
+
+		route := endpoint.Route
+		if route == "" {
+			route = routeTemplate(endpoint.URL)
+		}
+		path, pathParams := openAPIPath(route)
+		operations := document.Paths[path]
+		if operations == nil {
+			operations = make(map[string]*openAPIOperation)
+			document.Paths[path] = operations
+		}
+
+		method := strings.ToLower(endpoint.Method)
+		if method == "" {
+			method = "get"
+		}
+		operation := operations[method]
+		if operation == nil {
+			operation = &openAPIOperation{Responses: make(map[string]*openAPIResponse), Example: endpoint.URL}
+			operation.Parameters = append(operation.Parameters, pathParams...)
+			operations[method] = operation
+		}
+		addQueryParameters(operation, parsed.Query())
+		addResponse(operation, endpoint)
+		addSecurity(document, operation, endpoint)
+
+		for _, allowed := range strings.Split(endpoint.AllowedMethods, ",") {
+			allowed = strings.ToLower(strings.TrimSpace(allowed))
+			if allowed == "" || allowed == "options" || allowed == "head" || operations[allowed] != nil {
+				continue
+			}
+			operations[allowed] = &openAPIOperation{
+				Parameters: pathParams,
+				Responses:  map[string]*openAPIResponse{"default": {Description: "Advertised in the Allow header; not probed"}},
+				Example:    endpoint.URL,
+			}
+		}
+	}
+
+	for _, document := range documents {
+		sort.Slice(document.Servers, func(i, j int) bool { return document.Servers[i].URL < document.Servers[j].URL })
+	}
+	return documents
+}
+
+// openAPIPath gives each placeholder of a route a unique name, as OpenAPI
+// requires, and returns the matching path parameters
+func openAPIPath(route string) (string, []openAPIParameter) {
+	var params []openAPIParameter
+	counts := make(map[string]int)
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if segment != RouteID && segment != RouteUUID {
+			continue
+		}
+		base := strings.Trim(segment, "{}")
+		counts[base]++
+		name := base
+		if counts[base] > 1 {
+			name += strconv.Itoa(counts[base])
+		}
+		segments[i] = "{" + name + "}"
+
+		schema := map[string]string{"type": "string"}
+		if segment == RouteUUID {
+			schema["format"] = "uuid"
+		}
+		params = append(params, openAPIParameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// addQueryParameters adds the query parameters of an endpoint URL
+func addQueryParameters(operation *openAPIOperation, query url.Values) {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		operation.addParameter(openAPIParameter{Name: name, In: "query", Schema: map[string]string{"type": "string"}})
+	}
+}
+
+// addParameter adds a parameter once
+func (o *openAPIOperation) addParameter(param openAPIParameter) {
+	for _, existing := range o.Parameters {
+		if existing.Name == param.Name && existing.In == param.In {
+			return
+		}
+	}
+	o.Parameters = append(o.Parameters, param)
+}
+
+// addResponse records the status seen for an endpoint with its JSON shape
+func addResponse(operation *openAPIOperation, endpoint Endpoint) {
+	status := strconv.Itoa(endpoint.StatusCode)
+	if operation.Responses[status] != nil {
+		return
+	}
+	response := &openAPIResponse{Description: http.StatusText(endpoint.StatusCode)}
+	if response.Description == "" {
+		response.Description = "HTTP " + status
+	}
+	if mediaType, _, err := mime.ParseMediaType(endpoint.ContentType); err == nil {
+		response.Content = map[string]openAPIMedia{mediaType: {Schema: openAPISchema(endpoint.Schema)}}
+	}
+	operation.Responses[status] = response
+}
+
+// addSecurity records the auth scheme an endpoint challenged with and the
+// auth parameter it reacted to as security schemes
+func addSecurity(document *openAPIDocument, operation *openAPIOperation, endpoint Endpoint) {
+	add := func(name string, scheme openAPISecurityScheme) {
+		if document.Components == nil {
+			document.Components = &openAPIComponents{SecuritySchemes: make(map[string]openAPISecurityScheme)}
+		}
+		document.Components.SecuritySchemes[name] = scheme
+		for _, requirement := range operation.Security {
+			if _, exists := requirement[name]; exists {
+				return
+			}
+		}
+		operation.Security = append(operation.Security, map[string][]string{name: {}})
+	}
+
+	if endpoint.AuthScheme != "" {
+		scheme := strings.ToLower(endpoint.AuthScheme)
+		add(scheme, openAPISecurityScheme{Type: "http", Scheme: scheme})
+	}
+	if kind, name, ok := strings.Cut(endpoint.AuthParam, " "); ok {
+		if name == "Authorization" {
+			add("bearer", openAPISecurityScheme{Type: "http", Scheme: "bearer"})
+		} else {
+			add(name, openAPISecurityScheme{Type: "apiKey", Name: name, In: kind})
+		}
+	}
+}
+
+// openAPISchema converts a response shape written by jsonSchema into an
+// OpenAPI schema object, or nil when it is empty or cannot be read
+func openAPISchema(schema string) map[string]interface{} {
+	if schema == "" {
+		return nil
+	}
+	parser := &schemaParser{input: schema}
+	value, ok := parser.value()
+	if !ok || parser.pos != len(schema) {
+		return nil
+	}
+	return value
+}
+
+// schemaParser reads the compact shape notation of describeJSON
+type schemaParser struct {
+	input string
+	pos   int
+}
+
+func (p *schemaParser) consume(token string) bool {
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *schemaParser) value() (map[string]interface{}, bool) {
+	switch {
+	case p.consume("string"):
+		return map[string]interface{}{"type": "string"}, true
+	case p.consume("number"):
+		return map[string]interface{}{"type": "number"}, true
+	case p.consume("bool"):
+		return map[string]interface{}{"type": "boolean"}, true
+	case p.consume("null"):
+		return map[string]interface{}{"nullable": true}, true
+	case p.consume("{...}"):
+		return map[string]interface{}{"type": "object"}, true
+	case p.consume("[...]"), p.consume("[]"):
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{}}, true
+	case p.consume("["):
+		items, ok := p.value()
+		if !ok || !p.consume("]") {
+			return nil, false
+		}
+		return map[string]interface{}{"type": "array", "items": items}, true
+	case p.consume("{"):
+		return p.object()
+	}
+	return nil, false
+}
+
+func (p *schemaParser) object() (map[string]interface{}, bool) {
+	properties := make(map[string]interface{})
+	for !p.consume("}") {
+		if len(properties) > 0 && !p.consume(",") {
+			return nil, false
+		}
+		if p.consume("...+") {
+			for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+				p.pos++
+			}
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(p.input[p.pos:]))
+		var key string
+		if err := decoder.Decode(&key); err != nil {
+			return nil, false
+		}
+		p.pos += int(decoder.InputOffset())
+		if !p.consume(":") {
+			return nil, false
+		}
+		value, ok := p.value()
+		if !ok {
+			return nil, false
+		}
+		properties[key] = value
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}, true
+}
@@ -0,0 +1,149 @@
+package discovery
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RawRequest is a request file as saved from an intercepting proxy (Burp's
+// "Copy to file"), sent as written for each wordlist entry with FUZZ and BASE
+// filled in. Unlike a Template it keeps what http.NewRequest would normalize
+// away: nonstandard methods, header case, order and duplicates, odd spacing.
+// Line endings of the head are sent as CRLF; the body is sent verbatim.
+type RawRequest struct {
+	lines         []string // Request line and headers
+	body          string
+	scheme        string
+	host          string // Host header, or the host of an absolute-form target
+	contentLength int    // Index of the Content-Length line, -1 if there is none
+}
+
+// ParseRawRequest parses a request file; scheme (http or https) says how to
+// connect since the file does not record it, unless the request line holds
+// an absolute URL. FUZZ must appear at least once.
+func ParseRawRequest(data []byte, scheme string) (*RawRequest, error) {
+	text := strings.TrimLeft(string(data), "\r\n")
+	if !strings.Contains(text, FuzzKeyword) {
+		return nil, fmt.Errorf("raw request does not contain %s", FuzzKeyword)
+	}
+
+	head, body := text, ""
+	if end := strings.Index(text, "\n\n"); end >= 0 {
+		head, body = text[:end], text[end+2:]
+	}
+	if end := strings.Index(text, "\r\n\r\n"); end >= 0 && end < len(head) {
+		head, body = text[:end], text[end+4:]
+	}
+
+	request := &RawRequest{body: body, scheme: scheme, contentLength: -1}
+	for _, line := range strings.Split(head, "\n") {
+		request.lines = append(request.lines, strings.TrimSuffix(line, "\r"))
+	}
+
+	fields := strings.Fields(request.lines[0])
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid raw request line %q: expected \"METHOD target HTTP/1.1\"", request.lines[0])
+	}
+	for i, line := range request.lines[1:] {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid raw request header %q: expected \"Name: value\"", line)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "host":
+			if request.host == "" {
+				request.host = strings.TrimSpace(value)
+			}
+		case "content-length":
+			request.contentLength = i + 1
+		}
+	}
+	if target, err := url.Parse(fields[1]); err == nil && target.IsAbs() {
+		request.scheme = target.Scheme
+		if request.host == "" {
+			request.host = target.Host
+		}
+	}
+
+	if request.scheme != "http" && request.scheme != "https" {
+		return nil, fmt.Errorf("invalid raw request scheme %q: expected http or https", request.scheme)
+	}
+	sample := strings.NewReplacer(BaseKeyword, "example.com", FuzzKeyword, "word").Replace(request.host)
+	if parsed, err := url.Parse("http://" + sample); request.host == "" || err != nil || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("raw request has no valid Host header")
+	}
+	return request, nil
+}
+
+// PerBase reports whether the request is sent to every base URL
+func (r *RawRequest) PerBase() bool {
+	return strings.Contains(strings.Join(r.lines, "\n")+r.body, BaseKeyword)
+}
+
+// Origin returns the scheme and host of a request without BASE
+func (r *RawRequest) Origin() string {
+	return r.scheme + "://" + strings.ReplaceAll(r.host, FuzzKeyword, "word")
+}
+
+// String describes the request by its request line, host and header count
+func (r *RawRequest) String() string {
+	return fmt.Sprintf("%s (%s://%s, %d headers)", r.lines[0], r.scheme, r.host, len(r.lines)-1)
+}
+
+// Expand fills the request in for a base URL and wordlist entry. It returns
+// the origin to connect to, the method and URL the request stands for, and
+// its bytes. Content-Length is updated when the word changed the body.
+func (r *RawRequest) Expand(baseURL, word string) (origin, method, testURL string, data []byte) {
+	host := baseURL
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+	replacer := strings.NewReplacer(BaseKeyword, host, FuzzKeyword, word)
+
+	lines := make([]string, len(r.lines))
+	for i, line := range r.lines {
+		lines[i] = replacer.Replace(line)
+	}
+	body := replacer.Replace(r.body)
+	if r.contentLength >= 0 && body != r.body {
+		name, _, _ := strings.Cut(r.lines[r.contentLength], ":")
+		lines[r.contentLength] = name + ": " + strconv.Itoa(len(body))
+	}
+
+	// Requests with BASE go to the base URL whatever their Host header says
+	origin = r.scheme + "://" + replacer.Replace(r.host)
+	if r.PerBase() {
+		origin = strings.TrimSuffix(baseURL, "/")
+	}
+	testURL = origin
+	if fields := strings.Fields(lines[0]); len(fields) >= 2 {
+		method, testURL = fields[0], fields[1]
+		if !strings.Contains(testURL, "://") {
+			testURL = origin + testURL
+		}
+	}
+	return origin, method, testURL, []byte(strings.Join(lines, "\r\n") + "\r\n\r\n" + body)
+}
+
+// probeRaw sends the raw request for a base URL and wordlist entry
+func (d *Discovery) probeRaw(baseURL, word string) {
+	start := time.Now()
+	origin, method, testURL, data := d.config.Raw.Expand(baseURL, word)
+
+	op := d.timeoutMgr.StartOperation("probe", testURL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	resp, err := d.raw.Do(op.Ctx, origin, method, testURL, data)
+	if err != nil {
+		d.logger.WithField("target", testURL).Debugf("Raw request failed: %v", err)
+		d.stats.AddHostError(baseURL)
+		return
+	}
+	defer resp.Body.Close()
+	d.stats.AddHostResponse(baseURL, resp.StatusCode, time.Since(start))
+
+	d.record(op.ID, testURL, method, baseURL, "", nil, start, resp)
+}
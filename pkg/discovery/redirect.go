@@ -0,0 +1,98 @@
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Redirect-related endpoint tags
+const (
+	TagRedirectLoop        = "redirect-loop"
+	TagCrossOriginRedirect = "cross-origin-redirect"
+)
+
+// RedirectHop is a single step of a redirect chain
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location,omitempty"`
+}
+
+// checkRedirect limits redirects, stops on loops and optionally on origin changes
+func (d *Discovery) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= d.config.MaxRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	target := req.URL.String()
+	for _, previous := range via {
+		if previous.URL.String() == target {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	if d.config.StopCrossOrigin && !sameOrigin(req.URL.Scheme, req.URL.Host, via[0].URL.Scheme, via[0].URL.Host) {
+		return http.ErrUseLastResponse
+	}
+
+	return nil
+}
+
+// redirectHops reconstructs every hop that led to resp, ending with resp itself
+func redirectHops(resp *http.Response) []RedirectHop {
+	var hops []RedirectHop
+	for r := resp; r != nil; {
+		hops = append(hops, RedirectHop{
+			URL:        r.Request.URL.String(),
+			StatusCode: r.StatusCode,
+			Location:   r.Header.Get("Location"),
+		})
+		r = r.Request.Response
+	}
+
+	// Walked backwards from the final response
+	for i, j := 0, len(hops)-1; i < j; i, j = i+1, j-1 {
+		hops[i], hops[j] = hops[j], hops[i]
+	}
+	return hops
+}
+
+// analyzeRedirects records the redirect chain of a response on the endpoint
+func (d *Discovery) analyzeRedirects(endpoint *Endpoint, resp *http.Response) {
+	hops := redirectHops(resp)
+	if len(hops) < 2 && hops[0].Location == "" {
+		return
+	}
+
+	endpoint.Redirects = hops
+	parts := make([]string, len(hops))
+	for i, hop := range hops {
+		parts[i] = fmt.Sprintf("%s [%d]", hop.URL, hop.StatusCode)
+	}
+	endpoint.RedirectChain = strings.Join(parts, " -> ")
+
+	// A final 3xx means we stopped following; find out why
+	last := hops[len(hops)-1]
+	if last.Location == "" || resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return
+	}
+	next, err := resp.Request.URL.Parse(last.Location)
+	if err != nil {
+		return
+	}
+	for _, hop := range hops {
+		if hop.URL == next.String() {
+			endpoint.addTag(TagRedirectLoop)
+			return
+		}
+	}
+	if !sameOrigin(next.Scheme, next.Host, resp.Request.URL.Scheme, resp.Request.URL.Host) {
+		endpoint.addTag(TagCrossOriginRedirect)
+	}
+}
+
+// sameOrigin compares two scheme/host pairs
+func sameOrigin(schemeA, hostA, schemeB, hostB string) bool {
+	return strings.EqualFold(schemeA, schemeB) && strings.EqualFold(hostA, hostB)
+}
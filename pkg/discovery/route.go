@@ -0,0 +1,93 @@
+package discovery
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"jsfinder/pkg/utils"
+)
+
+// Placeholders for path segments that name a single object
+const (
+	RouteID   = "{id}"
+	RouteUUID = "{uuid}"
+)
+
+var (
+	numericSegment  = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment     = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	objectIDSegment = regexp.MustCompile(`^(?i)[0-9a-f]{24}$`) // MongoDB ObjectId
+)
+
+// routeTemplate returns the path of rawURL with numeric IDs, UUIDs and
+// ObjectIds replaced by placeholders, so /users/42 and /users/7 are both
+// /users/{id}. The query string is left out.
+func routeTemplate(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.Split(parsed.EscapedPath(), "/")
+	for i, segment := range segments {
+		switch {
+		case numericSegment.MatchString(segment), objectIDSegment.MatchString(segment):
+			segments[i] = RouteID
+		case uuidSegment.MatchString(segment):
+			segments[i] = RouteUUID
+		}
+	}
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+// routeKey identifies the endpoints one route inventory entry stands for:
+// same origin, route, method, virtual host and status
+func routeKey(endpoint Endpoint) string {
+	return strings.Join([]string{endpoint.Method, utils.Origin(endpoint.URL), endpoint.Route, endpoint.VirtualHost, strconv.Itoa(endpoint.StatusCode)}, " ")
+}
+
+// addReconstructedRoute reports whether a reconstructed endpoint is the first
+// of its route, so with CollapseRoutes /users/1 and /users/2 from the same JS
+// constants are probed once
+func (d *Discovery) addReconstructedRoute(endpointURL string) bool {
+	key := utils.Origin(endpointURL) + routeTemplate(endpointURL)
+	if d.routes[key] {
+		return false
+	}
+	d.routes[key] = true
+	return true
+}
+
+// collapseRoutes keeps the first endpoint found for each route when
+// CollapseRoutes is set, counting the others in its RouteHits
+func (d *Discovery) collapseRoutes() {
+	if !d.config.CollapseRoutes {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	first := make(map[string]int)
+	collapsed := make([]Endpoint, 0, len(d.results))
+	for _, endpoint := range d.results {
+		key := routeKey(endpoint)
+		if i, seen := first[key]; seen {
+			collapsed[i].RouteHits++
+			continue
+		}
+		endpoint.RouteHits = 1
+		first[key] = len(collapsed)
+		collapsed = append(collapsed, endpoint)
+	}
+	if len(collapsed) < len(d.results) {
+		d.logger.Infof("Collapsed %d endpoints into %d routes", len(d.results), len(collapsed))
+	}
+	d.results = collapsed
+}
@@ -0,0 +1,74 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Limits that keep a response schema short enough to scan in a CSV cell
+const (
+	schemaMaxKeys  = 20 // Keys listed per object; the rest are counted as "...+N"
+	schemaMaxDepth = 3  // Levels of nesting described before "{...}" or "[...]"
+)
+
+// jsonSchema describes the shape of a JSON response body: object keys with
+// their value types, and arrays by their first element, e.g.
+// {"data":[{"email":string,"id":number}],"total":number}. Bodies that are not
+// JSON, or were cut off at maxBodySample, have no schema.
+func jsonSchema(contentType string, body []byte) string {
+	body = bytes.TrimSpace(body)
+	if !strings.Contains(strings.ToLower(contentType), "json") && (len(body) == 0 || (body[0] != '{' && body[0] != '[')) {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return ""
+	}
+	return describeJSON(value, 0)
+}
+
+// describeJSON returns the schema of a decoded JSON value at the given depth
+func describeJSON(value interface{}, depth int) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if depth >= schemaMaxDepth {
+			return "{...}"
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, min(len(keys), schemaMaxKeys)+1)
+		for i, key := range keys {
+			if i == schemaMaxKeys {
+				parts = append(parts, fmt.Sprintf("...+%d", len(keys)-schemaMaxKeys))
+				break
+			}
+			name, _ := json.Marshal(key)
+			parts = append(parts, string(name)+":"+describeJSON(v[key], depth+1))
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case []interface{}:
+		if len(v) == 0 {
+			return "[]"
+		}
+		if depth >= schemaMaxDepth {
+			return "[...]"
+		}
+		return "[" + describeJSON(v[0], depth+1) + "]"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	default:
+		return "null"
+	}
+}
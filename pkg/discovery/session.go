@@ -0,0 +1,88 @@
+package discovery
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+)
+
+// Session cookies are only captured from a host's base URL and from the JS
+// files discovery downloads, never from probe responses, so every probe of a
+// host is made with the same session and a probe that happens to set a cookie
+// cannot change how the following ones are answered.
+
+// sessionClient returns the client for requests that make up a host's
+// session: with --session-cookies it stores every cookie set along the way,
+// redirects included
+func (d *Discovery) sessionClient() *http.Client {
+	if d.sessions == nil {
+		return d.client
+	}
+	client := *d.client
+	client.Jar = d.sessions
+	return &client
+}
+
+// addSessionCookies replays the session captured for the request's host,
+// fetching the host's base URL first if it has not been visited yet
+func (d *Discovery) addSessionCookies(req *http.Request) {
+	if d.sessions == nil {
+		return
+	}
+	d.primeSession(d.extractBaseURL(req.URL.String()))
+	for _, cookie := range d.sessions.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+}
+
+// primeSession requests a base URL once per run to capture the cookies it
+// sets, as a browser landing on the site would. Probes of the same host wait
+// for that request, while other hosts are not held up by it. Callers may
+// already hold an operation slot, so the request does not take one of its own.
+func (d *Discovery) primeSession(baseURL string) {
+	if baseURL == "" {
+		return
+	}
+	d.sessionsMutex.Lock()
+	done, exists := d.primed[baseURL]
+	if !exists {
+		done = make(chan struct{})
+		d.primed[baseURL] = done
+	}
+	d.sessionsMutex.Unlock()
+
+	if exists {
+		<-done
+		return
+	}
+	defer close(done)
+
+	op := d.timeoutMgr.StartNestedOperation("session", baseURL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	req, err := http.NewRequestWithContext(op.Ctx, "GET", baseURL+"/", nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", d.config.UserAgent)
+
+	resp, err := d.sessionClient().Do(req)
+	if err != nil {
+		d.logger.WithField("target", baseURL).Debugf("Session request failed: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySample))
+
+	d.logger.WithField("target", baseURL).Debugf("Captured %d session cookies", len(d.sessions.Cookies(req.URL)))
+}
+
+// newSessionJar returns the cookie store for --session-cookies, or nil when
+// sessions are off
+func newSessionJar(enabled bool) *cookiejar.Jar {
+	if !enabled {
+		return nil
+	}
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
@@ -0,0 +1,191 @@
+package discovery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Soft-404 handling modes
+const (
+	Soft404Filter = "filter" // Drop soft-404 responses (default)
+	Soft404Flag   = "flag"   // Keep them but tag as soft-404
+	Soft404Off    = "off"    // Disable detection
+)
+
+// TagSoft404 marks endpoints whose 2xx response looks like a "not found" page
+const TagSoft404 = "soft-404"
+
+// maxBodySample limits how much of a response body is kept for heuristics
+const maxBodySample = 1 << 20
+
+var (
+	titlePattern    = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	notFoundPhrases = []string{
+		"page not found",
+		"404 not found",
+		"not found</title>",
+		"<title>404",
+		"does not exist",
+		"could not be found",
+		"no longer available",
+		"nothing here",
+	}
+)
+
+// notFoundBaseline describes how a host answers a path that certainly does not exist
+type notFoundBaseline struct {
+	statusCode int
+	length     int64
+	title      string
+}
+
+// baselineProbe is a baseline request in flight or done; done is closed once
+// baseline is set
+type baselineProbe struct {
+	done     chan struct{}
+	baseline *notFoundBaseline
+}
+
+// soft404Mode returns the configured soft-404 mode
+func (d *Discovery) soft404Mode() string {
+	switch strings.ToLower(d.config.Soft404) {
+	case Soft404Flag:
+		return Soft404Flag
+	case Soft404Off:
+		return Soft404Off
+	default:
+		return Soft404Filter
+	}
+}
+
+// isSoft404 reports whether a successful response is really a "not found"
+// page. The phrase heuristic only applies to HTML pages, as API responses
+// routinely mention missing items.
+func (d *Discovery) isSoft404(baseURL string, statusCode int, contentType string, body []byte) bool {
+	if statusCode < 200 || statusCode >= 300 {
+		return false
+	}
+
+	if isHTML(contentType, body) {
+		lower := strings.ToLower(string(body))
+		for _, phrase := range notFoundPhrases {
+			if strings.Contains(lower, phrase) {
+				return true
+			}
+		}
+	}
+
+	baseline := d.getNotFoundBaseline(baseURL)
+	if baseline == nil || baseline.statusCode != statusCode {
+		return false
+	}
+
+	if title := pageTitle(body); title != "" && title == baseline.title {
+		return true
+	}
+
+	return similarLength(int64(len(body)), baseline.length)
+}
+
+// getNotFoundBaseline probes a random path once per host and caches the result
+func (d *Discovery) getNotFoundBaseline(baseURL string) *notFoundBaseline {
+	return d.cachedBaseline(baseURL, func() *notFoundBaseline {
+		return d.probeNotFoundBaseline(baseURL)
+	})
+}
+
+// cachedBaseline returns the baseline stored under key, running probe the
+// first time. Concurrent callers for the same key wait for that probe, while
+// other keys are not held up by it.
+func (d *Discovery) cachedBaseline(key string, probe func() *notFoundBaseline) *notFoundBaseline {
+	d.baselinesMutex.Lock()
+	entry, exists := d.baselineProbes[key]
+	if !exists {
+		entry = &baselineProbe{done: make(chan struct{})}
+		d.baselineProbes[key] = entry
+	}
+	d.baselinesMutex.Unlock()
+
+	if exists {
+		<-entry.done
+		return entry.baseline
+	}
+	defer close(entry.done)
+	entry.baseline = probe()
+	return entry.baseline
+}
+
+// probeNotFoundBaseline requests a random path of baseURL. It runs within the
+// probe that needs it, so it does not take an operation slot of its own.
+func (d *Discovery) probeNotFoundBaseline(baseURL string) *notFoundBaseline {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil
+	}
+
+	op := d.timeoutMgr.StartNestedOperation("baseline", baseURL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	req, err := http.NewRequestWithContext(op.Ctx, "GET", baseURL+"/"+hex.EncodeToString(token), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", d.config.UserAgent)
+	d.addSessionCookies(req)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySample))
+	if err != nil {
+		return nil
+	}
+
+	return &notFoundBaseline{
+		statusCode: resp.StatusCode,
+		length:     int64(len(body)),
+		title:      pageTitle(body),
+	}
+}
+
+// isHTML reports whether a response is an HTML page, going by its
+// Content-Type or, without one, by its body
+func isHTML(contentType string, body []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return strings.Contains(strings.ToLower(contentType), "html")
+}
+
+// pageTitle extracts the normalized HTML title of a body
+func pageTitle(body []byte) string {
+	match := titlePattern.FindSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	return strings.ToLower(string(bytes.TrimSpace(match[1])))
+}
+
+// similarLength reports whether two body lengths are within 5% of each other
+func similarLength(a, b int64) bool {
+	if a == b {
+		return true
+	}
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	larger := a
+	if b > larger {
+		larger = b
+	}
+	return larger > 0 && float64(diff)/float64(larger) <= 0.05
+}
@@ -0,0 +1,31 @@
+package discovery
+
+import "sort"
+
+// Orderings for Config.Sort. Ties are broken on every remaining field so the
+// same endpoints are always written in the same order.
+const (
+	SortURL    = "url"    // By URL, then method and virtual host
+	SortStatus = "status" // By status code, then as for SortURL
+)
+
+// sortEndpoints orders endpoints in place; an empty ordering leaves them as found
+func sortEndpoints(endpoints []Endpoint, by string) {
+	if by == "" {
+		return
+	}
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		if by == SortStatus && a.StatusCode != b.StatusCode {
+			return a.StatusCode < b.StatusCode
+		}
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.VirtualHost < b.VirtualHost
+	})
+}
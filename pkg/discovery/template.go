@@ -0,0 +1,94 @@
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Placeholders in a URL template and its headers
+const (
+	FuzzKeyword = "FUZZ" // Replaced by each wordlist entry
+	BaseKeyword = "BASE" // Replaced by the host (and port) of each base URL
+)
+
+// Template places wordlist entries exactly where the user wants them, in the
+// URL path, a query parameter or a header, instead of the fixed path
+// variations. A template without BASE targets one fixed host rather than every
+// base URL found in the JS files. A nil *Template uses the path variations.
+type Template struct {
+	url     string
+	headers []templateHeader
+}
+
+type templateHeader struct {
+	name  string
+	value string
+}
+
+// ParseTemplate validates a URL template such as https://BASE/api/FUZZ.json
+// and the "Name: value" headers sent with it; FUZZ must appear at least once
+func ParseTemplate(rawURL string, headers []string) (*Template, error) {
+	template := &Template{url: rawURL}
+	fuzzed := strings.Contains(rawURL, FuzzKeyword)
+
+	sample := strings.NewReplacer(BaseKeyword, "example.com", FuzzKeyword, "word").Replace(rawURL)
+	parsed, err := url.Parse(sample)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid URL template %q: expected an absolute http(s) URL", rawURL)
+	}
+
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid header %q: expected \"Name: value\"", header)
+		}
+		template.headers = append(template.headers, templateHeader{name: name, value: strings.TrimSpace(value)})
+		fuzzed = fuzzed || strings.Contains(header, FuzzKeyword)
+	}
+
+	if !fuzzed {
+		return nil, fmt.Errorf("URL template %q and its headers do not contain %s", rawURL, FuzzKeyword)
+	}
+	return template, nil
+}
+
+// PerBase reports whether the template is expanded for every base URL
+func (t *Template) PerBase() bool {
+	return strings.Contains(t.url, BaseKeyword)
+}
+
+// Origin returns the scheme and host of a template without BASE
+func (t *Template) Origin() string {
+	parsed, err := url.Parse(strings.ReplaceAll(t.url, FuzzKeyword, "word"))
+	if err != nil {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// String returns the URL template and its headers as written
+func (t *Template) String() string {
+	parts := []string{t.url}
+	for _, h := range t.headers {
+		parts = append(parts, fmt.Sprintf("(%s: %s)", h.name, h.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Expand fills the template in for a base URL and wordlist entry
+func (t *Template) Expand(baseURL, word string) (string, http.Header) {
+	host := baseURL
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+	replacer := strings.NewReplacer(BaseKeyword, host, FuzzKeyword, word)
+
+	header := make(http.Header)
+	for _, h := range t.headers {
+		header.Add(replacer.Replace(h.name), replacer.Replace(h.value))
+	}
+	return replacer.Replace(t.url), header
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VHostTemplate returns the template for virtual host discovery: every
+// wordlist entry is sent to target as the Host header, suffixed with
+// "."+domain when a domain is given
+func VHostTemplate(target, domain string) (*Template, error) {
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid vhost target %q: expected an http(s) URL such as https://203.0.113.10", target)
+	}
+
+	host := FuzzKeyword
+	if domain = strings.Trim(domain, "."); domain != "" {
+		host += "." + domain
+	}
+	return ParseTemplate(strings.TrimSuffix(target, "/")+"/", []string{"Host: " + host})
+}
+
+// isDefaultVHost reports whether a response is what the target serves for a
+// host it does not know, so only virtual hosts with their own content remain.
+// Default pages often echo the requested host, so it is left out of the
+// comparison.
+func (d *Discovery) isDefaultVHost(testURL, host string, statusCode int, body []byte) bool {
+	baseline := d.getDefaultVHost(d.extractBaseURL(testURL))
+	if baseline == nil || baseline.statusCode != statusCode {
+		return false
+	}
+
+	body = bytes.ReplaceAll(body, []byte(host), nil)
+	if pageTitle(body) != baseline.title {
+		return false
+	}
+	return similarLength(int64(len(body)), baseline.length)
+}
+
+// getDefaultVHost fingerprints the target's default virtual host once, by
+// requesting it with a random Host header
+func (d *Discovery) getDefaultVHost(baseURL string) *notFoundBaseline {
+	return d.cachedBaseline("vhost "+baseURL, func() *notFoundBaseline {
+		return d.probeDefaultVHost(baseURL)
+	})
+}
+
+// probeDefaultVHost requests baseURL with a random Host header. Like the
+// soft-404 baseline, it runs within a probe and takes no operation slot.
+func (d *Discovery) probeDefaultVHost(baseURL string) *notFoundBaseline {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil
+	}
+
+	op := d.timeoutMgr.StartNestedOperation("baseline", baseURL)
+	defer d.timeoutMgr.CompleteOperation(op.ID)
+
+	testURL, header := d.config.Template.Expand(baseURL, hex.EncodeToString(token))
+	req, err := http.NewRequestWithContext(op.Ctx, "GET", testURL, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", d.config.UserAgent)
+	req.Host = header.Get("Host")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySample))
+	if err != nil {
+		return nil
+	}
+	body = bytes.ReplaceAll(body, []byte(req.Host), nil)
+
+	return &notFoundBaseline{
+		statusCode: resp.StatusCode,
+		length:     int64(len(body)),
+		title:      pageTitle(body),
+	}
+}
+
+// DiscoverVHosts runs virtual host discovery against the single target of
+// Config.Template, which VHostTemplate builds; no JS input is read
+func (d *Discovery) DiscoverVHosts() error {
+	if err := d.loadWordlist(); err != nil {
+		return fmt.Errorf("failed to load wordlist: %w", err)
+	}
+	if err := d.confirmRequestCount(); err != nil {
+		return err
+	}
+	if err := d.discoverEndpoints(); err != nil {
+		return err
+	}
+	return d.outputResults()
+}
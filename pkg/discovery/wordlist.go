@@ -0,0 +1,43 @@
+package discovery
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// BuiltinPrefix selects a wordlist shipped inside the binary, e.g. builtin:endpoints
+const BuiltinPrefix = "builtin:"
+
+//go:embed wordlists/*.txt
+var builtinWordlists embed.FS
+
+// BuiltinWordlists returns the names of the wordlists shipped inside the binary
+func BuiltinWordlists() []string {
+	entries, _ := builtinWordlists.ReadDir("wordlists")
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".txt"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openWordlist opens a wordlist file, or a built-in one for a builtin: name
+func openWordlist(name string) (io.ReadCloser, error) {
+	builtin, found := strings.CutPrefix(name, BuiltinPrefix)
+	if !found {
+		return os.Open(name)
+	}
+
+	file, err := builtinWordlists.Open(path.Join("wordlists", builtin+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown built-in wordlist %q, available: %s", builtin, strings.Join(BuiltinWordlists(), ", "))
+	}
+	return file, nil
+}
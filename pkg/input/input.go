@@ -0,0 +1,268 @@
+package input
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Input formats recognized by Parse
+const (
+	FormatPlain     = "plain"
+	FormatHTTPX     = "httpx"
+	FormatAmass     = "amass"
+	FormatSubfinder = "subfinder"
+)
+
+// Target is a single input item with any metadata the producing tool reported
+type Target struct {
+	URL        string   `json:"url"`
+	Host       string   `json:"host"`
+	Scheme     string   `json:"scheme,omitempty"`
+	Inferred   bool     `json:"inferred,omitempty"` // The input had no scheme; see SchemeProber
+	Port       string   `json:"port,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Tech       []string `json:"tech,omitempty"`
+	Format     string   `json:"format"`
+}
+
+// Recon is the metadata a recon tool reported for a target, kept on the
+// results for its origin
+type Recon struct {
+	Format     string   `json:"format"`
+	Title      string   `json:"title,omitempty"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Tech       []string `json:"tech,omitempty"`
+}
+
+// Recon returns the target's recon metadata, nil when the tool reported none
+func (t Target) Recon() *Recon {
+	if t.Title == "" && t.StatusCode == 0 && len(t.Tech) == 0 {
+		return nil
+	}
+	return &Recon{Format: t.Format, Title: t.Title, StatusCode: t.StatusCode, Tech: t.Tech}
+}
+
+// Describe returns the recon metadata as a short suffix for log lines
+func (t Target) Describe() string {
+	var parts []string
+	if t.StatusCode != 0 {
+		parts = append(parts, strconv.Itoa(t.StatusCode))
+	}
+	if t.Title != "" {
+		parts = append(parts, fmt.Sprintf("%q", t.Title))
+	}
+	if len(t.Tech) > 0 {
+		parts = append(parts, strings.Join(t.Tech, ","))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%s: %s]", t.Format, strings.Join(parts, " "))
+}
+
+// reconRecord covers the JSON line fields of httpx, amass and subfinder output
+type reconRecord struct {
+	URL        string          `json:"url"`
+	Input      string          `json:"input"`
+	Host       string          `json:"host"`
+	Name       string          `json:"name"`
+	Scheme     string          `json:"scheme"`
+	Port       json.RawMessage `json:"port"`
+	Title      string          `json:"title"`
+	StatusCode int             `json:"status_code"`
+	Tech       []string        `json:"tech"`
+	Source     string          `json:"source"`
+	Sources    []string        `json:"sources"`
+}
+
+// Parse converts one line of plain, httpx, amass or subfinder output into a
+// target. Bare hostnames become https:// URLs, or http:// ones on port 80,
+// with their host lowercased. It returns false for blank lines and comments.
+func Parse(line string) (Target, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Target{}, false
+	}
+
+	if strings.HasPrefix(line, "{") {
+		if target, ok := parseJSON(line); ok {
+			return target, true
+		}
+	}
+	return parseText(line)
+}
+
+// parseJSON handles httpx -json, amass -json and subfinder -oJ lines
+func parseJSON(line string) (Target, bool) {
+	var record reconRecord
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return Target{}, false
+	}
+
+	switch {
+	case record.URL != "":
+		target := fromURL(record.URL, FormatHTTPX)
+		target.Title = record.Title
+		target.StatusCode = record.StatusCode
+		target.Tech = record.Tech
+		if record.Scheme != "" {
+			target.Scheme = record.Scheme
+		}
+		if port := strings.Trim(string(record.Port), `"`); port != "" && port != "null" {
+			target.Port = port
+		}
+		return target, true
+	case record.Name != "":
+		return fromHost(record.Name, FormatAmass), true
+	case record.Host != "":
+		return fromHost(record.Host, FormatSubfinder), true
+	}
+	return Target{}, false
+}
+
+// parseText handles plain lists, "[Source] host" amass lines, "host ip,ip"
+// amass -ip lines and "url [status] [title]" httpx lines
+func parseText(line string) (Target, bool) {
+	format := FormatPlain
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "]"); end > 0 {
+			line = strings.TrimSpace(line[end+1:])
+			format = FormatAmass
+		}
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Target{}, false
+	}
+
+	if !strings.Contains(fields[0], "://") {
+		if format == FormatPlain && len(fields) > 1 {
+			format = FormatAmass
+		}
+		return fromHost(fields[0], format), true
+	}
+
+	target := fromURL(fields[0], format)
+	if len(fields) > 1 {
+		target.Format = FormatHTTPX
+		var brackets []string
+		rest := line[len(fields[0]):]
+		for {
+			start := strings.Index(rest, "[")
+			end := strings.Index(rest, "]")
+			if start < 0 || end < start {
+				break
+			}
+			brackets = append(brackets, rest[start+1:end])
+			rest = rest[end+1:]
+		}
+		for _, value := range brackets {
+			if code, err := strconv.Atoi(value); err == nil && target.StatusCode == 0 {
+				target.StatusCode = code
+			} else if target.Title == "" {
+				target.Title = value
+			} else {
+				target.Tech = append(target.Tech, value)
+			}
+		}
+	}
+	return target, true
+}
+
+// fromURL builds a target from a full URL, lowercasing its scheme and host
+func fromURL(rawURL, format string) Target {
+	target := Target{URL: rawURL, Format: format}
+	if parsed, err := url.Parse(rawURL); err == nil {
+		target.Scheme = parsed.Scheme
+		target.Host = strings.ToLower(parsed.Hostname())
+		target.Port = parsed.Port()
+		if prefix := parsed.Scheme + "://" + parsed.Host; parsed.User == nil && len(rawURL) >= len(prefix) && strings.EqualFold(rawURL[:len(prefix)], prefix) {
+			target.URL = strings.ToLower(prefix) + rawURL[len(prefix):]
+		}
+	}
+	return target
+}
+
+// fromHost builds a target from a hostname without a scheme, keeping any
+// port and path. Trailing dots and wildcard labels (*.example.com) are
+// dropped; port 80 implies http, anything else https.
+func fromHost(host, format string) Target {
+	host = strings.TrimPrefix(host, "//")
+	var path string
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host, path = host[:i], host[i:]
+	}
+
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+	name = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(name), "."), "*.")
+	host = name
+	if port != "" {
+		host = net.JoinHostPort(name, port)
+	}
+
+	target := Target{Host: name, Port: port, Scheme: "https", Inferred: true, Format: format}
+	if port == "80" {
+		target.Scheme, target.Inferred = "http", false
+	}
+	target.URL = target.Scheme + "://" + host + path
+	return target
+}
+
+// ParseDomain parses a target given on the command line, such as crawl
+// --domain, reporting why it cannot be fetched up front
+func ParseDomain(raw string) (Target, error) {
+	target, ok := Parse(raw)
+	if !ok {
+		return Target{}, fmt.Errorf("invalid domain %q: no host name", raw)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return Target{}, fmt.Errorf("invalid domain %q: unsupported scheme %q (want http or https)", raw, target.Scheme)
+	}
+	if target.Host == "" || strings.ContainsAny(raw, " \t") {
+		return Target{}, fmt.Errorf("invalid domain %q: no host name", raw)
+	}
+	return target, nil
+}
+
+// Scanner reads targets line by line from any supported format
+type Scanner struct {
+	lines  *bufio.Scanner
+	target Target
+}
+
+// NewScanner creates a target scanner over reader
+func NewScanner(reader io.Reader) *Scanner {
+	return &Scanner{lines: bufio.NewScanner(reader)}
+}
+
+// Scan advances to the next target, skipping blank lines and comments
+func (s *Scanner) Scan() bool {
+	for s.lines.Scan() {
+		if target, ok := Parse(s.lines.Text()); ok {
+			s.target = target
+			return true
+		}
+	}
+	return false
+}
+
+// Target returns the most recent target read by Scan
+func (s *Scanner) Target() Target {
+	return s.target
+}
+
+// Err returns the first read error
+func (s *Scanner) Err() error {
+	return s.lines.Err()
+}
@@ -0,0 +1,159 @@
+package input
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		url      string
+		format   string
+		port     string
+		title    string
+		status   int
+		expected bool
+	}{
+		{"Plain URL", "https://example.com/app.js", "https://example.com/app.js", FormatPlain, "", "", 0, true},
+		{"Bare host", "api.example.com", "https://api.example.com", FormatPlain, "", "", 0, true},
+		{"Bare host with path", "Example.COM./static/app.js", "https://example.com/static/app.js", FormatPlain, "", "", 0, true},
+		{"Bare host on port 80", "example.com:80", "http://example.com:80", FormatPlain, "80", "", 0, true},
+		{"Wildcard host", "*.example.com", "https://example.com", FormatPlain, "", "", 0, true},
+		{"Mixed case URL", "HTTPS://WWW.Example.com/App.js", "https://www.example.com/App.js", FormatPlain, "", "", 0, true},
+		{"Blank", "   ", "", "", "", "", 0, false},
+		{"Comment", "# targets", "", "", "", "", 0, false},
+		{
+			name:     "httpx JSON",
+			line:     `{"url":"http://dev.example.com:8080","scheme":"http","port":"8080","title":"Dev Portal","status_code":200,"tech":["Nginx"]}`,
+			url:      "http://dev.example.com:8080",
+			format:   FormatHTTPX,
+			port:     "8080",
+			title:    "Dev Portal",
+			status:   200,
+			expected: true,
+		},
+		{"httpx text", "https://example.com [301] [Moved] [Cloudflare]", "https://example.com", FormatHTTPX, "", "Moved", 301, true},
+		{"amass JSON", `{"name":"mail.example.com","domain":"example.com","addresses":[{"ip":"1.2.3.4"}]}`, "https://mail.example.com", FormatAmass, "", "", 0, true},
+		{"amass source", "[CertSpotter]     www.example.com", "https://www.example.com", FormatAmass, "", "", 0, true},
+		{"amass ip", "www.example.com 1.2.3.4,5.6.7.8", "https://www.example.com", FormatAmass, "", "", 0, true},
+		{"subfinder JSON", `{"host":"cdn.example.com","input":"example.com","source":"crtsh"}`, "https://cdn.example.com", FormatSubfinder, "", "", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, ok := Parse(tc.line)
+			if ok != tc.expected {
+				t.Fatalf("Expected ok=%v, got %v", tc.expected, ok)
+			}
+			if !ok {
+				return
+			}
+			if target.URL != tc.url || target.Format != tc.format {
+				t.Errorf("Expected %s (%s), got %s (%s)", tc.url, tc.format, target.URL, target.Format)
+			}
+			if target.Port != tc.port || target.Title != tc.title || target.StatusCode != tc.status {
+				t.Errorf("Unexpected metadata: port=%q title=%q status=%d", target.Port, target.Title, target.StatusCode)
+			}
+		})
+	}
+}
+
+func TestScanner(t *testing.T) {
+	reader := strings.NewReader("https://a.example.com\n\n# comment\n{\"host\":\"b.example.com\"}\n")
+	scanner := NewScanner(reader)
+
+	var urls []string
+	for scanner.Scan() {
+		urls = append(urls, scanner.Target().URL)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(urls) != 2 || urls[1] != "https://b.example.com" {
+		t.Errorf("Unexpected targets: %v", urls)
+	}
+}
+
+func TestParseDomain(t *testing.T) {
+	target, err := ParseDomain("example.com")
+	if err != nil || target.URL != "https://example.com" || !target.Inferred {
+		t.Errorf("Expected an inferred https target, got %+v, %v", target, err)
+	}
+	if target, err := ParseDomain("http://example.com"); err != nil || target.Inferred {
+		t.Errorf("Expected the given scheme to be kept, got %+v, %v", target, err)
+	}
+	for _, domain := range []string{"", "ftp://example.com", "https://", "example .com"} {
+		if _, err := ParseDomain(domain); err == nil {
+			t.Errorf("Expected an error for %q", domain)
+		}
+	}
+}
+
+func TestSchemeProber(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+
+	prober := NewSchemeProber(secure.Client())
+	for server, expected := range map[*httptest.Server]string{plain: "http", secure: "https"} {
+		address := server.Listener.Addr().String()
+		target, _ := Parse(address + "/app.js")
+		resolved := prober.Resolve(context.Background(), target)
+		if resolved.Scheme != expected || resolved.URL != expected+"://"+address+"/app.js" {
+			t.Errorf("Expected %s for %s, got %s", expected, server.URL, resolved.URL)
+		}
+	}
+
+	explicit, _ := Parse("https://" + plain.Listener.Addr().String())
+	if resolved := prober.Resolve(context.Background(), explicit); resolved.URL != explicit.URL {
+		t.Errorf("Expected a given scheme to be kept, got %s", resolved.URL)
+	}
+
+	// A queued target is resolved once a worker takes it
+	target, _ := Parse(plain.Listener.Addr().String() + "/lib.js")
+	if pending := target.Pending(); strings.Contains(pending, "://") {
+		t.Errorf("Expected the inferred scheme left out of the queue, got %s", pending)
+	}
+	if resolved := prober.ResolveLine(context.Background(), target.Pending()); resolved != plain.URL+"/lib.js" {
+		t.Errorf("Expected the queued target resolved to http, got %s", resolved)
+	}
+	if pending := explicit.Pending(); pending != explicit.URL {
+		t.Errorf("Expected a given scheme queued as is, got %s", pending)
+	}
+}
+
+func TestSchemeProber_concurrent(t *testing.T) {
+	var heads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&heads, 1)
+	}))
+	defer server.Close()
+
+	prober := NewSchemeProber(server.Client())
+	target, _ := Parse(server.Listener.Addr().String())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resolved := prober.Resolve(context.Background(), target); resolved.Scheme != "http" {
+				t.Errorf("Expected http, got %s", resolved.URL)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// One failed https attempt, which the plain server never sees, then http
+	if heads != 1 {
+		t.Errorf("Expected the host probed once, got %d http requests", heads)
+	}
+}
@@ -0,0 +1,111 @@
+package input
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SchemeProber finds the scheme hosts given without one answer on, trying
+// https first and falling back to http. Each host is probed once; workers
+// resolving a host another one is probing wait for its answer.
+type SchemeProber struct {
+	client *http.Client
+	mutex  sync.Mutex
+	hosts  map[string]*hostScheme // Host and port to scheme
+}
+
+// hostScheme is the scheme of a host, known once done is closed
+type hostScheme struct {
+	done   chan struct{}
+	scheme string
+}
+
+// NewSchemeProber creates a prober sending its requests through client.
+// Redirects are not followed: any response shows the scheme is served.
+func NewSchemeProber(client *http.Client) *SchemeProber {
+	probeClient := *client
+	probeClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &SchemeProber{client: &probeClient, hosts: make(map[string]*hostScheme)}
+}
+
+// Resolve returns target with the scheme its host answers on. Targets whose
+// scheme was given are returned unchanged, and so are hosts answering on
+// neither, keeping https so the error names the URL the caller expects.
+// Probes send traffic, so callers resolve targets in their workers, after
+// the run window and budget checks.
+func (p *SchemeProber) Resolve(ctx context.Context, target Target) Target {
+	if p == nil || !target.Inferred {
+		return target
+	}
+	address := target.Host
+	if target.Port != "" {
+		address = net.JoinHostPort(target.Host, target.Port)
+	}
+
+	p.mutex.Lock()
+	host, probed := p.hosts[address]
+	if !probed {
+		host = &hostScheme{done: make(chan struct{}), scheme: "https"}
+		p.hosts[address] = host
+	}
+	p.mutex.Unlock()
+
+	scheme := "https"
+	if probed {
+		select {
+		case <-host.done:
+			scheme = host.scheme
+		case <-ctx.Done():
+		}
+	} else {
+		if !p.answers(ctx, "https://"+address+"/") && p.answers(ctx, "http://"+address+"/") {
+			scheme = "http"
+		}
+		host.scheme = scheme
+		close(host.done)
+	}
+
+	if scheme != target.Scheme {
+		target.URL = scheme + target.URL[len(target.Scheme):]
+		target.Scheme = scheme
+	}
+	return target
+}
+
+// Pending returns the line to queue for target until a worker resolves it
+// with ResolveLine: its URL, without the scheme when that was inferred so
+// Parse infers it again
+func (t Target) Pending() string {
+	if !t.Inferred {
+		return t.URL
+	}
+	return strings.TrimPrefix(t.URL, t.Scheme+"://")
+}
+
+// ResolveLine resolves a line queued with Target.Pending to a URL
+func (p *SchemeProber) ResolveLine(ctx context.Context, line string) string {
+	target, ok := Parse(line)
+	if !ok {
+		return line
+	}
+	return p.Resolve(ctx, target).URL
+}
+
+// answers reports whether a HEAD request to url gets any HTTP response
+func (p *SchemeProber) answers(ctx context.Context, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
@@ -0,0 +1,216 @@
+package literal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxResolvePasses bounds how many times constants built from other
+// constants are re-resolved, so reference cycles cannot loop forever
+const maxResolvePasses = 5
+
+// Building blocks for the expression patterns below
+const (
+	stringOperand  = "\"[^\"\\\\\\n]*\"|'[^'\\\\\\n]*'|`[^`\\\\]*`"
+	identifier     = `[A-Za-z_$][\w$]*`
+	reference      = identifier + `(?:\.` + identifier + `)*`
+	operand        = `(?:` + stringOperand + `|` + reference + `)`
+	concatenation  = operand + `(?:\s*\+\s*` + operand + `)+`
+	typeAnnotation = `(?::\s*[A-Za-z_$][\w$.<>\[\]| ]*?)?`
+)
+
+var (
+	// constantPattern matches string constants in JavaScript and TypeScript,
+	// including exported ones and ones with a type annotation, whose value is a
+	// literal or a concatenation: export const API: string = BASE + "/api"
+	constantPattern = regexp.MustCompile(`(?:^|[^\w$.])(?:const|let|var)\s+(` + identifier + `)\s*` + typeAnnotation + `\s*=\s*(` + concatenation + `|` + stringOperand + `)\s*(?:[;,)\n]|$)`)
+
+	// objectPattern finds object literal constants whose string properties can
+	// be referenced as NAME.property
+	objectPattern   = regexp.MustCompile(`(?:^|[^\w$.])(?:const|let|var)\s+(` + identifier + `)\s*` + typeAnnotation + `\s*=\s*\{`)
+	propertyPattern = regexp.MustCompile(`(?:^|[{,\s])(` + identifier + `|"[^"]+"|'[^']+')\s*:\s*(` + concatenation + `|` + stringOperand + `)\s*(?:,|$)`)
+
+	templatePattern      = regexp.MustCompile("`([^`\\\\]*\\$\\{[^`\\\\]*)`")
+	concatenationPattern = regexp.MustCompile(concatenation)
+	operandPattern       = regexp.MustCompile(operand)
+	placeholderPattern   = regexp.MustCompile(`\$\{\s*(` + reference + `)\s*\}`)
+)
+
+// Resolved is an expression whose references were all substituted
+type Resolved struct {
+	Value  string // The reconstructed string, e.g. https://api.example.com/users
+	Offset int    // Byte offset of the expression in the content
+	Length int    // Byte length of the expression
+}
+
+// Constants returns the string constants declared in content, keyed by name
+// and, for object literal properties, by NAME.property. A constant declared
+// more than once with different values is ambiguous and left out.
+func Constants(content string) map[string]string {
+	expressions := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	declare := func(name, expression string) {
+		if previous, exists := expressions[name]; (exists && previous != expression) || ambiguous[name] {
+			ambiguous[name] = true
+			delete(expressions, name)
+			return
+		}
+		expressions[name] = expression
+	}
+
+	for _, match := range constantPattern.FindAllStringSubmatch(content, -1) {
+		declare(match[1], match[2])
+	}
+	for _, loc := range objectPattern.FindAllStringSubmatchIndex(content, -1) {
+		name := content[loc[2]:loc[3]]
+		for _, property := range propertyPattern.FindAllStringSubmatch(topLevel(content[loc[1]:]), -1) {
+			declare(name+"."+strings.Trim(property[1], `"'`), property[2])
+		}
+	}
+
+	// Constants may themselves be built from other constants
+	constants := make(map[string]string)
+	for pass := 0; pass < maxResolvePasses; pass++ {
+		changed := false
+		for name, expression := range expressions {
+			if _, done := constants[name]; done {
+				continue
+			}
+			if value, ok := evaluate(expression, constants); ok {
+				constants[name] = value
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	return constants
+}
+
+// Resolve reconstructs the strings that content builds from constants
+// declared in the same file: template literals (`${API_BASE}/users`) and
+// concatenations (BASE_URL + '/api/users', config.apiHost + path). Only
+// expressions whose references all resolve are returned.
+func Resolve(content string) []Resolved {
+	constants := Constants(content)
+	if len(constants) == 0 {
+		return nil
+	}
+
+	var resolved []Resolved
+	add := func(loc []int, value string) {
+		resolved = append(resolved, Resolved{Value: value, Offset: loc[0], Length: loc[1] - loc[0]})
+	}
+
+	for _, loc := range concatenationPattern.FindAllStringIndex(content, -1) {
+		if value, ok := evaluate(content[loc[0]:loc[1]], constants); ok {
+			add(loc, value)
+		}
+	}
+	for _, loc := range templatePattern.FindAllStringSubmatchIndex(content, -1) {
+		if insideAny(loc[0], resolved) {
+			continue // Already reconstructed as part of a concatenation
+		}
+		if value, ok := substitute(content[loc[2]:loc[3]], constants); ok {
+			add(loc, value)
+		}
+	}
+
+	return resolved
+}
+
+// evaluate computes a literal, a reference or a concatenation of them
+func evaluate(expression string, constants map[string]string) (string, bool) {
+	var value strings.Builder
+	for _, operand := range operandPattern.FindAllString(expression, -1) {
+		switch operand[0] {
+		case '"', '\'':
+			value.WriteString(operand[1 : len(operand)-1])
+		case '`':
+			text, ok := substitute(operand[1:len(operand)-1], constants)
+			if !ok {
+				return "", false
+			}
+			value.WriteString(text)
+		default:
+			constant, exists := constants[operand]
+			if !exists {
+				return "", false
+			}
+			value.WriteString(constant)
+		}
+	}
+	return value.String(), true
+}
+
+// substitute replaces every ${reference} in template with its constant,
+// failing if any placeholder is unknown or is an expression rather than a
+// plain reference
+func substitute(template string, constants map[string]string) (string, bool) {
+	ok := true
+	value := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		constant, exists := constants[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+		if !exists {
+			ok = false
+			return placeholder
+		}
+		return constant
+	})
+	return value, ok && !strings.Contains(value, "${")
+}
+
+// topLevel returns the text of an object literal body (starting just after
+// its opening brace) with nested objects, arrays and calls removed, so only
+// its own properties remain
+func topLevel(body string) string {
+	var out strings.Builder
+	depth := 0
+	var quote byte
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if quote != 0 {
+			if depth == 0 {
+				out.WriteByte(c)
+			}
+			if c == '\\' && i+1 < len(body) {
+				i++
+				if depth == 0 {
+					out.WriteByte(body[i])
+				}
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '{', '[', '(':
+			depth++
+			continue
+		case '}', ']', ')':
+			if depth == 0 {
+				return out.String()
+			}
+			depth--
+			continue
+		}
+		if depth == 0 {
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+func insideAny(offset int, resolved []Resolved) bool {
+	for _, r := range resolved {
+		if offset >= r.Offset && offset < r.Offset+r.Length {
+			return true
+		}
+	}
+	return false
+}
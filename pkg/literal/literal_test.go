@@ -0,0 +1,113 @@
+package literal
+
+import (
+	"testing"
+)
+
+func TestConstants(t *testing.T) {
+	content := `
+export const API_BASE: string = "https://api.example.com";
+const VERSION = 'v2';
+let API_ROOT = ` + "`${API_BASE}/${VERSION}`" + `;
+const USERS = API_ROOT + "/users";
+var retries = 3;
+const config = {
+	apiHost: "https://internal.example.com",
+	"auth-path": '/oauth/token',
+	nested: { ignored: "/nested" },
+	timeout: 3000,
+};
+const handler = compute("/not-constant");
+const DUP = "a";
+const DUP = "b";
+`
+
+	constants := Constants(content)
+
+	expected := map[string]string{
+		"API_BASE":         "https://api.example.com",
+		"VERSION":          "v2",
+		"API_ROOT":         "https://api.example.com/v2",
+		"USERS":            "https://api.example.com/v2/users",
+		"config.apiHost":   "https://internal.example.com",
+		"config.auth-path": "/oauth/token",
+	}
+	for name, value := range expected {
+		if constants[name] != value {
+			t.Errorf("Expected %s = %q, got %q", name, value, constants[name])
+		}
+	}
+	if len(constants) != len(expected) {
+		t.Errorf("Expected only simple, unambiguous constants, got %v", constants)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	content := "const API_BASE = '/api';\n" +
+		"const config = { apiHost: 'https://api.example.com' };\n" +
+		"fetch(`${API_BASE}/users/${ id }`);\n" +
+		"fetch(`${API_BASE}/orders`);\n" +
+		"fetch(`${API_BASE}/items/${items.length}`);\n" +
+		"axios.get(config.apiHost + API_BASE + '/health');\n" +
+		"axios.get(API_BASE + '/users/' + id);\n"
+
+	values := make(map[string]string)
+	for _, resolved := range Resolve(content) {
+		values[resolved.Value] = content[resolved.Offset : resolved.Offset+resolved.Length]
+	}
+
+	expected := map[string]string{
+		"/api/orders":                        "`${API_BASE}/orders`",
+		"https://api.example.com/api/health": "config.apiHost + API_BASE + '/health'",
+	}
+	for value, expression := range expected {
+		if values[value] != expression {
+			t.Errorf("Expected %q reconstructed from %q, got %q", value, expression, values[value])
+		}
+	}
+	if len(values) != len(expected) {
+		t.Errorf("Expected only fully resolvable expressions, got %v", values)
+	}
+
+	if resolved := Resolve("fetch(`${BASE}/users`)"); resolved != nil {
+		t.Errorf("Expected nothing without constants, got %+v", resolved)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	content := "// apiKey = \"commented-out\"\n" +
+		"/* password: 'also-commented' */\n" +
+		"const apiKey =\n  \"k\\u0065y-\\x41\\n\";\n" +
+		"const ratio = total / count, re = /[\"/]+'/g;\n" +
+		"const cfg = {\"api_key\": 'abc', token: string = `t-${user.id + '}'}-x`};\n" +
+		"if (a == \"b\") fetch('/api/users');\n"
+
+	expected := []String{
+		{Value: "key-A\n", Key: "apiKey"},
+		{Value: "abc", Key: `"api_key"`},
+		{Value: "t-${user.id + '}'}-x", Key: "token"},
+		{Value: "b"},
+		{Value: "/api/users"},
+	}
+	literals := Strings(content)
+	var got []String
+	for _, str := range literals {
+		if str.Key == "" && str.Value == "api_key" {
+			continue // The quoted key itself
+		}
+		got = append(got, String{Value: str.Value, Key: str.Key})
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d literals, got %+v", len(expected), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Literal %d: expected %+v, got %+v", i, expected[i], got[i])
+		}
+	}
+
+	first := literals[0]
+	if raw := content[first.Offset : first.Offset+first.Length]; raw != `"k\u0065y-\x41\n"` {
+		t.Errorf("Expected the literal's position in the source, got %q", raw)
+	}
+}
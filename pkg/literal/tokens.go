@@ -0,0 +1,278 @@
+package literal
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// String is a string literal or template literal found in JavaScript source
+type String struct {
+	Value  string // Contents with escapes decoded; a template's ${...} expressions are kept as written
+	Key    string // Property or variable the literal is assigned to, as written (apiKey, "api_key"), if any
+	Offset int    // Byte offset of the opening quote
+	Length int    // Byte length of the literal, quotes included
+}
+
+// regexKeywords may be followed by a regular expression literal, where any
+// other identifier would be followed by a division
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "instanceof": true, "yield": true, "await": true,
+}
+
+// Strings tokenizes JavaScript (or JSON) source just far enough to return its
+// string and template literals in order, skipping comments and regular
+// expression literals. Each literal is paired with the name it is assigned
+// to in name = "...", name: "..." and name: Type = "..." forms.
+func Strings(content string) []String {
+	var literals []String
+	// prev is the last significant token before the current position: an
+	// identifier or keyword, or a single punctuation byte
+	prev := ""
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			i = skipLine(content, i)
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end == -1 {
+				return literals
+			}
+			i += end + 4
+		case c == '/' && regexAllowed(prev):
+			i = skipRegex(content, i)
+			prev = "/"
+		case c == '"' || c == '\'' || c == '`':
+			end, value := readString(content, i)
+			literals = append(literals, String{Value: value, Key: assignedKey(content[:i], literals), Offset: i, Length: end - i})
+			i = end
+			prev = "\""
+		case isIdentifierByte(c):
+			start := i
+			for i < len(content) && isIdentifierByte(content[i]) {
+				i++
+			}
+			prev = content[start:i]
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		default:
+			prev = string(c)
+			i++
+		}
+	}
+	return literals
+}
+
+// regexAllowed reports whether a slash after the token prev starts a regular
+// expression rather than a division
+func regexAllowed(prev string) bool {
+	if prev == "" {
+		return true
+	}
+	if isIdentifierByte(prev[0]) {
+		return regexKeywords[prev]
+	}
+	return prev != ")" && prev != "]" && prev != "}" && prev != "\""
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= utf8.RuneSelf
+}
+
+// skipLine returns the offset of the line ending after i
+func skipLine(content string, i int) int {
+	for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+		i++
+	}
+	return i
+}
+
+// skipRegex returns the offset just past the regular expression literal
+// starting at i, including its flags. A regex cannot span lines, so an
+// unterminated one ends at the line ending.
+func skipRegex(content string, i int) int {
+	inClass := false
+	for i++; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n', '\r':
+			return i
+		case '/':
+			if !inClass {
+				for i++; i < len(content) && isIdentifierByte(content[i]); i++ {
+				}
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// readString reads the literal whose opening quote is at start, returning the
+// offset just past its closing quote and its decoded value. Quoted strings end
+// at an unescaped line ending; template literals may span lines and contain
+// ${...} expressions with literals and nested templates of their own.
+func readString(content string, start int) (int, string) {
+	quote := content[start]
+	var value strings.Builder
+	for i := start + 1; i < len(content); {
+		c := content[i]
+		switch {
+		case c == quote:
+			return i + 1, value.String()
+		case c == '\\':
+			i = unescape(content, i, &value)
+		case quote != '`' && (c == '\n' || c == '\r'):
+			return i, value.String()
+		case quote == '`' && c == '$' && i+1 < len(content) && content[i+1] == '{':
+			end := skipExpression(content, i+2)
+			value.WriteString(content[i:end])
+			i = end
+		default:
+			value.WriteByte(c)
+			i++
+		}
+	}
+	return len(content), value.String()
+}
+
+// skipExpression returns the offset just past the brace closing a template
+// ${...} expression whose body starts at i
+func skipExpression(content string, i int) int {
+	depth := 1
+	for i < len(content) {
+		switch c := content[i]; c {
+		case '"', '\'', '`':
+			i, _ = readString(content, i)
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return i
+}
+
+// unescape decodes the escape sequence at i into value and returns the offset
+// after it. Unknown escapes stand for the escaped character itself.
+func unescape(content string, i int, value *strings.Builder) int {
+	if i+1 >= len(content) {
+		return len(content)
+	}
+	switch c := content[i+1]; c {
+	case 'n':
+		value.WriteByte('\n')
+	case 't':
+		value.WriteByte('\t')
+	case 'r':
+		value.WriteByte('\r')
+	case 'b':
+		value.WriteByte('\b')
+	case 'f':
+		value.WriteByte('\f')
+	case 'v':
+		value.WriteByte('\v')
+	case '0':
+		value.WriteByte(0)
+	case '\r':
+		if i+2 < len(content) && content[i+2] == '\n' {
+			return i + 3 // Line continuation
+		}
+	case '\n':
+	case 'x':
+		if code, err := strconv.ParseUint(hexAt(content, i+2, 2), 16, 8); err == nil {
+			value.WriteRune(rune(code))
+			return i + 4
+		}
+		value.WriteByte(c)
+	case 'u':
+		digits := hexAt(content, i+2, 4)
+		next := i + 6
+		if i+2 < len(content) && content[i+2] == '{' {
+			if end := strings.IndexByte(content[i+3:], '}'); end != -1 {
+				digits = content[i+3 : i+3+end]
+				next = i + 4 + end
+			}
+		}
+		if code, err := strconv.ParseUint(digits, 16, 32); err == nil && digits != "" {
+			value.WriteRune(rune(code))
+			return next
+		}
+		value.WriteByte(c)
+	default:
+		value.WriteByte(c)
+	}
+	return i + 2
+}
+
+// hexAt returns the n bytes at i, or "" if content ends first
+func hexAt(content string, i, n int) string {
+	if i+n > len(content) {
+		return ""
+	}
+	return content[i : i+n]
+}
+
+// assignedKey returns the name a literal starting after before is assigned
+// to: the identifier or quoted key before a ":" or "=", skipping a
+// TypeScript type annotation in name: Type = "...". found holds the literals
+// read so far, so a quoted key is returned as written.
+func assignedKey(before string, found []String) string {
+	before = strings.TrimRight(before, " \t\r\n")
+	if before == "" {
+		return ""
+	}
+	operator := before[len(before)-1]
+	if operator != ':' && operator != '=' {
+		return ""
+	}
+	before = before[:len(before)-1]
+	if operator == '=' && before != "" && strings.ContainsRune("=!<>+-*/%&|^?", rune(before[len(before)-1])) {
+		return "" // Comparison or compound assignment
+	}
+	before = strings.TrimRight(before, " \t\r\n")
+
+	if strings.HasSuffix(before, `"`) || strings.HasSuffix(before, "'") {
+		if n := len(found); n > 0 && found[n-1].Offset+found[n-1].Length == len(before) {
+			return before[found[n-1].Offset:]
+		}
+		return ""
+	}
+
+	key := trailingIdentifier(before)
+	if operator == '=' && key != "" {
+		// name: Type = "..." names the variable, not the type
+		rest := strings.TrimRight(before[:len(before)-len(key)], " \t\r\n")
+		if strings.HasSuffix(rest, ":") {
+			if name := trailingIdentifier(strings.TrimRight(rest[:len(rest)-1], " \t\r\n")); name != "" {
+				return name
+			}
+		}
+	}
+	return key
+}
+
+// trailingIdentifier returns the identifier at the end of text
+func trailingIdentifier(text string) string {
+	start := len(text)
+	for start > 0 && isIdentifierByte(text[start-1]) {
+		start--
+	}
+	if start == len(text) || text[start] >= '0' && text[start] <= '9' {
+		return ""
+	}
+	return text[start:]
+}
@@ -0,0 +1,419 @@
+package match
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Response is what an expression is evaluated against
+type Response struct {
+	Status      int
+	Length      int64
+	Time        int64 // Response time in milliseconds
+	ContentType string
+	URL         string
+	Body        []byte
+}
+
+// Fields an expression can refer to. Numeric fields compare with the
+// relational operators; text fields with ==, !=, contains and matches.
+var fields = map[string]bool{
+	"status": true, // Numeric
+	"length": true,
+	"time":   true,
+	"type":   false, // Text
+	"url":    false,
+	"body":   false,
+}
+
+// Expr is a compiled match expression, such as
+//
+//	status in (200,201) and length > 100 and not body contains "error"
+//
+// Comparisons are combined with and, or, not and parentheses; and binds
+// tighter than or. A nil *Expr matches nothing.
+type Expr struct {
+	source string
+	root   node
+}
+
+// Parse compiles a match expression
+func Parse(source string) (*Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("invalid expression %q: unexpected %q", source, p.peek().text)
+	}
+
+	return &Expr{source: source, root: root}, nil
+}
+
+// Matches reports whether a response satisfies the expression
+func (e *Expr) Matches(r *Response) bool {
+	if e == nil {
+		return false
+	}
+	return e.root.eval(r)
+}
+
+// String returns the expression as written
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.source
+}
+
+type node interface {
+	eval(r *Response) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ operand node }
+
+func (n andNode) eval(r *Response) bool { return n.left.eval(r) && n.right.eval(r) }
+func (n orNode) eval(r *Response) bool  { return n.left.eval(r) || n.right.eval(r) }
+func (n notNode) eval(r *Response) bool { return !n.operand.eval(r) }
+
+// comparison tests one field against one or more values
+type comparison struct {
+	field   string
+	op      string // ==, !=, <, <=, >, >=, in, contains or matches
+	numbers []int64
+	texts   []string
+	pattern *regexp.Regexp
+}
+
+func (c comparison) eval(r *Response) bool {
+	if fields[c.field] {
+		value := numericField(r, c.field)
+		switch c.op {
+		case "in":
+			for _, n := range c.numbers {
+				if value == n {
+					return true
+				}
+			}
+			return false
+		case "==":
+			return value == c.numbers[0]
+		case "!=":
+			return value != c.numbers[0]
+		case "<":
+			return value < c.numbers[0]
+		case "<=":
+			return value <= c.numbers[0]
+		case ">":
+			return value > c.numbers[0]
+		case ">=":
+			return value >= c.numbers[0]
+		}
+		return false
+	}
+
+	if c.field == "body" {
+		switch c.op {
+		case "contains":
+			return bytes.Contains(r.Body, []byte(c.texts[0]))
+		case "matches":
+			return c.pattern.Match(r.Body)
+		}
+	}
+
+	value := textField(r, c.field)
+	switch c.op {
+	case "in":
+		for _, text := range c.texts {
+			if value == text {
+				return true
+			}
+		}
+		return false
+	case "==":
+		return value == c.texts[0]
+	case "!=":
+		return value != c.texts[0]
+	case "contains":
+		return strings.Contains(value, c.texts[0])
+	case "matches":
+		return c.pattern.MatchString(value)
+	}
+	return false
+}
+
+func numericField(r *Response, field string) int64 {
+	switch field {
+	case "status":
+		return int64(r.Status)
+	case "length":
+		return r.Length
+	default:
+		return r.Time
+	}
+}
+
+func textField(r *Response, field string) string {
+	switch field {
+	case "type":
+		return r.ContentType
+	case "url":
+		return r.URL
+	default:
+		return string(r.Body)
+	}
+}
+
+// Token kinds
+const (
+	tokenWord = iota
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// tokenize splits an expression into words, numbers, quoted strings and symbols
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			text, n, err := unquote(source[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, text})
+			i += n
+		case c >= '0' && c <= '9' || c == '-':
+			j := i + 1
+			for j < len(source) && source[j] >= '0' && source[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, source[i:j]})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(source) && (unicode.IsLetter(rune(source[j])) || source[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{tokenWord, strings.ToLower(source[i:j])})
+			i = j
+		default:
+			symbol := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "<", ">", "=", "(", ")", ","} {
+				if strings.HasPrefix(source[i:], candidate) {
+					symbol = candidate
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, fmt.Errorf("invalid expression %q: unexpected character %q", source, c)
+			}
+			if symbol == "=" {
+				symbol = "=="
+			}
+			tokens = append(tokens, token{tokenSymbol, symbol})
+			i += len(symbol)
+		}
+	}
+	return tokens, nil
+}
+
+// unquote reads a quoted string at the start of s, returning its value and
+// the number of bytes it spans; a backslash escapes the next character
+func unquote(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", s)
+}
+
+// parser is a recursive descent parser over the token list
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{tokenSymbol, "end of expression"}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// accept consumes the next token if it is the given word or symbol
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); !p.done() && (t.kind == tokenWord || t.kind == tokenSymbol) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("not") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("expected ), got %q", p.peek().text)
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	t := p.next()
+	numeric, known := fields[t.text]
+	if t.kind != tokenWord || !known {
+		return nil, fmt.Errorf("expected a field (status, length, time, type, url, body), got %q", t.text)
+	}
+	c := comparison{field: t.text}
+
+	op := p.next()
+	c.op = op.text
+	switch {
+	case op.text == "in":
+		if !p.accept("(") {
+			return nil, fmt.Errorf("expected ( after in, got %q", p.peek().text)
+		}
+		for {
+			if err := p.parseValue(&c, numeric); err != nil {
+				return nil, err
+			}
+			if p.accept(")") {
+				return c, nil
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("expected , or ) in list, got %q", p.peek().text)
+			}
+		}
+	case op.text == "==" || op.text == "!=":
+		return c, p.parseValue(&c, numeric)
+	case op.kind == tokenSymbol && strings.ContainsAny(op.text, "<>"):
+		if !numeric {
+			return nil, fmt.Errorf("%s cannot be compared with %s", c.field, op.text)
+		}
+		return c, p.parseValue(&c, numeric)
+	case op.text == "contains" || op.text == "matches":
+		if numeric {
+			return nil, fmt.Errorf("%s is numeric and cannot use %s", c.field, op.text)
+		}
+		if err := p.parseValue(&c, false); err != nil {
+			return nil, err
+		}
+		if op.text == "matches" {
+			pattern, err := regexp.Compile(c.texts[0])
+			if err != nil {
+				return nil, err
+			}
+			c.pattern = pattern
+		}
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("expected an operator after %s, got %q", c.field, op.text)
+}
+
+// parseValue reads a number for numeric fields or a quoted string for text ones
+func (p *parser) parseValue(c *comparison, numeric bool) error {
+	t := p.next()
+	if numeric {
+		if t.kind != tokenNumber {
+			return fmt.Errorf("expected a number for %s, got %q", c.field, t.text)
+		}
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", t.text)
+		}
+		c.numbers = append(c.numbers, n)
+		return nil
+	}
+
+	if t.kind != tokenString {
+		return fmt.Errorf("expected a quoted string for %s, got %q", c.field, t.text)
+	}
+	c.texts = append(c.texts, t.text)
+	return nil
+}
@@ -0,0 +1,73 @@
+package match
+
+import "testing"
+
+func TestExpr_Matches(t *testing.T) {
+	response := &Response{
+		Status:      201,
+		Length:      512,
+		Time:        80,
+		ContentType: "application/json; charset=utf-8",
+		URL:         "https://example.com/api/v1/users",
+		Body:        []byte(`{"users": [], "error": null}`),
+	}
+
+	testCases := []struct {
+		expr     string
+		expected bool
+	}{
+		{`status in (200,201) and length > 100 and not body contains "error"`, false},
+		{`status in (200, 201) and length > 100 and not body contains "denied"`, true},
+		{`status == 201`, true},
+		{`status = 200 or status = 201`, true},
+		{`STATUS != 201`, false},
+		{`length >= 512 and length <= 512 and time < 100`, true},
+		{`type contains "json" and url matches "/api/v[0-9]+/"`, true},
+		{`type == 'text/html'`, false},
+		{`body matches "\"users\":\\s*\\[\\]"`, true},
+		{`not (status >= 400 or time > 1000)`, true},
+		{`status == 404 or length > 100 and time > 1000`, false},
+		{`url in ("https://example.com/api/v1/users", "https://example.com/")`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			if got := expr.Matches(response); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	var none *Expr
+	if none.Matches(response) {
+		t.Error("Expected nil expression to match nothing")
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	invalid := []string{
+		``,
+		`status`,
+		`status > "200"`,
+		`size > 10`,
+		`body > 10`,
+		`length contains "x"`,
+		`status in (200, 201`,
+		`(status == 200`,
+		`status == 200 and`,
+		`status == 200 200`,
+		`body contains "unterminated`,
+		`url matches "("`,
+		`status == 200 & length > 1`,
+	}
+
+	for _, source := range invalid {
+		if _, err := Parse(source); err == nil {
+			t.Errorf("Expected error parsing %q", source)
+		}
+	}
+}
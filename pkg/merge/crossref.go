@@ -0,0 +1,114 @@
+package merge
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"jsfinder/pkg/utils"
+)
+
+// endpointTypes are the finding types whose match names an endpoint
+var endpointTypes = map[string]bool{
+	"API_ENDPOINT":      true,
+	"INTERNAL_ENDPOINT": true,
+}
+
+// CrossReference joins an endpoint leaked in JavaScript with the discover
+// result for it, showing at a glance whether the endpoint is reachable
+type CrossReference struct {
+	Endpoint      string `json:"endpoint"` // URL or path as it appears in the JavaScript
+	Type          string `json:"type"`
+	FoundIn       string `json:"found_in"` // JS file the finding is in
+	URL           string `json:"url"`      // URL that was probed
+	Method        string `json:"method"`
+	StatusCode    int    `json:"status_code"`
+	ContentLength int64  `json:"content_length"`
+}
+
+// CrossReferences joins API_ENDPOINT and INTERNAL_ENDPOINT findings with the
+// endpoints probed at the same URL. Relative paths, as INTERNAL_ENDPOINT
+// findings are, resolve against the origin of the JS file and of the page it
+// was found in, so results merged from several targets are not mixed up.
+// Only when no endpoint was probed on either origin do they join the
+// endpoints at that path on any host.
+func (m *Merger) CrossReferences() []CrossReference {
+	byURL := make(map[string][]Endpoint)
+	byPath := make(map[string][]Endpoint)
+	origins := make(map[string]bool)
+	for _, endpoint := range m.Endpoints() {
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil {
+			continue
+		}
+		path := endpointPath(parsed)
+		key := utils.Origin(endpoint.URL) + path
+		byURL[key] = append(byURL[key], endpoint)
+		byPath[path] = append(byPath[path], endpoint)
+		origins[utils.Origin(endpoint.URL)] = true
+	}
+
+	var references []CrossReference
+	seen := make(map[string]bool)
+	for _, finding := range m.Findings() {
+		if !endpointTypes[finding.Type] {
+			continue
+		}
+
+		leaked := strings.Trim(finding.Match, "\"'`")
+		parsed, err := url.Parse(leaked)
+		if err != nil {
+			continue
+		}
+
+		path := endpointPath(parsed)
+		var matches []Endpoint
+		if parsed.IsAbs() {
+			matches = byURL[utils.Origin(leaked)+path]
+		} else {
+			probed := false
+			for _, page := range []string{finding.URL, finding.Source} {
+				if origin := utils.Origin(page); origin != "" && origins[origin] {
+					probed = true
+					matches = append(matches, byURL[origin+path]...)
+				}
+			}
+			if !probed {
+				matches = byPath[path]
+			}
+		}
+
+		for _, endpoint := range matches {
+			key := leaked + " " + finding.URL + " " + endpoint.Method + " " + endpoint.URL
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			references = append(references, CrossReference{
+				Endpoint:      leaked,
+				Type:          finding.Type,
+				FoundIn:       finding.URL,
+				URL:           endpoint.URL,
+				Method:        endpoint.Method,
+				StatusCode:    endpoint.StatusCode,
+				ContentLength: endpoint.ContentLength,
+			})
+		}
+	}
+
+	sort.SliceStable(references, func(i, j int) bool {
+		return references[i].Endpoint < references[j].Endpoint
+	})
+	return references
+}
+
+// endpointPath returns the path of a URL without its trailing slash, so
+// /api/users and /api/users/ join
+func endpointPath(u *url.URL) string {
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,267 @@
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/discovery"
+	"jsfinder/pkg/scanner"
+	"jsfinder/pkg/utils"
+)
+
+// Finding is a scanner finding merged across result files
+type Finding struct {
+	scanner.Finding
+	Occurrences int       `json:"occurrences"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Endpoint is a discovered endpoint merged across result files
+type Endpoint struct {
+	discovery.Endpoint
+	Occurrences int       `json:"occurrences"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Result is the merged output when findings and endpoints are mixed
+type Result struct {
+	Findings        []Finding        `json:"findings"`
+	Endpoints       []Endpoint       `json:"endpoints"`
+	CrossReferences []CrossReference `json:"cross_references,omitempty"` // Leaked endpoints joined with their probe results
+}
+
+// Merger deduplicates findings and endpoints from several runs or shards.
+// When the same record appears more than once the newest copy wins and the
+// occurrence counts add up; records from an earlier merge keep their counts.
+type Merger struct {
+	CSV       *utils.CSVOptions // Column selection and formula escaping for CSV output
+	findings  map[string]*Finding
+	endpoints map[string]*Endpoint
+}
+
+// New creates an empty merger
+func New() *Merger {
+	return &Merger{
+		findings:  make(map[string]*Finding),
+		endpoints: make(map[string]*Endpoint),
+	}
+}
+
+// AddFile merges a scan, discover or merge JSON result file, using its
+// modification time as the timestamp of records that carry none
+func (m *Merger) AddFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := m.Add(data, info.ModTime()); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Add merges the records of one JSON result document seen at the given time
+func (m *Merger) Add(data []byte, seen time.Time) error {
+	var records []json.RawMessage
+
+	switch trimmed := strings.TrimSpace(string(data)); {
+	case trimmed == "":
+		return nil
+	case strings.HasPrefix(trimmed, "["):
+		if err := json.Unmarshal(data, &records); err != nil {
+			return fmt.Errorf("invalid result file: %w", err)
+		}
+	default:
+		var result struct {
+			Findings  []json.RawMessage `json:"findings"`
+			Endpoints []json.RawMessage `json:"endpoints"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("invalid result file: %w", err)
+		}
+		records = append(result.Findings, result.Endpoints...)
+	}
+
+	for _, record := range records {
+		if err := m.addRecord(record, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Merger) addRecord(record json.RawMessage, seen time.Time) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(record, &keys); err != nil {
+		return fmt.Errorf("invalid record: %w", err)
+	}
+
+	switch {
+	case keys["status_code"] != nil:
+		var endpoint Endpoint
+		if err := json.Unmarshal(record, &endpoint); err != nil {
+			return fmt.Errorf("invalid endpoint: %w", err)
+		}
+		normalize(&endpoint.Occurrences, &endpoint.LastSeen, seen)
+
+		key := endpoint.Method + " " + endpoint.URL + " " + endpoint.VirtualHost + " " + endpoint.Labels.String()
+		if existing, exists := m.endpoints[key]; exists {
+			endpoint.Occurrences += existing.Occurrences
+			if existing.LastSeen.After(endpoint.LastSeen) {
+				existing.Occurrences = endpoint.Occurrences
+				return nil
+			}
+		}
+		m.endpoints[key] = &endpoint
+
+	case keys["match"] != nil:
+		var finding Finding
+		if err := json.Unmarshal(record, &finding); err != nil {
+			return fmt.Errorf("invalid finding: %w", err)
+		}
+		normalize(&finding.Occurrences, &finding.LastSeen, seen)
+
+		// The secret value, where the pattern extracts one, identifies the
+		// finding whatever the key name and quoting around it
+		value := finding.Match
+		if finding.Secret != "" {
+			value = finding.Secret
+		}
+		key := fmt.Sprintf("%s|%s|%s|%d|%s", finding.URL, finding.Type, value, finding.OffsetStart, finding.Labels)
+		if existing, exists := m.findings[key]; exists {
+			finding.Occurrences += existing.Occurrences
+			if existing.LastSeen.After(finding.LastSeen) {
+				existing.Occurrences = finding.Occurrences
+				return nil
+			}
+		}
+		m.findings[key] = &finding
+
+	default:
+		return fmt.Errorf("record is neither a finding nor an endpoint")
+	}
+	return nil
+}
+
+// normalize counts a plain record once and dates it by its file, while
+// records from an earlier merge keep their own count and timestamp
+func normalize(occurrences *int, lastSeen *time.Time, seen time.Time) {
+	if *occurrences < 1 {
+		*occurrences = 1
+	}
+	if lastSeen.IsZero() {
+		*lastSeen = seen
+	}
+}
+
+// Findings returns the merged findings ordered by URL and position
+func (m *Merger) Findings() []Finding {
+	findings := make([]Finding, 0, len(m.findings))
+	for _, finding := range m.findings {
+		findings = append(findings, *finding)
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if a.OffsetStart != b.OffsetStart {
+			return a.OffsetStart < b.OffsetStart
+		}
+		return a.Type < b.Type
+	})
+	return findings
+}
+
+// Endpoints returns the merged endpoints ordered by URL and method
+func (m *Merger) Endpoints() []Endpoint {
+	endpoints := make([]Endpoint, 0, len(m.endpoints))
+	for _, endpoint := range m.endpoints {
+		endpoints = append(endpoints, *endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.VirtualHost < b.VirtualHost
+	})
+	return endpoints
+}
+
+// Write outputs the merged set as json or csv. A single kind of record is
+// written in the same shape as the command that produced it; mixed findings
+// and endpoints become a {"findings", "endpoints", "cross_references"} object,
+// which CSV cannot hold.
+func (m *Merger) Write(output io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "", "json":
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		switch {
+		case len(m.endpoints) == 0:
+			return encoder.Encode(m.Findings())
+		case len(m.findings) == 0:
+			return encoder.Encode(m.Endpoints())
+		default:
+			return encoder.Encode(Result{Findings: m.Findings(), Endpoints: m.Endpoints(), CrossReferences: m.CrossReferences()})
+		}
+
+	case "csv":
+		if len(m.findings) > 0 && len(m.endpoints) > 0 {
+			return fmt.Errorf("cannot write findings and endpoints to one CSV; merge them separately or use --format json")
+		}
+
+		if len(m.endpoints) > 0 {
+			writer, err := utils.NewCSVWriter(output, beforeRoute(discovery.CSVHeader, "Occurrences", "Last Seen"), m.CSV)
+			if err != nil {
+				return err
+			}
+			for _, endpoint := range m.Endpoints() {
+				if err := writer.Write(beforeRoute(endpoint.CSVRecord(), fmt.Sprint(endpoint.Occurrences), endpoint.LastSeen.Format(time.RFC3339))); err != nil {
+					return err
+				}
+			}
+			return writer.Flush()
+		}
+
+		writer, err := utils.NewCSVWriter(output, append(scanner.CSVHeader, "Occurrences", "Last Seen"), m.CSV)
+		if err != nil {
+			return err
+		}
+		for _, finding := range m.Findings() {
+			if err := writer.Write(append(finding.CSVRecord(), fmt.Sprint(finding.Occurrences), finding.LastSeen.Format(time.RFC3339))); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+
+	default:
+		return fmt.Errorf("unsupported format %q, expected json or csv", format)
+	}
+}
+
+// beforeRoute inserts the merge columns ahead of the discovery route columns,
+// which were added last, so every column keeps the position it had in merged
+// output before routes existed
+func beforeRoute(row []string, columns ...string) []string {
+	split := len(row) - len(discovery.RouteCSVHeader)
+	merged := make([]string, 0, len(row)+len(columns))
+	merged = append(merged, row[:split]...)
+	merged = append(merged, columns...)
+	return append(merged, row[split:]...)
+}
@@ -0,0 +1,261 @@
+package merge
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMerger(t *testing.T) {
+	older := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+
+	m := New()
+	err := m.Add([]byte(`[
+		{"url": "https://example.com/app.js", "type": "AWS_KEY", "match": "AKIAOLD", "offset_start": 10, "confidence": "LOW"},
+		{"url": "https://example.com/app.js", "type": "JWT", "match": "eyJ", "offset_start": 50}
+	]`), older)
+	if err != nil {
+		t.Fatalf("Failed to add first run: %v", err)
+	}
+	err = m.Add([]byte(`[
+		{"url": "https://example.com/app.js", "type": "AWS_KEY", "match": "AKIAOLD", "offset_start": 10, "confidence": "HIGH"}
+	]`), newer)
+	if err != nil {
+		t.Fatalf("Failed to add second run: %v", err)
+	}
+
+	findings := m.Findings()
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 deduplicated findings, got %d", len(findings))
+	}
+	if findings[0].Type != "AWS_KEY" || findings[0].Occurrences != 2 {
+		t.Errorf("Expected AWS_KEY seen twice first, got %s x%d", findings[0].Type, findings[0].Occurrences)
+	}
+	if findings[0].Confidence != "HIGH" || !findings[0].LastSeen.Equal(newer) {
+		t.Errorf("Expected newest copy to win, got %s at %v", findings[0].Confidence, findings[0].LastSeen)
+	}
+
+	// An older copy arriving later still counts but does not overwrite
+	err = m.Add([]byte(`[{"url": "https://example.com/app.js", "type": "AWS_KEY", "match": "AKIAOLD", "offset_start": 10, "confidence": "MEDIUM"}]`), older)
+	if err != nil {
+		t.Fatalf("Failed to add stale run: %v", err)
+	}
+	if finding := m.Findings()[0]; finding.Occurrences != 3 || finding.Confidence != "HIGH" {
+		t.Errorf("Expected stale copy counted only, got %s x%d", finding.Confidence, finding.Occurrences)
+	}
+
+	var output bytes.Buffer
+	if err := m.Write(&output, "json"); err != nil {
+		t.Fatalf("Failed to write findings: %v", err)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(output.String()), "[") {
+		t.Errorf("Expected findings-only output as an array, got %s", output.String())
+	}
+
+	// Merged output can be merged again without losing counts
+	remerged := New()
+	if err := remerged.Add(output.Bytes(), time.Now()); err != nil {
+		t.Fatalf("Failed to re-merge output: %v", err)
+	}
+	if finding := remerged.Findings()[0]; finding.Occurrences != 3 || !finding.LastSeen.Equal(newer) {
+		t.Errorf("Expected counts and timestamps preserved, got x%d at %v", finding.Occurrences, finding.LastSeen)
+	}
+}
+
+func TestMerger_secret(t *testing.T) {
+	m := New()
+	err := m.Add([]byte(`[
+		{"url": "https://example.com/app.js", "type": "DATABASE_URL", "match": "db_url: 'postgres://app:pw@db/main'", "offset_start": 10},
+		{"url": "https://example.com/app.js", "type": "DATABASE_URL", "match": "db_url = \"postgres://app:pw@db/main\"", "secret": "postgres://app:pw@db/main", "offset_start": 10},
+		{"url": "https://example.com/app.js", "type": "DATABASE_URL", "match": "db_url = 'postgres://app:pw@db/main'", "secret": "postgres://app:pw@db/main", "offset_start": 10}
+	]`), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to add findings: %v", err)
+	}
+
+	findings := m.Findings()
+	if len(findings) != 2 {
+		t.Fatalf("Expected findings with the same secret merged and the one without kept, got %d", len(findings))
+	}
+	for _, finding := range findings {
+		if finding.Secret != "" && finding.Occurrences != 2 {
+			t.Errorf("Expected the secret seen twice, got x%d", finding.Occurrences)
+		}
+	}
+}
+
+func TestMerger_endpoints(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "shard-1.json")
+	second := filepath.Join(dir, "shard-2.json")
+	os.WriteFile(first, []byte(`[
+		{"url": "https://example.com/api/users", "method": "GET", "status_code": 401},
+		{"url": "https://example.com/api/users", "method": "POST", "status_code": 405}
+	]`), 0644)
+	os.WriteFile(second, []byte(`[{"url": "https://example.com/api/users", "method": "GET", "status_code": 200}]`), 0644)
+	os.Chtimes(first, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+
+	m := New()
+	for _, path := range []string{second, first} {
+		if err := m.AddFile(path); err != nil {
+			t.Fatalf("Failed to add %s: %v", path, err)
+		}
+	}
+
+	endpoints := m.Endpoints()
+	if len(endpoints) != 2 {
+		t.Fatalf("Expected 2 endpoints, got %d", len(endpoints))
+	}
+	if endpoints[0].Method != "GET" || endpoints[0].StatusCode != 200 || endpoints[0].Occurrences != 2 {
+		t.Errorf("Expected newest GET status 200 seen twice, got %s %d x%d", endpoints[0].Method, endpoints[0].StatusCode, endpoints[0].Occurrences)
+	}
+
+	var output bytes.Buffer
+	if err := m.Write(&output, "csv"); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	records, err := csv.NewReader(&output).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d: %v", len(records), err)
+	}
+	if header := records[0]; strings.Join(header[len(header)-4:], ",") != "Occurrences,Last Seen,Route,Route Hits" {
+		t.Errorf("Expected the occurrence columns before the route columns, got %v", header)
+	}
+
+	// Mixing findings in makes a combined object, which CSV cannot hold
+	if err := m.Add([]byte(`{"findings": [{"url": "https://example.com/app.js", "type": "JWT", "match": "eyJ"}]}`), time.Now()); err != nil {
+		t.Fatalf("Failed to add findings object: %v", err)
+	}
+	if err := m.Write(&bytes.Buffer{}, "csv"); err == nil {
+		t.Error("Expected CSV error for mixed results")
+	}
+	output.Reset()
+	if err := m.Write(&output, "json"); err != nil {
+		t.Fatalf("Failed to write mixed JSON: %v", err)
+	}
+	var result Result
+	if err := json.Unmarshal(output.Bytes(), &result); err != nil || len(result.Findings) != 1 || len(result.Endpoints) != 2 {
+		t.Errorf("Expected combined result object, got %s (%v)", output.String(), err)
+	}
+
+	if err := m.Add([]byte(`[{"foo": "bar"}]`), time.Now()); err == nil {
+		t.Error("Expected error for unknown record")
+	}
+}
+
+func TestMerger_CrossReferences(t *testing.T) {
+	m := New()
+	err := m.Add([]byte(`[
+		{"url": "https://example.com/app.js", "type": "API_ENDPOINT", "match": "\"https://api.example.com/v1/users/\""},
+		{"url": "https://example.com/app.js", "type": "INTERNAL_ENDPOINT", "match": "'/internal/health'"},
+		{"url": "https://example.com/app.js", "type": "INTERNAL_ENDPOINT", "match": "'/admin/unprobed'"},
+		{"url": "https://cdn.example.net/other.js", "type": "INTERNAL_ENDPOINT", "match": "'/internal/metrics'"},
+		{"url": "https://example.com/app.js", "type": "JWT", "match": "eyJ"}
+	]`), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to add findings: %v", err)
+	}
+	err = m.Add([]byte(`[
+		{"url": "https://api.example.com:443/v1/users", "method": "GET", "status_code": 200, "content_length": 512},
+		{"url": "https://other.example.com/v1/users", "method": "GET", "status_code": 200},
+		{"url": "https://example.com/internal/health", "method": "GET", "status_code": 403, "content_length": 9},
+		{"url": "https://other.example.com/admin/unprobed", "method": "GET", "status_code": 200},
+		{"url": "https://other.example.com/internal/metrics", "method": "GET", "status_code": 200}
+	]`), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to add endpoints: %v", err)
+	}
+
+	// /admin/unprobed was only found on another host than example.com's,
+	// while no endpoint shares the CDN's origin, so /internal/metrics joins any host
+	references := m.CrossReferences()
+	if len(references) != 3 {
+		t.Fatalf("Expected 2 cross-references, got %d: %+v", len(references), references)
+	}
+	if ref := references[0]; ref.Endpoint != "/internal/health" || ref.StatusCode != 403 || ref.ContentLength != 9 {
+		t.Errorf("Unexpected internal endpoint join: %+v", ref)
+	}
+	if ref := references[1]; ref.Endpoint != "/internal/metrics" || ref.URL != "https://other.example.com/internal/metrics" {
+		t.Errorf("Unexpected fallback join: %+v", ref)
+	}
+	if ref := references[2]; ref.Endpoint != "https://api.example.com/v1/users/" || ref.URL != "https://api.example.com:443/v1/users" || ref.StatusCode != 200 {
+		t.Errorf("Unexpected API endpoint join: %+v", ref)
+	}
+
+	var output bytes.Buffer
+	if err := m.Write(&output, "json"); err != nil {
+		t.Fatalf("Failed to write mixed JSON: %v", err)
+	}
+	var result Result
+	if err := json.Unmarshal(output.Bytes(), &result); err != nil || len(result.CrossReferences) != 3 {
+		t.Errorf("Expected cross-references in combined output, got %s (%v)", output.String(), err)
+	}
+}
+
+func TestProjectTrends(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"2024-03-01/jsfiles.txt":   "https://example.com/app.1.js\nhttps://example.com/vendor.js\n",
+		"2024-03-01/findings.json": `[{"url": "https://example.com/app.1.js", "type": "AWS_ACCESS_KEY", "match": "AKIA1"}]`,
+		"2024-03-02/jsfiles.txt":   "https://example.com/app.2.js\nhttps://example.com/vendor.js\n",
+		"2024-03-03/findings.csv":  "URL,Type,Match\nhttps://example.com/app.2.js,AWS_ACCESS_KEY,AKIA1\nhttps://cdn.example.net/x.js,JWT_TOKEN,eyJ\n",
+		"2024-03-03/endpoints.csv": "URL,Status Code,Method\nhttps://example.com/api,200,GET\n",
+		"2024-03-04/findings.json": `[]`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(dir, "notes"), 0755)
+
+	trends, err := ProjectTrends(dir)
+	if err != nil {
+		t.Fatalf("ProjectTrends failed: %v", err)
+	}
+
+	byKey := make(map[string]Trend)
+	for _, trend := range trends {
+		byKey[trend.Target+" "+trend.Date] = trend
+	}
+	if len(byKey) != 6 {
+		t.Fatalf("Expected 6 target days, got %d: %+v", len(byKey), trends)
+	}
+
+	if change := byKey["example.com 2024-03-02"].JSFiles; change == nil || *change != (Change{Total: 2, Added: 1, Removed: 1}) {
+		t.Errorf("Expected a rebuilt bundle to churn one JS file, got %+v", change)
+	}
+	if trend := byKey["example.com 2024-03-02"]; trend.Findings != nil {
+		t.Errorf("Expected no findings change on a day without a scan, got %+v", trend.Findings)
+	}
+	if change := byKey["example.com 2024-03-03"].Findings; change == nil || *change != (Change{Total: 1}) {
+		t.Errorf("Expected the moved finding to stay open, got %+v", change)
+	}
+	if change := byKey["cdn.example.net 2024-03-03"].Findings; change == nil || *change != (Change{Total: 1, Added: 1}) {
+		t.Errorf("Expected a new finding on the CDN host, got %+v", change)
+	}
+	if change := byKey["example.com 2024-03-04"].Findings; change == nil || *change != (Change{Removed: 1}) {
+		t.Errorf("Expected the finding closed by an empty scan, got %+v", change)
+	}
+
+	var output bytes.Buffer
+	if err := WriteTrends(&output, trends, "csv", nil); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	records, err := csv.NewReader(&output).ReadAll()
+	if err != nil || len(records) != 7 || len(records[1]) != len(TrendCSVHeader) {
+		t.Errorf("Expected a header and 6 rows of %d columns, got %v (%v)", len(TrendCSVHeader), records, err)
+	}
+
+	if _, err := ProjectTrends(t.TempDir()); err == nil {
+		t.Error("Expected an error for a directory without runs")
+	}
+}
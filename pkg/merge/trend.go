@@ -0,0 +1,354 @@
+package merge
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/utils"
+)
+
+// Change is how one kind of result for a target moved since the previous
+// run that produced it. On the first such run everything counts as added.
+type Change struct {
+	Total   int `json:"total"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// Trend is one target's results on one day of a project. A kind is nil when
+// no run that day produced it, so a day that only crawled does not close
+// every finding.
+type Trend struct {
+	Date      string  `json:"date"`
+	Target    string  `json:"target"`
+	JSFiles   *Change `json:"js_files,omitempty"`  // JS files crawled; added plus removed is the churn
+	Findings  *Change `json:"findings,omitempty"`  // Secrets by type and value; added were opened, removed closed
+	Endpoints *Change `json:"endpoints,omitempty"` // Endpoints discovered, by method and URL
+}
+
+// TrendCSVHeader is the header row of the trend CSV output
+var TrendCSVHeader = []string{"Date", "Target", "JS Files", "JS Added", "JS Removed", "Findings", "Findings Opened", "Findings Closed", "Endpoints", "Endpoints New", "Endpoints Gone"}
+
+// CSVRecord returns the trend as a CSV row matching TrendCSVHeader; the
+// cells of a kind no run produced are empty
+func (t Trend) CSVRecord() []string {
+	record := []string{t.Date, t.Target}
+	for _, change := range []*Change{t.JSFiles, t.Findings, t.Endpoints} {
+		if change == nil {
+			record = append(record, "", "", "")
+			continue
+		}
+		record = append(record, fmt.Sprint(change.Total), fmt.Sprint(change.Added), fmt.Sprint(change.Removed))
+	}
+	return record
+}
+
+// runResults holds the keys of one kind of result of a project day by target host
+type runResults map[string]map[string]bool
+
+func (r runResults) add(rawURL, key string) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Hostname() == "" {
+		return
+	}
+	target := strings.ToLower(u.Hostname())
+	if r[target] == nil {
+		r[target] = make(map[string]bool)
+	}
+	r[target][key] = true
+}
+
+// trendKind reads one kind of result from a project day; it returns nil
+// when the day has none
+type trendKind struct {
+	load func(dir string) (runResults, error)
+	set  func(trend *Trend, change *Change)
+}
+
+var trendKinds = []trendKind{
+	{loadJSFiles, func(trend *Trend, change *Change) { trend.JSFiles = change }},
+	{loadFindings, func(trend *Trend, change *Change) { trend.Findings = change }},
+	{loadEndpoints, func(trend *Trend, change *Change) { trend.Endpoints = change }},
+}
+
+// ProjectTrends compares the dated run directories of a --project, oldest
+// first, and reports per target how many JS files, findings and endpoints
+// each day had and how many appeared or disappeared since the last day that
+// produced the same kind of result. Trends are ordered by target, then date.
+func ProjectTrends(dir string) ([]Trend, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project: %w", err)
+	}
+
+	var days []string
+	for _, entry := range entries {
+		if _, err := time.Parse("2006-01-02", entry.Name()); entry.IsDir() && err == nil {
+			days = append(days, entry.Name())
+		}
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no project runs in %s, expected <date> directories written with --project", dir)
+	}
+	sort.Strings(days)
+
+	trends := make(map[string]*Trend)
+	previous := make([]runResults, len(trendKinds))
+	for _, day := range days {
+		for i, kind := range trendKinds {
+			current, err := kind.load(filepath.Join(dir, day))
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				continue
+			}
+
+			for target := range targetsOf(current, previous[i]) {
+				key := day + " " + target
+				if trends[key] == nil {
+					trends[key] = &Trend{Date: day, Target: target}
+				}
+				kind.set(trends[key], compare(current[target], previous[i][target]))
+			}
+			previous[i] = current
+		}
+	}
+
+	result := make([]Trend, 0, len(trends))
+	for _, trend := range trends {
+		result = append(result, *trend)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Target != result[j].Target {
+			return result[i].Target < result[j].Target
+		}
+		return result[i].Date < result[j].Date
+	})
+	return result, nil
+}
+
+// targetsOf returns the targets present in either run
+func targetsOf(current, previous runResults) map[string]bool {
+	targets := make(map[string]bool)
+	for target := range current {
+		targets[target] = true
+	}
+	for target := range previous {
+		targets[target] = true
+	}
+	return targets
+}
+
+func compare(current, previous map[string]bool) *Change {
+	change := &Change{Total: len(current)}
+	for key := range current {
+		if !previous[key] {
+			change.Added++
+		}
+	}
+	for key := range previous {
+		if !current[key] {
+			change.Removed++
+		}
+	}
+	return change
+}
+
+// loadJSFiles reads a crawl's jsfiles.txt, one URL per line
+func loadJSFiles(dir string) (runResults, error) {
+	file, err := os.Open(filepath.Join(dir, utils.ProjectJSFiles))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JS files: %w", err)
+	}
+	defer file.Close()
+
+	results := make(runResults)
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			results.add(line, line)
+		}
+	}
+	return results, lines.Err()
+}
+
+// loadFindings reads a scan's findings.json or findings.csv, or the severity
+// files of --split-by-severity. Findings are keyed by type and value rather
+// than position, so a secret that moves to a rebuilt bundle stays open.
+func loadFindings(dir string) (runResults, error) {
+	var paths []string
+	for _, name := range []string{utils.ProjectFindings + ".json", utils.ProjectFindings + ".csv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	split, _ := filepath.Glob(filepath.Join(dir, utils.ProjectFindings, "*.json"))
+	paths = append(paths, split...)
+	split, _ = filepath.Glob(filepath.Join(dir, utils.ProjectFindings, "*.csv"))
+	paths = append(paths, split...)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	results := make(runResults)
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".csv") {
+			rows, err := readCSVColumns(path, "URL", "Type", "Match")
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				results.add(row[0], row[1]+" "+row[2])
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			continue
+		}
+		var findings []Finding
+		if err := json.Unmarshal(data, &findings); err != nil {
+			return nil, fmt.Errorf("%s: invalid findings: %w", path, err)
+		}
+		for _, finding := range findings {
+			results.add(finding.URL, finding.Type+" "+finding.Match)
+		}
+	}
+	return results, nil
+}
+
+// loadEndpoints reads a discover run's endpoints.csv
+func loadEndpoints(dir string) (runResults, error) {
+	path := filepath.Join(dir, utils.ProjectEndpoints)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	rows, err := readCSVColumns(path, "URL", "Method", "Virtual Host")
+	if err != nil {
+		return nil, err
+	}
+	results := make(runResults)
+	for _, row := range rows {
+		results.add(row[0], row[1]+" "+row[0]+" "+row[2])
+	}
+	return results, nil
+}
+
+// readCSVColumns returns the named columns of every row of a CSV file with
+// a header row; columns left out with --columns read as empty
+func readCSVColumns(path string, names ...string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid CSV: %w", path, err)
+	}
+
+	indexes := make([]int, len(names))
+	for i, name := range names {
+		indexes[i] = -1
+		for j, column := range header {
+			if column == name {
+				indexes[i] = j
+			}
+		}
+	}
+	if indexes[0] < 0 {
+		return nil, fmt.Errorf("%s: no %s column", path, names[0])
+	}
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid CSV: %w", path, err)
+		}
+
+		row := make([]string, len(names))
+		for i, index := range indexes {
+			if index >= 0 && index < len(record) {
+				row[i] = record[index]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// WriteTrends outputs trends as txt (a table per target), json or csv
+func WriteTrends(output io.Writer, trends []Trend, format string, options *utils.CSVOptions) error {
+	switch strings.ToLower(format) {
+	case "", "txt":
+		target := ""
+		for _, trend := range trends {
+			if trend.Target != target {
+				if target != "" {
+					fmt.Fprintln(output)
+				}
+				target = trend.Target
+				fmt.Fprintln(output, target)
+			}
+			fmt.Fprintf(output, "  %s  js files %s  findings %s  endpoints %s\n",
+				trend.Date, describeChange(trend.JSFiles), describeChange(trend.Findings), describeChange(trend.Endpoints))
+		}
+		return nil
+
+	case "json":
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(trends)
+
+	case "csv":
+		writer, err := utils.NewCSVWriter(output, TrendCSVHeader, options)
+		if err != nil {
+			return err
+		}
+		for _, trend := range trends {
+			if err := writer.Write(trend.CSVRecord()); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+
+	default:
+		return fmt.Errorf("unsupported format %q, expected txt, json or csv", format)
+	}
+}
+
+// describeChange formats a change as "12 (+3 -1)", or "-" when nothing ran
+func describeChange(change *Change) string {
+	if change == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d (+%d -%d)", change.Total, change.Added, change.Removed)
+}
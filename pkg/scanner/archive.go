@@ -0,0 +1,174 @@
+package scanner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/utils"
+)
+
+// maxArchiveEntrySize caps how much of a single archive entry is read, so a
+// hostile or corrupt archive cannot exhaust memory
+const maxArchiveEntrySize = 64 << 20
+
+// hermesMagic starts a Hermes bytecode bundle, which React Native ships
+// instead of JavaScript source when the Hermes engine is enabled
+var hermesMagic = []byte{0xc6, 0x1f, 0xbc, 0x03, 0xc1, 0x03, 0x19, 0x1f}
+
+// isArchiveAsset reports whether an archive entry holds JavaScript, TypeScript
+// or HTML: plain web assets (Cordova/Capacitor www folders), repository
+// sources and React Native bundles
+func isArchiveAsset(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx", ".html", ".htm", ".bundle", ".jsbundle":
+		return true
+	}
+	return false
+}
+
+// ScanArchive scans the JavaScript and HTML assets inside an .apk, .ipa or
+// .zip archive, reporting findings as archive!/entry paths
+func (s *Scanner) ScanArchive(archivePath string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer reader.Close()
+
+	base := filepath.Base(archivePath)
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !isArchiveAsset(file.Name) {
+			continue
+		}
+		s.stats.AddQueued(1)
+
+		if err := s.scanArchiveEntry(base, file); err != nil {
+			s.logger.WithField("target", base+"!/"+file.Name).Warnf("Error scanning: %v", err)
+		}
+		s.stats.AddProcessed()
+	}
+
+	return s.outputResults()
+}
+
+func (s *Scanner) scanArchiveEntry(base string, file *zip.File) error {
+	docURL := base + "!/" + file.Name
+	s.logger.WithField("target", docURL).Debug("Scanning")
+
+	entry, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer entry.Close()
+
+	content, err := io.ReadAll(io.LimitReader(entry, maxArchiveEntrySize))
+	if err != nil {
+		return err
+	}
+
+	return s.scanAsset(docURL, content)
+}
+
+// scanAsset scans one downloaded JavaScript or HTML file, or an asset read
+// out of an archive or package, along with the scripts embedded in it
+func (s *Scanner) scanAsset(docURL string, content []byte) error {
+	if bytes.HasPrefix(content, hermesMagic) {
+		return fmt.Errorf("entry is a Hermes bytecode bundle; decompile it to scan its source")
+	}
+	if s.skipped(docURL, content) {
+		return nil
+	}
+
+	s.scanDocument(docURL, "", string(content))
+	for _, document := range extractEmbedded(string(content)) {
+		s.scanDocument(docURL+"#"+document.name, docURL, document.content)
+	}
+	s.ScanPageData(docURL, string(content))
+
+	return nil
+}
+
+// ScanPageData scans the hidden form fields, JSON-LD blocks and state blobs
+// of an HTML page, each as a virtual document named after the page; other
+// content has none. The crawler calls it on every page it visits.
+func (s *Scanner) ScanPageData(pageURL, page string) {
+	for _, document := range extractPageData(page) {
+		s.scanDocument(pageURL+"#"+document.name, pageURL, document.content)
+	}
+}
+
+// Findings returns a copy of the findings so far
+func (s *Scanner) Findings() []Finding {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Finding(nil), s.results...)
+}
+
+// scanTarball scans the JavaScript and HTML files in a gzipped tarball,
+// naming each finding's document with docURL
+func (s *Scanner) scanTarball(body io.Reader, docURL func(name string) string) error {
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("failed to read tarball: %w", err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tarball: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !isArchiveAsset(header.Name) {
+			continue
+		}
+		s.stats.AddQueued(1)
+
+		name := docURL(header.Name)
+		s.logger.WithField("target", name).Debug("Scanning")
+		content, err := io.ReadAll(io.LimitReader(archive, maxArchiveEntrySize))
+		if err == nil {
+			err = s.scanAsset(name, content)
+		}
+		if err != nil {
+			s.logger.WithField("target", name).Warnf("Error scanning: %v", err)
+		}
+		s.stats.AddProcessed()
+	}
+}
+
+// PlanArchive describes an archive scan for a dry run; archives are read
+// locally, so the plan lists the assets to scan but sends no requests
+func (s *Scanner) PlanArchive(archivePath string) (*utils.RequestPlan, error) {
+	plan := utils.NewRequestPlan("scan", s.config.Threads, time.Duration(s.config.Timeout)*time.Second)
+	plan.AddSetting("Patterns", len(s.patterns))
+
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer reader.Close()
+
+	assets := 0
+	for _, file := range reader.File {
+		if !file.FileInfo().IsDir() && isArchiveAsset(file.Name) {
+			assets++
+		}
+	}
+
+	plan.AddSetting("Archive", archivePath)
+	plan.AddSetting("Archive assets", assets)
+	plan.AddNote("archive assets are scanned locally; no requests are sent")
+	return plan, nil
+}
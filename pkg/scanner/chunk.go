@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"jsfinder/pkg/utils"
+)
+
+const (
+	chunkThreshold = 1 << 20   // Documents at least this large are scanned in parallel chunks
+	chunkSize      = 256 << 10 // Bytes of content each chunk owns
+	chunkOverlap   = 4 << 10   // Bytes scanned past a window's end so matches crossing it are found whole
+	chunkSplitScan = 1 << 10   // How far past the nominal split a window looks for a delimiter
+)
+
+// chunk is a unit of work in a chunked scan: a run of whole lines, or for a
+// line longer than chunkSize (a minified bundle), the byte window [from, to)
+// of that line. Matches belong to the chunk they start in.
+type chunk struct {
+	first, last int
+	window      bool
+	from, to    int
+}
+
+// planChunks groups short lines into chunks of about chunkSize and cuts long
+// lines into windows of that size
+func planChunks(lines []sourceLine) []chunk {
+	var chunks []chunk
+	first, size := 0, 0
+	flush := func(last int) {
+		if last >= first {
+			chunks = append(chunks, chunk{first: first, last: last})
+		}
+	}
+
+	for i, line := range lines {
+		if len(line.text) > chunkSize {
+			flush(i - 1)
+			for from := 0; from < len(line.text); {
+				to := splitPoint(line.text, from+chunkSize)
+				chunks = append(chunks, chunk{first: i, last: i, window: true, from: from, to: to})
+				from = to
+			}
+			first, size = i+1, 0
+			continue
+		}
+
+		size += len(line.text)
+		if size >= chunkSize {
+			flush(i)
+			first, size = i+1, 0
+		}
+	}
+	flush(len(lines) - 1)
+
+	return chunks
+}
+
+// splitPoint moves a window boundary to just after the next delimiter, so no
+// window starts inside an identifier or string where patterns would match a
+// fragment, falling back to the next character boundary
+func splitPoint(text string, at int) int {
+	if at >= len(text) {
+		return len(text)
+	}
+
+	limit := min(at+chunkSplitScan, len(text))
+	if i := strings.IndexAny(text[at:limit], " \t;,{}()[]"); i >= 0 {
+		return at + i + 1
+	}
+	for at < len(text) && !utf8.RuneStart(text[at]) {
+		at++
+	}
+	return at
+}
+
+// scanChunked scans a large document with one worker per CPU, then merges the
+// findings in document order. A window that starts inside a match found by
+// the previous window can yield a fragment of it, so a match overlapping an
+// earlier one of the same type on the same line is dropped, as a serial scan
+// would never report it.
+func (s *Scanner) scanChunked(ref docRef, lines []sourceLine) {
+	chunks := planChunks(lines)
+	found := make([][]Finding, len(chunks))
+
+	pool := utils.NewWorkerPool(context.Background(), utils.PoolOptions{Workers: runtime.GOMAXPROCS(0), Logger: s.logger})
+	for i, c := range chunks {
+		pool.Submit(func(ctx context.Context, workerID int) {
+			emit := func(finding Finding) { found[i] = append(found[i], finding) }
+			for n := c.first; n <= c.last; n++ {
+				from, to := 0, len(lines[n].text)
+				if c.window {
+					from, to = c.from, c.to
+				}
+				s.matchLine(ref, lines[n].text, n+1, lines[n].offset, from, to, emit)
+			}
+		})
+	}
+	pool.Wait()
+
+	type matchKey struct {
+		patternName string
+		line        int
+	}
+	lastEnd := make(map[matchKey]int)
+	for _, findings := range found {
+		for _, finding := range findings {
+			key := matchKey{finding.Type, finding.LineNumber}
+			if end, seen := lastEnd[key]; seen && finding.OffsetStart < end {
+				continue
+			}
+			lastEnd[key] = finding.OffsetEnd
+			s.addFinding(finding)
+		}
+	}
+}
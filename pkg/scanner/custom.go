@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits on user-supplied patterns. Go's RE2 engine matches in linear time,
+// so a custom pattern cannot backtrack catastrophically, but every compiled
+// instruction is still paid for on every byte scanned.
+const (
+	maxPatternInsts = 2000 // Compiled program size; the largest built-in pattern is under 500
+
+	slowPatternMinTime   = 500 * time.Millisecond // Time a pattern must have spent matching before it is judged
+	slowPatternNsPerByte = 300                    // Average cost per byte allowed; built-in patterns stay under 100
+	slowPatternNsPerCall = 5000                   // Fixed allowance per line, so many short lines do not count as slow
+)
+
+// customPattern holds what a pattern file says about a pattern and what
+// matching it has cost so far. It is shared by all workers.
+type customPattern struct {
+	description string
+	confidence  string
+	remediation *Remediation // Guidance for --remediation, if the file gives any
+	tags        []string     // Groups the pattern belongs to for --only and --disable
+	nanos       atomic.Int64
+	bytes       atomic.Int64
+	calls       atomic.Int64
+	disabled    atomic.Bool
+}
+
+// patternFile is the layout of a --config pattern file
+type patternFile struct {
+	Patterns map[string]struct {
+		Pattern     string       `yaml:"pattern"`
+		Description string       `yaml:"description"`
+		Confidence  string       `yaml:"confidence"`
+		Enabled     *bool        `yaml:"enabled"`
+		Remediation *Remediation `yaml:"remediation"`
+		Tags        []string     `yaml:"tags"`
+		ValueGroup  *int         `yaml:"value_group"`
+	} `yaml:"patterns"`
+}
+
+// LoadPatterns adds the patterns in a YAML pattern file to the built-in set.
+// Names are case-insensitive, so a pattern named after a built-in one replaces
+// it, and enabled: false removes it. Every pattern is validated first; see
+// ValidatePattern.
+func (s *Scanner) LoadPatterns(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read pattern file: %w", err)
+	}
+	return s.loadPatternData(data, path)
+}
+
+// loadPatternData adds the patterns of a pattern file's content, naming it
+// path in errors
+func (s *Scanner) loadPatternData(data []byte, path string) error {
+	var file patternFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse pattern file %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(file.Patterns))
+	for name := range file.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry := file.Patterns[name]
+		patternName := strings.ToUpper(name)
+
+		if entry.Enabled != nil && !*entry.Enabled {
+			s.removePattern(patternName)
+			continue
+		}
+
+		pattern, err := ValidatePattern(entry.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %s in %s: %w", name, path, err)
+		}
+
+		confidence := strings.ToUpper(entry.Confidence)
+		switch confidence {
+		case "":
+			confidence = "LOW"
+		case "HIGH", "MEDIUM", "LOW":
+		default:
+			return fmt.Errorf("pattern %s in %s: invalid confidence %q, expected HIGH, MEDIUM or LOW", name, path, entry.Confidence)
+		}
+
+		description := entry.Description
+		if description == "" {
+			description = "Custom pattern " + name
+		}
+
+		delete(s.valueGroups, patternName)
+		if group := entry.ValueGroup; group != nil {
+			if *group < 0 || *group > pattern.NumSubexp() {
+				return fmt.Errorf("pattern %s in %s: value_group %d out of range, the pattern has %d capture groups", name, path, *group, pattern.NumSubexp())
+			}
+			s.valueGroups[patternName] = *group
+		}
+
+		s.patterns[patternName] = pattern
+		s.custom[patternName] = &customPattern{description: description, confidence: confidence, remediation: entry.Remediation, tags: entry.Tags}
+	}
+
+	return nil
+}
+
+// ValidatePattern compiles a user-supplied pattern, rejecting syntax RE2 does
+// not support (lookaround, backreferences), patterns whose compiled program
+// exceeds the complexity budget, and patterns that match the empty string and
+// would report a finding at every position.
+func ValidatePattern(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, errors.New("empty pattern")
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > maxPatternInsts {
+		return nil, fmt.Errorf("pattern too complex: compiles to %d instructions, the limit is %d", len(prog.Inst), maxPatternInsts)
+	}
+
+	if pattern.MatchString("") {
+		return nil, errors.New("pattern matches the empty string")
+	}
+
+	return pattern, nil
+}
+
+// locate returns the positions of a pattern's matches in text, with the
+// submatch positions too when the pattern has a value group past the whole match
+func (s *Scanner) locate(patternName string, pattern *regexp.Regexp, text string) [][]int {
+	if s.valueGroups[patternName] > 0 {
+		return pattern.FindAllStringSubmatchIndex(text, -1)
+	}
+	return pattern.FindAllStringIndex(text, -1)
+}
+
+// findAll runs a pattern over text. Custom patterns are timed, and one that
+// proves pathologically slow is disabled for the rest of the run with a
+// warning, so a single bad regex cannot dominate the scan.
+func (s *Scanner) findAll(patternName string, pattern *regexp.Regexp, text string) [][]int {
+	custom := s.custom[patternName]
+	if custom == nil {
+		return s.locate(patternName, pattern, text)
+	}
+	if custom.disabled.Load() {
+		return nil
+	}
+
+	start := time.Now()
+	locs := s.locate(patternName, pattern, text)
+
+	nanos := custom.nanos.Add(int64(time.Since(start)))
+	bytes := custom.bytes.Add(int64(len(text)))
+	calls := custom.calls.Add(1)
+	if nanos < int64(slowPatternMinTime) || nanos <= bytes*slowPatternNsPerByte+calls*slowPatternNsPerCall {
+		return locs
+	}
+
+	if custom.disabled.CompareAndSwap(false, true) {
+		s.logger.Warnf("Disabled slow pattern %s after %s matching %d bytes (%d ns/byte)",
+			patternName, time.Duration(nanos).Round(time.Millisecond), bytes, nanos/max(bytes, 1))
+		s.stats.AddDisabledPattern(patternName)
+	}
+	return locs
+}
@@ -0,0 +1,229 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Periods for Config.MonitorDigest
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// maxDigestEntries bounds the files and endpoints a digest lists per target
+const maxDigestEntries = 50
+
+// Digest summarizes what the monitor saw over a period, per target host. With
+// Config.MonitorDigest it is sent to the webhook once the period is over,
+// instead of one notification per change.
+type Digest struct {
+	Text    string          `json:"text"` // One-line summary, as chat webhooks display it
+	Period  string          `json:"period"`
+	Since   time.Time       `json:"since"`
+	Until   time.Time       `json:"until"`
+	Targets []*DigestTarget `json:"targets"`
+}
+
+// DigestTarget is what changed on one host during a digest period
+type DigestTarget struct {
+	Host         string         `json:"host"`
+	NewFiles     []string       `json:"new_files,omitempty"`
+	ChangedFiles []string       `json:"changed_files,omitempty"`
+	NewEndpoints []string       `json:"new_endpoints,omitempty"`
+	NewFindings  map[string]int `json:"new_findings,omitempty"` // Secret findings by confidence
+}
+
+// digestPeriod returns how long a digest collects changes before it is sent
+func digestPeriod(name string) time.Duration {
+	if name == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// digestPath is the file the pending digest is kept in between runs, next to
+// the monitor state
+func digestPath(monitor string) string {
+	return monitor + ".digest"
+}
+
+// target returns the entry for the host of fileURL, adding it if needed
+func (d *Digest) target(fileURL string) *DigestTarget {
+	host := fileURL
+	if parsed, err := url.Parse(fileURL); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+	for _, target := range d.Targets {
+		if target.Host == host {
+			return target
+		}
+	}
+	target := &DigestTarget{Host: host}
+	d.Targets = append(d.Targets, target)
+	sort.Slice(d.Targets, func(i, j int) bool { return d.Targets[i].Host < d.Targets[j].Host })
+	return target
+}
+
+// addNewFile records a JS file seen for the first time and its findings
+func (d *Digest) addNewFile(fileURL string, findings []Finding) {
+	target := d.target(fileURL)
+	target.NewFiles = appendEntry(target.NewFiles, fileURL)
+	for _, finding := range findings {
+		target.addFinding(finding.Type, finding.Confidence, finding.Match)
+	}
+}
+
+// addChange records a changed JS file and the findings it introduced
+func (d *Digest) addChange(change Change) {
+	target := d.target(change.URL)
+	target.ChangedFiles = appendEntry(target.ChangedFiles, change.URL)
+	for _, finding := range change.NewFindings {
+		target.addFinding(finding.Type, finding.Confidence, finding.Match)
+	}
+}
+
+// addFinding lists an endpoint finding by its URL and counts any other by
+// confidence; match must already have its secret masked
+func (t *DigestTarget) addFinding(findingType, confidence, match string) {
+	for _, endpointType := range PatternGroups["endpoints"] {
+		if findingType == endpointType {
+			t.NewEndpoints = appendEntry(t.NewEndpoints, strings.Trim(match, "\"'`"))
+			return
+		}
+	}
+	if t.NewFindings == nil {
+		t.NewFindings = make(map[string]int)
+	}
+	t.NewFindings[confidence]++
+}
+
+// appendEntry adds value to a digest list unless it is already there or full
+func appendEntry(values []string, value string) []string {
+	if len(values) >= maxDigestEntries {
+		return values
+	}
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// summarize sets the digest's text from its targets
+func (d *Digest) summarize() {
+	files, changed, endpoints := 0, 0, 0
+	findings := make(map[string]int)
+	for _, target := range d.Targets {
+		files += len(target.NewFiles)
+		changed += len(target.ChangedFiles)
+		endpoints += len(target.NewEndpoints)
+		for confidence, count := range target.NewFindings {
+			findings[confidence] += count
+		}
+	}
+
+	d.Text = fmt.Sprintf("jsfinder %s digest since %s: ", d.Period, d.Since.Format("2006-01-02 15:04"))
+	if len(d.Targets) == 0 {
+		d.Text += "no changes"
+		return
+	}
+	d.Text += fmt.Sprintf("%d targets, %d new JS files, %d changed, %d new endpoints", len(d.Targets), files, changed, endpoints)
+
+	levels := make([]string, 0, len(findings))
+	for confidence := range findings {
+		levels = append(levels, confidence)
+	}
+	sort.Slice(levels, func(i, j int) bool { return rank(levels[i]) < rank(levels[j]) })
+	var counts []string
+	for _, confidence := range levels {
+		counts = append(counts, fmt.Sprintf("%d %s", findings[confidence], confidence))
+	}
+	if len(counts) > 0 {
+		d.Text += ", new findings: " + strings.Join(counts, ", ")
+	}
+}
+
+// sendDigest adds this run's new files and changes to the pending digest and
+// posts it once its period is over. The digest stays pending when the webhook
+// fails, so the next run retries it.
+func (s *Scanner) sendDigest(newFiles []string, changes []Change, findings map[string][]Finding) error {
+	path := digestPath(s.config.Monitor)
+	digest, err := loadDigest(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if digest == nil {
+		digest = &Digest{Period: s.config.MonitorDigest, Since: now}
+	}
+
+	for _, fileURL := range newFiles {
+		mask := literalMasker(&trackedFile{}, findings[fileURL])
+		masked := make([]Finding, 0, len(findings[fileURL]))
+		for _, finding := range findings[fileURL] {
+			finding.Match = mask(finding.Match)
+			masked = append(masked, finding)
+		}
+		digest.addNewFile(fileURL, masked)
+	}
+	for _, change := range changes {
+		digest.addChange(change)
+	}
+
+	if now.Sub(digest.Since) < digestPeriod(digest.Period) {
+		return saveDigest(path, digest)
+	}
+
+	digest.Until = now
+	digest.summarize()
+	s.logger.Info(digest.Text)
+	if err := s.postWebhook(digest); err != nil {
+		s.logger.Warnf("Failed to send the %s digest, keeping it for the next run: %v", digest.Period, err)
+		digest.Until = time.Time{}
+		return saveDigest(path, digest)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to clear the sent digest: %w", err)
+	}
+	return nil
+}
+
+// loadDigest reads the pending digest, nil when there is none
+func loadDigest(path string) (*Digest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read monitor digest: %w", err)
+	}
+	var digest Digest
+	if err := json.Unmarshal(data, &digest); err != nil {
+		return nil, fmt.Errorf("invalid monitor digest %s: %w", path, err)
+	}
+	return &digest, nil
+}
+
+// saveDigest replaces the pending digest atomically, like the monitor state
+func saveDigest(path string, digest *Digest) error {
+	data, err := json.MarshalIndent(digest, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save monitor digest: %w", err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return fmt.Errorf("failed to save monitor digest: %w", err)
+	}
+	return nil
+}
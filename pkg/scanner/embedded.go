@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Patterns for scripts embedded in an HTML page rather than served as their own file
+var (
+	dataURIScriptPattern = regexp.MustCompile(`(?is)<script[^>]+src=["']?(data:[^"'\s>]+)`)
+	importMapPattern     = regexp.MustCompile(`(?is)<script[^>]+type=["']?importmap["']?[^>]*>(.*?)</script>`)
+)
+
+// embeddedDocument is a script decoded out of a page, scanned as its own virtual document
+type embeddedDocument struct {
+	name    string
+	content string
+}
+
+// importMap is the subset of an import map that can carry module URLs
+type importMap struct {
+	Imports map[string]string            `json:"imports"`
+	Scopes  map[string]map[string]string `json:"scopes"`
+}
+
+// extractEmbedded decodes data: URI scripts and data: URI modules declared in
+// import maps, naming each after where it was found in the page
+func extractEmbedded(page string) []embeddedDocument {
+	var documents []embeddedDocument
+
+	for i, match := range dataURIScriptPattern.FindAllStringSubmatch(page, -1) {
+		if content, ok := decodeDataURI(match[1]); ok {
+			documents = append(documents, embeddedDocument{
+				name:    fmt.Sprintf("data-uri-%d", i+1),
+				content: content,
+			})
+		}
+	}
+
+	for _, match := range importMapPattern.FindAllStringSubmatch(page, -1) {
+		var imports importMap
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[1])), &imports); err != nil {
+			continue
+		}
+
+		specifiers := imports.Imports
+		for _, scoped := range imports.Scopes {
+			for specifier, target := range scoped {
+				if specifiers == nil {
+					specifiers = make(map[string]string)
+				}
+				if _, exists := specifiers[specifier]; !exists {
+					specifiers[specifier] = target
+				}
+			}
+		}
+
+		// Sort so virtual document names are stable between runs
+		names := make([]string, 0, len(specifiers))
+		for specifier := range specifiers {
+			names = append(names, specifier)
+		}
+		sort.Strings(names)
+
+		for _, specifier := range names {
+			if content, ok := decodeDataURI(specifiers[specifier]); ok {
+				documents = append(documents, embeddedDocument{
+					name:    "importmap:" + specifier,
+					content: content,
+				})
+			}
+		}
+	}
+
+	return documents
+}
+
+// decodeDataURI returns the payload of a data: URI whose media type is
+// JavaScript (or unspecified), decoding base64 and percent-encoded forms
+func decodeDataURI(uri string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(uri), "data:") {
+		return "", false
+	}
+
+	header, payload, found := strings.Cut(uri[len("data:"):], ",")
+	if !found {
+		return "", false
+	}
+
+	params := strings.Split(strings.ToLower(header), ";")
+	mediaType := strings.TrimSpace(params[0])
+	if mediaType != "" && !strings.Contains(mediaType, "javascript") && !strings.Contains(mediaType, "ecmascript") {
+		return "", false
+	}
+
+	for _, param := range params[1:] {
+		if strings.TrimSpace(param) != "base64" {
+			continue
+		}
+		payload, err := url.PathUnescape(payload)
+		if err != nil {
+			return "", false
+		}
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if decoded, err := encoding.DecodeString(payload); err == nil {
+				return string(decoded), true
+			}
+		}
+		return "", false
+	}
+
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", false
+	}
+	return decoded, true
+}
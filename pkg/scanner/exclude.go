@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SkipExcluded is the skip reason of documents matching an ExcludeURLs glob
+const SkipExcluded = "excluded"
+
+// compileGlobs turns URL globs into anchored patterns. A * matches any run of
+// characters, slashes included, so */vendor/* matches a vendor directory at
+// any depth; a ? matches one character.
+func compileGlobs(globs []string) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, glob := range globs {
+		var b strings.Builder
+		b.WriteString("^")
+		for _, r := range glob {
+			switch r {
+			case '*':
+				b.WriteString(".*")
+			case '?':
+				b.WriteString(".")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		b.WriteString("$")
+		patterns = append(patterns, regexp.MustCompile(b.String()))
+	}
+	return patterns
+}
+
+// excluded reports whether a URL matches one of the ExcludeURLs globs
+func (s *Scanner) excluded(docURL string) bool {
+	for _, pattern := range s.excludes {
+		if pattern.MatchString(docURL) {
+			return true
+		}
+	}
+	return false
+}
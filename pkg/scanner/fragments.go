@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reportable reports whether a finding gets a ticket fragment: a credential
+// whose value was extracted, or a WebSocket endpoint that accepted an
+// unauthenticated handshake
+func reportable(finding Finding) bool {
+	return finding.Secret != "" || finding.Handshake == HandshakeAccepted
+}
+
+// writeReportFragments writes a Markdown ticket body per reportable finding
+// into Config.ReportFragments, named after its type and a hash of where it
+// was found so reruns overwrite the same files
+func (s *Scanner) writeReportFragments() error {
+	if s.config.ReportFragments == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.config.ReportFragments, 0755); err != nil {
+		return fmt.Errorf("failed to create report fragment directory: %w", err)
+	}
+
+	written := 0
+	for _, finding := range s.results {
+		if !reportable(finding) {
+			continue
+		}
+		key := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", finding.URL, finding.Type, finding.Match, finding.OffsetStart)))
+		name := strings.ToLower(finding.Type) + "-" + hex.EncodeToString(key[:4]) + ".md"
+		if err := os.WriteFile(filepath.Join(s.config.ReportFragments, name), []byte(s.reportFragment(finding)), 0644); err != nil {
+			return fmt.Errorf("failed to write report fragment: %w", err)
+		}
+		written++
+	}
+	s.logger.Infof("Wrote %d report fragments to %s", written, s.config.ReportFragments)
+	return nil
+}
+
+// reportFragment renders a finding as a ticket body: what was exposed and
+// where, the evidence with the secret masked, and what to do about it
+func (s *Scanner) reportFragment(finding Finding) string {
+	remediation := finding.Remediation
+	if remediation == nil {
+		remediation = s.getRemediation(finding.Type)
+	}
+	mask := finding.maskIn
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Exposed %s in %s\n\n", finding.Description, finding.URL)
+	fmt.Fprintf(&b, "**Severity:** %s  \n", finding.Confidence)
+	fmt.Fprintf(&b, "**Type:** %s  \n", finding.Type)
+	fmt.Fprintf(&b, "**Affected URL:** %s  \n", finding.URL)
+	if finding.Source != "" {
+		fmt.Fprintf(&b, "**Embedded in:** %s  \n", finding.Source)
+	}
+	fmt.Fprintf(&b, "**Location:** line %d, column %d (%s)  \n", finding.LineNumber, finding.Column, finding.Location)
+	if finding.Commit != "" {
+		fmt.Fprintf(&b, "**Introduced:** commit %s, %s  \n", finding.Commit, finding.Introduced)
+	}
+	if finding.Handshake != "" {
+		fmt.Fprintf(&b, "**WebSocket handshake:** %s  \n", finding.Handshake)
+	}
+	if len(finding.Labels) > 0 {
+		fmt.Fprintf(&b, "**Labels:** %s  \n", finding.Labels)
+	}
+
+	b.WriteString("\n## Evidence\n\n")
+	if finding.Secret != "" {
+		fmt.Fprintf(&b, "Secret value (masked): `%s`\n\n", maskSecret(finding.Secret))
+	}
+	fmt.Fprintf(&b, "```\n%s\n```\n", mask(finding.Context))
+
+	fmt.Fprintf(&b, "\n## Remediation\n\n%s\n", remediation.Summary)
+	if remediation.Rotation != "" {
+		b.WriteString("\n## Rotation steps\n\n")
+		for i, step := range sentences(remediation.Rotation) {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+		}
+	}
+	if len(remediation.References) > 0 {
+		b.WriteString("\n## References\n\n")
+		for _, reference := range remediation.References {
+			fmt.Fprintf(&b, "- %s\n", reference)
+		}
+	}
+	return b.String()
+}
+
+// maskIn returns text with the finding's secret value masked wherever it
+// appears; text is unchanged for findings without one
+func (f Finding) maskIn(text string) string {
+	if f.Secret == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, f.Secret, maskSecret(f.Secret))
+}
+
+// maskSecret keeps the first and last four characters of a secret long
+// enough to be told apart by them, so a ticket identifies the credential
+// without handing it out again
+func maskSecret(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 12 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + strings.Repeat("*", len(runes)-8) + string(runes[len(runes)-4:])
+}
+
+// sentences splits guidance text into its sentences, one step each
+func sentences(text string) []string {
+	var steps []string
+	for {
+		end := strings.Index(text, ". ")
+		if end < 0 {
+			break
+		}
+		steps = append(steps, text[:end+1])
+		text = strings.TrimSpace(text[end+2:])
+	}
+	if text != "" {
+		steps = append(steps, text)
+	}
+	return steps
+}
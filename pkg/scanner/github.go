@@ -0,0 +1,183 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/utils"
+)
+
+// DefaultGitHubAPI is the API repositories are fetched from unless configured otherwise
+const DefaultGitHubAPI = "https://api.github.com"
+
+// githubRepo is the subset of a repository listing entry the scanner needs
+type githubRepo struct {
+	FullName string `json:"full_name"`
+	Fork     bool   `json:"fork"`
+}
+
+// parseGitHubTarget accepts owner/repo, a bare owner (organization or user)
+// or a github.com URL for either, returning the owner and the optional repo
+func parseGitHubTarget(target string) (string, string, error) {
+	target = strings.TrimPrefix(strings.TrimPrefix(target, "https://"), "http://")
+	target = strings.TrimPrefix(target, "github.com/")
+	target = strings.TrimSuffix(strings.TrimSuffix(target, "/"), ".git")
+
+	parts := strings.Split(target, "/")
+	if parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] == "") {
+		return "", "", fmt.Errorf("invalid GitHub target %q, expected owner or owner/repo", target)
+	}
+	if len(parts) == 1 {
+		return parts[0], "", nil
+	}
+	return parts[0], parts[1], nil
+}
+
+func (s *Scanner) githubAPI() string {
+	if s.config.GitHubAPI != "" {
+		return strings.TrimSuffix(s.config.GitHubAPI, "/")
+	}
+	return DefaultGitHubAPI
+}
+
+// githubGet sends an API request, authenticated when a token is configured
+func (s *Scanner) githubGet(apiURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if s.config.GitHubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.GitHubToken)
+	}
+
+	return s.client.Do(req)
+}
+
+// listRepos returns the non-fork repositories of an organization, falling
+// back to the user endpoint when owner is not an organization
+func (s *Scanner) listRepos(owner string) ([]string, error) {
+	var repos []string
+	endpoint := fmt.Sprintf("%s/orgs/%s/repos", s.githubAPI(), owner)
+	isUser := false
+
+	for page := 1; ; page++ {
+		pageURL := fmt.Sprintf("%s?per_page=100&page=%d", endpoint, page)
+		resp, err := s.githubGet(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound && !isUser {
+			resp.Body.Close()
+			endpoint = fmt.Sprintf("%s/users/%s/repos", s.githubAPI(), owner)
+			isUser = true
+			page = 0
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, pageURL)
+		}
+
+		var batch []githubRepo
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse repository list for %s: %w", owner, err)
+		}
+
+		for _, repo := range batch {
+			if !repo.Fork {
+				repos = append(repos, repo.FullName)
+			}
+		}
+		if len(batch) < 100 {
+			return repos, nil
+		}
+	}
+}
+
+// scanRepo downloads the default branch tarball of a repository and scans its
+// JavaScript/TypeScript sources, reporting findings as owner/repo!/path
+func (s *Scanner) scanRepo(fullName string) error {
+	tarballURL := fmt.Sprintf("%s/repos/%s/tarball", s.githubAPI(), fullName)
+	resp, err := s.githubGet(tarballURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, tarballURL)
+	}
+
+	// Entries are prefixed with an owner-repo-sha directory that means nothing to the reader
+	return s.scanTarball(resp.Body, func(name string) string {
+		if _, path, found := strings.Cut(name, "/"); found {
+			name = path
+		}
+		return fullName + "!/" + name
+	})
+}
+
+// ScanGitHub scans a single repository (owner/repo) or every non-fork
+// repository of an organization or user (owner)
+func (s *Scanner) ScanGitHub(target string) error {
+	owner, repo, err := parseGitHubTarget(target)
+	if err != nil {
+		return err
+	}
+
+	repos := []string{owner + "/" + repo}
+	if repo == "" {
+		if repos, err = s.listRepos(owner); err != nil {
+			return err
+		}
+	}
+
+	for _, fullName := range repos {
+		if s.config.Budget.Exceeded() {
+			s.stats.SetStopReason(s.config.Budget.Reason())
+			break
+		}
+		if s.timeoutMgr.Expired() {
+			s.stats.SetStopReason(s.timeoutMgr.ExpiredReason())
+			break
+		}
+		s.logger.WithField("target", fullName).Debug("Scanning repository")
+		if err := s.scanRepo(fullName); err != nil {
+			if repo != "" {
+				return err
+			}
+			s.logger.WithField("target", fullName).Warnf("Error scanning repository: %v", err)
+		}
+	}
+
+	return s.outputResults()
+}
+
+// PlanGitHub describes a GitHub scan for a dry run: one tarball download per
+// repository, plus the repository listing for an organization or user
+func (s *Scanner) PlanGitHub(target string) (*utils.RequestPlan, error) {
+	owner, repo, err := parseGitHubTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := utils.NewRequestPlan("scan", s.config.Threads, time.Duration(s.config.Timeout)*time.Second)
+	plan.AddSetting("Patterns", len(s.patterns))
+	plan.AddSetting("GitHub authenticated", s.config.GitHubToken != "")
+	if repo != "" {
+		plan.AddSetting("GitHub repository", owner+"/"+repo)
+		plan.Add(fmt.Sprintf("%s/repos/%s/%s/tarball", s.githubAPI(), owner, repo), 1)
+		return plan, nil
+	}
+
+	plan.AddSetting("GitHub owner", owner)
+	plan.Add(fmt.Sprintf("%s/orgs/%s/repos", s.githubAPI(), owner), 1)
+	plan.AddNote("one more request per 100 repositories listed, plus one tarball download per non-fork repository")
+	return plan, nil
+}
@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PatternGroups names sets of built-in patterns that --only and --disable
+// accept alongside pattern names. Custom patterns join groups with tags.
+var PatternGroups = map[string][]string{
+	"aws":             {"AWS_ACCESS_KEY", "AWS_SECRET_KEY", "AWS_SESSION_TOKEN"},
+	"gcp":             {"GCP_API_KEY", "GCP_SERVICE_KEY"},
+	"firebase":        {"FIREBASE_API_KEY"},
+	"github":          {"GITHUB_TOKEN"},
+	"jwt":             {"JWT_TOKEN"},
+	"oauth":           {"OAUTH_TOKEN"},
+	"slack":           {"SLACK_TOKEN"},
+	"stripe":          {"STRIPE_KEY"},
+	"twilio":          {"TWILIO_SID"},
+	"database":        {"DATABASE_URL"},
+	"url-credentials": {"URL_CREDENTIALS"},
+	"password":        {"PASSWORD"},
+	"secret":          {"SECRET"},
+	"api-key":         {"API_KEY"},
+	"cloud":           {"AWS_ACCESS_KEY", "AWS_SECRET_KEY", "AWS_SESSION_TOKEN", "GCP_API_KEY", "GCP_SERVICE_KEY", "FIREBASE_API_KEY"},
+	"tokens":          {"GITHUB_TOKEN", "JWT_TOKEN", "OAUTH_TOKEN", "SLACK_TOKEN", "STRIPE_KEY", "TWILIO_SID"},
+	"credentials":     {"PASSWORD", "SECRET", "API_KEY", "DATABASE_URL", "URL_CREDENTIALS"},
+	"endpoints":       {"API_ENDPOINT", "INTERNAL_ENDPOINT", "WEBSOCKET_ENDPOINT", "WORKER_SCRIPT"},
+}
+
+// SelectPatterns narrows the pattern set to the groups or pattern names in
+// only, when it is not empty, then removes those in disable. Names are
+// case-insensitive. Call it after LoadPatterns so custom patterns can be
+// selected too.
+func (s *Scanner) SelectPatterns(only, disable []string) error {
+	if len(only) > 0 {
+		keep, err := s.resolvePatterns(only, "only")
+		if err != nil {
+			return err
+		}
+		for name := range s.patterns {
+			if !keep[name] {
+				s.removePattern(name)
+			}
+		}
+	}
+
+	if len(disable) > 0 {
+		remove, err := s.resolvePatterns(disable, "disable")
+		if err != nil {
+			return err
+		}
+		for name := range remove {
+			s.removePattern(name)
+		}
+	}
+
+	if len(only)+len(disable) > 0 && len(s.patterns) == 0 {
+		return fmt.Errorf("--only and --disable leave no patterns to scan with")
+	}
+	return nil
+}
+
+// resolvePatterns expands group and pattern names into the pattern names
+// currently loaded
+func (s *Scanner) resolvePatterns(names []string, flag string) (map[string]bool, error) {
+	resolved := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		group := strings.ReplaceAll(strings.ToLower(name), "_", "-")
+		patternName := strings.ReplaceAll(strings.ToUpper(name), "-", "_")
+		found := false
+		for _, member := range PatternGroups[group] {
+			if s.patterns[member] != nil {
+				resolved[member] = true
+			}
+			found = true
+		}
+		for customName, custom := range s.custom {
+			for _, tag := range custom.tags {
+				if strings.EqualFold(tag, name) || strings.EqualFold(tag, group) {
+					resolved[customName] = true
+					found = true
+				}
+			}
+		}
+		if s.patterns[patternName] != nil {
+			resolved[patternName] = true
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("--%s: unknown pattern or group %q (groups: %s)", flag, name, strings.Join(PatternGroupNames(), ", "))
+		}
+	}
+	return resolved, nil
+}
+
+// removePattern drops a pattern from the set used for this run
+func (s *Scanner) removePattern(name string) {
+	delete(s.patterns, name)
+	delete(s.custom, name)
+	delete(s.valueGroups, name)
+}
+
+// PatternGroupNames returns the names of the built-in pattern groups, sorted
+func PatternGroupNames() []string {
+	names := make([]string, 0, len(PatternGroups))
+	for name := range PatternGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
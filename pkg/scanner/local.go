@@ -0,0 +1,246 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/utils"
+)
+
+// skippedDirs are never descended into when scanning a directory: git's own
+// data and installed third-party packages
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+}
+
+// ScanDirectory scans the JavaScript, TypeScript and HTML files under a local
+// directory, reporting findings by their slash-separated path relative to it.
+// When the directory is in a git work tree and git is installed, each finding
+// is annotated with the commit that introduced its line.
+func (s *Scanner) ScanDirectory(root string) error {
+	files, err := directoryAssets(root)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		s.stats.AddQueued(1)
+		s.logger.WithField("target", name).Debug("Scanning")
+
+		content, err := readAsset(filepath.Join(root, filepath.FromSlash(name)))
+		if err == nil {
+			err = s.scanAsset(name, content)
+		}
+		if err != nil {
+			s.logger.WithField("target", name).Warnf("Error scanning: %v", err)
+		}
+		s.stats.AddProcessed()
+	}
+
+	s.blameFindings(root)
+	return s.outputResults()
+}
+
+// PlanDirectory describes a directory scan for a dry run; files are read
+// locally, so the plan lists the assets to scan but sends no requests
+func (s *Scanner) PlanDirectory(root string) (*utils.RequestPlan, error) {
+	plan := utils.NewRequestPlan("scan", s.config.Threads, time.Duration(s.config.Timeout)*time.Second)
+	plan.AddSetting("Patterns", len(s.patterns))
+
+	files, err := directoryAssets(root)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.AddSetting("Directory", root)
+	plan.AddSetting("Directory assets", len(files))
+	if gitWorkTree(root) {
+		plan.AddNote("findings are annotated with git blame")
+	}
+	plan.AddNote("directory assets are scanned locally; no requests are sent")
+	return plan, nil
+}
+
+// ScanContent scans JavaScript or HTML read from reader itself, such as a
+// bundle piped into stdin, reporting findings under name
+func (s *Scanner) ScanContent(reader io.Reader, name string) error {
+	content, err := io.ReadAll(io.LimitReader(reader, maxArchiveEntrySize))
+	if err != nil {
+		return fmt.Errorf("failed to read content: %w", err)
+	}
+
+	s.stats.AddQueued(1)
+	s.logger.WithField("target", name).Debug("Scanning")
+	err = s.scanAsset(name, content)
+	s.stats.AddProcessed()
+	if err != nil {
+		return err
+	}
+
+	return s.outputResults()
+}
+
+// PlanContent describes a content scan for a dry run, which sends no requests
+func (s *Scanner) PlanContent(name string) (*utils.RequestPlan, error) {
+	plan := utils.NewRequestPlan("scan", s.config.Threads, time.Duration(s.config.Timeout)*time.Second)
+	plan.AddSetting("Patterns", len(s.patterns))
+	plan.AddSetting("Content", name)
+	plan.AddNote("content read from stdin is scanned locally; no requests are sent")
+	return plan, nil
+}
+
+// directoryAssets lists the scannable files under root as sorted
+// slash-separated relative paths
+func directoryAssets(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && skippedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !isArchiveAsset(entry.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	return files, nil
+}
+
+// readAsset reads a local file, capped like an archive entry
+func readAsset(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, maxArchiveEntrySize))
+}
+
+// blameLine is the commit that last changed a line and when it was written
+type blameLine struct {
+	commit string
+	time   time.Time
+}
+
+// gitWorkTree reports whether git is installed and root is inside a work tree
+func gitWorkTree(root string) bool {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false
+	}
+	out, err := exec.Command("git", "-C", root, "rev-parse", "--is-inside-work-tree").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// blameFindings sets the commit and introduction time of the findings made
+// directly in a file under root. Findings in decoded embedded scripts are
+// left alone, as their line numbers are not lines of the file, and so are
+// lines not committed yet.
+func (s *Scanner) blameFindings(root string) {
+	if len(s.results) == 0 {
+		return
+	}
+	if !gitWorkTree(root) {
+		s.logger.Debugf("%s is not in a git work tree (or git is not installed); findings are not annotated with git blame", root)
+		return
+	}
+
+	blames := make(map[string]map[int]blameLine)
+	for i := range s.results {
+		finding := &s.results[i]
+		if finding.Source != "" {
+			continue
+		}
+
+		lines, done := blames[finding.URL]
+		if !done {
+			var err error
+			lines, err = gitBlame(root, finding.URL)
+			if err != nil {
+				s.logger.WithField("target", finding.URL).Warnf("git blame failed: %v", err)
+			}
+			blames[finding.URL] = lines
+		}
+
+		if line, found := lines[finding.LineNumber]; found {
+			finding.Commit = line.commit
+			finding.Introduced = line.time.UTC().Format(time.RFC3339)
+		}
+	}
+}
+
+// gitBlame returns the commit of every committed line of a file, by line number
+func gitBlame(root, name string) (map[int]blameLine, error) {
+	out, err := exec.Command("git", "-C", root, "blame", "--line-porcelain", "--", filepath.FromSlash(name)).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame(out), nil
+}
+
+// parseBlame reads git blame --line-porcelain output, in which every line of
+// the file is a header ("<sha> <original line> <final line> ..."), key-value
+// lines such as "author-time <unix seconds>", then the line itself after a tab
+func parseBlame(out []byte) map[int]blameLine {
+	lines := make(map[int]blameLine)
+	var current blameLine
+	lineNumber := 0
+
+	// Minified files have very long lines, so allow lines as large as a file
+	reader := bufio.NewScanner(bytes.NewReader(out))
+	reader.Buffer(make([]byte, 0, 64*1024), maxArchiveEntrySize)
+	for reader.Scan() {
+		text := reader.Text()
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			// Lines not committed yet are blamed on the all-zero commit
+			if lineNumber > 0 && strings.Trim(current.commit, "0") != "" {
+				lines[lineNumber] = current
+			}
+			current, lineNumber = blameLine{}, 0
+		case strings.HasPrefix(text, "author-time "):
+			if seconds, err := strconv.ParseInt(strings.TrimPrefix(text, "author-time "), 10, 64); err == nil {
+				current.time = time.Unix(seconds, 0)
+			}
+		case lineNumber == 0:
+			fields := strings.Fields(text)
+			if len(fields) >= 3 && len(fields[0]) >= 40 {
+				if n, err := strconv.Atoi(fields[2]); err == nil {
+					current.commit, lineNumber = fields[0], n
+				}
+			}
+		}
+	}
+	return lines
+}
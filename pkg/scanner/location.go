@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Locations a finding can be classified into. A key in a runtime config file
+// is almost always live, while one in a vendored library is usually a test
+// fixture or placeholder, so consumers can triage by this field.
+const (
+	LocationConfig = "config"      // Runtime config: env.js, config.json, settings.js
+	LocationBundle = "bundle"      // Application code: main bundle and chunks
+	LocationVendor = "vendor"      // Third-party libraries and vendor chunks
+	LocationInline = "inline-html" // Script inline in an HTML page or embedded in one
+)
+
+var (
+	configFilePattern = regexp.MustCompile(`^(?:__)?(?:env|environment|config|configuration|settings|runtime-config|app-config|appsettings)(?:[._-][\w-]+)*\.(?:js|mjs|cjs|json|ts)$|^\.env(?:\.[\w-]+)?$|\.config\.(?:js|mjs|cjs|json|ts)$`)
+	vendorFilePattern = regexp.MustCompile(`(?:^|[._~-])vendors?(?:[._~-]|$)|^(?:jquery|bootstrap|lodash|underscore|moment|react(?:-dom)?|vue|angular|polyfills?|modernizr|d3|three|axios|core-js|zone)(?:[.-][\w.-]*)?\.(?:js|mjs)$`)
+	vendorDirs        = []string{"/node_modules/", "/vendor/", "/vendors/", "/bower_components/", "/third_party/", "/third-party/", "/lib/", "/libs/"}
+	vendorHosts       = []string{"cdnjs.cloudflare.com", "unpkg.com", "cdn.jsdelivr.net", "ajax.googleapis.com", "code.jquery.com", "stackpath.bootstrapcdn.com", "cdn.skypack.dev", "esm.sh"}
+)
+
+// classifyLocation decides which kind of file a document is from its path
+// (the part after "!/" for archive entries) and, for HTML, its content
+func classifyLocation(docURL, source, content string) string {
+	if source != "" || looksLikeHTML(content) {
+		return LocationInline
+	}
+
+	host, filePath := "", docURL
+	if _, entry, found := strings.Cut(docURL, "!/"); found {
+		filePath = "/" + entry
+	} else if parsed, err := url.Parse(docURL); err == nil {
+		host, filePath = strings.ToLower(parsed.Hostname()), parsed.Path
+	}
+	filePath = strings.ToLower(filePath)
+	base := path.Base(filePath)
+
+	switch ext := path.Ext(base); {
+	case ext == ".html" || ext == ".htm":
+		return LocationInline
+	case configFilePattern.MatchString(base):
+		return LocationConfig
+	case vendorFilePattern.MatchString(base):
+		return LocationVendor
+	}
+
+	for _, vendorHost := range vendorHosts {
+		if host == vendorHost {
+			return LocationVendor
+		}
+	}
+	for _, dir := range vendorDirs {
+		if strings.Contains(filePath, dir) {
+			return LocationVendor
+		}
+	}
+
+	return LocationBundle
+}
+
+// looksLikeHTML reports whether content is an HTML page rather than a script
+func looksLikeHTML(content string) bool {
+	start := strings.TrimSpace(content)
+	if len(start) > 512 {
+		start = start[:512]
+	}
+	start = strings.ToLower(start)
+	return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html") ||
+		(strings.HasPrefix(start, "<") && strings.Contains(start, "<script"))
+}
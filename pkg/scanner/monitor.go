@@ -0,0 +1,308 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/literal"
+)
+
+// Limits on what the monitor state keeps per file and a change notification lists
+const (
+	maxTrackedLiterals  = 20000
+	maxTrackedLiteral   = 200 // Longer literals (inlined data, templates) are left out
+	minTrackedLiteral   = 3
+	maxNotifiedLiterals = 50
+)
+
+// trackedFile is the state the monitor keeps of a JS file between runs
+type trackedFile struct {
+	Hash     string    `json:"hash"`
+	Checked  time.Time `json:"checked"`
+	Literals []string  `json:"literals"`
+	Findings []string  `json:"findings"`          // Keys of the findings in the file, see findingKey
+	Secrets  []string  `json:"secrets,omitempty"` // Secrets of those findings, masked in the strings a later change removes
+}
+
+// Change is a tracked JS file whose content hash changed since the previous run
+type Change struct {
+	Text            string          `json:"text"` // One-line summary, as chat webhooks display it
+	URL             string          `json:"url"`
+	PreviousHash    string          `json:"previous_hash"`
+	Hash            string          `json:"hash"`
+	PreviousChecked time.Time       `json:"previous_checked"`
+	AddedCount      int             `json:"added_count"`
+	RemovedCount    int             `json:"removed_count"`
+	Added           []string        `json:"added_strings,omitempty"`
+	Removed         []string        `json:"removed_strings,omitempty"`
+	NewFindings     []ChangeFinding `json:"new_findings,omitempty"`
+}
+
+// ChangeFinding is a finding introduced by a change, with its secret masked
+// since notifications travel further than the results file
+type ChangeFinding struct {
+	Type       string `json:"type"`
+	Confidence string `json:"confidence"`
+	LineNumber int    `json:"line_number"`
+	Match      string `json:"match"`
+}
+
+// track records the hash and string literals of a downloaded JS file for
+// the monitor
+func (s *Scanner) track(jsURL string, content []byte) {
+	if s.config.Monitor == "" {
+		return
+	}
+	sum := sha256.Sum256(content)
+	file := trackedFile{Hash: hex.EncodeToString(sum[:]), Checked: time.Now()}
+
+	seen := make(map[string]bool)
+	for _, str := range literal.Strings(string(content)) {
+		value := strings.TrimSpace(str.Value)
+		if len(value) < minTrackedLiteral || len(value) > maxTrackedLiteral || seen[value] {
+			continue
+		}
+		seen[value] = true
+		file.Literals = append(file.Literals, value)
+		if len(file.Literals) == maxTrackedLiterals {
+			break
+		}
+	}
+	sort.Strings(file.Literals)
+
+	s.mutex.Lock()
+	s.tracked[jsURL] = &file
+	s.mutex.Unlock()
+}
+
+// findingKey identifies a finding across runs regardless of where it moved
+// to in the file
+func findingKey(finding Finding) string {
+	return finding.Type + "|" + finding.Match
+}
+
+// reportChanges compares the JS files downloaded in this run with the
+// monitor state, logs and notifies each file whose content changed, and
+// saves the new state. Files seen for the first time only join the state.
+func (s *Scanner) reportChanges() error {
+	if s.config.Monitor == "" {
+		return nil
+	}
+	state, err := loadMonitorState(s.config.Monitor)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	findings := make(map[string][]Finding)
+	for _, finding := range s.results {
+		url := finding.URL
+		if finding.Source != "" {
+			url = finding.Source
+		}
+		findings[url] = append(findings[url], finding)
+	}
+	urls := make([]string, 0, len(s.tracked))
+	for url, file := range s.tracked {
+		urls = append(urls, url)
+		file.Findings, file.Secrets = nil, nil
+		for _, finding := range findings[url] {
+			file.Findings = append(file.Findings, findingKey(finding))
+			if finding.Secret != "" {
+				file.Secrets = append(file.Secrets, finding.Secret)
+			}
+		}
+	}
+	s.mutex.Unlock()
+	sort.Strings(urls)
+
+	// On the first run every file is new, which is not worth a digest entry
+	firstRun := len(state) == 0
+	var changes []Change
+	var newFiles []string
+	for _, url := range urls {
+		file := s.tracked[url]
+		previous, known := state[url]
+		switch {
+		case known && previous.Hash != file.Hash:
+			changes = append(changes, diffTracked(url, previous, file, findings[url]))
+		case !known && !firstRun:
+			newFiles = append(newFiles, url)
+		}
+		state[url] = file
+	}
+
+	for _, change := range changes {
+		s.logger.WithField("target", change.URL).Info(change.Text)
+		if s.config.MonitorDigest != "" {
+			continue
+		}
+		if err := s.notify(change); err != nil {
+			s.logger.WithField("target", change.URL).Warnf("%v", err)
+		}
+	}
+	if len(changes) == 0 {
+		s.logger.Infof("No changes in %d monitored JS files", len(urls))
+	}
+	if err := saveMonitorState(s.config.Monitor, state); err != nil {
+		return err
+	}
+	if s.config.MonitorDigest != "" {
+		return s.sendDigest(newFiles, changes, findings)
+	}
+	return nil
+}
+
+// diffTracked summarizes how a file changed: the string literals added and
+// removed, and the findings it did not have before. Secrets are masked in
+// both, since notifications travel further than the results file.
+func diffTracked(url string, previous, current *trackedFile, findings []Finding) Change {
+	change := Change{URL: url, PreviousHash: previous.Hash, Hash: current.Hash, PreviousChecked: previous.Checked}
+	mask := literalMasker(previous, findings)
+
+	before := make(map[string]bool, len(previous.Literals))
+	for _, value := range previous.Literals {
+		before[value] = true
+	}
+	after := make(map[string]bool, len(current.Literals))
+	for _, value := range current.Literals {
+		after[value] = true
+		if !before[value] {
+			change.AddedCount++
+			if len(change.Added) < maxNotifiedLiterals {
+				change.Added = append(change.Added, mask(value))
+			}
+		}
+	}
+	for _, value := range previous.Literals {
+		if !after[value] {
+			change.RemovedCount++
+			if len(change.Removed) < maxNotifiedLiterals {
+				change.Removed = append(change.Removed, mask(value))
+			}
+		}
+	}
+
+	known := make(map[string]bool, len(previous.Findings))
+	for _, key := range previous.Findings {
+		known[key] = true
+	}
+	// In file order; the patterns run in no particular order
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].LineNumber != findings[j].LineNumber {
+			return findings[i].LineNumber < findings[j].LineNumber
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	var types []string
+	for _, finding := range findings {
+		if known[findingKey(finding)] {
+			continue
+		}
+		known[findingKey(finding)] = true
+		match := finding.Match
+		if finding.Secret != "" {
+			match = strings.ReplaceAll(match, finding.Secret, maskSecret(finding.Secret))
+		}
+		change.NewFindings = append(change.NewFindings, ChangeFinding{Type: finding.Type, Confidence: finding.Confidence, LineNumber: finding.LineNumber, Match: match})
+		types = append(types, finding.Type)
+	}
+
+	change.Text = fmt.Sprintf("%s changed: %d strings added, %d removed", url, change.AddedCount, change.RemovedCount)
+	if len(types) > 0 {
+		change.Text += fmt.Sprintf(", %d new findings (%s)", len(types), strings.Join(types, ", "))
+	}
+	return change
+}
+
+// literalMasker returns the function that masks the secrets of the file's
+// findings, in this run or the previous one, wherever they appear in a
+// changed literal
+func literalMasker(previous *trackedFile, findings []Finding) func(string) string {
+	secrets := append([]string(nil), previous.Secrets...)
+	for _, finding := range findings {
+		if finding.Secret != "" {
+			secrets = append(secrets, finding.Secret)
+		}
+	}
+
+	return func(value string) string {
+		for _, secret := range secrets {
+			value = strings.ReplaceAll(value, secret, maskSecret(secret))
+		}
+		return value
+	}
+}
+
+// notify posts a change as JSON to the monitor webhook
+func (s *Scanner) notify(change Change) error {
+	if err := s.postWebhook(change); err != nil {
+		return fmt.Errorf("failed to send change notification: %w", err)
+	}
+	return nil
+}
+
+// postWebhook posts payload as JSON to the monitor webhook, if one is set
+func (s *Scanner) postWebhook(payload any) error {
+	if s.config.MonitorWebhook == "" {
+		return nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(s.config.MonitorWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookClient sends change notifications; the webhook is the user's own
+// endpoint, so it bypasses the engagement scope the scan client enforces
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+func loadMonitorState(path string) (map[string]*trackedFile, error) {
+	state := make(map[string]*trackedFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read monitor state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid monitor state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// saveMonitorState replaces the state file atomically, so an interrupted
+// run leaves the previous state intact
+func saveMonitorState(path string, state map[string]*trackedFile) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save monitor state: %w", err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return fmt.Errorf("failed to save monitor state: %w", err)
+	}
+	return nil
+}
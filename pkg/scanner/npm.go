@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/utils"
+)
+
+// DefaultNpmRegistry is the registry packages are fetched from unless configured otherwise
+const DefaultNpmRegistry = "https://registry.npmjs.org"
+
+// npmVersion is the subset of a registry version document needed to fetch its tarball
+type npmVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Dist    struct {
+		Tarball string `json:"tarball"`
+	} `json:"dist"`
+}
+
+// parsePackageSpec splits name[@version] into its parts, keeping the leading
+// @ of scoped packages; the version defaults to the latest dist-tag
+func parsePackageSpec(spec string) (string, string, error) {
+	name, version := spec, "latest"
+	if at := strings.LastIndex(spec, "@"); at > 0 {
+		name, version = spec[:at], spec[at+1:]
+	}
+
+	if name == "" || version == "" || strings.Count(name, "/") > 1 || (strings.Contains(name, "/") && !strings.HasPrefix(name, "@")) {
+		return "", "", fmt.Errorf("invalid npm package %q, expected name[@version] or @scope/name[@version]", spec)
+	}
+	return name, version, nil
+}
+
+func (s *Scanner) registry() string {
+	if s.config.NpmRegistry != "" {
+		return strings.TrimSuffix(s.config.NpmRegistry, "/")
+	}
+	return DefaultNpmRegistry
+}
+
+// resolvePackage looks up the tarball for a package version in the registry
+func (s *Scanner) resolvePackage(spec string) (*npmVersion, error) {
+	name, version, err := parsePackageSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Get(fmt.Sprintf("%s/%s/%s", s.registry(), name, version))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: npm package %s@%s", resp.StatusCode, name, version)
+	}
+
+	var pkg npmVersion
+	if err := json.NewDecoder(resp.Body).Decode(&pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse registry response for %s: %w", spec, err)
+	}
+	if pkg.Dist.Tarball == "" {
+		return nil, fmt.Errorf("registry returned no tarball for %s@%s", name, version)
+	}
+	return &pkg, nil
+}
+
+// ScanNpmPackage downloads a package tarball from the npm registry and scans
+// its JavaScript and HTML files, reporting findings as name@version!/path
+func (s *Scanner) ScanNpmPackage(spec string) error {
+	pkg, err := s.resolvePackage(spec)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Get(pkg.Dist.Tarball)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, pkg.Dist.Tarball)
+	}
+
+	base := pkg.Name + "@" + pkg.Version
+	if err := s.scanTarball(resp.Body, func(name string) string { return base + "!/" + name }); err != nil {
+		return err
+	}
+
+	return s.outputResults()
+}
+
+// PlanNpmPackage describes an npm package scan for a dry run: one registry
+// lookup and one tarball download
+func (s *Scanner) PlanNpmPackage(spec string) (*utils.RequestPlan, error) {
+	name, version, err := parsePackageSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := utils.NewRequestPlan("scan", s.config.Threads, time.Duration(s.config.Timeout)*time.Second)
+	plan.AddSetting("Patterns", len(s.patterns))
+	plan.AddSetting("npm package", name+"@"+version)
+	plan.Add(s.registry()+"/"+name+"/"+version, 2)
+	plan.AddNote("the tarball may be served from a different host than the registry")
+	return plan, nil
+}
@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPackURL is where `patterns update` fetches the project's pattern
+// pack from; its signature is published next to it with a .sig suffix. The
+// pack is config/pattern-pack.yaml in the repository. No key to verify it
+// with is built in, so users pass the publisher's key explicitly.
+const DefaultPackURL = "https://raw.githubusercontent.com/devthedeveloper/jsfinder/main/config/pattern-pack.yaml"
+
+// Size limits for downloaded packs and their signatures
+const (
+	maxPackSize      = 4 << 20
+	maxSignatureSize = 1 << 10
+)
+
+// Pack is a versioned pattern file signed by its publisher. It has the
+// layout of a --config pattern file plus a top-level version.
+type Pack struct {
+	Version   string
+	Data      []byte
+	Signature []byte // Raw ed25519 signature of Data
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: expected %d bytes of base64", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// FetchPack downloads the pack at url and its signature at url.sig, a
+// base64 ed25519 signature of the pack file. The pack is returned only if
+// the signature verifies against publicKey and its patterns are valid.
+func FetchPack(ctx context.Context, client *http.Client, url string, publicKey ed25519.PublicKey) (*Pack, error) {
+	data, err := fetchPackFile(ctx, client, url, maxPackSize)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := fetchPackFile(ctx, client, url+".sig", maxSignatureSize)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyPack(data, encoded, publicKey, url)
+}
+
+// VerifyPack checks a pack file against its base64 signature and publicKey,
+// naming the pack source in errors, and parses it
+func VerifyPack(data, encoded []byte, publicKey ed25519.PublicKey, source string) (*Pack, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("no valid public key to verify %s with", source)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pack signature: %w", err)
+	}
+	if !ed25519.Verify(publicKey, data, signature) {
+		return nil, fmt.Errorf("pattern pack signature does not verify; refusing to load %s", source)
+	}
+	return ParsePack(data, signature)
+}
+
+// SignPack returns the base64 signature of a pack file, as published in its
+// .sig file, after checking the pack parses
+func SignPack(data []byte, privateKey ed25519.PrivateKey) (string, error) {
+	if _, err := ParsePack(data, nil); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data)) + "\n", nil
+}
+
+// ParsePrivateKey decodes a base64 ed25519 private key, as written by
+// `patterns keygen`
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key: expected %d bytes of base64", ed25519.PrivateKeySize)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+func fetchPackFile(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: HTTP %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// ParsePack reads a pack's version and checks its patterns as LoadPatterns would
+func ParsePack(data, signature []byte) (*Pack, error) {
+	var header struct {
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("invalid pattern pack: %w", err)
+	}
+	if header.Version == "" {
+		return nil, fmt.Errorf("invalid pattern pack: no version")
+	}
+	if err := New(&Config{}).loadPatternData(data, "pattern pack"); err != nil {
+		return nil, err
+	}
+	return &Pack{Version: header.Version, Data: data, Signature: signature}, nil
+}
+
+// DefaultPackPath is where packs are installed, ~/.jsfinder/patterns.yaml
+func DefaultPackPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".jsfinder", "patterns.yaml"), nil
+}
+
+// InstalledPack reads the pack installed at path, or returns nil if there is
+// none. The pack is verified again against publicKey, so a file changed or
+// replaced since `patterns update` is never loaded.
+func InstalledPack(path string, publicKey ed25519.PublicKey) (*Pack, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installed pattern pack: %w", err)
+	}
+	encoded, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		return nil, fmt.Errorf("installed pattern pack %s has no signature: %w", path, err)
+	}
+	return VerifyPack(data, encoded, publicKey, path)
+}
+
+// LoadPack adds the patterns of a verified pack to the scanner
+func (s *Scanner) LoadPack(pack *Pack) error {
+	return s.loadPatternData(pack.Data, "pattern pack "+pack.Version)
+}
+
+// Install writes the pack to path with its signature next to it. Both are
+// written to temporary files first and renamed into place, so neither is
+// ever half written and a failed write leaves the previous pack as it was.
+// Only a crash between the two renames can pair the new pack with the old
+// signature; scans then skip the pack until the next update replaces it.
+func (p *Pack) Install(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to install pattern pack: %w", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(p.Signature) + "\n"
+	files := []struct{ path, temp string }{{path, path + ".tmp"}, {path + ".sig", path + ".sig.tmp"}}
+	for i, data := range [][]byte{p.Data, []byte(signature)} {
+		if err := os.WriteFile(files[i].temp, data, 0644); err != nil {
+			os.Remove(files[0].temp)
+			return fmt.Errorf("failed to install pattern pack: %w", err)
+		}
+	}
+	for _, file := range files {
+		if err := os.Rename(file.temp, file.path); err != nil {
+			return fmt.Errorf("failed to install pattern pack: %w", err)
+		}
+	}
+	return nil
+}
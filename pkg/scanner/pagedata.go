@@ -0,0 +1,157 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Patterns for the data server-rendered pages carry outside their scripts'
+// code: hidden form fields, JSON-LD and serialized application state
+var (
+	hiddenInputPattern = regexp.MustCompile(`(?is)<input\b[^>]*\btype\s*=\s*["']?hidden\b[^>]*>`)
+	attributePattern   = regexp.MustCompile(`(?is)\b(name|id|value)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	scriptBlockPattern = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	scriptTypePattern  = regexp.MustCompile(`(?is)\btype\s*=\s*["']?([^"'\s>]+)`)
+	scriptIDPattern    = regexp.MustCompile(`(?is)\bid\s*=\s*["']?([^"'\s>]+)`)
+	stateGlobalPattern = regexp.MustCompile(`window\s*(?:\.\s*|\[\s*["'])(__[A-Za-z][A-Za-z0-9_]*__)(?:["']\s*\])?\s*=\s*`)
+	jsonParsePattern   = regexp.MustCompile(`^JSON\.parse\(\s*("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`)
+)
+
+// extractPageData returns the hidden form fields, JSON-LD blocks and state
+// blobs (window.__INITIAL_STATE__, __NEXT_DATA__ and the like) of an HTML
+// page as virtual documents. JSON is flattened into key: "value" lines so the
+// key/value patterns match across the JSON syntax; see flattenJSON.
+func extractPageData(page string) []embeddedDocument {
+	if !looksLikeHTML(page) {
+		return nil
+	}
+	var documents []embeddedDocument
+
+	var fields []string
+	for _, input := range hiddenInputPattern.FindAllString(page, -1) {
+		attributes := make(map[string]string)
+		for _, match := range attributePattern.FindAllStringSubmatch(input, -1) {
+			attributes[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+		}
+		name := attributes["name"]
+		if name == "" {
+			name = attributes["id"]
+		}
+		if value := attributes["value"]; name != "" && value != "" {
+			fields = append(fields, fmt.Sprintf("%s: %q", name, value))
+		}
+	}
+	if len(fields) > 0 {
+		documents = append(documents, embeddedDocument{name: "hidden-inputs", content: strings.Join(fields, "\n")})
+	}
+
+	jsonLD := 0
+	for _, match := range scriptBlockPattern.FindAllStringSubmatch(page, -1) {
+		attributes, body := match[1], strings.TrimSpace(match[2])
+		var scriptType, id string
+		if m := scriptTypePattern.FindStringSubmatch(attributes); m != nil {
+			scriptType = strings.ToLower(m[1])
+		}
+		if m := scriptIDPattern.FindStringSubmatch(attributes); m != nil {
+			id = m[1]
+		}
+
+		switch {
+		case body == "":
+		case scriptType == "application/ld+json":
+			jsonLD++
+			documents = append(documents, embeddedDocument{name: fmt.Sprintf("json-ld-%d", jsonLD), content: flattenJSON(body)})
+		case scriptType == "application/json" && id != "":
+			documents = append(documents, embeddedDocument{name: "state:" + id, content: flattenJSON(body)})
+		case scriptType == "" || strings.Contains(scriptType, "javascript"):
+			for _, global := range stateGlobalPattern.FindAllStringSubmatchIndex(body, -1) {
+				if value := stateValue(body[global[1]:]); value != "" {
+					documents = append(documents, embeddedDocument{name: "state:" + body[global[2]:global[3]], content: flattenJSON(value)})
+				}
+			}
+		}
+	}
+	return documents
+}
+
+// stateValue returns the object or array literal at the start of code, or
+// the string passed to JSON.parse there, decoded
+func stateValue(code string) string {
+	if match := jsonParsePattern.FindStringSubmatch(code); match != nil {
+		literal := match[1]
+		if literal[0] == '\'' {
+			literal = `"` + strings.ReplaceAll(strings.ReplaceAll(literal[1:len(literal)-1], `\'`, `'`), `"`, `\"`) + `"`
+		}
+		if decoded, err := strconv.Unquote(literal); err == nil {
+			return decoded
+		}
+		return ""
+	}
+	if code == "" || (code[0] != '{' && code[0] != '[') {
+		return ""
+	}
+
+	// Find the matching bracket, skipping over strings
+	depth := 0
+	var quote byte
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return code[:i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// flattenJSON renders every string in a JSON document as a key: "value" line
+// named after its closest object key, in a stable order. Text that is not
+// JSON, such as a JavaScript object literal, is returned as is.
+func flattenJSON(text string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return text
+	}
+	var lines []string
+	var walk func(key string, value interface{})
+	walk = func(key string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(k, v[k])
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(key, item)
+			}
+		case string:
+			if v != "" {
+				lines = append(lines, fmt.Sprintf("%s: %q", key, v))
+			}
+		}
+	}
+	walk("value", value)
+	return strings.Join(lines, "\n")
+}
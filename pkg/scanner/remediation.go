@@ -0,0 +1,193 @@
+package scanner
+
+// Remediation is the guidance attached to findings with --remediation, for
+// reports handed to whoever owns the exposed credential
+type Remediation struct {
+	Summary    string   `json:"summary" yaml:"summary"`                           // What to do about the finding
+	Rotation   string   `json:"rotation,omitempty" yaml:"rotation"`               // How to revoke or rotate the credential, if it is one
+	References []string `json:"references,omitempty" yaml:"references,omitempty"` // Provider documentation
+}
+
+// secretsManagementGuide is the general reference for keeping credentials
+// out of client-side code
+const secretsManagementGuide = "https://cheatsheetseries.owasp.org/cheatsheets/Secrets_Management_Cheat_Sheet.html"
+
+// remediations is the knowledge base behind --remediation, by pattern type
+var remediations = map[string]Remediation{
+	"AWS_ACCESS_KEY": {
+		Summary:  "Treat the key as compromised: any client can read it from the script. Remove it from the bundle and call AWS from a backend or with short-lived credentials from Amazon Cognito.",
+		Rotation: "Create a new access key for the IAM user, switch its legitimate consumers to it, then deactivate and delete the exposed key. Review CloudTrail for calls made with the exposed key ID.",
+		References: []string{
+			"https://docs.aws.amazon.com/IAM/latest/UserGuide/id_credentials_access-keys.html#rotating_access_keys_console",
+			"https://docs.aws.amazon.com/IAM/latest/UserGuide/best-practices.html",
+		},
+	},
+	"AWS_SECRET_KEY": {
+		Summary:  "Treat the key pair as compromised. Remove the secret from the bundle and call AWS from a backend or with short-lived credentials from Amazon Cognito.",
+		Rotation: "Identify the access key ID the secret belongs to, create a replacement key, switch legitimate consumers to it, then deactivate and delete the exposed key. Review CloudTrail for its use.",
+		References: []string{
+			"https://docs.aws.amazon.com/IAM/latest/UserGuide/id_credentials_access-keys.html#rotating_access_keys_console",
+		},
+	},
+	"AWS_SESSION_TOKEN": {
+		Summary:  "Temporary credentials stay valid until they expire. Do not ship them in scripts; have clients request their own through Amazon Cognito or a backend.",
+		Rotation: "Revoke active sessions for the role the token was issued for, which denies every session issued before the revocation time.",
+		References: []string{
+			"https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_use_revoke-sessions.html",
+		},
+	},
+	"GCP_API_KEY": {
+		Summary:  "Google API keys in browser code are visible to anyone. Restrict the key to the APIs it needs and to the site's HTTP referrers, or move the calls server-side.",
+		Rotation: "If the key was unrestricted or is used by a server, create a new key with restrictions, update its consumers, then delete the exposed key in the Google Cloud console.",
+		References: []string{
+			"https://cloud.google.com/docs/authentication/api-keys",
+		},
+	},
+	"GCP_SERVICE_KEY": {
+		Summary:  "A service account key grants the account's full permissions and must never reach a client. Remove it and use a backend or workload identity federation instead.",
+		Rotation: "Delete the exposed key from the service account (IAM > Service Accounts > Keys), issue a new key only where one is unavoidable, and review Cloud Audit Logs for its use.",
+		References: []string{
+			"https://cloud.google.com/iam/docs/keys-create-delete",
+			"https://cloud.google.com/iam/docs/best-practices-for-managing-service-account-keys",
+		},
+	},
+	"FIREBASE_API_KEY": {
+		Summary: "Firebase API keys identify the project and are expected in client code; they are not secrets. Make sure Security Rules and App Check protect the project's data instead of the key.",
+		References: []string{
+			"https://firebase.google.com/docs/projects/api-keys",
+			"https://firebase.google.com/docs/rules",
+		},
+	},
+	"GITHUB_TOKEN": {
+		Summary:  "The token acts as its owner on GitHub. Remove it from the bundle; browser code should never hold a GitHub token.",
+		Rotation: "Revoke the token in the owner's Developer settings (or the organisation's token policy) and issue a fine-grained token with the minimum scope where one is still needed. Check the audit log for its use.",
+		References: []string{
+			"https://docs.github.com/en/authentication/keeping-your-account-and-data-secure/managing-your-personal-access-tokens",
+			"https://docs.github.com/en/authentication/keeping-your-account-and-data-secure/token-expiration-and-revocation",
+		},
+	},
+	"JWT_TOKEN": {
+		Summary:  "A JWT embedded in a script is usable by anyone until it expires. Decode it to see who it was issued to and when it expires, and stop embedding tokens at build time.",
+		Rotation: "Revoke the session if the issuer supports it. If the token does not expire or cannot be revoked, rotate the signing key, which invalidates every token it signed.",
+		References: []string{
+			"https://datatracker.ietf.org/doc/html/rfc8725",
+		},
+	},
+	"OAUTH_TOKEN": {
+		Summary:  "The access token grants its scopes to whoever holds it. Remove it from the bundle and obtain tokens at runtime with the authorization code flow and PKCE.",
+		Rotation: "Revoke the token, and any refresh token issued with it, through the provider's revocation endpoint or console.",
+		References: []string{
+			"https://datatracker.ietf.org/doc/html/rfc7009",
+			"https://datatracker.ietf.org/doc/html/rfc9700",
+		},
+	},
+	"API_KEY": {
+		Summary:  "Check which service the key belongs to and whether it is meant to be public. Keys that grant access to data or paid usage belong on a backend that proxies the calls.",
+		Rotation: "Regenerate the key in the provider's console, update its legitimate consumers, then revoke the exposed key.",
+		References: []string{
+			secretsManagementGuide,
+		},
+	},
+	"DATABASE_URL": {
+		Summary:  "Database credentials must never reach a client. Remove the connection string and make sure the database is not reachable from the internet.",
+		Rotation: "Change the database user's password (or drop and recreate the user), update the application's secret store, and review the database logs for connections from unknown addresses.",
+		References: []string{
+			secretsManagementGuide,
+			"https://cheatsheetseries.owasp.org/cheatsheets/Database_Security_Cheat_Sheet.html",
+		},
+	},
+	"PASSWORD": {
+		Summary:  "Confirm whether the value is a real credential. If so, remove it from the bundle; client code cannot keep a password secret.",
+		Rotation: "Change the password wherever it is used, including any other account sharing it, and check the account's login history.",
+		References: []string{
+			secretsManagementGuide,
+		},
+	},
+	"URL_CREDENTIALS": {
+		Summary:  "The URL carries a username and password that every visitor can read. Remove them and authenticate the request server-side.",
+		Rotation: "Change the password of the account in the URL and check the service's access logs for its use.",
+		References: []string{
+			secretsManagementGuide,
+		},
+	},
+	"SECRET": {
+		Summary:  "Identify what the secret protects: signing keys, client secrets and encryption keys must stay on the server. Remove it from the bundle.",
+		Rotation: "Generate a new secret, deploy it to the server side only, and retire the exposed value. Anything signed or encrypted with it should be considered forgeable or readable.",
+		References: []string{
+			secretsManagementGuide,
+		},
+	},
+	"SLACK_TOKEN": {
+		Summary:  "Slack tokens can read and post in the workspace. Remove the token from the bundle and make Slack calls from a backend.",
+		Rotation: "Revoke the token with auth.revoke or by reinstalling the app, regenerate it in the app's settings, and review the workspace access logs.",
+		References: []string{
+			"https://api.slack.com/methods/auth.revoke",
+			"https://api.slack.com/authentication/best-practices",
+		},
+	},
+	"STRIPE_KEY": {
+		Summary:  "Publishable keys (pk_) are meant for client code. Secret and restricted keys (sk_, rk_) allow charges and refunds and must be removed from the bundle immediately.",
+		Rotation: "For a secret or restricted key, roll it in the Stripe Dashboard (Developers > API keys), update the backend, and review the account's request logs.",
+		References: []string{
+			"https://docs.stripe.com/keys",
+		},
+	},
+	"TWILIO_SID": {
+		Summary:  "An Account SID identifies the account and is not a credential by itself. Check the surrounding code for an auth token or API key secret shipped alongside it.",
+		Rotation: "If an auth token was exposed too, promote the secondary auth token in the Twilio Console and delete the old one.",
+		References: []string{
+			"https://www.twilio.com/docs/iam/api/authtoken",
+		},
+	},
+	"API_ENDPOINT": {
+		Summary: "Endpoints referenced by client code are public knowledge. Make sure each one enforces authentication and authorization on the server and does not rely on being undiscovered.",
+		References: []string{
+			"https://owasp.org/API-Security/",
+		},
+	},
+	"INTERNAL_ENDPOINT": {
+		Summary: "Internal and admin paths in a public script reveal the attack surface. Remove paths the client does not need and make sure the rest enforce authorization on the server.",
+		References: []string{
+			"https://owasp.org/API-Security/",
+		},
+	},
+	"WORKER_SCRIPT": {
+		Summary: "Worker scripts run with the page's origin and intercept its requests. Review them for hardcoded secrets and make sure they are served from the site's own origin.",
+		References: []string{
+			"https://developer.mozilla.org/en-US/docs/Web/API/Service_Worker_API",
+		},
+	},
+	"WEBSOCKET_ENDPOINT": {
+		Summary: "Make sure the server authenticates the handshake, checks the Origin header and authorizes every message, since the socket is reachable by anyone who reads the script.",
+		References: []string{
+			"https://cheatsheetseries.owasp.org/cheatsheets/HTML5_Security_Cheat_Sheet.html#websockets",
+		},
+	},
+}
+
+// genericRemediation is the guidance for types the knowledge base does not cover
+var genericRemediation = Remediation{
+	Summary:    "Review the match and, if it is a credential, remove it from client-side code and serve it from a backend.",
+	Rotation:   "Revoke or regenerate the credential with its issuer and update its legitimate consumers.",
+	References: []string{secretsManagementGuide},
+}
+
+// getRemediation returns the guidance for a pattern type; a pattern file can
+// supply its own for custom patterns
+func (s *Scanner) getRemediation(patternType string) *Remediation {
+	if custom := s.custom[patternType]; custom != nil && custom.remediation != nil {
+		return custom.remediation
+	}
+	if remediation, exists := remediations[patternType]; exists {
+		return &remediation
+	}
+	remediation := genericRemediation
+	return &remediation
+}
+
+// attachRemediation adds guidance to every finding, for --remediation
+func (s *Scanner) attachRemediation() {
+	for i := range s.results {
+		s.results[i].Remediation = s.getRemediation(s.results[i].Type)
+	}
+}
@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"jsfinder/pkg/utils"
+)
+
+// partialDownload is what the attempts of one download have received so far.
+// A retry asks for the rest with a Range request when the server supports
+// it, instead of starting again from the first byte.
+type partialDownload struct {
+	data      []byte
+	validator string // Strong ETag or Last-Modified of the response the data is from, sent as If-Range
+	resumable bool   // The server accepts byte ranges and the body was not decompressed on the fly
+}
+
+// fetchAttempt makes one attempt at downloading jsURL into partial, resuming
+// after the bytes a previous attempt received where possible
+func (s *Scanner) fetchAttempt(ctx context.Context, opID, jsURL string, partial *partialDownload) error {
+	s.timeoutMgr.SendHeartbeat(opID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", jsURL, nil)
+	if err != nil {
+		return utils.NewNetworkError(fmt.Sprintf("failed to create request for %s", jsURL), err)
+	}
+	resuming := partial.resumable && len(partial.data) > 0
+	if resuming {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", len(partial.data)))
+		req.Header.Set("If-Range", partial.validator)
+		// The offset counts bytes as first sent, so keep them uncompressed
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return utils.NewNetworkError(fmt.Sprintf("failed to fetch %s", jsURL), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resuming && resp.StatusCode == http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != len(partial.data) {
+			*partial = partialDownload{}
+			return utils.NewNetworkError(fmt.Sprintf("unexpected Content-Range %q resuming %s", resp.Header.Get("Content-Range"), jsURL), nil)
+		}
+		s.logger.WithField("target", jsURL).Infof("Resuming download at %s", utils.FormatBytes(int64(len(partial.data))))
+		s.stats.AddResumed(int64(len(partial.data)))
+	case resp.StatusCode == http.StatusOK:
+		// A fresh copy, because this is the first attempt, the file changed
+		// or the server ignored the Range header
+		*partial = partialDownload{validator: rangeValidator(resp.Header)}
+		partial.resumable = resp.Header.Get("Accept-Ranges") == "bytes" && partial.validator != "" &&
+			!resp.Uncompressed && resp.Header.Get("Content-Encoding") == ""
+	default:
+		*partial = partialDownload{}
+		return utils.NewHTTPError(fmt.Sprintf("HTTP %d: %s", resp.StatusCode, jsURL), resp.StatusCode, nil)
+	}
+
+	buffer := bytes.NewBuffer(partial.data)
+	_, err = buffer.ReadFrom(s.timeoutMgr.HeartbeatReader(opID, resp.Body))
+	partial.data = buffer.Bytes()
+	if err != nil {
+		return utils.NewNetworkError(fmt.Sprintf("failed to read %s after %s", jsURL, utils.FormatBytes(int64(len(partial.data)))), err)
+	}
+	return nil
+}
+
+// rangeValidator returns the validator a resumed request must match for the
+// server to send a range rather than the whole file: a strong ETag, since
+// If-Range does not accept weak ones, or else Last-Modified
+func rangeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the first byte position of a
+// "bytes start-end/size" Content-Range header
+func contentRangeStart(contentRange string) (int, bool) {
+	spec, found := strings.CutPrefix(contentRange, "bytes ")
+	if !found {
+		return 0, false
+	}
+	start, _, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(start))
+	return n, err == nil
+}
@@ -0,0 +1,261 @@
+package scanner
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SIEM message formats
+const (
+	SIEMFormatCEF    = "cef"    // ArcSight Common Event Format, carried in a syslog message
+	SIEMFormatSyslog = "syslog" // RFC 5424 syslog with the finding as structured data
+)
+
+// siemEnterpriseID is the private enterprise number RFC 5612 reserves for
+// documentation, used to name the structured data element
+const siemEnterpriseID = "32473"
+
+// syslogFacility is the local0 facility
+const syslogFacility = 16
+
+// SIEMSender streams findings to a SIEM or syslog collector, one message per
+// finding, so results can be ingested without a parser for the output file.
+// UDP sends one message per datagram; TCP and TLS frame messages with an
+// octet count (RFC 6587, RFC 5425). A nil *SIEMSender sends nothing.
+type SIEMSender struct {
+	network  string
+	format   string
+	version  string
+	hostname string
+	timeout  time.Duration // Write deadline of each message, so a stalled collector cannot hold up the scan
+	conn     net.Conn
+	failed   bool // A write failed; the connection is not used again
+	mutex    sync.Mutex
+}
+
+// NewSIEMSender connects to target, given as udp://, tcp:// or tls://
+// host[:port], and sends messages in format. The port defaults to 514, or
+// 6514 for TLS. version is reported as the CEF device version.
+func NewSIEMSender(target, format, version string, timeout time.Duration) (*SIEMSender, error) {
+	format = strings.ToLower(format)
+	if format != SIEMFormatCEF && format != SIEMFormatSyslog {
+		return nil, fmt.Errorf("invalid SIEM format %q: must be %s or %s", format, SIEMFormatCEF, SIEMFormatSyslog)
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid SIEM target %q: expected udp://, tcp:// or tls://host[:port]", target)
+	}
+
+	network := strings.ToLower(parsed.Scheme)
+	port := parsed.Port()
+	switch network {
+	case "udp", "tcp":
+		if port == "" {
+			port = "514"
+		}
+	case "tls":
+		if port == "" {
+			port = "6514"
+		}
+	default:
+		return nil, fmt.Errorf("invalid SIEM target %q: scheme must be udp, tcp or tls", target)
+	}
+	address := net.JoinHostPort(parsed.Hostname(), port)
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: parsed.Hostname()})
+	} else {
+		conn, err = dialer.Dial(network, address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SIEM %s: %w", target, err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &SIEMSender{
+		network:  network,
+		format:   format,
+		version:  version,
+		hostname: hostname,
+		timeout:  timeout,
+		conn:     conn,
+	}, nil
+}
+
+// Send writes one message per finding. The first failure is returned and
+// ends the stream: later calls send nothing, since a partly written TCP
+// message would corrupt the framing of the next one.
+func (s *SIEMSender) Send(findings []Finding) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, finding := range findings {
+		if s.failed {
+			return nil
+		}
+		message := s.syslogMessage(finding, time.Now())
+		if s.network != "udp" {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+		if s.timeout > 0 {
+			s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+		}
+		if _, err := s.conn.Write([]byte(message)); err != nil {
+			s.failed = true
+			return fmt.Errorf("failed to send finding %d of %d to SIEM, no more findings will be sent: %w", i+1, len(findings), err)
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the collector
+func (s *SIEMSender) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// syslogMessage formats a finding as an RFC 5424 message. In CEF format the
+// message body is the CEF record and the structured data is left empty.
+func (s *SIEMSender) syslogMessage(finding Finding, now time.Time) string {
+	header := fmt.Sprintf("<%d>1 %s %s jsfinder %d %s",
+		syslogFacility*8+syslogSeverity(finding.Confidence),
+		now.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		os.Getpid(),
+		syslogName(finding.Type))
+
+	if s.format == SIEMFormatCEF {
+		return header + " - " + s.cefRecord(finding)
+	}
+
+	params := []struct{ name, value string }{
+		{"url", finding.URL},
+		{"source", finding.Source},
+		{"confidence", finding.Confidence},
+		{"line", strconv.Itoa(finding.LineNumber)},
+		{"column", strconv.Itoa(finding.Column)},
+		{"location", finding.Location},
+		{"match", finding.Match},
+	}
+	var data strings.Builder
+	data.WriteString("[finding@" + siemEnterpriseID)
+	for _, param := range params {
+		if param.value != "" {
+			fmt.Fprintf(&data, ` %s="%s"`, param.name, sdEscaper.Replace(param.value))
+		}
+	}
+	data.WriteString("]")
+	if len(finding.Labels) > 0 {
+		data.WriteString("[labels@" + siemEnterpriseID)
+		for _, key := range finding.Labels.Keys() {
+			fmt.Fprintf(&data, ` %s="%s"`, key, sdEscaper.Replace(finding.Labels[key]))
+		}
+		data.WriteString("]")
+	}
+
+	return fmt.Sprintf("%s %s %s in %s", header, data.String(), finding.Description, finding.URL)
+}
+
+// cefRecord formats a finding as a CEF:0 record
+func (s *SIEMSender) cefRecord(finding Finding) string {
+	extensions := []struct{ key, value string }{
+		{"request", finding.URL},
+		{"cs1Label", "confidence"},
+		{"cs1", finding.Confidence},
+		{"cs2Label", "match"},
+		{"cs2", finding.Match},
+		{"cs3Label", "location"},
+		{"cs3", finding.Location},
+		{"cn1Label", "line"},
+		{"cn1", strconv.Itoa(finding.LineNumber)},
+		{"cn2Label", "column"},
+		{"cn2", strconv.Itoa(finding.Column)},
+	}
+	if finding.Source != "" {
+		extensions = append(extensions, struct{ key, value string }{"cs4Label", "source"}, struct{ key, value string }{"cs4", finding.Source})
+	}
+	if len(finding.Labels) > 0 {
+		extensions = append(extensions, struct{ key, value string }{"cs5Label", "labels"}, struct{ key, value string }{"cs5", finding.Labels.String()})
+	}
+
+	fields := make([]string, len(extensions))
+	for i, extension := range extensions {
+		fields[i] = extension.key + "=" + cefExtensionEscaper.Replace(extension.value)
+	}
+
+	return fmt.Sprintf("CEF:0|jsfinder|jsfinder|%s|%s|%s|%d|%s",
+		cefHeaderEscaper.Replace(s.version),
+		cefHeaderEscaper.Replace(finding.Type),
+		cefHeaderEscaper.Replace(finding.Description),
+		cefSeverity(finding.Confidence),
+		strings.Join(fields, " "))
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+	sdEscaper           = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+)
+
+// cefSeverity maps a confidence level onto CEF's 0-10 severity
+func cefSeverity(confidence string) int {
+	switch confidence {
+	case "HIGH":
+		return 8
+	case "MEDIUM":
+		return 5
+	default:
+		return 3
+	}
+}
+
+// syslogSeverity maps a confidence level onto a syslog severity: critical,
+// warning or notice
+func syslogSeverity(confidence string) int {
+	switch confidence {
+	case "HIGH":
+		return 2
+	case "MEDIUM":
+		return 4
+	default:
+		return 5
+	}
+}
+
+// syslogName makes a value fit RFC 5424's header fields: printable ASCII
+// without spaces, at most 32 characters
+func syslogName(value string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+	if name == "" {
+		return "-"
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Reasons a file is skipped without being scanned
+const (
+	SkipBinary    = "binary"    // Not text: an image, font, wasm module or other blob served as a script
+	SkipAnalytics = "analytics" // A known analytics or tag-manager bundle
+)
+
+const (
+	binarySniffSize      = 8192 // Bytes inspected to decide whether a file is text
+	maxNonPrintableRatio = 0.3  // Share of control characters and invalid UTF-8 above which a file is binary
+)
+
+var (
+	// analyticsHosts serve analytics and tag-manager bundles, which are large,
+	// identical on every site and full of public IDs that look like keys
+	analyticsHosts = []string{
+		"googletagmanager.com", "google-analytics.com", "connect.facebook.net",
+		"static.hotjar.com", "script.hotjar.com", "cdn.segment.com", "cdn.mxpnl.com", "cdn.amplitude.com",
+		"snap.licdn.com", "bat.bing.com", "static.ads-twitter.com", "js.hs-analytics.net", "js.hs-scripts.com",
+		"cdn.heapanalytics.com", "static.cloudflareinsights.com", "cdn.optimizely.com", "tags.tiqcdn.com",
+		"assets.adobedtm.com", "plausible.io",
+	}
+	// analyticsFilePattern matches the names those bundles keep when self-hosted
+	analyticsFilePattern = regexp.MustCompile(`^(?:gtm|gtag|ga|analytics|fbevents|hotjar-[\d.]+|utag(?:\.sync)?|beacon\.min)\.js$`)
+)
+
+// skipReason reports why a document should not be scanned, or "" to scan it
+func skipReason(docURL string, content []byte) string {
+	if isAnalyticsBundle(docURL) {
+		return SkipAnalytics
+	}
+	if looksBinary(content) {
+		return SkipBinary
+	}
+	return ""
+}
+
+// isAnalyticsBundle reports whether a document is a known analytics or
+// tag-manager bundle, by host or, for self-hosted copies and archive
+// entries, by file name
+func isAnalyticsBundle(docURL string) bool {
+	filePath := docURL
+	if _, entry, found := strings.Cut(docURL, "!/"); found {
+		filePath = entry
+	} else if parsed, err := url.Parse(docURL); err == nil {
+		host := strings.ToLower(parsed.Hostname())
+		for _, analyticsHost := range analyticsHosts {
+			if host == analyticsHost || strings.HasSuffix(host, "."+analyticsHost) {
+				return true
+			}
+		}
+		filePath = parsed.Path
+	}
+
+	return analyticsFilePattern.MatchString(strings.ToLower(path.Base(filePath)))
+}
+
+// looksBinary reports whether content is not text: it contains a NUL byte, or
+// too much of its start is control characters or invalid UTF-8
+func looksBinary(content []byte) bool {
+	sample := content[:min(len(content), binarySniffSize)]
+
+	chars, nonPrintable := 0, 0
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		switch {
+		case r == 0:
+			return true
+		case r == utf8.RuneError && size == 1, r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f', r == 0x7f:
+			nonPrintable++
+		}
+		chars++
+		sample = sample[size:]
+	}
+
+	return chars > 0 && float64(nonPrintable)/float64(chars) > maxNonPrintableRatio
+}
+
+// skipped reports whether a document is skipped, logging and recording it
+// in the run summary if so; --no-skip scans everything but excluded URLs
+func (s *Scanner) skipped(docURL string, content []byte) bool {
+	var reason string
+	if s.excluded(docURL) {
+		reason = SkipExcluded
+	} else if !s.config.NoSkip {
+		reason = skipReason(docURL, content)
+	}
+	if reason == "" {
+		return false
+	}
+
+	s.logger.WithField("target", docURL).Infof("Skipping %s file", reason)
+	s.stats.AddSkippedFile(docURL, reason)
+	return true
+}
@@ -0,0 +1,49 @@
+package scanner
+
+import "sort"
+
+// Orderings for Config.Sort. Ties are broken on every remaining field so the
+// same findings are always written in the same order.
+const (
+	SortURL      = "url"      // By URL, then position in the file
+	SortSeverity = "severity" // HIGH, MEDIUM, LOW, then as for SortURL
+	SortRecent   = "recent"   // Most recently introduced first (directory scans in git), then as for SortURL
+)
+
+var confidenceRank = map[string]int{"HIGH": 0, "MEDIUM": 1, "LOW": 2}
+
+// sortFindings orders findings in place; an empty ordering leaves them as found
+func sortFindings(findings []Finding, by string) {
+	if by == "" {
+		return
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if by == SortSeverity && a.Confidence != b.Confidence {
+			return rank(a.Confidence) < rank(b.Confidence)
+		}
+		// RFC 3339 UTC times order as strings; findings without one go last
+		if by == SortRecent && a.Introduced != b.Introduced {
+			return a.Introduced > b.Introduced
+		}
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if a.OffsetStart != b.OffsetStart {
+			return a.OffsetStart < b.OffsetStart
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Match < b.Match
+	})
+}
+
+// rank places unknown confidence levels after the known ones
+func rank(confidence string) int {
+	if r, known := confidenceRank[confidence]; known {
+		return r
+	}
+	return len(confidenceRank)
+}
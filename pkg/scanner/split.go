@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// severityFiles lists the confidence levels written by Config.SplitBySeverity,
+// each to its own file named after the level
+var severityFiles = []string{"HIGH", "MEDIUM", "LOW"}
+
+// outputBySeverity writes the findings of each confidence level to its own
+// file (high.json, medium.json, low.json) inside the OutputFile directory, so
+// each can be handed to the team triaging that level. Every level gets a file,
+// empty or not, so a missing file never has to be told apart from a clean one.
+func (s *Scanner) outputBySeverity() error {
+	dir := s.config.OutputFile
+	if dir == "" {
+		return fmt.Errorf("splitting findings by severity requires an output directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	groups := make(map[string][]Finding)
+	for _, finding := range s.results {
+		groups[finding.Confidence] = append(groups[finding.Confidence], finding)
+	}
+
+	extension := strings.ToLower(s.config.Format)
+	if extension != "csv" && extension != "txt" {
+		extension = "json"
+	}
+
+	for _, level := range severityFiles {
+		path := filepath.Join(dir, strings.ToLower(level)+"."+extension)
+		if err := s.writeSeverityFile(path, groups[level]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scanner) writeSeverityFile(path string, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	return s.writeFindings(file, findings)
+}
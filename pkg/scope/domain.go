@@ -0,0 +1,51 @@
+package scope
+
+import (
+	"net"
+	"net/url"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// RegistrableDomain returns the domain a host was registered under: its
+// public suffix plus one label, so www.example.co.uk gives example.co.uk
+// rather than co.uk. IP addresses and hosts without a public suffix are
+// returned as they are.
+func RegistrableDomain(host string) string {
+	host = normalizeHost(host)
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// SameSite reports whether two URLs are on the same registrable domain, so
+// api.example.com and www.example.com match while example.co.uk and
+// other.co.uk do not. URLs without a host, such as mailto: links, match
+// nothing.
+func SameSite(a, b string) bool {
+	domain := urlDomain(a)
+	return domain != "" && domain == urlDomain(b)
+}
+
+// AllowsLinked reports whether a URL found on a page or in a file at source
+// may be followed. It must be allowed by the scope and, unless the scope has
+// allow rules naming the assets in scope, be on source's registrable domain.
+func (s *Scope) AllowsLinked(target, source string) bool {
+	if !s.AllowsURL(target) {
+		return false
+	}
+	return s.HasAllowRules() || SameSite(target, source)
+}
+
+// urlDomain returns the registrable domain of a URL's host
+func urlDomain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return RegistrableDomain(parsed.Hostname())
+}
@@ -0,0 +1,236 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrOutOfScope is returned when a request targets an asset outside the engagement scope
+var ErrOutOfScope = errors.New("target is out of scope")
+
+// File is the on-disk representation of a scope file
+type File struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Scope holds allow/deny rules for hosts and networks. Deny rules always win;
+// when no allow rules are present everything not denied is in scope.
+// A nil *Scope allows everything.
+type Scope struct {
+	allowHosts []string
+	denyHosts  []string
+	allowNets  []*net.IPNet
+	denyNets   []*net.IPNet
+}
+
+// Load reads a scope file from disk
+func Load(path string) (*Scope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scope file: %w", err)
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse scope file: %w", err)
+	}
+
+	return New(file.Allow, file.Deny)
+}
+
+// New builds a scope from allow and deny rules. Each rule is a hostname,
+// a wildcard such as *.example.com, an IP address or a CIDR range.
+func New(allow, deny []string) (*Scope, error) {
+	s := &Scope{}
+
+	for _, rule := range allow {
+		if err := s.addRule(rule, &s.allowHosts, &s.allowNets); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range deny {
+		if err := s.addRule(rule, &s.denyHosts, &s.denyNets); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Scope) addRule(rule string, hosts *[]string, nets *[]*net.IPNet) error {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	if rule == "" {
+		return nil
+	}
+
+	if strings.Contains(rule, "/") {
+		_, network, err := net.ParseCIDR(rule)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR rule %q: %w", rule, err)
+		}
+		*nets = append(*nets, network)
+		return nil
+	}
+
+	if ip := net.ParseIP(rule); ip != nil {
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		*nets = append(*nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		return nil
+	}
+
+	*hosts = append(*hosts, strings.TrimSuffix(rule, "."))
+	return nil
+}
+
+// AllowsURL reports whether a URL's host may be contacted
+func (s *Scope) AllowsURL(rawURL string) bool {
+	if s == nil {
+		return true
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return false
+	}
+	return s.AllowsHost(parsed.Hostname())
+}
+
+// HasAllowRules reports whether the scope names the assets in scope, rather
+// than only excluding some
+func (s *Scope) HasAllowRules() bool {
+	return s != nil && (len(s.allowHosts) > 0 || len(s.allowNets) > 0)
+}
+
+// AllowsHost makes the decision that is possible from the hostname alone.
+// Hosts that could still be allowed by a CIDR rule are accepted here and
+// checked again against their resolved addresses at dial time.
+func (s *Scope) AllowsHost(host string) bool {
+	if s == nil {
+		return true
+	}
+
+	host = normalizeHost(host)
+	if ip := net.ParseIP(host); ip != nil {
+		return s.Allows(host, ip)
+	}
+
+	if matchesAny(host, s.denyHosts) {
+		return false
+	}
+	if len(s.allowHosts) == 0 && len(s.allowNets) == 0 {
+		return true
+	}
+	return matchesAny(host, s.allowHosts) || len(s.allowNets) > 0
+}
+
+// AllowsUnresolved decides for a host that is never resolved locally, as
+// when connections go through a bastion: CIDR rules then only match IP
+// literals, so a host name needs a host rule of its own to be allowed.
+func (s *Scope) AllowsUnresolved(host string) bool {
+	if s == nil {
+		return true
+	}
+
+	host = normalizeHost(host)
+	if ip := net.ParseIP(host); ip != nil {
+		return s.Allows(host, ip)
+	}
+	if matchesAny(host, s.denyHosts) {
+		return false
+	}
+	return !s.HasAllowRules() || matchesAny(host, s.allowHosts)
+}
+
+// Allows reports whether a host resolved to ip is in scope
+func (s *Scope) Allows(host string, ip net.IP) bool {
+	if s == nil {
+		return true
+	}
+
+	host = normalizeHost(host)
+	if matchesAny(host, s.denyHosts) || containsIP(s.denyNets, ip) {
+		return false
+	}
+	if len(s.allowHosts) == 0 && len(s.allowNets) == 0 {
+		return true
+	}
+	return matchesAny(host, s.allowHosts) || containsIP(s.allowNets, ip)
+}
+
+// DialContext wraps a dialer so connections are only opened to in-scope
+// addresses, which also catches hostnames resolving into denied networks.
+func (s *Scope) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if s == nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error = fmt.Errorf("%w: %s", ErrOutOfScope, host)
+		for _, addr := range addrs {
+			if !s.Allows(host, addr.IP) {
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// matchesAny reports whether host matches one of the host rules
+func matchesAny(host string, rules []string) bool {
+	for _, rule := range rules {
+		if strings.HasPrefix(rule, "*.") {
+			if strings.HasSuffix(host, rule[1:]) {
+				return true
+			}
+		} else if host == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// containsIP reports whether any network contains ip
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost lowercases a host and strips brackets and trailing dots
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimPrefix(strings.TrimSuffix(host, "]"), "[")
+	return strings.TrimSuffix(host, ".")
+}
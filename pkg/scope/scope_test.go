@@ -0,0 +1,142 @@
+package scope
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScope_AllowsHost(t *testing.T) {
+	s, err := New(
+		[]string{"example.com", "*.example.com", "10.0.0.0/8"},
+		[]string{"admin.example.com", "10.0.5.0/24"},
+	)
+	if err != nil {
+		t.Fatalf("Failed to build scope: %v", err)
+	}
+
+	testCases := []struct {
+		host     string
+		ip       string
+		expected bool
+	}{
+		{"example.com", "93.184.216.34", true},
+		{"api.example.com", "93.184.216.34", true},
+		{"admin.example.com", "93.184.216.34", false},
+		{"other.org", "93.184.216.34", false},
+		{"internal.corp", "10.1.2.3", true},
+		{"internal.corp", "10.0.5.7", false},
+		{"api.example.com", "10.0.5.7", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host+"/"+tc.ip, func(t *testing.T) {
+			if result := s.Allows(tc.host, net.ParseIP(tc.ip)); result != tc.expected {
+				t.Errorf("Expected %v for %s (%s), got %v", tc.expected, tc.host, tc.ip, result)
+			}
+		})
+	}
+
+	if s.AllowsHost("admin.example.com") {
+		t.Error("Expected denied host to be rejected before resolution")
+	}
+
+	// Behind a custom dialer, CIDR rules only cover IP literals
+	if !s.AllowsUnresolved("api.example.com") || !s.AllowsUnresolved("10.1.2.3") {
+		t.Error("Expected host rules and IP literals to be decided without resolution")
+	}
+	if s.AllowsUnresolved("internal.corp") || s.AllowsUnresolved("10.0.5.7") {
+		t.Error("Expected a host name only a CIDR rule could allow to be rejected")
+	}
+}
+
+func TestScope_DenyOnly(t *testing.T) {
+	s, err := New(nil, []string{"*.gov"})
+	if err != nil {
+		t.Fatalf("Failed to build scope: %v", err)
+	}
+
+	if !s.AllowsURL("https://example.com/app.js") {
+		t.Error("Expected hosts not denied to be allowed without allow rules")
+	}
+	if s.AllowsURL("https://www.agency.gov/app.js") {
+		t.Error("Expected denied wildcard host to be rejected")
+	}
+
+	var nilScope *Scope
+	if !nilScope.AllowsURL("https://anything.test") {
+		t.Error("Expected nil scope to allow everything")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scope.yaml")
+	content := "allow:\n  - \"*.example.com\"\ndeny:\n  - 192.168.0.0/16\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write scope file: %v", err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load scope: %v", err)
+	}
+	if !s.AllowsURL("https://api.example.com") {
+		t.Error("Expected allowed host from file")
+	}
+
+	if _, err := New([]string{"10.0.0.0/99"}, nil); err == nil {
+		t.Error("Expected error for invalid CIDR")
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"www.example.com":        "example.com",
+		"API.Example.com.":       "example.com",
+		"cdn.shop.example.co.uk": "example.co.uk",
+		"example.github.io":      "example.github.io",
+		"192.0.2.10":             "192.0.2.10",
+		"[2001:db8::1]":          "2001:db8::1",
+		"localhost":              "localhost",
+		"":                       "",
+	}
+
+	for input, expected := range tests {
+		if domain := RegistrableDomain(input); domain != expected {
+			t.Errorf("RegistrableDomain(%q) = %q, expected %q", input, domain, expected)
+		}
+	}
+}
+
+func TestScope_AllowsLinked(t *testing.T) {
+	var nilScope *Scope
+	tests := []struct {
+		target   string
+		source   string
+		expected bool
+	}{
+		{"https://api.example.com/v1", "https://www.example.com/", true},
+		{"https://example.com:8443/", "https://example.com/", true},
+		{"https://shop.example.co.uk/", "https://www.example.co.uk/", true},
+		{"https://other.co.uk/", "https://www.example.co.uk/", false},
+		{"https://cdn.example.net/app.js", "https://example.com/", false},
+		{"mailto:test@example.com", "https://example.com/", false},
+	}
+	for _, tc := range tests {
+		if allowed := nilScope.AllowsLinked(tc.target, tc.source); allowed != tc.expected {
+			t.Errorf("AllowsLinked(%q, %q) = %v, expected %v", tc.target, tc.source, allowed, tc.expected)
+		}
+	}
+
+	s, err := New([]string{"example.com", "example.net"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to build scope: %v", err)
+	}
+	if !s.AllowsLinked("https://example.net/", "https://example.com/") {
+		t.Error("Expected allow rules to permit hosts on other registrable domains")
+	}
+	if s.AllowsLinked("https://evil.com/", "https://example.com/") {
+		t.Error("Expected hosts outside allow rules to be rejected")
+	}
+}
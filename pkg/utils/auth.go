@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Auth sends HTTP basic or bearer credentials with every request.
+// A nil *Auth leaves requests untouched.
+type Auth struct {
+	scheme string // Basic or Bearer
+	user   string // Basic auth user name, shown by String
+	value  string // Authorization header value
+}
+
+// NewAuth builds the credentials of the --auth-basic "user:pass" and
+// --auth-bearer token flags, which may be secret references (see
+// ResolveSecret). It returns nil when neither is set.
+func NewAuth(basic, bearer string) (*Auth, error) {
+	if basic != "" && bearer != "" {
+		return nil, NewValidationError("--auth-basic and --auth-bearer cannot be combined", nil)
+	}
+
+	if basic != "" {
+		credentials, err := resolveAuthValue(basic)
+		if err != nil {
+			return nil, err
+		}
+		user, _, found := strings.Cut(credentials, ":")
+		if !found || user == "" {
+			return nil, NewValidationError("invalid --auth-basic, expected \"user:password\"", nil)
+		}
+		return &Auth{
+			scheme: "Basic",
+			user:   user,
+			value:  "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)),
+		}, nil
+	}
+
+	if bearer != "" {
+		token, err := resolveAuthValue(bearer)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(token), "Bearer "))
+		if token == "" {
+			return nil, NewValidationError("--auth-bearer token is empty", nil)
+		}
+		return &Auth{scheme: "Bearer", value: "Bearer " + token}, nil
+	}
+	return nil, nil
+}
+
+// resolveAuthValue resolves a credential given as a secret reference
+func resolveAuthValue(value string) (string, error) {
+	if IsSecretRef(value) {
+		return ResolveSecret(value)
+	}
+	return value, nil
+}
+
+// String describes the credentials sent without revealing them
+func (a *Auth) String() string {
+	if a == nil {
+		return "none"
+	}
+	if a.user != "" {
+		return a.scheme + " " + a.user + ":<secret>"
+	}
+	return a.scheme + " <secret>"
+}
+
+// apply sets the Authorization header on a request that has none, so probes
+// setting their own credentials keep them
+func (a *Auth) apply(req *http.Request) {
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", a.value)
+	}
+}
+
+// applyRaw adds the Authorization header to the head of a raw request, its
+// request line and header lines joined by CRLF, unless it has one already
+func (a *Auth) applyRaw(head string) string {
+	lines := strings.Split(head, "\r\n")
+	for _, line := range lines[1:] {
+		name, _, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Authorization") {
+			return head
+		}
+	}
+	return head + "\r\nAuthorization: " + a.value
+}
+
+// authTransport adds the run's credentials to every request. Redirect hops
+// only get them while they stay on the host of the request that started the
+// chain or one of its subdomains, the rule net/http applies to an
+// Authorization header set by the caller.
+type authTransport struct {
+	base http.RoundTripper
+	auth *Auth
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !sameAuthHost(initialRequest(req).URL, req.URL) {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	t.auth.apply(req)
+	return t.base.RoundTrip(req)
+}
+
+// initialRequest returns the request that started req's redirect chain
+func initialRequest(req *http.Request) *http.Request {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req
+}
+
+// sameAuthHost reports whether credentials meant for initial may be sent to
+// next: the same host or a subdomain of it
+func sameAuthHost(initial, next *url.URL) bool {
+	origin := strings.ToLower(initial.Hostname())
+	host := strings.ToLower(next.Hostname())
+	return host == origin || strings.HasSuffix(host, "."+origin)
+}
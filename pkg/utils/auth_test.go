@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewAuth(t *testing.T) {
+	auth, err := NewAuth("", "")
+	if err != nil || auth != nil {
+		t.Fatalf("Expected nil auth without flags, got %v (%v)", auth, err)
+	}
+	if _, err := NewAuth("user:pass", "token"); err == nil {
+		t.Error("Expected error when combining basic and bearer auth")
+	}
+	if _, err := NewAuth("no-password", ""); err == nil {
+		t.Error("Expected error for basic credentials without a colon")
+	}
+
+	auth, err = NewAuth("alice:s3cret", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if auth.value != "Basic YWxpY2U6czNjcmV0" {
+		t.Errorf("Unexpected basic header %q", auth.value)
+	}
+	if got := auth.String(); got != "Basic alice:<secret>" {
+		t.Errorf("Expected password to be masked, got %q", got)
+	}
+
+	t.Setenv("JSFINDER_TEST_TOKEN", "Bearer abc123")
+	auth, err = NewAuth("", "env:JSFINDER_TEST_TOKEN")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if auth.value != "Bearer abc123" || auth.String() != "Bearer <secret>" {
+		t.Errorf("Expected token resolved without a doubled scheme, got %q", auth.value)
+	}
+
+	head := auth.applyRaw("GET / HTTP/1.1\r\nHost: example.com")
+	if head != "GET / HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer abc123" {
+		t.Errorf("Unexpected raw head %q", head)
+	}
+	own := "GET / HTTP/1.1\r\nauthorization: Basic eDp5"
+	if head := auth.applyRaw(own); head != own {
+		t.Errorf("Expected the raw request's own Authorization kept, got %q", head)
+	}
+}
+
+func TestAuthTransport(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	auth, err := NewAuth("", "abc123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := NewHTTPClient(&ClientOptions{Auth: auth})
+
+	for _, header := range []string{"", "Bearer probe"} {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(received) != 2 || received[0] != "Bearer abc123" || received[1] != "Bearer probe" {
+		t.Errorf("Expected the token added unless the request sets its own, got %v", received)
+	}
+}
+
+func TestAuthTransport_redirect(t *testing.T) {
+	var thirdParty []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		thirdParty = append(thirdParty, r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+
+	var target []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = append(target, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/done", http.StatusFound)
+		case "/away":
+			// 127.0.0.1 and localhost are different hosts to the client
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	auth, err := NewAuth("", "abc123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := NewHTTPClient(&ClientOptions{Auth: auth})
+
+	for _, path := range []string{"/same", "/away"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(target) != 3 || target[0] != "Bearer abc123" || target[1] != "Bearer abc123" || target[2] != "Bearer abc123" {
+		t.Errorf("Expected the token on every request to the target, got %v", target)
+	}
+	if len(thirdParty) != 1 || thirdParty[0] != "" {
+		t.Errorf("Expected no token after a redirect to another host, got %v", thirdParty)
+	}
+}
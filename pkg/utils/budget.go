@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExceeded is returned when a run exhausts its request or bandwidth budget
+var ErrBudgetExceeded = errors.New("run budget exceeded")
+
+// Budget enforces global request and bandwidth caps across all workers.
+// A nil *Budget imposes no limits.
+type Budget struct {
+	maxRequests int64
+	maxBytes    int64
+	requests    int64
+	bytes       int64
+	reason      string
+	mutex       sync.Mutex
+}
+
+// NewBudget creates a budget; zero values mean unlimited
+func NewBudget(maxRequests, maxBytes int64) *Budget {
+	return &Budget{
+		maxRequests: maxRequests,
+		maxBytes:    maxBytes,
+	}
+}
+
+// MaxRequests returns the request cap, or 0 when unlimited
+func (b *Budget) MaxRequests() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.maxRequests
+}
+
+// AllowRequest reserves one request from the budget
+func (b *Budget) AllowRequest() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.reason != "" {
+		return fmt.Errorf("%w: %s", ErrBudgetExceeded, b.reason)
+	}
+	if b.maxRequests > 0 && b.requests >= b.maxRequests {
+		b.reason = fmt.Sprintf("max requests (%d) reached", b.maxRequests)
+		return fmt.Errorf("%w: %s", ErrBudgetExceeded, b.reason)
+	}
+
+	b.requests++
+	return nil
+}
+
+// AddBytes accounts downloaded bytes against the bandwidth cap
+func (b *Budget) AddBytes(n int64) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.bytes += n
+	if b.maxBytes > 0 && b.bytes >= b.maxBytes && b.reason == "" {
+		b.reason = fmt.Sprintf("max bandwidth (%s) reached", FormatBytes(b.maxBytes))
+	}
+}
+
+// Exceeded reports whether the budget has been exhausted
+func (b *Budget) Exceeded() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.maxRequests > 0 && b.requests >= b.maxRequests && b.reason == "" {
+		b.reason = fmt.Sprintf("max requests (%d) reached", b.maxRequests)
+	}
+	return b.reason != ""
+}
+
+// Reason returns why the budget was exhausted, or an empty string
+func (b *Budget) Reason() string {
+	if b == nil {
+		return ""
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.reason
+}
+
+// ParseByteSize parses sizes such as "500MB", "2GB" or "1024"
+func ParseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(strings.ToUpper(value))
+	if value == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"T", 1 << 40},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.multiplier
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, NewValidationError(fmt.Sprintf("invalid size %q", value), err)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
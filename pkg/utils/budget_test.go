@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBudget_MaxRequests(t *testing.T) {
+	budget := NewBudget(2, 0)
+
+	for i := 0; i < 2; i++ {
+		if err := budget.AllowRequest(); err != nil {
+			t.Fatalf("Expected request %d to be allowed, got: %v", i+1, err)
+		}
+	}
+
+	err := budget.AllowRequest()
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got: %v", err)
+	}
+	if !budget.Exceeded() {
+		t.Error("Expected budget to be exceeded")
+	}
+}
+
+func TestBudget_Nil(t *testing.T) {
+	var budget *Budget
+	if err := budget.AllowRequest(); err != nil {
+		t.Errorf("Expected nil budget to allow requests, got: %v", err)
+	}
+	if budget.Exceeded() {
+		t.Error("Expected nil budget to never be exceeded")
+	}
+}
+
+func TestBudget_Transport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1024))
+	}))
+	defer server.Close()
+
+	budget := NewBudget(0, 1000)
+	client := NewHTTPClient(&ClientOptions{Budget: budget})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("First request failed: %v", err)
+	}
+	buf := make([]byte, 2048)
+	for {
+		if _, err := resp.Body.Read(buf); err != nil {
+			break
+		}
+	}
+	resp.Body.Close()
+
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected bandwidth cap to block request, got: %v", err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+		hasError bool
+	}{
+		{"", 0, false},
+		{"1024", 1024, false},
+		{"500MB", 500 << 20, false},
+		{"2GB", 2 << 30, false},
+		{"1.5k", 1536, false},
+		{"lots", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := ParseByteSize(tc.input)
+			if tc.hasError {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error for %q: %v", tc.input, err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %d for %q, got %d", tc.expected, tc.input, result)
+			}
+		})
+	}
+}
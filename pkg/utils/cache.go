@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// ContentCache keeps downloaded bodies in memory for the length of a run, so
+// a URL listed more than once (a shared CDN script in the crawl output of
+// several domains) is fetched once. Bodies are stored by SHA-256, so the same
+// file served under several URLs is held once, and the least recently used
+// are evicted to stay within the byte budget. A nil *ContentCache caches
+// nothing.
+type ContentCache struct {
+	mutex    sync.Mutex
+	limit    int64
+	size     int64
+	urls     map[string]string        // URL to the hash of its body
+	entries  map[string]*list.Element // Hash to its entry in order
+	order    *list.List               // Entries, most recently used first
+	inflight map[string]*cacheCall    // Downloads in progress by URL
+	hits     int64
+}
+
+type cacheEntry struct {
+	hash    string
+	content []byte
+}
+
+// cacheCall is a download other callers for the same URL wait on
+type cacheCall struct {
+	done    chan struct{}
+	content []byte
+	err     error
+}
+
+// NewContentCache returns a cache holding up to limit bytes of bodies, or nil
+// when limit is not positive
+func NewContentCache(limit int64) *ContentCache {
+	if limit <= 0 {
+		return nil
+	}
+	return &ContentCache{
+		limit:    limit,
+		urls:     make(map[string]string),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		inflight: make(map[string]*cacheCall),
+	}
+}
+
+// Fetch returns the body of url from the cache, or downloads it with fetch
+// and caches it. Concurrent callers for a URL being downloaded wait for that
+// download instead of starting their own. Failed downloads are not cached.
+func (c *ContentCache) Fetch(url string, fetch func() ([]byte, error)) ([]byte, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	c.mutex.Lock()
+	if content, found := c.lookup(url); found {
+		c.hits++
+		c.mutex.Unlock()
+		return content, nil
+	}
+	if call, found := c.inflight[url]; found {
+		c.mutex.Unlock()
+		<-call.done
+		if call.err == nil {
+			c.mutex.Lock()
+			c.hits++
+			c.mutex.Unlock()
+		}
+		return call.content, call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[url] = call
+	c.mutex.Unlock()
+
+	call.content, call.err = fetch()
+
+	c.mutex.Lock()
+	delete(c.inflight, url)
+	if call.err == nil {
+		c.store(url, call.content)
+	}
+	c.mutex.Unlock()
+	close(call.done)
+
+	return call.content, call.err
+}
+
+// Hits returns how many fetches were served without a download
+func (c *ContentCache) Hits() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits
+}
+
+// lookup returns the cached body of url, marking it recently used
+func (c *ContentCache) lookup(url string) ([]byte, bool) {
+	hash, found := c.urls[url]
+	if !found {
+		return nil, false
+	}
+	element, found := c.entries[hash]
+	if !found {
+		// The body was evicted
+		delete(c.urls, url)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).content, true
+}
+
+// store caches a body, evicting the least recently used bodies to make room.
+// Bodies larger than the whole budget are not cached.
+func (c *ContentCache) store(url string, content []byte) {
+	size := int64(len(content))
+	if size > c.limit {
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	c.urls[url] = hash
+	if element, found := c.entries[hash]; found {
+		c.order.MoveToFront(element)
+		return
+	}
+
+	for c.size+size > c.limit {
+		oldest := c.order.Back()
+		entry := c.order.Remove(oldest).(*cacheEntry)
+		delete(c.entries, entry.hash)
+		c.size -= int64(len(entry.content))
+	}
+	c.entries[hash] = c.order.PushFront(&cacheEntry{hash: hash, content: content})
+	c.size += size
+}
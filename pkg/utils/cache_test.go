@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestContentCache(t *testing.T) {
+	var nilCache *ContentCache
+	calls := 0
+	fetch := func(body string) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			calls++
+			return []byte(body), nil
+		}
+	}
+	nilCache.Fetch("https://a.test/x.js", fetch("x"))
+	nilCache.Fetch("https://a.test/x.js", fetch("x"))
+	if calls != 2 || nilCache.Hits() != 0 || NewContentCache(0) != nil {
+		t.Errorf("Expected a nil cache to fetch every time, got %d fetches", calls)
+	}
+
+	cache := NewContentCache(10)
+	calls = 0
+	for i := 0; i < 3; i++ {
+		if content, err := cache.Fetch("https://a.test/app.js", fetch("abcd")); err != nil || string(content) != "abcd" {
+			t.Fatalf("Unexpected fetch result %q (%v)", content, err)
+		}
+	}
+	if calls != 1 || cache.Hits() != 2 {
+		t.Errorf("Expected one download and two hits, got %d downloads and %d hits", calls, cache.Hits())
+	}
+
+	// The same body under another URL is stored once
+	cache.Fetch("https://b.test/app.js", fetch("abcd"))
+	if cache.size != 4 {
+		t.Errorf("Expected identical bodies to be stored once, cache holds %d bytes", cache.size)
+	}
+
+	// Filling the budget evicts the least recently used body
+	cache.Fetch("https://a.test/big.js", fetch("0123456"))
+	calls = 0
+	cache.Fetch("https://a.test/app.js", fetch("abcd"))
+	if calls != 1 {
+		t.Error("Expected the evicted body to be downloaded again")
+	}
+
+	// Bodies larger than the budget are never cached
+	calls = 0
+	cache.Fetch("https://a.test/huge.js", fetch("0123456789abc"))
+	cache.Fetch("https://a.test/huge.js", fetch("0123456789abc"))
+	if calls != 2 {
+		t.Errorf("Expected an oversized body to be downloaded each time, got %d downloads", calls)
+	}
+
+	calls = 0
+	failing := func() ([]byte, error) {
+		calls++
+		return nil, errors.New("timeout")
+	}
+	cache.Fetch("https://a.test/down.js", failing)
+	if _, err := cache.Fetch("https://a.test/down.js", failing); err == nil || calls != 2 {
+		t.Errorf("Expected failed downloads not to be cached, got %d downloads (%v)", calls, err)
+	}
+}
+
+func TestContentCache_concurrent(t *testing.T) {
+	cache := NewContentCache(1 << 20)
+	var downloads int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := cache.Fetch("https://cdn.test/lib.js", func() ([]byte, error) {
+				atomic.AddInt32(&downloads, 1)
+				<-release
+				return []byte("lib"), nil
+			})
+			if err != nil || string(content) != "lib" {
+				t.Errorf("Unexpected fetch result %q (%v)", content, err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if downloads != 1 || cache.Hits() != 4 {
+		t.Errorf("Expected concurrent fetches to share one download, got %d downloads and %d hits", downloads, cache.Hits())
+	}
+}
@@ -0,0 +1,383 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"jsfinder/pkg/scope"
+)
+
+// Defaults for the ClientTimeouts fields left at zero
+const (
+	DefaultDialTimeout         = 10 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+	DefaultFallbackDelay       = 300 * time.Millisecond
+)
+
+// ClientTimeouts limits each phase of a request separately, so a host that is
+// slow to accept connections fails fast without raising the overall request
+// timeout for every other host. Zero fields use the defaults; a zero
+// ResponseHeader leaves the wait for headers to the overall timeout, and a
+// zero JS applies it to JavaScript downloads too.
+type ClientTimeouts struct {
+	Dial           time.Duration `yaml:"dial"`            // Establishing the TCP connection
+	TLSHandshake   time.Duration `yaml:"tls_handshake"`   // Completing the TLS handshake
+	ResponseHeader time.Duration `yaml:"response_header"` // Waiting for the response headers once the request is sent
+	FallbackDelay  time.Duration `yaml:"fallback_delay"`  // Happy Eyeballs: head start for IPv6 before IPv4 is tried in parallel
+	JS             time.Duration `yaml:"js"`              // Overall timeout for JavaScript responses, replacing the request timeout
+}
+
+// Validate reports an error for a negative timeout
+func (t *ClientTimeouts) Validate() error {
+	if t == nil {
+		return nil
+	}
+	for name, value := range map[string]time.Duration{
+		"dial":            t.Dial,
+		"tls_handshake":   t.TLSHandshake,
+		"response_header": t.ResponseHeader,
+		"fallback_delay":  t.FallbackDelay,
+		"js":              t.JS,
+	} {
+		if value < 0 {
+			return NewValidationError(fmt.Sprintf("timeouts.%s must not be negative, got %s", name, value), nil)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns the timeouts with zero fields set to the defaults
+func (t *ClientTimeouts) withDefaults() ClientTimeouts {
+	var timeouts ClientTimeouts
+	if t != nil {
+		timeouts = *t
+	}
+	if timeouts.Dial == 0 {
+		timeouts.Dial = DefaultDialTimeout
+	}
+	if timeouts.TLSHandshake == 0 {
+		timeouts.TLSHandshake = DefaultTLSHandshakeTimeout
+	}
+	if timeouts.FallbackDelay == 0 {
+		timeouts.FallbackDelay = DefaultFallbackDelay
+	}
+	return timeouts
+}
+
+// dialer returns the dialer for the dial and fallback timeouts
+func (t ClientTimeouts) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:       t.Dial,
+		KeepAlive:     30 * time.Second,
+		FallbackDelay: t.FallbackDelay,
+	}
+}
+
+// transport builds the base transport with the phase timeouts applied,
+// connecting through dialer when it is set
+func (t *ClientTimeouts) transport(engagement *scope.Scope, dialer *Dialer) *http.Transport {
+	timeouts := t.withDefaults()
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = engagement.DialContext(timeouts.dialer())
+	if dialer != nil {
+		// Proxies from the environment would be dialed through it too
+		base.DialContext = dialer.dialContext(timeouts, engagement)
+		base.Proxy = nil
+	}
+	base.TLSHandshakeTimeout = timeouts.TLSHandshake
+	base.ResponseHeaderTimeout = timeouts.ResponseHeader
+	return base
+}
+
+// ClientOptions holds the settings shared by the HTTP clients of all engines
+type ClientOptions struct {
+	Timeout    time.Duration   // Overall request timeout, including reading the body
+	Timeouts   *ClientTimeouts // Optional dial, TLS handshake and response header timeouts
+	Stats      *RunStats       // Optional run statistics collector
+	Budget     *Budget         // Optional global request/bandwidth budget
+	Scope      *scope.Scope    // Optional engagement scope enforced before every request
+	Identity   *Identity       // Optional researcher identification added to every request
+	Auth       *Auth           // Optional basic or bearer credentials added to every request
+	UAFallback *UAFallback     // Optional browser User-Agent retry for hosts answering 403/406
+	Dialer     *Dialer         // Optional Unix socket or SSH bastion every connection goes through
+	HostSNI    bool            // Send an overridden Host header as the TLS server name, for virtual hosts behind an IP
+}
+
+// NewHTTPClient creates an HTTP client configured from the shared options
+func NewHTTPClient(options *ClientOptions) *http.Client {
+	if options == nil {
+		options = &ClientOptions{}
+	}
+
+	base := options.Timeouts.transport(options.Scope, options.Dialer)
+	var transport http.RoundTripper = base
+	if options.HostSNI {
+		transport = &hostSNITransport{base: base}
+	}
+	if options.Scope != nil {
+		transport = &scopeTransport{base: transport, scope: options.Scope}
+	}
+	if options.Identity != nil {
+		transport = &identityTransport{base: transport, identity: options.Identity}
+	}
+	if options.Auth != nil {
+		transport = &authTransport{base: transport, auth: options.Auth}
+	}
+	if options.Stats != nil {
+		transport = &statsTransport{base: transport, stats: options.Stats}
+	}
+	if options.Budget != nil {
+		transport = &budgetTransport{base: transport, budget: options.Budget}
+	}
+	// Outermost so fallback retries are counted and budgeted like any other request
+	if options.UAFallback != nil {
+		transport = &uaFallbackTransport{base: transport, fallback: options.UAFallback}
+	}
+
+	// A JS timeout needs the deadline to depend on the response, which the
+	// client's fixed Timeout cannot, so the transport enforces both
+	timeout := options.Timeout
+	if options.Timeouts != nil && options.Timeouts.JS > 0 {
+		transport = &contentTimeoutTransport{base: transport, timeout: timeout, js: options.Timeouts.JS}
+		timeout = 0
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// contentTimeoutTransport limits each request, body included, to timeout,
+// or to js once the URL or the response's Content-Type shows it is a
+// JavaScript file. Zero durations mean no limit.
+type contentTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	js      time.Duration
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *contentTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	deadline := &requestDeadline{start: time.Now(), cancel: cancel}
+	limit := t.timeout
+	if isJSPath(req.URL.Path) {
+		limit = t.js
+	}
+	deadline.set(limit)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		deadline.stop()
+		if deadline.expired.Load() {
+			return nil, &contentTimeoutError{url: req.URL.String(), limit: limit}
+		}
+		return nil, err
+	}
+	if limit != t.js && isJSContentType(resp.Header.Get("Content-Type")) {
+		limit = t.js
+		deadline.set(limit)
+	}
+	resp.Body = &deadlineBody{ReadCloser: resp.Body, deadline: deadline, url: req.URL.String(), limit: limit}
+	return resp, nil
+}
+
+// requestDeadline cancels a request once its limit has passed since it started
+type requestDeadline struct {
+	start   time.Time
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+// set replaces the limit, counted from the start of the request
+func (d *requestDeadline) set(limit time.Duration) {
+	if d.timer != nil && !d.timer.Stop() {
+		return // Already expired
+	}
+	d.timer = nil
+	if limit > 0 {
+		d.timer = time.AfterFunc(time.Until(d.start.Add(limit)), func() {
+			d.expired.Store(true)
+			d.cancel()
+		})
+	}
+}
+
+func (d *requestDeadline) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.cancel()
+}
+
+// deadlineBody reports reads cut off by the deadline as timeouts and
+// releases the deadline when closed
+type deadlineBody struct {
+	io.ReadCloser
+	deadline *requestDeadline
+	url      string
+	limit    time.Duration
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.deadline.expired.Load() {
+		err = &contentTimeoutError{url: b.url, limit: b.limit}
+	}
+	return n, err
+}
+
+func (b *deadlineBody) Close() error {
+	b.deadline.stop()
+	return b.ReadCloser.Close()
+}
+
+// contentTimeoutError is a net.Error timeout, like the error of a request
+// that exceeds http.Client.Timeout
+type contentTimeoutError struct {
+	url   string
+	limit time.Duration
+}
+
+func (e *contentTimeoutError) Error() string {
+	return fmt.Sprintf("request to %s exceeded its %s timeout", e.url, e.limit)
+}
+
+func (e *contentTimeoutError) Timeout() bool   { return true }
+func (e *contentTimeoutError) Temporary() bool { return true }
+
+// isJSPath reports whether a URL path names a JavaScript file
+func isJSPath(urlPath string) bool {
+	switch strings.ToLower(path.Ext(urlPath)) {
+	case ".js", ".mjs", ".cjs":
+		return true
+	}
+	return false
+}
+
+// isJSContentType reports whether a Content-Type is a JavaScript type
+func isJSContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.Contains(contentType, "javascript") || strings.Contains(contentType, "ecmascript")
+}
+
+// hostSNITransport sends HTTPS requests whose Host header differs from the
+// URL's host with that host as the TLS server name, so a server picking its
+// certificate and site by SNI answers for the virtual host being requested
+type hostSNITransport struct {
+	base *http.Transport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hostSNITransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" || req.Host == "" || req.Host == req.URL.Host {
+		return t.base.RoundTrip(req)
+	}
+	serverName := req.Host
+	if host, _, err := net.SplitHostPort(serverName); err == nil {
+		serverName = host
+	}
+
+	// Connections are pooled by address only, so one made for another server
+	// name must not be reused
+	transport := t.base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.ServerName = serverName
+	transport.DisableKeepAlives = true
+	return transport.RoundTrip(req)
+}
+
+// statsTransport records request counts and downloaded bytes in RunStats
+type statsTransport struct {
+	base  http.RoundTripper
+	stats *RunStats
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.stats.AddRequest()
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &countingBody{ReadCloser: resp.Body, onRead: t.stats.AddBytes}
+	return resp, nil
+}
+
+// budgetTransport refuses requests once the run budget is exhausted
+type budgetTransport struct {
+	base   http.RoundTripper
+	budget *Budget
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.budget.AllowRequest(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &countingBody{ReadCloser: resp.Body, onRead: t.budget.AddBytes}
+	return resp, nil
+}
+
+// scopeTransport refuses requests to hosts outside the engagement scope
+type scopeTransport struct {
+	base  http.RoundTripper
+	scope *scope.Scope
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *scopeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.scope.AllowsHost(req.URL.Hostname()) {
+		return nil, fmt.Errorf("%w: %s", scope.ErrOutOfScope, req.URL.Host)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// identityTransport adds researcher identification headers to every request
+type identityTransport struct {
+	base     http.RoundTripper
+	identity *Identity
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *identityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	t.identity.apply(req)
+	return t.base.RoundTrip(req)
+}
+
+// countingBody reports bytes read from a response body
+type countingBody struct {
+	io.ReadCloser
+	onRead func(n int64)
+}
+
+// Read implements io.Reader
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.onRead(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestClientTimeouts(t *testing.T) {
+	var config Config
+	data := "timeouts:\n  dial: 3s\n  response_header: 15s\n"
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("Failed to parse timeouts: %v", err)
+	}
+	if config.Timeouts.Dial != 3*time.Second {
+		t.Errorf("Expected a 3s dial timeout, got %s", config.Timeouts.Dial)
+	}
+
+	transport := config.Timeouts.transport(nil, nil)
+	if transport.TLSHandshakeTimeout != DefaultTLSHandshakeTimeout || transport.ResponseHeaderTimeout != 15*time.Second {
+		t.Errorf("Unexpected transport timeouts: TLS %s, headers %s", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+
+	var nilTimeouts *ClientTimeouts
+	if transport := nilTimeouts.transport(nil, nil); transport.TLSHandshakeTimeout != DefaultTLSHandshakeTimeout || transport.ResponseHeaderTimeout != 0 {
+		t.Errorf("Expected defaults without timeouts, got TLS %s, headers %s", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+
+	if err := (&ClientTimeouts{Dial: -time.Second}).Validate(); err == nil {
+		t.Error("Expected error for a negative timeout")
+	}
+	if err := nilTimeouts.Validate(); err != nil {
+		t.Errorf("Unexpected error for nil timeouts: %v", err)
+	}
+}
+
+func TestClientTimeouts_responseHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&ClientOptions{
+		Timeout:  10 * time.Second,
+		Timeouts: &ClientTimeouts{ResponseHeader: 50 * time.Millisecond},
+	})
+	start := time.Now()
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("Expected the response header timeout to fail the request")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the request to fail at the header timeout, took %s", elapsed)
+	}
+
+}
+
+func TestClientTimeouts_js(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app.js":
+			time.Sleep(200 * time.Millisecond) // Slow to answer, but named as JS
+			w.Header().Set("Content-Type", "text/plain")
+		case "/bundle":
+			w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.Write([]byte("start "))
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("end"))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&ClientOptions{
+		Timeout:  100 * time.Millisecond,
+		Timeouts: &ClientTimeouts{JS: 5 * time.Second},
+	})
+	fetch := func(path string) (string, error) {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	for _, path := range []string{"/app.js", "/bundle"} {
+		if body, err := fetch(path); err != nil || body != "start end" {
+			t.Errorf("Expected %s to download under the JS timeout, got %q, %v", path, body, err)
+		}
+	}
+
+	_, err := fetch("/page")
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected the page to fail with a timeout, got %v", err)
+	}
+}
+
+func TestHostSNITransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.ServerName))
+	}))
+	defer server.Close()
+
+	transport := &hostSNITransport{base: server.Client().Transport.(*http.Transport)}
+	fetch := func(host string) string {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Host = host
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Request with Host %q failed: %v", host, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// The test certificate is valid for example.com
+	if serverName := fetch("example.com"); serverName != "example.com" {
+		t.Errorf("Expected the Host header as server name, got %q", serverName)
+	}
+	if serverName := fetch(strings.TrimPrefix(server.URL, "https://")); serverName != "" {
+		t.Errorf("Expected no server name for the IP itself, got %q", serverName)
+	}
+}
@@ -15,6 +15,10 @@ type Config struct {
 	Scanner   ScannerConfig            `yaml:"scanner"`
 	Discovery DiscoveryConfig          `yaml:"discovery"`
 	Wordlists WordlistsConfig          `yaml:"wordlists"`
+	Identity  IdentityConfig           `yaml:"identity"`
+	Tokens    TokensConfig             `yaml:"tokens"`
+	Timeouts  ClientTimeouts           `yaml:"timeouts"`
+	Retry     RetryPolicies            `yaml:"retry"`
 }
 
 // PatternConfig represents a regex pattern configuration
@@ -36,9 +40,10 @@ type CrawlerConfig struct {
 
 // ScannerConfig represents scanner settings
 type ScannerConfig struct {
-	Threads      int    `yaml:"threads"`
-	Timeout      int    `yaml:"timeout"`
-	OutputFormat string `yaml:"output_format"`
+	Threads       int    `yaml:"threads"`
+	Timeout       int    `yaml:"timeout"`
+	OutputFormat  string `yaml:"output_format"`
+	PackPublicKey string `yaml:"pack_public_key"` // Base64 ed25519 key pattern packs are verified with instead of the project's
 }
 
 // DiscoveryConfig represents discovery settings
@@ -50,6 +55,13 @@ type DiscoveryConfig struct {
 	UserAgent    string `yaml:"user_agent"`
 }
 
+// TokensConfig holds the API tokens jsfinder itself uses. Each may be the
+// token or a secret reference (env:, vault: or aws-sm:, see ResolveSecret),
+// resolved only when the token is needed.
+type TokensConfig struct {
+	GitHub string `yaml:"github"` // Used by scan --github when GITHUB_TOKEN is not set
+}
+
 // WordlistsConfig represents wordlist configurations
 type WordlistsConfig struct {
 	CommonEndpoints []string `yaml:"common_endpoints"`
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// formulaPrefixes are the leading characters that make a spreadsheet treat a
+// cell as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// CSVOptions selects and sanitizes the columns of CSV output. A nil
+// *CSVOptions writes every column unchanged.
+type CSVOptions struct {
+	Columns        []string // Columns to write, in this order; all when empty
+	EscapeFormulas bool     // Prefix cells starting with =, +, -, @, tab or CR with a single quote
+}
+
+// CSVWriter writes RFC 4180 CSV (quoted as needed, CRLF line endings) with
+// the column selection and escaping of its CSVOptions
+type CSVWriter struct {
+	writer  *csv.Writer
+	indexes []int // Positions of the selected columns in each record; nil for all
+	escape  bool
+}
+
+// NewCSVWriter writes the header row, reduced to the selected columns, and
+// returns a writer for the records that follow it
+func NewCSVWriter(output io.Writer, header []string, options *CSVOptions) (*CSVWriter, error) {
+	w := &CSVWriter{writer: csv.NewWriter(output)}
+	w.writer.UseCRLF = true
+
+	if options != nil {
+		indexes, err := columnIndexes(header, options.Columns)
+		if err != nil {
+			return nil, err
+		}
+		w.indexes = indexes
+		w.escape = options.EscapeFormulas
+	}
+
+	if err := w.writer.Write(w.selectColumns(header)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes one record, laid out like the header
+func (w *CSVWriter) Write(record []string) error {
+	record = w.selectColumns(record)
+	if w.escape {
+		for i, cell := range record {
+			record[i] = EscapeFormula(cell)
+		}
+	}
+	return w.writer.Write(record)
+}
+
+// Flush writes any buffered records and returns the first write error
+func (w *CSVWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *CSVWriter) selectColumns(record []string) []string {
+	if w.indexes == nil {
+		return append([]string(nil), record...)
+	}
+
+	selected := make([]string, len(w.indexes))
+	for i, index := range w.indexes {
+		if index < len(record) {
+			selected[i] = record[index]
+		}
+	}
+	return selected
+}
+
+// ValidateColumns reports an error naming the available columns when one of
+// columns is not in header
+func (o *CSVOptions) ValidateColumns(header []string) error {
+	if o == nil {
+		return nil
+	}
+	_, err := columnIndexes(header, o.Columns)
+	return err
+}
+
+// columnIndexes finds each column in header. Names match the header text or
+// its snake_case form, so "Line Number" and line_number both select the same
+// column.
+func columnIndexes(header, columns []string) ([]int, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[columnKey(name)] = i
+	}
+
+	indexes := make([]int, 0, len(columns))
+	for _, column := range columns {
+		index, exists := positions[columnKey(column)]
+		if !exists {
+			return nil, NewValidationError(fmt.Sprintf("unknown column %q, expected one of: %s", column, strings.Join(header, ", ")), nil)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// columnKey reduces a column name to its lowercase letters and digits
+func columnKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// EscapeFormula prefixes a cell with a single quote when a spreadsheet would
+// otherwise evaluate it as a formula (CSV injection)
+func EscapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
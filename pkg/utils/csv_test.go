@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	header := []string{"URL", "Line Number", "Match"}
+	record := []string{"https://example.com/app.js", "3", `=HYPERLINK("http://evil.example","x")`}
+
+	tests := []struct {
+		name     string
+		options  *CSVOptions
+		expected string
+	}{
+		{
+			name:     "all columns",
+			options:  nil,
+			expected: "URL,Line Number,Match\r\nhttps://example.com/app.js,3,\"=HYPERLINK(\"\"http://evil.example\"\",\"\"x\"\")\"\r\n",
+		},
+		{
+			name:     "selected columns by snake_case name",
+			options:  &CSVOptions{Columns: []string{"line_number", "url"}},
+			expected: "Line Number,URL\r\n3,https://example.com/app.js\r\n",
+		},
+		{
+			name:     "escaped formulas",
+			options:  &CSVOptions{Columns: []string{"Match"}, EscapeFormulas: true},
+			expected: "Match\r\n\"'=HYPERLINK(\"\"http://evil.example\"\",\"\"x\"\")\"\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewCSVWriter(&buf, header, tt.options)
+			if err != nil {
+				t.Fatalf("NewCSVWriter() error = %v", err)
+			}
+			if err := writer.Write(record); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	if _, err := NewCSVWriter(&bytes.Buffer{}, header, &CSVOptions{Columns: []string{"status"}}); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := map[string]string{
+		"=1+1":          "'=1+1",
+		"+1":            "'+1",
+		"-1":            "'-1",
+		"@SUM(A1)":      "'@SUM(A1)",
+		"\tcmd":         "'\tcmd",
+		"api_key=abc":   "api_key=abc",
+		"":              "",
+		"https://x.com": "https://x.com",
+	}
+
+	for input, expected := range tests {
+		if escaped := EscapeFormula(input); escaped != expected {
+			t.Errorf("EscapeFormula(%q) = %q, expected %q", input, escaped, expected)
+		}
+	}
+}
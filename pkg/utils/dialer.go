@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"jsfinder/pkg/scope"
+)
+
+// sshCommand is the OpenSSH client the ssh dialer runs
+var sshCommand = "ssh"
+
+// Dialer opens the connections of the shared clients through a Unix domain
+// socket or an SSH bastion instead of directly, for targets only reachable
+// that way. Requests keep their URL and Host header; only where the bytes go
+// changes. A nil *Dialer dials directly.
+type Dialer struct {
+	unixSocket string   // Every connection goes to this socket, whatever the target host
+	ssh        *url.URL // Bastion forwarding each connection with ssh -W
+}
+
+// NewDialer builds the dialer of the --dial ssh://[user@]host[:port] or
+// unix:///path flag and the --unix-socket path shorthand. It returns nil
+// when neither is set.
+func NewDialer(dial, unixSocket string) (*Dialer, error) {
+	if dial != "" && unixSocket != "" {
+		return nil, NewValidationError("--dial and --unix-socket cannot be combined", nil)
+	}
+	if unixSocket != "" {
+		return &Dialer{unixSocket: unixSocket}, nil
+	}
+	if dial == "" {
+		return nil, nil
+	}
+
+	target, err := url.Parse(dial)
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid --dial %q", dial), err)
+	}
+	switch target.Scheme {
+	case "unix":
+		if target.Path == "" {
+			return nil, NewValidationError(fmt.Sprintf("invalid --dial %q, expected unix:///path/to/socket", dial), nil)
+		}
+		return &Dialer{unixSocket: target.Path}, nil
+	case "ssh":
+		if target.Hostname() == "" || (target.Path != "" && target.Path != "/") || target.RawQuery != "" {
+			return nil, NewValidationError(fmt.Sprintf("invalid --dial %q, expected ssh://[user@]host[:port]", dial), nil)
+		}
+		if _, hasPassword := target.User.Password(); hasPassword {
+			return nil, NewValidationError("--dial does not take a password; use an SSH key or agent", nil)
+		}
+		return &Dialer{ssh: target}, nil
+	}
+	return nil, NewValidationError(fmt.Sprintf("unsupported --dial %q, expected ssh:// or unix://", dial), nil)
+}
+
+// String describes the dialer for the startup message
+func (d *Dialer) String() string {
+	if d.unixSocket != "" {
+		return "unix socket " + d.unixSocket
+	}
+	return "SSH bastion " + d.ssh.Redacted()
+}
+
+// dialContext returns the dial function of the transports, honouring the
+// dial timeout. Target hosts are never resolved locally, so the engagement
+// scope is checked on their names only.
+func (d *Dialer) dialContext(timeouts ClientTimeouts, engagement *scope.Scope) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if !engagement.AllowsUnresolved(host) {
+			return nil, fmt.Errorf("%w: %s", scope.ErrOutOfScope, host)
+		}
+		if d.unixSocket != "" {
+			dialer := &net.Dialer{Timeout: timeouts.Dial}
+			return dialer.DialContext(ctx, "unix", d.unixSocket)
+		}
+		return d.dialSSH(ctx, address, timeouts.Dial)
+	}
+}
+
+// sshArgs returns the ssh arguments forwarding a connection to address
+func (d *Dialer) sshArgs(address string, timeout time.Duration) []string {
+	args := []string{
+		"-W", address,
+		"-o", "BatchMode=yes", // Never prompt: there is no terminal to answer on
+		"-o", "LogLevel=ERROR",
+		"-o", "ConnectTimeout=" + strconv.Itoa(max(1, int(timeout.Seconds()))),
+	}
+	if port := d.ssh.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	if user := d.ssh.User.Username(); user != "" {
+		args = append(args, "-l", user)
+	}
+	return append(args, "--", d.ssh.Hostname())
+}
+
+// dialSSH starts ssh -W to address through the bastion, so the user's keys,
+// agent and ~/.ssh/config (ControlMaster, ProxyJump) apply. The process
+// lives as long as the connection; its errors go to stderr, as with a
+// ProxyCommand.
+func (d *Dialer) dialSSH(ctx context.Context, address string, timeout time.Duration) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	stdin, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	reader, stdout, err := os.Pipe()
+	if err != nil {
+		stdin.Close()
+		writer.Close()
+		return nil, err
+	}
+
+	cmd := exec.Command(sshCommand, d.sshArgs(address, timeout)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	// The child holds its ends now
+	stdin.Close()
+	stdout.Close()
+	if err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, fmt.Errorf("failed to start ssh to %s: %w", d.ssh.Hostname(), err)
+	}
+	return &commandConn{cmd: cmd, reader: reader, writer: writer, remote: commandAddr(address)}, nil
+}
+
+// commandConn is a connection over the standard input and output of a
+// command. Pipes support deadlines, so it behaves like a socket to the
+// transports.
+type commandConn struct {
+	cmd       *exec.Cmd
+	reader    *os.File
+	writer    *os.File
+	remote    commandAddr
+	closeOnce sync.Once
+}
+
+func (c *commandConn) Read(b []byte) (int, error)  { return c.reader.Read(b) }
+func (c *commandConn) Write(b []byte) (int, error) { return c.writer.Write(b) }
+func (c *commandConn) LocalAddr() net.Addr         { return commandAddr("ssh") }
+func (c *commandConn) RemoteAddr() net.Addr        { return c.remote }
+
+// Close closes the pipes and stops the command
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.writer.Close()
+		c.reader.Close()
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConn) SetDeadline(t time.Time) error {
+	if err := c.reader.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.writer.SetWriteDeadline(t)
+}
+
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return c.reader.SetReadDeadline(t) }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return c.writer.SetWriteDeadline(t) }
+
+// commandAddr is the address of a commandConn end
+type commandAddr string
+
+func (a commandAddr) Network() string { return "ssh" }
+func (a commandAddr) String() string  { return string(a) }
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"jsfinder/pkg/scope"
+)
+
+// Kinds of failure recorded in the error log
+const (
+	ErrorKindDNS     = "dns"
+	ErrorKindTimeout = "timeout"
+	ErrorKindTLS     = "tls"
+	ErrorKindNetwork = "network"
+	ErrorKindHTTP    = "http"
+	ErrorKindBudget  = "budget"
+	ErrorKindScope   = "scope"
+	ErrorKindOther   = "other"
+)
+
+// ErrorRecord is one line of the error log
+type ErrorRecord struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	URL        string    `json:"url"`
+	Kind       string    `json:"kind"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error"`
+	Labels     Labels    `json:"labels,omitempty"`
+}
+
+// ErrorLog writes every URL that failed during a run to a JSON Lines file,
+// so the failed subset can be retried later. A nil *ErrorLog records nothing.
+type ErrorLog struct {
+	path    string
+	command string
+	labels  Labels
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	count   int
+}
+
+// NewErrorLog creates the error log file for a command run, or returns nil
+// when path is empty
+func NewErrorLog(path, command string) (*ErrorLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, NewFileError(fmt.Sprintf("failed to create errors file %s", path), err)
+	}
+	return &ErrorLog{
+		path:    path,
+		command: command,
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// Record logs a URL that could not be processed and why
+func (l *ErrorLog) Record(url string, err error) {
+	if l == nil || err == nil {
+		return
+	}
+
+	kind, statusCode := ClassifyError(err)
+	record := ErrorRecord{
+		Time:       time.Now(),
+		Command:    l.command,
+		URL:        url,
+		Kind:       kind,
+		StatusCode: statusCode,
+		Error:      err.Error(),
+		Labels:     l.labels,
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.encoder.Encode(record) == nil {
+		l.count++
+	}
+}
+
+// SetLabels attaches --label pairs to every record written from now on
+func (l *ErrorLog) SetLabels(labels Labels) {
+	if l != nil {
+		l.labels = labels
+	}
+}
+
+// Count returns the number of URLs recorded so far
+func (l *ErrorLog) Count() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.count
+}
+
+// Path returns the file the log is written to
+func (l *ErrorLog) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Close flushes and closes the log file
+func (l *ErrorLog) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+// ReadErrorLog reads the records of an error log file
+func ReadErrorLog(path string) ([]ErrorRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewFileError(fmt.Sprintf("failed to open errors file %s", path), err)
+	}
+	defer file.Close()
+
+	var records []ErrorRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record ErrorRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, NewParseError(fmt.Sprintf("%s:%d: invalid error record", path, line), err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NewFileError(fmt.Sprintf("failed to read errors file %s", path), err)
+	}
+	return records, nil
+}
+
+// ClassifyError returns the kind of a per-URL failure and, for HTTP errors,
+// the status code
+func ClassifyError(err error) (string, int) {
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Type == HTTPError {
+		statusCode, _ := appErr.Context["status_code"].(int)
+		return ErrorKindHTTP, statusCode
+	}
+
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, ErrBudgetExceeded):
+		return ErrorKindBudget, 0
+	case errors.Is(err, scope.ErrOutOfScope):
+		return ErrorKindScope, 0
+	case errors.As(err, &dnsErr):
+		return ErrorKindDNS, 0
+	case IsTimeoutError(err):
+		return ErrorKindTimeout, 0
+	case isTLSError(err):
+		return ErrorKindTLS, 0
+	case IsNetworkError(err):
+		return ErrorKindNetwork, 0
+	}
+	return ErrorKindOther, 0
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jsfinder/pkg/scope"
+)
+
+func TestClassifyError(t *testing.T) {
+	dnsErr := &net.DNSError{Err: "no such host", Name: "missing.example.com", IsNotFound: true}
+
+	tests := []struct {
+		err        error
+		kind       string
+		statusCode int
+	}{
+		{NewHTTPError("HTTP 404: https://example.com/app.js", 404, nil), ErrorKindHTTP, 404},
+		{WrapError(NewHTTPError("HTTP error", 503, nil), "failed after 3 attempts"), ErrorKindHTTP, 503},
+		{fmt.Errorf("Get \"https://missing.example.com/\": %w", dnsErr), ErrorKindDNS, 0},
+		{NewNetworkError("failed to fetch", dnsErr), ErrorKindDNS, 0},
+		{fmt.Errorf("request: %w", context.DeadlineExceeded), ErrorKindTimeout, 0},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ErrorKindNetwork, 0},
+		{fmt.Errorf("request: %w", ErrBudgetExceeded), ErrorKindBudget, 0},
+		{fmt.Errorf("%w: evil.example.com", scope.ErrOutOfScope), ErrorKindScope, 0},
+		{errors.New("unexpected"), ErrorKindOther, 0},
+	}
+
+	for _, tc := range tests {
+		kind, statusCode := ClassifyError(tc.err)
+		if kind != tc.kind || statusCode != tc.statusCode {
+			t.Errorf("ClassifyError(%v) = %s %d, expected %s %d", tc.err, kind, statusCode, tc.kind, tc.statusCode)
+		}
+	}
+}
+
+func TestErrorLog(t *testing.T) {
+	var disabled *ErrorLog
+	disabled.Record("https://example.com/", errors.New("ignored"))
+	if disabled.Count() != 0 || disabled.Close() != nil {
+		t.Error("Expected nil error log to record nothing")
+	}
+
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	log, err := NewErrorLog(path, "scan")
+	if err != nil {
+		t.Fatalf("Failed to create error log: %v", err)
+	}
+	log.Record("https://example.com/app.js", NewHTTPError("HTTP 500", 500, nil))
+	log.Record("https://example.com/ok.js", nil)
+	log.Record("https://missing.example.com/app.js", &net.DNSError{Err: "no such host", Name: "missing.example.com"})
+	if err := log.Close(); err != nil {
+		t.Fatalf("Failed to close error log: %v", err)
+	}
+	if log.Count() != 2 {
+		t.Errorf("Expected 2 recorded URLs, got %d", log.Count())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read error log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per failed URL, got %q", data)
+	}
+
+	var record ErrorRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Invalid JSON line %q: %v", lines[0], err)
+	}
+	if record.Command != "scan" || record.URL != "https://example.com/app.js" || record.Kind != ErrorKindHTTP || record.StatusCode != 500 {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	records, err := ReadErrorLog(path)
+	if err != nil {
+		t.Fatalf("Failed to read error log: %v", err)
+	}
+	if len(records) != 2 || records[1].Kind != ErrorKindDNS || records[1].URL != "https://missing.example.com/app.js" {
+		t.Errorf("Expected the records to read back, got %+v", records)
+	}
+
+	os.WriteFile(path, []byte("{\"url\": \"https://example.com/\"}\nnot json\n"), 0644)
+	if _, err := ReadErrorLog(path); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("Expected the invalid line to be reported, got %v", err)
+	}
+}
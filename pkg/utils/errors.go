@@ -2,11 +2,16 @@ package utils
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net"
 	"strings"
+	"syscall"
 	"time"
+
+	"jsfinder/pkg/scope"
 )
 
 // ErrorType represents different types of errors
@@ -21,6 +26,10 @@ const (
 	ConfigError
 	ValidationError
 	FileError
+	DNSError               // The host does not exist (NXDOMAIN); retrying cannot help
+	ConnectionRefusedError // Nothing listens on the port
+	TLSError               // Handshake or certificate failure
+	ConnectionResetError   // The peer dropped the connection, often a transient overload
 )
 
 // String returns the string representation of the error type
@@ -40,6 +49,14 @@ func (e ErrorType) String() string {
 		return "VALIDATION_ERROR"
 	case FileError:
 		return "FILE_ERROR"
+	case DNSError:
+		return "DNS_ERROR"
+	case ConnectionRefusedError:
+		return "CONNECTION_REFUSED"
+	case TLSError:
+		return "TLS_ERROR"
+	case ConnectionResetError:
+		return "CONNECTION_RESET"
 	default:
 		return "UNKNOWN_ERROR"
 	}
@@ -94,8 +111,12 @@ func NewError(errType ErrorType, message string, cause error) *AppError {
 	}
 }
 
-// NewNetworkError creates a network error
+// NewNetworkError creates a network error, typed as a DNS, connection
+// refused, TLS or connection reset error when the cause is one
 func NewNetworkError(message string, cause error) *AppError {
+	if errType := networkErrorType(cause); errType != UnknownError {
+		return NewError(errType, message, cause)
+	}
 	return NewError(NetworkError, message, cause)
 }
 
@@ -104,13 +125,22 @@ func NewTimeoutError(message string, cause error) *AppError {
 	return NewError(TimeoutError, message, cause)
 }
 
-// NewHTTPError creates an HTTP error
+// NewHTTPError creates an HTTP error, retryable only for a status that
+// RetryableStatus accepts
 func NewHTTPError(message string, statusCode int, cause error) *AppError {
 	err := NewError(HTTPError, message, cause)
 	err.WithContext("status_code", statusCode)
+	err.Retryable = err.Retryable && RetryableStatus(statusCode)
 	return err
 }
 
+// RetryableStatus reports whether a request that got statusCode may succeed
+// when sent again: request timeouts, rate limiting and server errors. Any
+// other status, such as a 404 or 403, is the server's answer.
+func RetryableStatus(statusCode int) bool {
+	return statusCode == 408 || statusCode == 429 || statusCode >= 500
+}
+
 // NewParseError creates a parse error
 func NewParseError(message string, cause error) *AppError {
 	return NewError(ParseError, message, cause)
@@ -133,12 +163,16 @@ func NewFileError(message string, cause error) *AppError {
 
 // isRetryableError determines if an error is retryable
 func isRetryableError(errType ErrorType, cause error) bool {
+	// An exhausted budget or an out-of-scope target never recovers within the same run
+	if errors.Is(cause, ErrBudgetExceeded) || errors.Is(cause, scope.ErrOutOfScope) {
+		return false
+	}
+
 	switch errType {
-	case NetworkError, TimeoutError:
+	case NetworkError, TimeoutError, ConnectionRefusedError, ConnectionResetError:
 		return true
 	case HTTPError:
-		// HTTP errors are generally retryable for 5xx and 429 status codes
-		// The actual status code should be stored in the error context
+		// NewHTTPError narrows this down by status code
 		return true
 	default:
 		return false
@@ -148,7 +182,7 @@ func isRetryableError(errType ErrorType, cause error) bool {
 // IsNetworkError checks if an error is a network error
 func IsNetworkError(err error) bool {
 	if appErr, ok := err.(*AppError); ok {
-		return appErr.Type == NetworkError
+		return isNetworkType(appErr.Type)
 	}
 	
 	// Check for common network errors
@@ -166,6 +200,84 @@ func IsNetworkError(err error) bool {
 	return false
 }
 
+// isNetworkType reports whether an error type is a network failure
+func isNetworkType(errType ErrorType) bool {
+	switch errType {
+	case NetworkError, DNSError, ConnectionRefusedError, TLSError, ConnectionResetError:
+		return true
+	}
+	return false
+}
+
+// ErrorTypeOf returns the type of an error: an AppError's own type, refined
+// for network errors wrapping a more specific cause, or the type its cause
+// indicates
+func ErrorTypeOf(err error) ErrorType {
+	if err == nil {
+		return UnknownError
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Type != NetworkError && appErr.Type != UnknownError {
+		return appErr.Type
+	}
+	if errType := networkErrorType(err); errType != UnknownError {
+		return errType
+	}
+	if appErr != nil {
+		return appErr.Type
+	}
+	if IsTimeoutError(err) {
+		return TimeoutError
+	}
+	if IsNetworkError(err) {
+		return NetworkError
+	}
+	return UnknownError
+}
+
+// networkErrorType tells apart the network failures that call for different
+// retry policies; other errors, including temporary DNS failures, are
+// UnknownError
+func networkErrorType(err error) ErrorType {
+	if err == nil {
+		return UnknownError
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsNotFound {
+			return DNSError
+		}
+		return UnknownError
+	}
+	if isTLSError(err) {
+		return TLSError
+	}
+
+	// Windows reports its own WSA error codes, so fall back to the message
+	message := strings.ToLower(err.Error())
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), strings.Contains(message, "connection refused"), strings.Contains(message, "actively refused"):
+		return ConnectionRefusedError
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE),
+		strings.Contains(message, "connection reset"), strings.Contains(message, "forcibly closed"), strings.Contains(message, "broken pipe"):
+		return ConnectionResetError
+	}
+	return UnknownError
+}
+
+// isTLSError reports whether an error is a failed handshake or certificate check
+func isTLSError(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	return errors.As(err, &certErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr)
+}
+
 // IsTimeoutError checks if an error is a timeout error
 func IsTimeoutError(err error) bool {
 	if appErr, ok := err.(*AppError); ok {
@@ -218,12 +330,8 @@ func WrapError(err error, message string) error {
 	}
 	
 	// Determine error type from the original error
-	errType := UnknownError
-	if IsNetworkError(err) {
-		errType = NetworkError
-	} else if IsTimeoutError(err) {
-		errType = TimeoutError
-	} else if strings.Contains(err.Error(), "parse") {
+	errType := ErrorTypeOf(err)
+	if errType == UnknownError && strings.Contains(err.Error(), "parse") {
 		errType = ParseError
 	}
 	
@@ -248,12 +356,18 @@ func LogError(logger *Logger, err error, context map[string]interface{}) {
 			allContext[k] = v
 		}
 		
-		// Create field logger with context
+		// Create field logger with context, sampling repeats of the same error class
+		// (e.g. every 404) rather than each unique message
 		fieldLogger := logger.WithFields(convertToStringMap(allContext))
+		sampleKey := appErr.Type.String()
+		if statusCode, ok := appErr.Context["status_code"]; ok {
+			sampleKey = fmt.Sprintf("%s:%v", sampleKey, statusCode)
+		}
+		fieldLogger = fieldLogger.Sampled(sampleKey)
 		
 		// Log based on error type
 		switch appErr.Type {
-		case NetworkError, TimeoutError:
+		case NetworkError, TimeoutError, DNSError, ConnectionRefusedError, TLSError, ConnectionResetError:
 			fieldLogger.Warn(appErr.Error())
 		case HTTPError:
 			if statusCode, ok := appErr.Context["status_code"].(int); ok && statusCode >= 500 {
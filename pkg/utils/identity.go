@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// defaultUserAgent is used when an identity contact is set but no engine User-Agent is
+const defaultUserAgent = "jsfinder/1.0"
+
+// IdentityConfig represents the researcher identification many bug bounty
+// programs require on every request under their safe-harbor terms
+type IdentityConfig struct {
+	Headers map[string]string `yaml:"headers"` // e.g. X-Bug-Bounty: researcher-id; values may be secret references
+	Contact string            `yaml:"contact"` // Appended to the User-Agent, e.g. an email or profile URL
+}
+
+// Identity attaches researcher identification to outgoing requests.
+// A nil *Identity leaves requests untouched.
+type Identity struct {
+	headers http.Header
+	contact string
+	secrets map[string]bool // Canonical names of headers resolved from secret references
+}
+
+// NewIdentity builds an identity from the config section and any "Name: value"
+// header flags; flags override headers of the same name from the config.
+// Header values that are secret references (see ResolveSecret) are resolved.
+func NewIdentity(config IdentityConfig, headerFlags []string, contact string) (*Identity, error) {
+	identity := &Identity{
+		headers: make(http.Header),
+		contact: strings.TrimSpace(config.Contact),
+		secrets: make(map[string]bool),
+	}
+
+	for name, value := range config.Headers {
+		if err := identity.setHeader(name, value); err != nil {
+			return nil, err
+		}
+	}
+	for _, spec := range headerFlags {
+		name, value, err := ParseHeader(spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := identity.setHeader(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if contact = strings.TrimSpace(contact); contact != "" {
+		identity.contact = contact
+	}
+
+	if len(identity.headers) == 0 && identity.contact == "" {
+		return nil, nil
+	}
+	return identity, nil
+}
+
+// setHeader sets a header, resolving a secret reference in its value
+func (i *Identity) setHeader(name, value string) error {
+	secret := IsSecretRef(value)
+	if secret {
+		resolved, err := ResolveSecret(value)
+		if err != nil {
+			return err
+		}
+		value = resolved
+	}
+
+	i.headers.Set(name, value)
+	i.secrets[http.CanonicalHeaderKey(name)] = secret
+	return nil
+}
+
+// ParseHeader parses a "Name: value" header specification
+func ParseHeader(spec string) (string, string, error) {
+	name, value, found := strings.Cut(spec, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", NewValidationError(fmt.Sprintf("invalid header %q, expected \"Name: value\"", spec), nil)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// UserAgent returns the base User-Agent with the researcher contact appended
+func (i *Identity) UserAgent(base string) string {
+	if i == nil || i.contact == "" {
+		return base
+	}
+	if base == "" {
+		base = defaultUserAgent
+	}
+	return fmt.Sprintf("%s (+%s)", base, i.contact)
+}
+
+// String describes the identification sent with each request, masking the
+// values of headers read from a secret store
+func (i *Identity) String() string {
+	if i == nil {
+		return "none"
+	}
+
+	var parts []string
+	for name := range i.headers {
+		value := i.headers.Get(name)
+		if i.secrets[name] {
+			value = "<secret>"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", name, value))
+	}
+	sort.Strings(parts)
+	if i.contact != "" {
+		parts = append(parts, "User-Agent contact: "+i.contact)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// apply sets the identification headers on a request
+func (i *Identity) apply(req *http.Request) {
+	for name, values := range i.headers {
+		req.Header[name] = values
+	}
+	if i.contact != "" {
+		req.Header.Set("User-Agent", i.UserAgent(req.Header.Get("User-Agent")))
+	}
+}
+
+// applyRaw adds the identification headers to the head of a raw request, its
+// request line and header lines joined by CRLF. The request's own headers stay
+// as written, except that the contact is appended to its User-Agent.
+func (i *Identity) applyRaw(head string) string {
+	lines := strings.Split(head, "\r\n")
+	userAgent := false
+	if i.contact != "" {
+		for n := 1; n < len(lines); n++ {
+			name, value, found := strings.Cut(lines[n], ":")
+			if found && strings.EqualFold(strings.TrimSpace(name), "User-Agent") {
+				lines[n] = name + ": " + i.UserAgent(strings.TrimSpace(value))
+				userAgent = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(i.headers))
+	for name := range i.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range i.headers[name] {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	if i.contact != "" && !userAgent {
+		lines = append(lines, "User-Agent: "+i.UserAgent(""))
+	}
+	return strings.Join(lines, "\r\n")
+}
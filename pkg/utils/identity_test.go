@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewIdentity(t *testing.T) {
+	identity, err := NewIdentity(IdentityConfig{}, nil, "")
+	if err != nil || identity != nil {
+		t.Fatalf("Expected nil identity without settings, got %v (%v)", identity, err)
+	}
+
+	config := IdentityConfig{
+		Headers: map[string]string{"X-Bug-Bounty": "from-config"},
+		Contact: "config@example.com",
+	}
+	identity, err = NewIdentity(config, []string{"x-bug-bounty: from-flag"}, "flag@example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := identity.headers.Get("X-Bug-Bounty"); got != "from-flag" {
+		t.Errorf("Expected flag to override config header, got %q", got)
+	}
+	if got := identity.UserAgent(""); got != "jsfinder/1.0 (+flag@example.com)" {
+		t.Errorf("Unexpected User-Agent %q", got)
+	}
+
+	if _, err := NewIdentity(IdentityConfig{}, []string{"no separator"}, ""); err == nil {
+		t.Error("Expected error for malformed header")
+	}
+
+	t.Setenv("JSFINDER_TEST_COOKIE", "session=abc123")
+	identity, err = NewIdentity(IdentityConfig{}, []string{"Cookie: env:JSFINDER_TEST_COOKIE"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := identity.headers.Get("Cookie"); got != "session=abc123" {
+		t.Errorf("Expected header resolved from the environment, got %q", got)
+	}
+	if got := identity.String(); got != "Cookie: <secret>" {
+		t.Errorf("Expected secret header value to be masked, got %q", got)
+	}
+}
+
+func TestIdentityTransport(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	identity, err := NewIdentity(IdentityConfig{}, []string{"X-Bug-Bounty: researcher-id"}, "me@example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := NewHTTPClient(&ClientOptions{Identity: identity})
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("User-Agent", "custom/2.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := received.Get("X-Bug-Bounty"); got != "researcher-id" {
+		t.Errorf("Expected identification header, got %q", got)
+	}
+	if got := received.Get("User-Agent"); got != "custom/2.0 (+me@example.com)" {
+		t.Errorf("Expected contact in User-Agent, got %q", got)
+	}
+	if req.Header.Get("X-Bug-Bounty") != "" {
+		t.Error("Expected caller's request to be left unmodified")
+	}
+}
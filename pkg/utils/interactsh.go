@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultInteractshServer is a public interactsh server run by ProjectDiscovery
+const DefaultInteractshServer = "oast.fun"
+
+// Lengths of the two parts of an interactsh subdomain: the correlation ID
+// the server files interactions under, then a nonce telling payloads apart
+const (
+	interactshCorrelationLen = 20
+	interactshNonceLen       = 13
+)
+
+// interactshAlphabet is what subdomain IDs are drawn from; DNS names are case-insensitive
+const interactshAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// Interaction is a DNS, HTTP or SMTP request an interactsh server received
+// for one of the client's payload hosts
+type Interaction struct {
+	Protocol      string    `json:"protocol"`
+	UniqueID      string    `json:"unique-id"`
+	FullID        string    `json:"full-id"`
+	RemoteAddress string    `json:"remote-address"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Nonce returns the payload part of the interaction's subdomain
+func (i Interaction) Nonce() string {
+	id := strings.ToLower(i.UniqueID)
+	if len(id) < interactshCorrelationLen+interactshNonceLen {
+		return ""
+	}
+	return id[interactshCorrelationLen : interactshCorrelationLen+interactshNonceLen]
+}
+
+// Interactsh is a client of an interactsh server (github.com/projectdiscovery/interactsh),
+// which records out-of-band DNS and HTTP interactions with unique
+// subdomains. Interactions are encrypted with a key only this client holds.
+type Interactsh struct {
+	server        *url.URL
+	token         string
+	client        *http.Client
+	key           *rsa.PrivateKey
+	correlationID string
+	secret        string
+}
+
+// NewInteractsh registers a new session with server, given as a host or
+// http(s) URL; token is sent to servers that require authorization. Requests
+// to the server use a client built from options, or a plain one with a 30
+// second timeout when options is nil. The target's scope, budget and
+// credentials do not apply to the server, so callers leave them out.
+func NewInteractsh(server, token string, options *ClientOptions) (*Interactsh, error) {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	serverURL, err := url.Parse(server)
+	if err != nil || serverURL.Hostname() == "" || (serverURL.Scheme != "http" && serverURL.Scheme != "https") {
+		return nil, NewValidationError(fmt.Sprintf("invalid interactsh server %q, expected host or http(s)://host", server), nil)
+	}
+	serverURL.Path = strings.TrimSuffix(serverURL.Path, "/")
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = &ClientOptions{Timeout: 30 * time.Second}
+	}
+	c := &Interactsh{
+		server:        serverURL,
+		token:         token,
+		client:        NewHTTPClient(options),
+		key:           key,
+		correlationID: randomID(interactshCorrelationLen),
+		secret:        randomID(32),
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: publicKey}))
+	if err := c.post("/register", map[string]string{"public-key": encoded, "secret-key": c.secret, "correlation-id": c.correlationID}); err != nil {
+		return nil, fmt.Errorf("failed to register with interactsh server %s: %w", serverURL.Host, err)
+	}
+	return c, nil
+}
+
+// Server returns the host payloads are subdomains of
+func (c *Interactsh) Server() string {
+	return c.server.Hostname()
+}
+
+// Payload returns a new unique host under the server and the nonce that
+// identifies interactions with it
+func (c *Interactsh) Payload() (host, nonce string) {
+	nonce = randomID(interactshNonceLen)
+	return c.correlationID + nonce + "." + c.Server(), nonce
+}
+
+// Poll returns the interactions received since the last poll
+func (c *Interactsh) Poll() ([]Interaction, error) {
+	query := url.Values{"id": {c.correlationID}, "secret": {c.secret}}
+	req, err := http.NewRequest("GET", c.server.String()+"/poll?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll interactsh server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to poll interactsh server: HTTP %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Data   []string `json:"data"`
+		Extra  []string `json:"extra"`
+		AESKey string   `json:"aes_key"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid interactsh poll response: %w", err)
+	}
+
+	var interactions []Interaction
+	if len(response.Data) > 0 {
+		encryptedKey, err := base64.StdEncoding.DecodeString(response.AESKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interactsh key: %w", err)
+		}
+		aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, c.key, encryptedKey, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt interactsh key: %w", err)
+		}
+
+		for _, data := range response.Data {
+			plain, err := decryptInteraction(aesKey, data)
+			if err != nil {
+				return interactions, err
+			}
+			var interaction Interaction
+			if json.Unmarshal(plain, &interaction) == nil {
+				interactions = append(interactions, interaction)
+			}
+		}
+	}
+	// Extra holds unencrypted interactions, from servers sharing them across sessions
+	for _, data := range response.Extra {
+		var interaction Interaction
+		if json.Unmarshal([]byte(data), &interaction) == nil {
+			interactions = append(interactions, interaction)
+		}
+	}
+	return interactions, nil
+}
+
+// Close ends the session so the server stops recording interactions for it
+func (c *Interactsh) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.post("/deregister", map[string]string{"correlation-id": c.correlationID, "secret-key": c.secret})
+}
+
+func (c *Interactsh) post(path string, body map[string]string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", c.server.String()+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Interactsh) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+}
+
+// decryptInteraction decrypts one poll record: base64 of an AES-CFB IV
+// followed by the ciphertext
+func decryptInteraction(key []byte, data string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interactsh record: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < aes.BlockSize {
+		return nil, fmt.Errorf("invalid interactsh record: too short")
+	}
+
+	plain := make([]byte, len(raw)-aes.BlockSize)
+	cipher.NewCFBDecrypter(block, raw[:aes.BlockSize]).XORKeyStream(plain, raw[aes.BlockSize:])
+	return plain, nil
+}
+
+// randomID returns n random characters valid in a DNS label
+func randomID(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	for i, b := range buf {
+		buf[i] = interactshAlphabet[int(b)%len(interactshAlphabet)]
+	}
+	return string(buf)
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInteractsh(t *testing.T) {
+	var (
+		mutex        sync.Mutex
+		publicKey    *rsa.PublicKey
+		correlation  string
+		pending      []string
+		deregistered bool
+		anonymous    atomic.Bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Bug-Bounty") == "" {
+			anonymous.Store(true)
+		}
+		if r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/register":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			encoded, _ := base64.StdEncoding.DecodeString(body["public-key"])
+			block, _ := pem.Decode(encoded)
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			publicKey = key.(*rsa.PublicKey)
+			correlation = body["correlation-id"]
+		case "/poll":
+			if r.URL.Query().Get("id") != correlation {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			aesKey := make([]byte, 32)
+			rand.Read(aesKey)
+			encryptedKey, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, aesKey, nil)
+
+			var data []string
+			for _, plain := range pending {
+				block, _ := aes.NewCipher(aesKey)
+				raw := make([]byte, aes.BlockSize+len(plain))
+				rand.Read(raw[:aes.BlockSize])
+				cipher.NewCFBEncrypter(block, raw[:aes.BlockSize]).XORKeyStream(raw[aes.BlockSize:], []byte(plain))
+				data = append(data, base64.StdEncoding.EncodeToString(raw))
+			}
+			pending = nil
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "aes_key": base64.StdEncoding.EncodeToString(encryptedKey)})
+		case "/deregister":
+			deregistered = true
+		}
+	}))
+	defer server.Close()
+
+	identity, err := NewIdentity(IdentityConfig{}, []string{"X-Bug-Bounty: researcher"}, "")
+	if err != nil {
+		t.Fatalf("NewIdentity() error = %v", err)
+	}
+	options := &ClientOptions{Timeout: 5 * time.Second, Identity: identity}
+
+	if _, err := NewInteractsh(server.URL, "wrong", options); err == nil {
+		t.Error("Expected registration to fail with a rejected token")
+	}
+
+	client, err := NewInteractsh(server.URL, "token", options)
+	if err != nil {
+		t.Fatalf("NewInteractsh() error = %v", err)
+	}
+
+	host, nonce := client.Payload()
+	if !strings.HasSuffix(host, ".127.0.0.1") || len(nonce) != interactshNonceLen || !strings.HasPrefix(host, correlation+nonce) {
+		t.Errorf("Unexpected payload host %q (nonce %q)", host, nonce)
+	}
+
+	id := strings.TrimSuffix(host, ".127.0.0.1")
+	mutex.Lock()
+	pending = []string{`{"protocol":"dns","unique-id":"` + id + `","full-id":"` + id + `","remote-address":"203.0.113.7"}`}
+	mutex.Unlock()
+
+	interactions, err := client.Poll()
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if len(interactions) != 1 || interactions[0].Protocol != "dns" || interactions[0].Nonce() != nonce || interactions[0].RemoteAddress != "203.0.113.7" {
+		t.Errorf("Unexpected interactions %+v", interactions)
+	}
+	if interactions, _ := client.Poll(); len(interactions) != 0 {
+		t.Errorf("Expected interactions to be returned once, got %+v", interactions)
+	}
+
+	if err := client.Close(); err != nil || !deregistered {
+		t.Errorf("Expected the session to be deregistered, got %v", err)
+	}
+	if anonymous.Load() {
+		t.Error("Expected every request to carry the identity header of the client options")
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// labelKey limits keys to characters every export format can carry
+// unescaped; RFC 5424 caps structured data parameter names at 32 characters
+var labelKey = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,31}$`)
+
+// Labels are the key=value pairs given with --label, attached to every
+// record a run writes so results of runs for several teams or customers can
+// be told apart without post-processing
+type Labels map[string]string
+
+// ParseLabels parses key=value specs; a key may only be given once
+func ParseLabels(specs []string) (Labels, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	labels := make(Labels, len(specs))
+	for _, spec := range specs {
+		key, value, found := strings.Cut(spec, "=")
+		key = strings.TrimSpace(key)
+		if !found || !labelKey.MatchString(key) {
+			return nil, NewValidationError(fmt.Sprintf("invalid label %q, expected key=value with a key of up to 32 letters, digits, '_', '.' or '-'", spec), nil)
+		}
+		if _, exists := labels[key]; exists {
+			return nil, NewValidationError(fmt.Sprintf("label %s given more than once", key), nil)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// Keys returns the label keys in order
+func (l Labels) Keys() []string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String returns the labels as key=value pairs ordered by key and joined
+// with ';', as they appear in CSV and text output
+func (l Labels) String() string {
+	pairs := make([]string, 0, len(l))
+	for _, key := range l.Keys() {
+		pairs = append(pairs, key+"="+l[key])
+	}
+	return strings.Join(pairs, ";")
+}
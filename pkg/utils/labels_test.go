@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]string{"team=payments", "env=prod", "note=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("Failed to parse labels: %v", err)
+	}
+	if labels["note"] != "a=b" || labels["empty"] != "" || len(labels) != 4 {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+	if got := labels.String(); got != "empty=;env=prod;note=a=b;team=payments" {
+		t.Errorf("Expected labels ordered by key, got %q", got)
+	}
+
+	if labels, err := ParseLabels(nil); err != nil || labels != nil {
+		t.Errorf("Expected no labels without --label, got %v (%v)", labels, err)
+	}
+
+	for _, invalid := range [][]string{{"team"}, {"=x"}, {"bad key=x"}, {"a\"b=x"}, {"team=a", "team=b"}, {"k123456789012345678901234567890123=x"}} {
+		if _, err := ParseLabels(invalid); err == nil {
+			t.Errorf("Expected error for labels %q", invalid)
+		}
+	}
+}
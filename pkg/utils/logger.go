@@ -1,10 +1,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -37,11 +41,82 @@ func (l LogLevel) String() string {
 	}
 }
 
+// ParseLogLevel parses a level name such as "debug" or "WARN"
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	case "FATAL":
+		return FATAL, nil
+	default:
+		return INFO, NewValidationError(fmt.Sprintf("invalid log level %q", name), nil)
+	}
+}
+
+// LogFormat selects how log lines are rendered
+type LogFormat int
+
+const (
+	TextFormat LogFormat = iota
+	JSONFormat
+)
+
+// ParseLogFormat parses a log format name ("text" or "json")
+func ParseLogFormat(name string) (LogFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "text":
+		return TextFormat, nil
+	case "json":
+		return JSONFormat, nil
+	default:
+		return TextFormat, NewValidationError(fmt.Sprintf("invalid log format %q, expected text or json", name), nil)
+	}
+}
+
+// defaultFormat is the format used by newly created loggers
+var defaultFormat = TextFormat
+
+// defaultOutput is where newly created module loggers write
+var defaultOutput io.Writer = os.Stderr
+
+// Per-module levels and the shared sampler applied to module loggers
+var (
+	moduleLevels   = map[string]LogLevel{}
+	moduleDefault  = INFO
+	defaultSampler *logSampler
+)
+
+// logSampler passes the first of every n occurrences of a repeated message
+type logSampler struct {
+	every  int64
+	mutex  sync.Mutex
+	counts map[string]int64
+}
+
+// allow records an occurrence of key and reports whether it should be logged
+func (s *logSampler) allow(key string) (int64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counts[key]++
+	count := s.counts[key]
+	return count, (count-1)%s.every == 0
+}
+
 // Logger represents a structured logger
 type Logger struct {
-	level  LogLevel
-	output io.Writer
-	logger *log.Logger
+	level   LogLevel
+	output  io.Writer
+	logger  *log.Logger
+	format  LogFormat
+	fields  map[string]string // Fields attached to every line, set via FieldLogger.Logger
+	sampler *logSampler       // Optional sampler for repetitive messages
 }
 
 // NewLogger creates a new logger instance
@@ -51,9 +126,11 @@ func NewLogger(level LogLevel, output io.Writer) *Logger {
 	}
 
 	return &Logger{
-		level:  level,
-		output: output,
-		logger: log.New(output, "", 0),
+		level:   level,
+		output:  output,
+		logger:  log.New(output, "", 0),
+		format:  defaultFormat,
+		sampler: defaultSampler,
 	}
 }
 
@@ -62,11 +139,27 @@ func NewDefaultLogger() *Logger {
 	return NewLogger(INFO, os.Stderr)
 }
 
+// NewModuleLogger creates a logger for a named component, using the level
+// configured for that module and tagging every line with component=<module>
+func NewModuleLogger(module string) *Logger {
+	level, exists := moduleLevels[module]
+	if !exists {
+		level = moduleDefault
+	}
+
+	return NewLogger(level, defaultOutput).WithField("component", module).Logger()
+}
+
 // SetLevel sets the logging level
 func (l *Logger) SetLevel(level LogLevel) {
 	l.level = level
 }
 
+// SetFormat sets the output format
+func (l *Logger) SetFormat(format LogFormat) {
+	l.format = format
+}
+
 // SetOutput sets the output writer
 func (l *Logger) SetOutput(output io.Writer) {
 	l.output = output
@@ -127,17 +220,14 @@ func (l *Logger) Fatalf(format string, args ...interface{}) {
 
 // WithField returns a new logger with additional field
 func (l *Logger) WithField(key, value string) *FieldLogger {
-	return &FieldLogger{
-		logger: l,
-		fields: map[string]string{key: value},
-	}
+	return l.WithFields(map[string]string{key: value})
 }
 
 // WithFields returns a new logger with additional fields
 func (l *Logger) WithFields(fields map[string]string) *FieldLogger {
 	return &FieldLogger{
 		logger: l,
-		fields: fields,
+		fields: mergeFields(l.fields, fields),
 	}
 }
 
@@ -146,15 +236,12 @@ func (l *Logger) log(level LogLevel, msg string, args ...interface{}) {
 		return
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	levelStr := level.String()
-
+	template := msg
 	if len(args) > 0 {
 		msg = fmt.Sprintf(msg, args...)
 	}
 
-	logMsg := fmt.Sprintf("[%s] %s: %s", timestamp, levelStr, msg)
-	l.logger.Println(logMsg)
+	l.write(level, msg, template, l.fields)
 }
 
 func (l *Logger) logf(level LogLevel, format string, args ...interface{}) {
@@ -162,18 +249,79 @@ func (l *Logger) logf(level LogLevel, format string, args ...interface{}) {
 		return
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	levelStr := level.String()
-	msg := fmt.Sprintf(format, args...)
+	l.write(level, fmt.Sprintf(format, args...), format, l.fields)
+}
+
+// write renders a single log line with its fields in the configured format.
+// Lines sharing a sample key (the format template by default) are sampled.
+func (l *Logger) write(level LogLevel, msg, sampleKey string, fields map[string]string) {
+	if l.sampler != nil && level < FATAL {
+		if sampleKey == "" {
+			sampleKey = msg
+		}
+		count, ok := l.sampler.allow(level.String() + "|" + sampleKey)
+		if !ok {
+			return
+		}
+		if count > 1 {
+			msg = fmt.Sprintf("%s (sampled, %d occurrences)", msg, count)
+		}
+	}
+
+	now := time.Now()
+
+	if l.format == JSONFormat {
+		entry := make(map[string]string, len(fields)+3)
+		for key, value := range fields {
+			entry[key] = value
+		}
+		entry["time"] = now.Format(time.RFC3339)
+		entry["level"] = level.String()
+		entry["msg"] = msg
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		l.logger.Println(string(data))
+		return
+	}
+
+	logMsg := fmt.Sprintf("[%s] %s: %s", now.Format("2006-01-02 15:04:05"), level.String(), msg)
+	if len(fields) > 0 {
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = fmt.Sprintf("%s=%s", key, fields[key])
+		}
+		logMsg += fmt.Sprintf(" [%s]", strings.Join(pairs, " "))
+	}
 
-	logMsg := fmt.Sprintf("[%s] %s: %s", timestamp, levelStr, msg)
 	l.logger.Println(logMsg)
 }
 
+// mergeFields returns a new map holding base overlaid with extra
+func mergeFields(base, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(extra))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	return merged
+}
+
 // FieldLogger represents a logger with additional fields
 type FieldLogger struct {
-	logger *Logger
-	fields map[string]string
+	logger    *Logger
+	fields    map[string]string
+	sampleKey string // Overrides the message template as the sampling key
 }
 
 // Debug logs a debug message with fields
@@ -202,17 +350,66 @@ func (fl *FieldLogger) Fatal(msg string, args ...interface{}) {
 	os.Exit(1)
 }
 
+// Debugf logs a formatted debug message with fields
+func (fl *FieldLogger) Debugf(format string, args ...interface{}) {
+	fl.logf(DEBUG, format, args...)
+}
+
+// Infof logs a formatted info message with fields
+func (fl *FieldLogger) Infof(format string, args ...interface{}) {
+	fl.logf(INFO, format, args...)
+}
+
+// Warnf logs a formatted warning message with fields
+func (fl *FieldLogger) Warnf(format string, args ...interface{}) {
+	fl.logf(WARN, format, args...)
+}
+
+// Errorf logs a formatted error message with fields
+func (fl *FieldLogger) Errorf(format string, args ...interface{}) {
+	fl.logf(ERROR, format, args...)
+}
+
+// Fatalf logs a formatted fatal message with fields and exits
+func (fl *FieldLogger) Fatalf(format string, args ...interface{}) {
+	fl.logf(FATAL, format, args...)
+	os.Exit(1)
+}
+
 // WithField adds another field to the logger
 func (fl *FieldLogger) WithField(key, value string) *FieldLogger {
-	newFields := make(map[string]string)
-	for k, v := range fl.fields {
-		newFields[k] = v
+	return fl.WithFields(map[string]string{key: value})
+}
+
+// WithFields adds more fields to the logger
+func (fl *FieldLogger) WithFields(fields map[string]string) *FieldLogger {
+	return &FieldLogger{
+		logger:    fl.logger,
+		fields:    mergeFields(fl.fields, fields),
+		sampleKey: fl.sampleKey,
 	}
-	newFields[key] = value
+}
 
+// Sampled returns a logger whose lines are sampled under key rather than their
+// message text, for messages that embed unique values such as URLs
+func (fl *FieldLogger) Sampled(key string) *FieldLogger {
 	return &FieldLogger{
-		logger: fl.logger,
-		fields: newFields,
+		logger:    fl.logger,
+		fields:    fl.fields,
+		sampleKey: key,
+	}
+}
+
+// Logger returns a *Logger that attaches these fields to every line, so they
+// propagate into helpers such as Retry, LogError and TimeoutManager
+func (fl *FieldLogger) Logger() *Logger {
+	return &Logger{
+		level:   fl.logger.level,
+		output:  fl.logger.output,
+		logger:  fl.logger.logger,
+		format:  fl.logger.format,
+		fields:  mergeFields(fl.fields, nil),
+		sampler: fl.logger.sampler,
 	}
 }
 
@@ -221,28 +418,28 @@ func (fl *FieldLogger) log(level LogLevel, msg string, args ...interface{}) {
 		return
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	levelStr := level.String()
-
+	key := fl.sampleKey
+	if key == "" {
+		key = msg
+	}
 	if len(args) > 0 {
 		msg = fmt.Sprintf(msg, args...)
 	}
 
-	// Build fields string
-	fieldsStr := ""
-	for key, value := range fl.fields {
-		if fieldsStr != "" {
-			fieldsStr += " "
-		}
-		fieldsStr += fmt.Sprintf("%s=%s", key, value)
+	fl.logger.write(level, msg, key, fl.fields)
+}
+
+func (fl *FieldLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if level < fl.logger.level {
+		return
 	}
 
-	logMsg := fmt.Sprintf("[%s] %s: %s", timestamp, levelStr, msg)
-	if fieldsStr != "" {
-		logMsg += fmt.Sprintf(" [%s]", fieldsStr)
+	key := fl.sampleKey
+	if key == "" {
+		key = format
 	}
 
-	fl.logger.logger.Println(logMsg)
+	fl.logger.write(level, fmt.Sprintf(format, args...), key, fl.fields)
 }
 
 // Global logger instance
@@ -294,7 +491,60 @@ func SetGlobalLevel(level LogLevel) {
 	defaultLogger.SetLevel(level)
 }
 
-// SetGlobalOutput sets the global logger output
+// SetGlobalFormat sets the format of the global logger and of loggers created afterwards
+func SetGlobalFormat(format LogFormat) {
+	defaultFormat = format
+	defaultLogger.SetFormat(format)
+}
+
+// SetModuleLevels configures log levels from a spec such as "warn,crawler=debug,scanner=warn".
+// A bare level sets the default for all modules and the global logger.
+func SetModuleLevels(spec string) error {
+	levels := make(map[string]LogLevel)
+	fallback := INFO
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		module, name, found := strings.Cut(part, "=")
+		if !found {
+			level, err := ParseLogLevel(part)
+			if err != nil {
+				return err
+			}
+			fallback = level
+			continue
+		}
+
+		level, err := ParseLogLevel(name)
+		if err != nil {
+			return err
+		}
+		levels[strings.TrimSpace(module)] = level
+	}
+
+	moduleLevels = levels
+	moduleDefault = fallback
+	defaultLogger.SetLevel(fallback)
+	return nil
+}
+
+// SetGlobalSampling logs only the first of every n identical messages on loggers
+// created afterwards; n <= 1 disables sampling
+func SetGlobalSampling(n int) {
+	if n <= 1 {
+		defaultSampler = nil
+	} else {
+		defaultSampler = &logSampler{every: int64(n), counts: make(map[string]int64)}
+	}
+	defaultLogger.sampler = defaultSampler
+}
+
+// SetGlobalOutput sets the output of the global logger and of module loggers created afterwards
 func SetGlobalOutput(output io.Writer) {
+	defaultOutput = output
 	defaultLogger.SetOutput(output)
 }
\ No newline at end of file
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -106,6 +108,117 @@ func TestLogger_Formatted(t *testing.T) {
 	}
 }
 
+func TestFieldLogger_Formatted(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLogger(INFO, buf)
+
+	logger.WithField("component", "scanner").Warnf("failed after %d attempts", 3)
+
+	output := buf.String()
+	if !strings.Contains(output, "WARN: failed after 3 attempts [component=scanner]") {
+		t.Errorf("Unexpected formatted field output: %q", output)
+	}
+}
+
+func TestLogger_JSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLogger(INFO, buf)
+	logger.SetFormat(JSONFormat)
+
+	logger.WithFields(map[string]string{"target": "https://example.com", "worker_id": "2"}).Info("scanning")
+
+	var entry map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "scanning" || entry["level"] != "INFO" {
+		t.Errorf("Unexpected message fields: %v", entry)
+	}
+	if entry["target"] != "https://example.com" || entry["worker_id"] != "2" {
+		t.Errorf("Expected fields in JSON output, got %v", entry)
+	}
+}
+
+func TestFieldLogger_Logger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	base := NewLogger(DEBUG, buf)
+
+	logger := base.WithField("component", "crawler").Logger()
+	logger.WithField("target", "https://example.com").Debug("fetching")
+	logger.Info("plain")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "[component=crawler target=https://example.com]") {
+		t.Errorf("Expected merged fields, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "[component=crawler]") {
+		t.Errorf("Expected propagated fields on derived logger, got %q", lines[1])
+	}
+}
+
+func TestParseLogFormat(t *testing.T) {
+	if format, err := ParseLogFormat("JSON"); err != nil || format != JSONFormat {
+		t.Errorf("Expected JSONFormat, got %v (%v)", format, err)
+	}
+	if _, err := ParseLogFormat("xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestSetModuleLevels(t *testing.T) {
+	defer SetModuleLevels("")
+
+	if err := SetModuleLevels("warn,crawler=debug,scanner=error"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		module   string
+		expected LogLevel
+	}{
+		{"crawler", DEBUG},
+		{"scanner", ERROR},
+		{"discovery", WARN},
+	}
+	for _, tc := range testCases {
+		if level := NewModuleLogger(tc.module).level; level != tc.expected {
+			t.Errorf("Expected %s for %s, got %s", tc.expected, tc.module, level)
+		}
+	}
+
+	if err := SetModuleLevels("crawler=loud"); err == nil {
+		t.Error("Expected error for invalid level")
+	}
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	defer SetGlobalSampling(0)
+	SetGlobalSampling(3)
+
+	buf := &bytes.Buffer{}
+	logger := NewLogger(INFO, buf)
+	for i := 0; i < 7; i++ {
+		logger.Infof("request %d returned 404", i)
+	}
+	for i := 0; i < 4; i++ {
+		logger.WithField("url", "https://example.com").Sampled("HTTP:404").Warn(fmt.Sprintf("failed %d", i))
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 sampled lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[1], "request 3 returned 404 (sampled, 4 occurrences)") {
+		t.Errorf("Expected sampled occurrence count, got %q", lines[1])
+	}
+	if !strings.Contains(lines[4], "failed 3") {
+		t.Errorf("Expected explicit sample key to group unique messages, got %q", lines[4])
+	}
+}
+
 func TestNewDefaultLogger(t *testing.T) {
 	logger := NewDefaultLogger()
 	if logger == nil {
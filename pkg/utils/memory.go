@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fractions of the memory limit at which the guard reacts
+const (
+	memorySpillRatio    = 0.75 // Queues move their backlog to disk
+	memoryThrottleRatio = 0.90 // New work is held back
+	memoryResumeRatio   = 0.80 // Held work resumes once usage falls below this
+)
+
+// MemoryGuard watches the process's memory use against a limit. Near the
+// limit it reports pressure so queues spill to disk, and over it holds new
+// work until the garbage collector has brought usage back down. A nil guard
+// imposes no limit.
+type MemoryGuard struct {
+	limit     uint64
+	interval  time.Duration
+	sample    func() uint64
+	current   atomic.Uint64
+	peak      atomic.Uint64
+	throttled atomic.Bool
+	logger    *Logger
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewMemoryGuard creates a guard for the given limit in bytes, or nil for no limit.
+// The limit also becomes the Go runtime's soft memory limit, so the garbage
+// collector runs more often as usage approaches it.
+func NewMemoryGuard(limit int64) *MemoryGuard {
+	if limit <= 0 {
+		return nil
+	}
+
+	debug.SetMemoryLimit(limit)
+	return &MemoryGuard{
+		limit:    uint64(limit),
+		interval: 250 * time.Millisecond,
+		sample:   readMemory,
+		logger:   NewModuleLogger("memory"),
+		stop:     make(chan struct{}),
+	}
+}
+
+// readMemory returns the memory the Go runtime holds from the OS
+func readMemory() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys - stats.HeapReleased
+}
+
+// Start samples memory use in the background until Stop is called
+func (g *MemoryGuard) Start() {
+	if g == nil {
+		return
+	}
+
+	g.update()
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.stop:
+				return
+			case <-ticker.C:
+				g.update()
+			}
+		}
+	}()
+}
+
+// Stop ends background sampling
+func (g *MemoryGuard) Stop() {
+	if g == nil {
+		return
+	}
+	g.stopOnce.Do(func() { close(g.stop) })
+}
+
+// update takes one sample and moves in or out of the throttled state
+func (g *MemoryGuard) update() {
+	used := g.sample()
+	g.current.Store(used)
+	for {
+		peak := g.peak.Load()
+		if used <= peak || g.peak.CompareAndSwap(peak, used) {
+			break
+		}
+	}
+
+	switch {
+	case !g.throttled.Load() && used > uint64(float64(g.limit)*memoryThrottleRatio):
+		g.throttled.Store(true)
+		g.logger.Warnf("Memory use %s is near the %s limit, holding new work", FormatBytes(int64(used)), FormatBytes(int64(g.limit)))
+		debug.FreeOSMemory()
+	case g.throttled.Load() && used < uint64(float64(g.limit)*memoryResumeRatio):
+		g.throttled.Store(false)
+		g.logger.Infof("Memory use back to %s, resuming", FormatBytes(int64(used)))
+	}
+}
+
+// Pressure reports whether memory use is high enough that queued work should
+// be kept on disk rather than in memory
+func (g *MemoryGuard) Pressure() bool {
+	if g == nil {
+		return false
+	}
+	return g.current.Load() > uint64(float64(g.limit)*memorySpillRatio)
+}
+
+// Wait blocks while the guard is holding new work, returning early with an
+// error if ctx is cancelled
+func (g *MemoryGuard) Wait(ctx context.Context) error {
+	if g == nil || !g.throttled.Load() {
+		return nil
+	}
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for g.throttled.Load() {
+		select {
+		case <-ctx.Done():
+			return NewTimeoutError("cancelled while waiting for memory", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Peak returns the highest memory use sampled
+func (g *MemoryGuard) Peak() int64 {
+	if g == nil {
+		return 0
+	}
+	return int64(g.peak.Load())
+}
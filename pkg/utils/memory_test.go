@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"math"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestMemoryGuard(t *testing.T) {
+	defer debug.SetMemoryLimit(math.MaxInt64)
+
+	guard := NewMemoryGuard(1000)
+	guard.logger = NewLogger(ERROR, io.Discard)
+	guard.interval = 10 * time.Millisecond
+
+	used := uint64(500)
+	guard.sample = func() uint64 { return used }
+	guard.update()
+	if guard.Pressure() || guard.Wait(context.Background()) != nil {
+		t.Error("Expected no pressure at half the limit")
+	}
+
+	used = 800
+	guard.update()
+	if !guard.Pressure() {
+		t.Error("Expected pressure above the spill threshold")
+	}
+
+	used = 950
+	guard.update()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := guard.Wait(ctx); err == nil {
+		t.Error("Expected Wait to hold work over the throttle threshold")
+	}
+
+	// Usage must fall below the resume threshold, not just the throttle one
+	used = 850
+	guard.update()
+	if !guard.throttled.Load() {
+		t.Error("Expected guard to stay throttled above the resume threshold")
+	}
+	used = 700
+	guard.update()
+	if err := guard.Wait(context.Background()); err != nil {
+		t.Errorf("Expected work to resume, got %v", err)
+	}
+
+	if guard.Peak() != 950 {
+		t.Errorf("Expected peak 950, got %d", guard.Peak())
+	}
+}
+
+func TestMemoryGuard_Nil(t *testing.T) {
+	guard := NewMemoryGuard(0)
+	if guard != nil {
+		t.Fatal("Expected no guard without a limit")
+	}
+
+	guard.Start()
+	guard.Stop()
+	if guard.Pressure() || guard.Wait(context.Background()) != nil || guard.Peak() != 0 {
+		t.Error("Expected nil guard to impose no limit")
+	}
+}
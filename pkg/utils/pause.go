@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// PauseSwitch holds new work while paused, for runs that must stop sending
+// traffic on request without losing their progress. Work already in flight
+// finishes. A nil *PauseSwitch never pauses.
+type PauseSwitch struct {
+	mutex   sync.Mutex
+	paused  bool
+	resumed chan struct{} // Closed when the current pause ends
+}
+
+// NewPauseSwitch creates a switch in the running state
+func NewPauseSwitch() *PauseSwitch {
+	return &PauseSwitch{}
+}
+
+// Pause holds new work until Resume is called
+func (p *PauseSwitch) Pause() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.paused {
+		p.paused = true
+		p.resumed = make(chan struct{})
+	}
+}
+
+// Resume releases the work held by Pause
+func (p *PauseSwitch) Resume() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.paused {
+		p.paused = false
+		close(p.resumed)
+	}
+}
+
+// Toggle pauses a running switch or resumes a paused one, returning whether
+// it is now paused
+func (p *PauseSwitch) Toggle() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.paused {
+		p.paused = false
+		close(p.resumed)
+	} else {
+		p.paused = true
+		p.resumed = make(chan struct{})
+	}
+	return p.paused
+}
+
+// Paused reports whether new work is being held
+func (p *PauseSwitch) Paused() bool {
+	if p == nil {
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.paused
+}
+
+// Wait blocks while the switch is paused, returning early with an error if
+// the context is cancelled
+func (p *PauseSwitch) Wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	paused, resumed := p.paused, p.resumed
+	p.mutex.Unlock()
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return NewTimeoutError("cancelled while paused", ctx.Err())
+	}
+}
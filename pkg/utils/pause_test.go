@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPauseSwitch(t *testing.T) {
+	var disabled *PauseSwitch
+	if disabled.Paused() || disabled.Wait(context.Background()) != nil {
+		t.Error("Expected nil switch to never pause")
+	}
+
+	pause := NewPauseSwitch()
+	if err := pause.Wait(context.Background()); err != nil {
+		t.Fatalf("Expected running switch not to block, got %v", err)
+	}
+
+	if !pause.Toggle() || !pause.Paused() {
+		t.Fatal("Expected Toggle to pause a running switch")
+	}
+	pause.Pause() // Pausing twice keeps the same pause
+
+	released := make(chan error)
+	go func() { released <- pause.Wait(context.Background()) }()
+	select {
+	case <-released:
+		t.Fatal("Expected Wait to block while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if pause.Toggle() {
+		t.Fatal("Expected Toggle to resume a paused switch")
+	}
+	select {
+	case err := <-released:
+		if err != nil {
+			t.Errorf("Expected resume to release waiters, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected resume to release waiters")
+	}
+	pause.Resume() // Resuming a running switch is a no-op
+
+	pause.Pause()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pause.Wait(ctx); err == nil {
+		t.Error("Expected cancelled context to end the wait")
+	}
+}
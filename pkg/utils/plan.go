@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// assumedLatency is the average request time used for dry-run duration estimates
+const assumedLatency = 250 * time.Millisecond
+
+// RequestPlan summarizes the requests a run would send without sending any,
+// for --dry-run sanity checks before an engagement
+type RequestPlan struct {
+	Command  string
+	Threads  int
+	Timeout  time.Duration
+	Settings []string // Resolved configuration lines, printed before the plan
+	Notes    []string // Caveats about requests the plan cannot predict
+	hosts    map[string]int64
+}
+
+// NewRequestPlan creates an empty plan for a command
+func NewRequestPlan(command string, threads int, timeout time.Duration) *RequestPlan {
+	return &RequestPlan{
+		Command: command,
+		Threads: threads,
+		Timeout: timeout,
+		hosts:   make(map[string]int64),
+	}
+}
+
+// Add records n planned requests to the host of rawURL
+func (p *RequestPlan) Add(rawURL string, n int64) {
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+	p.hosts[host] += n
+}
+
+// AddSetting records a resolved configuration value
+func (p *RequestPlan) AddSetting(name string, value interface{}) {
+	p.Settings = append(p.Settings, fmt.Sprintf("%s: %v", name, value))
+}
+
+// AddNote records a caveat about the plan
+func (p *RequestPlan) AddNote(format string, args ...interface{}) {
+	p.Notes = append(p.Notes, fmt.Sprintf(format, args...))
+}
+
+// Hosts returns the planned request count per host
+func (p *RequestPlan) Hosts() map[string]int64 {
+	return p.hosts
+}
+
+// Total returns the total number of planned requests
+func (p *RequestPlan) Total() int64 {
+	var total int64
+	for _, count := range p.hosts {
+		total += count
+	}
+	return total
+}
+
+// EstimatedDuration estimates run time at the configured concurrency for the given per-request latency
+func (p *RequestPlan) EstimatedDuration(latency time.Duration) time.Duration {
+	threads := int64(p.Threads)
+	if threads < 1 {
+		threads = 1
+	}
+	rounds := (p.Total() + threads - 1) / threads
+	return time.Duration(rounds) * latency
+}
+
+// Write prints the plan in a human-readable form
+func (p *RequestPlan) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Dry Run: %s ===\n", p.Command)
+	for _, setting := range p.Settings {
+		fmt.Fprintf(&b, "%s\n", setting)
+	}
+
+	hosts := make([]string, 0, len(p.hosts))
+	for host := range p.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if p.hosts[hosts[i]] != p.hosts[hosts[j]] {
+			return p.hosts[hosts[i]] > p.hosts[hosts[j]]
+		}
+		return hosts[i] < hosts[j]
+	})
+
+	fmt.Fprintf(&b, "\nRequests per host:\n")
+	for _, host := range hosts {
+		fmt.Fprintf(&b, "  %-40s %d\n", host, p.hosts[host])
+	}
+	fmt.Fprintf(&b, "\nTotal requests:   %d across %d hosts\n", p.Total(), len(hosts))
+	fmt.Fprintf(&b, "Estimated time:   ~%s at %d threads (%s/request), up to %s if every request times out\n",
+		p.EstimatedDuration(assumedLatency).Round(time.Second), p.Threads, assumedLatency,
+		p.EstimatedDuration(p.Timeout).Round(time.Second))
+
+	for _, note := range p.Notes {
+		fmt.Fprintf(&b, "Note: %s\n", note)
+	}
+	b.WriteString("No requests were sent.\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
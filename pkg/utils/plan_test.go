@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestPlan(t *testing.T) {
+	plan := NewRequestPlan("discover", 4, 10*time.Second)
+	plan.Add("https://a.example.com/app.js", 1)
+	plan.Add("https://a.example.com", 60)
+	plan.Add("https://b.example.com:8443/x.js", 3)
+
+	if total := plan.Total(); total != 64 {
+		t.Errorf("Expected 64 requests, got %d", total)
+	}
+	if count := plan.Hosts()["a.example.com"]; count != 61 {
+		t.Errorf("Expected 61 requests for a.example.com, got %d", count)
+	}
+	if count := plan.Hosts()["b.example.com:8443"]; count != 3 {
+		t.Errorf("Expected host with port to be kept, got %d", count)
+	}
+
+	// 64 requests over 4 threads is 16 rounds
+	if duration := plan.EstimatedDuration(time.Second); duration != 16*time.Second {
+		t.Errorf("Expected 16s estimate, got %s", duration)
+	}
+
+	var buf bytes.Buffer
+	if err := plan.Write(&buf); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "Total requests:   64 across 2 hosts") {
+		t.Errorf("Expected totals in output, got %q", output)
+	}
+	if strings.Index(output, "a.example.com") > strings.Index(output, "b.example.com") {
+		t.Error("Expected hosts ordered by request count")
+	}
+}
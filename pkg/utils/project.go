@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Standard files inside a project run directory
+const (
+	ProjectJSFiles   = "jsfiles.txt"
+	ProjectFindings  = "findings"
+	ProjectEndpoints = "endpoints.csv"
+	ProjectWordlist  = "wordlist.txt"
+	ProjectEvidence  = "evidence"
+	ProjectLogs      = "logs"
+	projectRuns      = "runs.jsonl"
+)
+
+// Project is a per-engagement directory laid out as <name>/<date>/ so the
+// crawl, scan and discover runs of one day land side by side
+type Project struct {
+	Name string
+	Dir  string
+}
+
+// RunRecord describes one command run for reproducing it later
+type RunRecord struct {
+	Command  string            `json:"command"`
+	Version  string            `json:"version"`
+	Args     []string          `json:"args"`
+	Flags    map[string]string `json:"flags"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Duration string            `json:"duration"`
+	Stats    StatsSnapshot     `json:"stats"`
+	Labels   Labels            `json:"labels,omitempty"`
+}
+
+// NewProject creates the project directory for the given day, including its
+// evidence and logs subdirectories; an existing directory is reused
+func NewProject(name string, day time.Time) (*Project, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	dir := filepath.Join(name, day.Format("2006-01-02"))
+	for _, sub := range []string{ProjectEvidence, ProjectLogs} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create project directory: %w", err)
+		}
+	}
+
+	return &Project{Name: name, Dir: dir}, nil
+}
+
+// Path returns the path of a file inside the run directory
+func (p *Project) Path(elem ...string) string {
+	return filepath.Join(append([]string{p.Dir}, elem...)...)
+}
+
+// OpenLog opens the log file for a command run, named after the command and start time
+func (p *Project) OpenLog(command string, started time.Time) (*os.File, error) {
+	name := fmt.Sprintf("%s-%s.log", command, started.Format("150405"))
+	return os.OpenFile(p.Path(ProjectLogs, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// RecordRun appends a run record to the project's runs.jsonl
+func (p *Project) RecordRun(record RunRecord) error {
+	file, err := os.OpenFile(p.Path(projectRuns), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	defer file.Close()
+
+	if record.Duration == "" {
+		record.Duration = record.Finished.Sub(record.Started).Round(time.Millisecond).String()
+	}
+	return json.NewEncoder(file).Encode(record)
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProject(t *testing.T) {
+	if project, err := NewProject("", time.Now()); project != nil || err != nil {
+		t.Fatalf("Expected no project without a name, got %v, %v", project, err)
+	}
+
+	name := filepath.Join(t.TempDir(), "acme")
+	started := time.Date(2024, 3, 9, 14, 30, 5, 0, time.UTC)
+	project, err := NewProject(name, started)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	if project.Dir != filepath.Join(name, "2024-03-09") {
+		t.Errorf("Expected dated run directory, got %s", project.Dir)
+	}
+	for _, sub := range []string{ProjectEvidence, ProjectLogs} {
+		if info, err := os.Stat(project.Path(sub)); err != nil || !info.IsDir() {
+			t.Errorf("Expected %s directory: %v", sub, err)
+		}
+	}
+
+	logFile, err := project.OpenLog("crawl", started)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	logFile.Close()
+	if logFile.Name() != project.Path(ProjectLogs, "crawl-143005.log") {
+		t.Errorf("Unexpected log file %s", logFile.Name())
+	}
+
+	for _, command := range []string{"crawl", "scan"} {
+		err := project.RecordRun(RunRecord{
+			Command:  command,
+			Version:  "dev",
+			Flags:    map[string]string{"threads": "10"},
+			Started:  started,
+			Finished: started.Add(1500 * time.Millisecond),
+		})
+		if err != nil {
+			t.Fatalf("Failed to record run: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(project.Path(projectRuns))
+	if err != nil {
+		t.Fatalf("Failed to read run records: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one record per run, got %d", len(lines))
+	}
+
+	var record RunRecord
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("Failed to parse run record: %v", err)
+	}
+	if record.Command != "scan" || record.Duration != "1.5s" || record.Flags["threads"] != "10" {
+		t.Errorf("Unexpected run record %+v", record)
+	}
+}
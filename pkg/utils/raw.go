@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"jsfinder/pkg/scope"
+)
+
+// RawClient sends requests byte for byte as given, for the ones http.Client
+// would normalize away: nonstandard methods, duplicate or oddly spaced
+// headers, header case and order, conflicting framing headers. It applies
+// the scope, budget, stats and timeouts of NewHTTPClient the same way;
+// identification headers are added after the request's own, and the
+// User-Agent fallback is not retried since it would change the request.
+type RawClient struct {
+	options  ClientOptions
+	timeouts ClientTimeouts
+}
+
+// NewRawClient creates a raw client configured from the shared options
+func NewRawClient(options *ClientOptions) *RawClient {
+	if options == nil {
+		options = &ClientOptions{}
+	}
+	return &RawClient{options: *options, timeouts: options.Timeouts.withDefaults()}
+}
+
+// Do sends raw, a complete HTTP/1.x request whose head ends in a blank line,
+// to origin (scheme://host[:port]) over a new connection and reads the
+// response. The connection is closed with the response body; resp.Request
+// holds method and url for code that reads it.
+func (c *RawClient) Do(ctx context.Context, origin, method, rawURL string, raw []byte) (*http.Response, error) {
+	target, err := url.Parse(origin)
+	if err != nil || target.Hostname() == "" || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil, fmt.Errorf("invalid origin %q, expected http(s)://host", origin)
+	}
+	if c.options.Scope != nil && !c.options.Scope.AllowsHost(target.Hostname()) {
+		return nil, fmt.Errorf("%w: %s", scope.ErrOutOfScope, target.Host)
+	}
+	if err := c.options.Budget.AllowRequest(); err != nil {
+		return nil, err
+	}
+	if c.options.Stats != nil {
+		c.options.Stats.AddRequest()
+	}
+
+	if c.options.Identity != nil {
+		head, body, _ := strings.Cut(string(raw), "\r\n\r\n")
+		raw = []byte(c.options.Identity.applyRaw(head) + "\r\n\r\n" + body)
+	}
+	if c.options.Auth != nil {
+		head, body, _ := strings.Cut(string(raw), "\r\n\r\n")
+		raw = []byte(c.options.Auth.applyRaw(head) + "\r\n\r\n" + body)
+	}
+
+	address := target.Host
+	if target.Port() == "" {
+		port := "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+		address = net.JoinHostPort(target.Hostname(), port)
+	}
+	dial := c.options.Scope.DialContext(c.timeouts.dialer())
+	if c.options.Dialer != nil {
+		dial = c.options.Dialer.dialContext(c.timeouts, c.options.Scope)
+	}
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if c.options.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.options.Timeout))
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	if target.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: target.Hostname(), NextProtos: []string{"http/1.1"}})
+		handshakeCtx, cancel := context.WithTimeout(ctx, c.timeouts.TLSHandshake)
+		err = tlsConn.HandshakeContext(handshakeCtx)
+		cancel()
+		if err != nil {
+			stop()
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if _, err := conn.Write(raw); err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	if c.timeouts.ResponseHeader > 0 {
+		conn.SetReadDeadline(time.Now().Add(c.timeouts.ResponseHeader))
+	}
+
+	requestURL, err := url.Parse(rawURL)
+	if err != nil {
+		requestURL = target
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: method, URL: requestURL, Header: make(http.Header)})
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	if c.timeouts.ResponseHeader > 0 {
+		deadline := time.Time{}
+		if c.options.Timeout > 0 {
+			deadline = time.Now().Add(c.options.Timeout)
+		}
+		conn.SetReadDeadline(deadline)
+	}
+
+	resp.Body = &rawBody{ReadCloser: resp.Body, conn: conn, stop: stop}
+	if c.options.Stats != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, onRead: c.options.Stats.AddBytes}
+	}
+	if c.options.Budget != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, onRead: c.options.Budget.AddBytes}
+	}
+	return resp, nil
+}
+
+// rawBody closes the connection of a raw request with the response body
+type rawBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+// Close implements io.Closer
+func (b *rawBody) Close() error {
+	b.stop()
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
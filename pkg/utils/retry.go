@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -10,13 +11,75 @@ import (
 
 // RetryConfig holds configuration for retry operations
 type RetryConfig struct {
-	MaxAttempts     int           // Maximum number of retry attempts
-	InitialDelay    time.Duration // Initial delay between retries
-	MaxDelay        time.Duration // Maximum delay between retries
-	BackoffFactor   float64       // Exponential backoff factor
-	Jitter          bool          // Whether to add random jitter
-	RetryableErrors []ErrorType   // Types of errors that should trigger retries
-	Timeout         time.Duration // Overall timeout for all retry attempts
+	MaxAttempts     int                       // Maximum number of retry attempts
+	InitialDelay    time.Duration             // Initial delay between retries
+	MaxDelay        time.Duration             // Maximum delay between retries
+	BackoffFactor   float64                   // Exponential backoff factor
+	Jitter          bool                      // Whether to add random jitter
+	RetryableErrors []ErrorType               // Types of errors that should trigger retries
+	Timeout         time.Duration             // Overall timeout for all retry attempts
+	Policies        map[ErrorType]RetryPolicy // Per error type overrides; a type with a policy is retried by it, listed in RetryableErrors or not
+}
+
+// RetryPolicy overrides the retry settings for one type of error
+type RetryPolicy struct {
+	MaxAttempts  int           `yaml:"max_attempts"`  // Attempts in total, 1 to fail on the first error; 0 keeps the config's
+	InitialDelay time.Duration `yaml:"initial_delay"` // Delay before the first retry; 0 keeps the config's
+}
+
+// defaultRetryPolicies fail fast on errors a retry cannot fix: a host that
+// does not exist, or a certificate that will not change. A refused
+// connection gets one more try in case a service is restarting.
+func defaultRetryPolicies() map[ErrorType]RetryPolicy {
+	return map[ErrorType]RetryPolicy{
+		DNSError:               {MaxAttempts: 1},
+		TLSError:               {MaxAttempts: 1},
+		ConnectionRefusedError: {MaxAttempts: 2},
+	}
+}
+
+// RetryPolicies are the per error type policies of the config file's retry
+// section, keyed by the names in retryPolicyTypes
+type RetryPolicies map[string]RetryPolicy
+
+// retryPolicyTypes maps the keys of the retry section to error types
+var retryPolicyTypes = map[string]ErrorType{
+	"dns":                DNSError,
+	"connection_refused": ConnectionRefusedError,
+	"tls":                TLSError,
+	"connection_reset":   ConnectionResetError,
+	"timeout":            TimeoutError,
+	"network":            NetworkError,
+	"http":               HTTPError,
+}
+
+// Validate reports unknown error types and negative settings
+func (p RetryPolicies) Validate() error {
+	for name, policy := range p {
+		if _, found := retryPolicyTypes[name]; !found {
+			return NewValidationError(fmt.Sprintf("unknown retry error type %q, expected dns, connection_refused, tls, connection_reset, timeout, network or http", name), nil)
+		}
+		if policy.MaxAttempts < 0 || policy.InitialDelay < 0 {
+			return NewValidationError(fmt.Sprintf("retry.%s must not be negative", name), nil)
+		}
+	}
+	return nil
+}
+
+// WithPolicies returns a copy of the config with the policies applied over
+// its own
+func (c *RetryConfig) WithPolicies(policies RetryPolicies) *RetryConfig {
+	merged := *c
+	merged.Policies = make(map[ErrorType]RetryPolicy, len(c.Policies)+len(policies))
+	for errType, policy := range c.Policies {
+		merged.Policies[errType] = policy
+	}
+	for name, policy := range policies {
+		if errType, found := retryPolicyTypes[name]; found {
+			merged.Policies[errType] = policy
+		}
+	}
+	return &merged
 }
 
 // DefaultRetryConfig returns a default retry configuration
@@ -31,8 +94,10 @@ func DefaultRetryConfig() *RetryConfig {
 			NetworkError,
 			TimeoutError,
 			HTTPError,
+			ConnectionResetError,
 		},
-		Timeout: 5 * time.Minute,
+		Timeout:  5 * time.Minute,
+		Policies: defaultRetryPolicies(),
 	}
 }
 
@@ -48,8 +113,10 @@ func NetworkRetryConfig() *RetryConfig {
 			NetworkError,
 			TimeoutError,
 			HTTPError,
+			ConnectionResetError,
 		},
-		Timeout: 2 * time.Minute,
+		Timeout:  2 * time.Minute,
+		Policies: defaultRetryPolicies(),
 	}
 }
 
@@ -64,8 +131,10 @@ func QuickRetryConfig() *RetryConfig {
 		RetryableErrors: []ErrorType{
 			NetworkError,
 			TimeoutError,
+			ConnectionResetError,
 		},
-		Timeout: 10 * time.Second,
+		Timeout:  10 * time.Second,
+		Policies: defaultRetryPolicies(),
 	}
 }
 
@@ -100,7 +169,7 @@ func Retry(ctx context.Context, config *RetryConfig, fn RetryableFunc, logger *L
 	ctx, cancel := createContextWithTimeout(ctx, config.Timeout)
 	defer cancel()
 	
-	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
+	for attempt := 1; ; attempt++ {
 		result.Attempts = attempt
 		
 		// Check if context is cancelled
@@ -125,21 +194,35 @@ func Retry(ctx context.Context, config *RetryConfig, fn RetryableFunc, logger *L
 		result.LastError = err
 		result.AllErrors = append(result.AllErrors, err)
 		
-		// Check if error is retryable
-		if !isErrorRetryable(err, config.RetryableErrors) {
+		// Check if error is retryable, under its type's policy if it has one
+		retryable := isErrorRetryable(err, config.RetryableErrors)
+		maxAttempts := config.MaxAttempts
+		delayConfig := config
+		if policy, found := config.Policies[ErrorTypeOf(err)]; found && !isFinalStatus(err) {
+			retryable = true
+			if policy.MaxAttempts > 0 {
+				maxAttempts = policy.MaxAttempts
+			}
+			if policy.InitialDelay > 0 {
+				withDelay := *config
+				withDelay.InitialDelay = policy.InitialDelay
+				delayConfig = &withDelay
+			}
+		}
+		if !retryable || maxAttempts <= 1 {
 			logger.Debug(fmt.Sprintf("Non-retryable error on attempt %d: %v", attempt, err))
 			result.TotalTime = time.Since(startTime)
 			return result
 		}
 		
 		// Don't sleep after the last attempt
-		if attempt == config.MaxAttempts {
-			logger.Debug(fmt.Sprintf("Max attempts (%d) reached, giving up", config.MaxAttempts))
+		if attempt >= maxAttempts {
+			logger.Debug(fmt.Sprintf("Max attempts (%d) reached, giving up", maxAttempts))
 			break
 		}
 		
 		// Calculate delay for next attempt
-		delay := calculateDelay(attempt, config)
+		delay := calculateDelay(attempt, delayConfig)
 		logger.Debug(fmt.Sprintf("Attempt %d failed: %v. Retrying in %v", attempt, err, delay))
 		
 		// Sleep with context cancellation check
@@ -203,6 +286,17 @@ func isErrorRetryable(err error, retryableErrors []ErrorType) bool {
 	return IsRetryableError(err)
 }
 
+// isFinalStatus reports whether err is an HTTP error whose status a retry
+// would not change, which no retry policy overrides
+func isFinalStatus(err error) bool {
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Type != HTTPError {
+		return false
+	}
+	statusCode, ok := appErr.Context["status_code"].(int)
+	return ok && !RetryableStatus(statusCode)
+}
+
 // calculateDelay calculates the delay for the next retry attempt
 func calculateDelay(attempt int, config *RetryConfig) time.Duration {
 	// Calculate exponential backoff
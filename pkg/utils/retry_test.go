@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestErrorTypeOf(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected ErrorType
+	}{
+		{&net.DNSError{Err: "no such host", Name: "missing.example.com", IsNotFound: true}, DNSError},
+		{&net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}, NetworkError},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ConnectionRefusedError},
+		{&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, ConnectionResetError},
+		{fmt.Errorf("tls: %w", x509.UnknownAuthorityError{}), TLSError},
+		{NewNetworkError("failed to fetch", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), ConnectionResetError},
+		{WrapError(NewNetworkError("failed to fetch", &net.DNSError{Err: "no such host", IsNotFound: true}), "failed after 1 attempt"), DNSError},
+		{NewHTTPError("HTTP 503", 503, nil), HTTPError},
+		{fmt.Errorf("request: %w", context.DeadlineExceeded), TimeoutError},
+		{errors.New("unexpected"), UnknownError},
+	}
+
+	for _, tc := range tests {
+		if errType := ErrorTypeOf(tc.err); errType != tc.expected {
+			t.Errorf("ErrorTypeOf(%v) = %s, expected %s", tc.err, errType, tc.expected)
+		}
+	}
+}
+
+func TestRetry_policies(t *testing.T) {
+	config := NetworkRetryConfig()
+	config.InitialDelay = time.Millisecond
+	config.MaxDelay = time.Millisecond
+
+	tests := []struct {
+		name     string
+		config   *RetryConfig
+		err      error
+		attempts int
+	}{
+		{"NXDOMAIN fails fast", config, NewNetworkError("fetch", &net.DNSError{Err: "no such host", IsNotFound: true}), 1},
+		{"Refused retried once", config, NewNetworkError("fetch", syscall.ECONNREFUSED), 2},
+		{"Reset retried fully", config, NewNetworkError("fetch", syscall.ECONNRESET), 5},
+		{"Configured override", config.WithPolicies(RetryPolicies{"dns": {MaxAttempts: 3}, "connection_reset": {MaxAttempts: 2}}),
+			NewNetworkError("fetch", &net.DNSError{Err: "no such host", IsNotFound: true}), 3},
+		{"Server error retried", config, NewHTTPError("HTTP 503", 503, nil), 5},
+		{"Rate limit retried", config, NewHTTPError("HTTP 429", 429, nil), 5},
+		{"Not found fails fast", config, NewHTTPError("HTTP 404", 404, nil), 1},
+		{"Forbidden fails fast under a policy", config.WithPolicies(RetryPolicies{"http": {MaxAttempts: 3}}), NewHTTPError("HTTP 403", 403, nil), 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Retry(context.Background(), tc.config, func(ctx context.Context) error { return tc.err }, nil)
+			if result.Success || result.Attempts != tc.attempts {
+				t.Errorf("Expected %d attempts, got %d", tc.attempts, result.Attempts)
+			}
+		})
+	}
+
+	if err := (RetryPolicies{"nxdomain": {MaxAttempts: 1}}).Validate(); err == nil {
+		t.Error("Expected an unknown error type to be rejected")
+	}
+	if err := (RetryPolicies{"dns": {MaxAttempts: -1}}).Validate(); err == nil {
+		t.Error("Expected a negative attempt count to be rejected")
+	}
+}
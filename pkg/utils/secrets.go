@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Prefixes of the secret references accepted wherever the config holds a token
+const (
+	SecretEnv   = "env:"    // env:NAME reads an environment variable
+	SecretVault = "vault:"  // vault:mount/path#key reads a HashiCorp Vault KV secret
+	SecretAWS   = "aws-sm:" // aws-sm:secret-id[#key] reads an AWS Secrets Manager secret
+)
+
+// defaultVaultAddr is used when VAULT_ADDR is not set, as the vault CLI does
+const defaultVaultAddr = "https://127.0.0.1:8200"
+
+// secretClient fetches secrets from Vault and AWS
+var secretClient = &http.Client{Timeout: 30 * time.Second}
+
+// IsSecretRef reports whether a config value is a secret reference rather
+// than the secret itself
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretEnv) || strings.HasPrefix(value, SecretVault) || strings.HasPrefix(value, SecretAWS)
+}
+
+// ResolveSecret returns the secret a reference points to, so tokens need not
+// be kept in plain text in config.yaml. Values that are not references are
+// returned unchanged.
+//
+// Vault is reached at VAULT_ADDR with VAULT_TOKEN (or ~/.vault-token) and
+// VAULT_NAMESPACE, and AWS with the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN and AWS_REGION environment variables.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, SecretEnv):
+		name := strings.TrimPrefix(value, SecretEnv)
+		secret, set := os.LookupEnv(name)
+		if !set {
+			return "", NewValidationError(fmt.Sprintf("secret %s: environment variable %s is not set", value, name), nil)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, SecretVault):
+		return vaultSecret(strings.TrimPrefix(value, SecretVault))
+	case strings.HasPrefix(value, SecretAWS):
+		return awsSecret(strings.TrimPrefix(value, SecretAWS))
+	}
+	return value, nil
+}
+
+// vaultSecret reads key from a KV secret, trying the version 2 API
+// (mount/data/path) before version 1 (mount/path)
+func vaultSecret(ref string) (string, error) {
+	secretPath, key, _ := strings.Cut(ref, "#")
+	mount, path, found := strings.Cut(strings.Trim(secretPath, "/"), "/")
+	if !found || path == "" || key == "" {
+		return "", NewValidationError(fmt.Sprintf("invalid Vault reference %q, expected vault:mount/path#key", SecretVault+ref), nil)
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return "", NewValidationError("Vault secrets need VAULT_TOKEN or ~/.vault-token", nil)
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = defaultVaultAddr
+	}
+	addr = strings.TrimSuffix(addr, "/")
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	status, err := vaultGet(addr+"/v1/"+mount+"/data/"+path, token, &response)
+	if err == nil && status == http.StatusNotFound {
+		status, err = vaultGet(addr+"/v1/"+mount+"/"+path, token, &response)
+	} else if err == nil && status == http.StatusOK {
+		// KV version 2 nests the secret under data.data
+		nested, _ := response.Data["data"].(map[string]interface{})
+		response.Data = nested
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read Vault secret %s: %w", secretPath, err)
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("failed to read Vault secret %s: HTTP %d", secretPath, status)
+	}
+
+	secret, ok := response.Data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("Vault secret %s has no key %q", secretPath, key)
+	}
+	return secret, nil
+}
+
+// vaultGet reads a Vault API path into response, which is only decoded on 200 OK
+func vaultGet(apiURL, token string, response interface{}) (int, error) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := secretClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return 0, fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// awsSecret reads a Secrets Manager secret with GetSecretValue. With a key,
+// the secret string is read as a JSON object and the key's value returned.
+func awsSecret(ref string) (string, error) {
+	secretID, key, _ := strings.Cut(ref, "#")
+	if secretID == "" {
+		return "", NewValidationError(fmt.Sprintf("invalid AWS Secrets Manager reference %q, expected aws-sm:secret-id[#key]", SecretAWS+ref), nil)
+	}
+
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return "", NewValidationError("AWS Secrets Manager secrets need AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", nil)
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(secretID, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", NewValidationError("AWS Secrets Manager secrets need AWS_REGION", nil)
+	}
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAWSRequest(req, body, accessKey, secretKey, region, "secretsmanager", time.Now().UTC())
+
+	resp, err := secretClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read AWS secret %s: %w", secretID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read AWS secret %s: HTTP %d", secretID, resp.StatusCode)
+	}
+
+	var response struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("invalid response for AWS secret %s: %w", secretID, err)
+	}
+	if key == "" {
+		return response.SecretString, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(response.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("AWS secret %s is not a JSON object, so it has no key %q", secretID, key)
+	}
+	secret, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("AWS secret %s has no key %q", secretID, key)
+	}
+	return secret, nil
+}
+
+// signAWSRequest adds an AWS Signature Version 4 Authorization header,
+// signing the host, X-Amz-* and Content-Type headers and the body
+func signAWSRequest(req *http.Request, body []byte, accessKey, secretKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
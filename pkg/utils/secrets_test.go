@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	if value, err := ResolveSecret("plain-token"); err != nil || value != "plain-token" {
+		t.Errorf("Expected plain values unchanged, got %q (%v)", value, err)
+	}
+
+	t.Setenv("JSFINDER_TEST_TOKEN", "from-env")
+	if value, err := ResolveSecret("env:JSFINDER_TEST_TOKEN"); err != nil || value != "from-env" {
+		t.Errorf("Expected environment secret, got %q (%v)", value, err)
+	}
+	if _, err := ResolveSecret("env:JSFINDER_TEST_UNSET"); err == nil {
+		t.Error("Expected error for an unset environment variable")
+	}
+
+	for _, ref := range []string{"vault:kv", "vault:kv/path", "aws-sm:"} {
+		if _, err := ResolveSecret(ref); err == nil {
+			t.Errorf("Expected error for invalid reference %q", ref)
+		}
+	}
+}
+
+func TestResolveSecret_vault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/jsfinder":
+			w.Write([]byte(`{"data":{"data":{"github":"v2-token"}}}`))
+		case "/v1/secret/jsfinder":
+			w.Write([]byte(`{"data":{"github":"v1-token"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	tests := map[string]string{
+		"vault:kv/jsfinder#github":     "v2-token",
+		"vault:secret/jsfinder#github": "v1-token",
+	}
+	for ref, expected := range tests {
+		if value, err := ResolveSecret(ref); err != nil || value != expected {
+			t.Errorf("ResolveSecret(%q) = %q (%v), expected %q", ref, value, err, expected)
+		}
+	}
+
+	if _, err := ResolveSecret("vault:kv/jsfinder#missing"); err == nil {
+		t.Error("Expected error for a missing key")
+	}
+	if _, err := ResolveSecret("vault:kv/other#github"); err == nil {
+		t.Error("Expected error for a missing secret")
+	}
+}
+
+func TestResolveSecret_aws(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+			!strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var request struct{ SecretId string }
+		json.Unmarshal(body, &request)
+		switch request.SecretId {
+		case "jsfinder/tokens":
+			w.Write([]byte(`{"SecretString":"{\"github\":\"aws-token\"}"}`))
+		case "plain":
+			w.Write([]byte(`{"SecretString":"raw-token"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	tests := map[string]string{
+		"aws-sm:jsfinder/tokens#github": "aws-token",
+		"aws-sm:plain":                  "raw-token",
+	}
+	for ref, expected := range tests {
+		if value, err := ResolveSecret(ref); err != nil || value != expected {
+			t.Errorf("ResolveSecret(%q) = %q (%v), expected %q", ref, value, err, expected)
+		}
+	}
+
+	if _, err := ResolveSecret("aws-sm:plain#github"); err == nil {
+		t.Error("Expected error for a key in a secret that is not JSON")
+	}
+	if _, err := ResolveSecret("aws-sm:missing"); err == nil {
+		t.Error("Expected error for an unknown secret")
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard selects a deterministic subset of input items so a large run can be
+// split across machines without a coordinator. A nil *Shard includes everything.
+type Shard struct {
+	Index int // 1-based shard number
+	Total int // Total number of shards
+}
+
+// ParseShard parses a "N/M" shard specification such as "2/5"
+func ParseShard(spec string) (*Shard, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(spec, "/")
+	if len(parts) != 2 {
+		return nil, NewValidationError(fmt.Sprintf("invalid shard %q, expected N/M", spec), nil)
+	}
+
+	index, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid shard index %q", parts[0]), err)
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid shard total %q", parts[1]), err)
+	}
+	if total < 1 || index < 1 || index > total {
+		return nil, NewValidationError(fmt.Sprintf("shard %q out of range", spec), nil)
+	}
+
+	return &Shard{Index: index, Total: total}, nil
+}
+
+// Includes reports whether the item belongs to this shard
+func (s *Shard) Includes(item string) bool {
+	if s == nil || s.Total <= 1 {
+		return true
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(item))
+	return int(hash.Sum32()%uint32(s.Total)) == s.Index-1
+}
+
+// String returns the shard specification
+func (s *Shard) String() string {
+	if s == nil {
+		return "1/1"
+	}
+	return fmt.Sprintf("%d/%d", s.Index, s.Total)
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	shard, err := ParseShard("2/5")
+	if err != nil {
+		t.Fatalf("Failed to parse shard: %v", err)
+	}
+	if shard.Index != 2 || shard.Total != 5 {
+		t.Errorf("Expected shard 2/5, got %s", shard)
+	}
+
+	for _, invalid := range []string{"2", "0/5", "6/5", "a/b", "1/0"} {
+		if _, err := ParseShard(invalid); err == nil {
+			t.Errorf("Expected error for shard %q", invalid)
+		}
+	}
+}
+
+func TestShard_Partition(t *testing.T) {
+	const total = 4
+	items := make([]string, 200)
+	for i := range items {
+		items[i] = fmt.Sprintf("https://example.com/js/%d.js", i)
+	}
+
+	for _, item := range items {
+		matches := 0
+		for index := 1; index <= total; index++ {
+			if (&Shard{Index: index, Total: total}).Includes(item) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			t.Fatalf("Expected %s to belong to exactly one shard, got %d", item, matches)
+		}
+	}
+
+	var shard *Shard
+	if !shard.Includes(items[0]) {
+		t.Error("Expected nil shard to include every item")
+	}
+}
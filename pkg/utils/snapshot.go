@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxSnapshotFileSize caps how much of a snapshot file is read, like a download
+const maxSnapshotFileSize = 64 << 20
+
+// Snapshot maps between the URLs of a site and the files of a saved copy of
+// it, such as a wget --mirror directory, so pages and scripts can be analysed
+// without network access. Root holds the files of Base's host; other hosts
+// are looked up in sibling directories named after them, as wget --mirror
+// --span-hosts lays them out.
+type Snapshot struct {
+	Root string
+	Base *url.URL
+}
+
+// NewSnapshot opens a snapshot directory. Without a base URL the directory
+// must be named after the host it mirrors (example.com), which is then
+// assumed to be served over HTTPS.
+func NewSnapshot(root, baseURL string) (*Snapshot, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, NewValidationError(fmt.Sprintf("snapshot %s is not a directory", root), nil)
+	}
+
+	if baseURL == "" {
+		name := filepath.Base(filepath.Clean(root))
+		if !strings.Contains(name, ".") {
+			return nil, NewValidationError(fmt.Sprintf("cannot infer the base URL of snapshot %s; set --base-url", root), nil)
+		}
+		baseURL = "https://" + name + "/"
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, NewValidationError(fmt.Sprintf("invalid snapshot base URL %q, expected http(s)://host[/path]", baseURL), nil)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	return &Snapshot{Root: root, Base: base}, nil
+}
+
+// URL returns the URL a file of the snapshot was saved from, given its
+// slash-separated path relative to Root. index.html stands for its directory.
+func (s *Snapshot) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if path.Base(name) == "index.html" {
+		name = strings.TrimSuffix(name, "index.html")
+	}
+
+	// wget keeps query strings in file names (app.js?v=2)
+	rawPath, query, _ := strings.Cut(name, "?")
+	u := *s.Base
+	u.Path = s.Base.Path + rawPath
+	u.RawPath = ""
+	u.RawQuery = query
+	return u.String()
+}
+
+// Path returns the snapshot file holding a URL, if it was saved
+func (s *Snapshot) Path(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+
+	root := s.Root
+	if !strings.EqualFold(u.Host, s.Base.Host) {
+		root = filepath.Join(filepath.Dir(filepath.Clean(s.Root)), strings.ToLower(u.Host))
+	}
+
+	name := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") || name == "/" {
+		name = path.Join(name, "index.html")
+	}
+	candidates := []string{name}
+	if u.RawQuery != "" {
+		candidates = append([]string{name + "?" + u.RawQuery}, candidates...)
+	}
+
+	for _, candidate := range candidates {
+		local := filepath.Join(root, filepath.FromSlash(candidate))
+		if info, err := os.Stat(local); err == nil && info.Mode().IsRegular() {
+			return local, true
+		}
+	}
+	return "", false
+}
+
+// Read returns the saved body of a URL
+func (s *Snapshot) Read(rawURL string) ([]byte, error) {
+	local, found := s.Path(rawURL)
+	if !found {
+		return nil, fmt.Errorf("%s is not in snapshot %s", rawURL, s.Root)
+	}
+
+	file, err := os.Open(local)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, maxSnapshotFileSize))
+}
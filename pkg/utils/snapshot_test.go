@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	mirror := t.TempDir()
+	root := filepath.Join(mirror, "example.com")
+	files := map[string]string{
+		"example.com/index.html":        "home",
+		"example.com/js/app.js?v=2":     "versioned",
+		"example.com/js/app.js":         "plain",
+		"cdn.example.com/lib/vendor.js": "vendor",
+	}
+	for name, content := range files {
+		path := filepath.Join(mirror, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	snapshot, err := NewSnapshot(root, "")
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	if snapshot.Base.String() != "https://example.com/" {
+		t.Errorf("Expected the base URL inferred from the directory name, got %s", snapshot.Base)
+	}
+
+	urls := map[string]string{
+		"index.html":         "https://example.com/",
+		"about/index.html":   "https://example.com/about/",
+		"js/app.js?v=2":      "https://example.com/js/app.js?v=2",
+		"docs/page one.html": "https://example.com/docs/page%20one.html",
+	}
+	for name, expected := range urls {
+		if got := snapshot.URL(name); got != expected {
+			t.Errorf("URL(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+
+	bodies := map[string]string{
+		"https://example.com/":                  "home",
+		"https://example.com/js/app.js?v=2":     "versioned",
+		"https://example.com/js/app.js?v=3":     "plain",
+		"https://cdn.example.com/lib/vendor.js": "vendor",
+	}
+	for rawURL, expected := range bodies {
+		if body, err := snapshot.Read(rawURL); err != nil || string(body) != expected {
+			t.Errorf("Read(%q) = %q (%v), expected %q", rawURL, body, err, expected)
+		}
+	}
+	if _, err := snapshot.Read("https://example.com/missing.js"); err == nil {
+		t.Error("Expected error for a URL not in the snapshot")
+	}
+
+	if _, err := NewSnapshot(t.TempDir(), ""); err == nil {
+		t.Error("Expected error when the base URL cannot be inferred")
+	}
+	if _, err := NewSnapshot(root, "ftp://example.com"); err == nil {
+		t.Error("Expected error for a base URL that is not HTTP")
+	}
+}
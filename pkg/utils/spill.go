@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SpillQueue is a FIFO of work items, one per line, that keeps its backlog in
+// a temporary file while the memory guard reports pressure. Producers push as
+// they read input and consumers pop as workers free up, so a large input
+// never has to be held in memory at once. With a nil guard it never spills.
+type SpillQueue struct {
+	guard    *MemoryGuard
+	mutex    sync.Mutex
+	ready    *sync.Cond
+	memory   []string
+	file     *os.File
+	readFile *os.File
+	writer   *bufio.Writer
+	reader   *bufio.Reader
+	onDisk   int
+	spilled  int
+	closed   bool
+}
+
+// NewSpillQueue creates an empty queue spilling under the guard's pressure
+func NewSpillQueue(guard *MemoryGuard) *SpillQueue {
+	q := &SpillQueue{guard: guard}
+	q.ready = sync.NewCond(&q.mutex)
+	return q
+}
+
+// Push appends an item. Once anything is on disk later items follow it there
+// so order is kept; if the spill file cannot be written the item stays in memory.
+func (q *SpillQueue) Push(item string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if (q.onDisk > 0 || q.guard.Pressure()) && q.spill(item) {
+		q.onDisk++
+		q.spilled++
+	} else {
+		q.memory = append(q.memory, item)
+	}
+	q.ready.Signal()
+}
+
+// spill writes an item to the spill file, creating it on first use with a
+// second handle for reading so reads do not move the write position
+func (q *SpillQueue) spill(item string) bool {
+	if q.file == nil {
+		file, err := os.CreateTemp("", "jsfinder-queue-*")
+		if err != nil {
+			return false
+		}
+		readFile, err := os.Open(file.Name())
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return false
+		}
+		q.file, q.readFile = file, readFile
+		q.writer = bufio.NewWriter(file)
+		q.reader = bufio.NewReader(readFile)
+	}
+
+	_, err := q.writer.WriteString(item + "\n")
+	return err == nil
+}
+
+// Pop removes the oldest item, blocking until one is pushed. It returns false
+// once the queue is closed and empty.
+func (q *SpillQueue) Pop() (string, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for {
+		for len(q.memory) == 0 && q.onDisk == 0 {
+			if q.closed {
+				return "", false
+			}
+			q.ready.Wait()
+		}
+
+		if len(q.memory) > 0 {
+			item := q.memory[0]
+			q.memory[0] = ""
+			q.memory = q.memory[1:]
+			return item, true
+		}
+
+		// An item lost to a failed read is dropped rather than blocking the queue
+		q.onDisk--
+		if err := q.writer.Flush(); err != nil {
+			continue
+		}
+		if line, err := q.reader.ReadString('\n'); err == nil {
+			return strings.TrimSuffix(line, "\n"), true
+		}
+	}
+}
+
+// Close marks the end of input; Pop drains what is left and then returns false
+func (q *SpillQueue) Close() {
+	q.mutex.Lock()
+	q.closed = true
+	q.mutex.Unlock()
+	q.ready.Broadcast()
+}
+
+// Spilled returns how many items went through the spill file
+func (q *SpillQueue) Spilled() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.spilled
+}
+
+// Remove deletes the spill file
+func (q *SpillQueue) Remove() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.file != nil {
+		q.file.Close()
+		q.readFile.Close()
+		os.Remove(q.file.Name())
+		q.file, q.readFile = nil, nil
+	}
+}
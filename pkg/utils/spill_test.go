@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime/debug"
+	"testing"
+)
+
+func TestSpillQueue(t *testing.T) {
+	defer debug.SetMemoryLimit(math.MaxInt64)
+
+	guard := NewMemoryGuard(1000)
+	guard.logger = NewLogger(ERROR, io.Discard)
+	used := uint64(100)
+	guard.sample = func() uint64 { return used }
+	guard.update()
+
+	queue := NewSpillQueue(guard)
+	defer queue.Remove()
+
+	queue.Push("item-0")
+	queue.Push("item-1")
+
+	// Under pressure new items go to disk, and stay there after it eases so order is kept
+	used = 900
+	guard.update()
+	queue.Push("item-2")
+	used = 100
+	guard.update()
+	queue.Push("item-3")
+
+	if queue.Spilled() != 2 {
+		t.Errorf("Expected 2 spilled items, got %d", queue.Spilled())
+	}
+	spillFile := queue.file.Name()
+
+	go func() {
+		queue.Push("item-4")
+		queue.Close()
+	}()
+
+	for i := 0; ; i++ {
+		item, ok := queue.Pop()
+		if !ok {
+			if i != 5 {
+				t.Errorf("Expected 5 items, got %d", i)
+			}
+			break
+		}
+		if item != fmt.Sprintf("item-%d", i) {
+			t.Errorf("Expected item-%d, got %s", i, item)
+		}
+	}
+
+	queue.Remove()
+	if _, err := os.Stat(spillFile); !os.IsNotExist(err) {
+		t.Errorf("Expected spill file to be removed, got %v", err)
+	}
+}
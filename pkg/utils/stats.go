@@ -0,0 +1,463 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunStats collects summary counters for a single command run.
+// It is safe for concurrent use by all workers of an engine.
+type RunStats struct {
+	mutex              sync.Mutex
+	startTime          time.Time
+	endTime            time.Time
+	requests           int64
+	bytesDownloaded    int64
+	pagesCrawled       int64
+	jsFiles            int64
+	findingsBySeverity map[string]int64
+	endpointsByStatus  map[int]int64
+	retry              RetryStats
+	queued             int64
+	processed          int64
+	stopReason         string
+	hostUserAgents     map[string]string
+	peakMemory         int64
+	disabledPatterns   []string
+	skippedFiles       map[string]string
+	timeoutManagers    []*TimeoutManager
+	workers            *PoolStats
+	resumed            int64
+	resumedBytes       int64
+	hosts              map[string]*hostCounters
+}
+
+// hostCounters accumulates the probes sent to one base URL
+type hostCounters struct {
+	requests int64
+	classes  map[string]int64
+	latency  time.Duration
+	errors   int64
+}
+
+// HostStats summarizes the probes sent to one base URL
+type HostStats struct {
+	Requests     int64            `json:"requests"`
+	Responses    map[string]int64 `json:"responses"` // By status class: 2xx, 3xx, 4xx, 5xx
+	AvgLatencyMs int64            `json:"avg_latency_ms"`
+	Errors       int64            `json:"errors"` // Requests that got no response
+}
+
+// StatsSnapshot is a point-in-time copy of RunStats suitable for output
+type StatsSnapshot struct {
+	StartTime          time.Time            `json:"start_time"`
+	Duration           string               `json:"duration"`
+	DurationMs         int64                `json:"duration_ms"`
+	Requests           int64                `json:"requests"`
+	BytesDownloaded    int64                `json:"bytes_downloaded"`
+	PagesCrawled       int64                `json:"pages_crawled"`
+	JSFiles            int64                `json:"js_files"`
+	FindingsBySeverity map[string]int64     `json:"findings_by_severity"`
+	EndpointsByStatus  map[string]int64     `json:"endpoints_by_status"`
+	Retry              RetryStats           `json:"retry"`
+	QueueTotal         int64                `json:"queue_total"`
+	QueueProcessed     int64                `json:"queue_processed"`
+	StopReason         string               `json:"stop_reason,omitempty"`
+	HostUserAgents     map[string]string    `json:"host_user_agents,omitempty"`
+	PeakMemory         int64                `json:"peak_memory_bytes,omitempty"`
+	DisabledPatterns   []string             `json:"disabled_patterns,omitempty"`
+	SkippedFiles       map[string]string    `json:"skipped_files,omitempty"` // URL to reason
+	Operations         *OperationMetrics    `json:"operations,omitempty"`
+	Workers            *PoolStats           `json:"workers,omitempty"`
+	ResumedDownloads   int64                `json:"resumed_downloads,omitempty"`
+	ResumedBytes       int64                `json:"resumed_bytes,omitempty"` // Bytes not downloaded again thanks to resumption
+	Hosts              map[string]HostStats `json:"hosts,omitempty"`         // By base URL, for discovery probes
+}
+
+// NewRunStats creates a new run statistics collector starting now
+func NewRunStats() *RunStats {
+	return &RunStats{
+		startTime:          time.Now(),
+		findingsBySeverity: make(map[string]int64),
+		endpointsByStatus:  make(map[int]int64),
+		hostUserAgents:     make(map[string]string),
+		skippedFiles:       make(map[string]string),
+		hosts:              make(map[string]*hostCounters),
+	}
+}
+
+// AddRequest records an outgoing HTTP request
+func (s *RunStats) AddRequest() {
+	s.mutex.Lock()
+	s.requests++
+	s.mutex.Unlock()
+}
+
+// AddBytes records downloaded response body bytes
+func (s *RunStats) AddBytes(n int64) {
+	s.mutex.Lock()
+	s.bytesDownloaded += n
+	s.mutex.Unlock()
+}
+
+// AddPage records a successfully crawled page
+func (s *RunStats) AddPage() {
+	s.mutex.Lock()
+	s.pagesCrawled++
+	s.mutex.Unlock()
+}
+
+// AddJSFile records a newly discovered JavaScript file
+func (s *RunStats) AddJSFile() {
+	s.mutex.Lock()
+	s.jsFiles++
+	s.mutex.Unlock()
+}
+
+// AddFinding records a scanner finding with the given severity
+func (s *RunStats) AddFinding(severity string) {
+	s.mutex.Lock()
+	s.findingsBySeverity[severity]++
+	s.mutex.Unlock()
+}
+
+// AddEndpoint records a reported endpoint with the given status code
+func (s *RunStats) AddEndpoint(statusCode int) {
+	s.mutex.Lock()
+	s.endpointsByStatus[statusCode]++
+	s.mutex.Unlock()
+}
+
+// AddRetryResult records the outcome of a retried operation
+func (s *RunStats) AddRetryResult(result *RetryResult) {
+	if result == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.retry.UpdateStats(result)
+	s.mutex.Unlock()
+}
+
+// AddQueued records work items added to the run queue
+func (s *RunStats) AddQueued(n int64) {
+	s.mutex.Lock()
+	s.queued += n
+	s.mutex.Unlock()
+}
+
+// AddProcessed records a completed work item
+func (s *RunStats) AddProcessed() {
+	s.mutex.Lock()
+	s.processed++
+	s.mutex.Unlock()
+}
+
+// SetStopReason records why the run stopped before draining its queue
+func (s *RunStats) SetStopReason(reason string) {
+	s.mutex.Lock()
+	if s.stopReason == "" {
+		s.stopReason = reason
+	}
+	s.mutex.Unlock()
+}
+
+// SetPeakMemory records the highest memory use sampled during the run
+func (s *RunStats) SetPeakMemory(bytes int64) {
+	s.mutex.Lock()
+	s.peakMemory = bytes
+	s.mutex.Unlock()
+}
+
+// AddDisabledPattern records a pattern switched off mid-run for being too slow
+func (s *RunStats) AddDisabledPattern(name string) {
+	s.mutex.Lock()
+	s.disabledPatterns = append(s.disabledPatterns, name)
+	s.mutex.Unlock()
+}
+
+// AddSkippedFile records a file left unscanned and why
+func (s *RunStats) AddSkippedFile(url, reason string) {
+	s.mutex.Lock()
+	s.skippedFiles[url] = reason
+	s.mutex.Unlock()
+}
+
+// SetHostUserAgent records the fallback User-Agent that got through to a host
+func (s *RunStats) SetHostUserAgent(host, userAgent string) {
+	s.mutex.Lock()
+	s.hostUserAgents[host] = userAgent
+	s.mutex.Unlock()
+}
+
+// TrackOperations includes the operation counters of a timeout manager in
+// the snapshot. Engines sharing one RunStats each register their own.
+func (s *RunStats) TrackOperations(tm *TimeoutManager) {
+	s.mutex.Lock()
+	s.timeoutManagers = append(s.timeoutManagers, tm)
+	s.mutex.Unlock()
+}
+
+// AddResumed records a download resumed after the given number of bytes
+func (s *RunStats) AddResumed(bytes int64) {
+	s.mutex.Lock()
+	s.resumed++
+	s.resumedBytes += bytes
+	s.mutex.Unlock()
+}
+
+// AddHostResponse records a probe of a base URL answered with status after latency
+func (s *RunStats) AddHostResponse(host string, status int, latency time.Duration) {
+	s.mutex.Lock()
+	counters := s.host(host)
+	counters.requests++
+	counters.classes[fmt.Sprintf("%dxx", status/100)]++
+	counters.latency += latency
+	s.mutex.Unlock()
+}
+
+// AddHostError records a probe of a base URL that got no response
+func (s *RunStats) AddHostError(host string) {
+	s.mutex.Lock()
+	counters := s.host(host)
+	counters.requests++
+	counters.errors++
+	s.mutex.Unlock()
+}
+
+// host returns the counters of a base URL; the caller holds the mutex
+func (s *RunStats) host(host string) *hostCounters {
+	counters, exists := s.hosts[host]
+	if !exists {
+		counters = &hostCounters{classes: make(map[string]int64)}
+		s.hosts[host] = counters
+	}
+	return counters
+}
+
+// AddWorkerPool adds the stats of a finished worker pool
+func (s *RunStats) AddWorkerPool(pool *WorkerPool) {
+	stats := pool.Stats()
+	s.mutex.Lock()
+	if s.workers == nil {
+		s.workers = &PoolStats{}
+	}
+	s.workers.add(stats)
+	s.mutex.Unlock()
+}
+
+// Finish marks the end of the run; later calls are ignored
+func (s *RunStats) Finish() {
+	s.mutex.Lock()
+	if s.endTime.IsZero() {
+		s.endTime = time.Now()
+	}
+	s.mutex.Unlock()
+}
+
+// Snapshot returns a copy of the current statistics
+func (s *RunStats) Snapshot() StatsSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	end := s.endTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	duration := end.Sub(s.startTime)
+
+	snapshot := StatsSnapshot{
+		StartTime:          s.startTime,
+		Duration:           duration.Round(time.Millisecond).String(),
+		DurationMs:         duration.Milliseconds(),
+		Requests:           s.requests,
+		BytesDownloaded:    s.bytesDownloaded,
+		PagesCrawled:       s.pagesCrawled,
+		JSFiles:            s.jsFiles,
+		FindingsBySeverity: make(map[string]int64, len(s.findingsBySeverity)),
+		EndpointsByStatus:  make(map[string]int64, len(s.endpointsByStatus)),
+		Retry:              s.retry,
+		QueueTotal:         s.queued,
+		QueueProcessed:     s.processed,
+		StopReason:         s.stopReason,
+		PeakMemory:         s.peakMemory,
+		DisabledPatterns:   append([]string(nil), s.disabledPatterns...),
+		ResumedDownloads:   s.resumed,
+		ResumedBytes:       s.resumedBytes,
+	}
+	for severity, count := range s.findingsBySeverity {
+		snapshot.FindingsBySeverity[severity] = count
+	}
+	for status, count := range s.endpointsByStatus {
+		snapshot.EndpointsByStatus[fmt.Sprintf("%d", status)] = count
+	}
+	if len(s.hostUserAgents) > 0 {
+		snapshot.HostUserAgents = make(map[string]string, len(s.hostUserAgents))
+		for host, userAgent := range s.hostUserAgents {
+			snapshot.HostUserAgents[host] = userAgent
+		}
+	}
+	if len(s.skippedFiles) > 0 {
+		snapshot.SkippedFiles = make(map[string]string, len(s.skippedFiles))
+		for url, reason := range s.skippedFiles {
+			snapshot.SkippedFiles[url] = reason
+		}
+	}
+	if len(s.hosts) > 0 {
+		snapshot.Hosts = make(map[string]HostStats, len(s.hosts))
+		for host, counters := range s.hosts {
+			stats := HostStats{Requests: counters.requests, Responses: make(map[string]int64, len(counters.classes)), Errors: counters.errors}
+			for class, count := range counters.classes {
+				stats.Responses[class] = count
+			}
+			if answered := counters.requests - counters.errors; answered > 0 {
+				stats.AvgLatencyMs = (counters.latency / time.Duration(answered)).Milliseconds()
+			}
+			snapshot.Hosts[host] = stats
+		}
+	}
+	if s.workers != nil {
+		workers := *s.workers
+		snapshot.Workers = &workers
+	}
+	if len(s.timeoutManagers) > 0 {
+		snapshot.Operations = &OperationMetrics{}
+		for _, tm := range s.timeoutManagers {
+			snapshot.Operations.add(tm.Metrics())
+		}
+	}
+
+	return snapshot
+}
+
+// WriteJSON writes the statistics as a JSON object
+func (s *RunStats) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s.Snapshot())
+}
+
+// WriteSummary writes a human-readable summary block
+func (s *RunStats) WriteSummary(w io.Writer) error {
+	snapshot := s.Snapshot()
+
+	var b strings.Builder
+	b.WriteString("=== Run Summary ===\n")
+	fmt.Fprintf(&b, "Duration:         %s\n", snapshot.Duration)
+	fmt.Fprintf(&b, "Requests:         %d\n", snapshot.Requests)
+	fmt.Fprintf(&b, "Downloaded:       %s\n", FormatBytes(snapshot.BytesDownloaded))
+	fmt.Fprintf(&b, "Pages crawled:    %d\n", snapshot.PagesCrawled)
+	fmt.Fprintf(&b, "JS files found:   %d\n", snapshot.JSFiles)
+	fmt.Fprintf(&b, "Findings:         %s\n", formatCounts(snapshot.FindingsBySeverity, []string{"HIGH", "MEDIUM", "LOW"}))
+	fmt.Fprintf(&b, "Endpoints:        %s\n", formatCounts(snapshot.EndpointsByStatus, nil))
+	if snapshot.QueueTotal > 0 {
+		fmt.Fprintf(&b, "Queue processed:  %d/%d (%.1f%%)\n", snapshot.QueueProcessed, snapshot.QueueTotal,
+			float64(snapshot.QueueProcessed)/float64(snapshot.QueueTotal)*100)
+	}
+	if snapshot.StopReason != "" {
+		fmt.Fprintf(&b, "Stopped early:    %s\n", snapshot.StopReason)
+	}
+	if len(snapshot.HostUserAgents) > 0 {
+		hosts := make([]string, 0, len(snapshot.HostUserAgents))
+		for host := range snapshot.HostUserAgents {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		fmt.Fprintf(&b, "Browser UA used:  %s\n", strings.Join(hosts, " "))
+	}
+	if snapshot.PeakMemory > 0 {
+		fmt.Fprintf(&b, "Peak memory:      %s\n", FormatBytes(snapshot.PeakMemory))
+	}
+	if len(snapshot.SkippedFiles) > 0 {
+		urls := make([]string, 0, len(snapshot.SkippedFiles))
+		for url := range snapshot.SkippedFiles {
+			urls = append(urls, url)
+		}
+		sort.Strings(urls)
+		fmt.Fprintf(&b, "Skipped files:    %d\n", len(urls))
+		for _, url := range urls {
+			fmt.Fprintf(&b, "                  %s (%s)\n", url, snapshot.SkippedFiles[url])
+		}
+	}
+	if len(snapshot.DisabledPatterns) > 0 {
+		fmt.Fprintf(&b, "Slow patterns:    %s (disabled)\n", strings.Join(snapshot.DisabledPatterns, " "))
+	}
+	if snapshot.Workers != nil && snapshot.Workers.Tasks > 0 {
+		fmt.Fprintf(&b, "Workers:          %s\n", snapshot.Workers.String())
+	}
+	if ops := snapshot.Operations; ops != nil && (ops.TimedOut > 0 || ops.Waited > 0) {
+		fmt.Fprintf(&b, "Operations:       %s\n", ops.String())
+	}
+	if snapshot.ResumedDownloads > 0 {
+		fmt.Fprintf(&b, "Resumed:          %d downloads, %s not downloaded again\n", snapshot.ResumedDownloads, FormatBytes(snapshot.ResumedBytes))
+	}
+	if snapshot.Retry.TotalOperations > 0 {
+		fmt.Fprintf(&b, "Retries:          %s\n", snapshot.Retry.String())
+	}
+	if len(snapshot.Hosts) > 0 {
+		hosts := make([]string, 0, len(snapshot.Hosts))
+		for host := range snapshot.Hosts {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		fmt.Fprintf(&b, "Hosts:            %d\n", len(hosts))
+		for _, host := range hosts {
+			fmt.Fprintf(&b, "                  %s %s\n", host, snapshot.Hosts[host].String())
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// String renders the stats as "120 requests (2xx=3 4xx=117), avg 45ms, 2 errors"
+func (h HostStats) String() string {
+	return fmt.Sprintf("%d requests (%s), avg %dms, %d errors", h.Requests, formatCounts(h.Responses, nil), h.AvgLatencyMs, h.Errors)
+}
+
+// FormatBytes renders a byte count using binary units
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatCounts renders a count map as "KEY=n" pairs, listing preferred keys first
+func formatCounts(counts map[string]int64, preferred []string) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+
+	seen := make(map[string]bool)
+	var parts []string
+	for _, key := range preferred {
+		if count, exists := counts[key]; exists {
+			parts = append(parts, fmt.Sprintf("%s=%d", key, count))
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key := range counts {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		parts = append(parts, fmt.Sprintf("%s=%d", key, counts[key]))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunStats_Snapshot(t *testing.T) {
+	stats := NewRunStats()
+	stats.AddRequest()
+	stats.AddRequest()
+	stats.AddBytes(2048)
+	stats.AddPage()
+	stats.AddJSFile()
+	stats.AddFinding("HIGH")
+	stats.AddFinding("HIGH")
+	stats.AddFinding("LOW")
+	stats.AddEndpoint(200)
+	stats.AddRetryResult(&RetryResult{Success: true, Attempts: 2})
+	stats.Finish()
+
+	snapshot := stats.Snapshot()
+	if snapshot.Requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", snapshot.Requests)
+	}
+	if snapshot.BytesDownloaded != 2048 {
+		t.Errorf("Expected 2048 bytes, got %d", snapshot.BytesDownloaded)
+	}
+	if snapshot.FindingsBySeverity["HIGH"] != 2 {
+		t.Errorf("Expected 2 HIGH findings, got %d", snapshot.FindingsBySeverity["HIGH"])
+	}
+	if snapshot.EndpointsByStatus["200"] != 1 {
+		t.Errorf("Expected 1 endpoint with status 200, got %d", snapshot.EndpointsByStatus["200"])
+	}
+	if snapshot.Retry.TotalRetries != 1 {
+		t.Errorf("Expected 1 retry, got %d", snapshot.Retry.TotalRetries)
+	}
+}
+
+func TestRunStats_WriteSummary(t *testing.T) {
+	stats := NewRunStats()
+	stats.AddFinding("MEDIUM")
+	stats.AddEndpoint(403)
+
+	buf := &bytes.Buffer{}
+	if err := stats.WriteSummary(buf); err != nil {
+		t.Fatalf("Failed to write summary: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"Run Summary", "MEDIUM=1", "403=1"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	buf.Reset()
+	if err := stats.WriteJSON(buf); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	var snapshot StatsSnapshot
+	if err := json.Unmarshal(buf.Bytes(), &snapshot); err != nil {
+		t.Fatalf("Failed to parse JSON stats: %v", err)
+	}
+}
+
+func TestRunStats_hosts(t *testing.T) {
+	stats := NewRunStats()
+	stats.AddHostResponse("https://api.example.com", 200, 30*time.Millisecond)
+	stats.AddHostResponse("https://api.example.com", 404, 50*time.Millisecond)
+	stats.AddHostResponse("https://api.example.com", 403, 40*time.Millisecond)
+	stats.AddHostError("https://api.example.com")
+	stats.AddHostError("https://down.example.com")
+
+	snapshot := stats.Snapshot()
+	api := snapshot.Hosts["https://api.example.com"]
+	if api.Requests != 4 || api.Errors != 1 || api.Responses["4xx"] != 2 || api.Responses["2xx"] != 1 {
+		t.Errorf("Unexpected host stats %+v", api)
+	}
+	if api.AvgLatencyMs != 40 {
+		t.Errorf("Expected the average over answered requests, got %dms", api.AvgLatencyMs)
+	}
+	if down := snapshot.Hosts["https://down.example.com"]; down.AvgLatencyMs != 0 || down.Errors != 1 {
+		t.Errorf("Unexpected host stats %+v", down)
+	}
+
+	buf := &bytes.Buffer{}
+	stats.WriteSummary(buf)
+	if !strings.Contains(buf.String(), "https://api.example.com 4 requests (2xx=1 4xx=2), avg 40ms, 1 errors") {
+		t.Errorf("Expected a line per host, got:\n%s", buf.String())
+	}
+}
+
+func TestNewHTTPClient_CountsTraffic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	stats := NewRunStats()
+	client := NewHTTPClient(&ClientOptions{Stats: stats})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	snapshot := stats.Snapshot()
+	if snapshot.Requests != 1 {
+		t.Errorf("Expected 1 request, got %d", snapshot.Requests)
+	}
+	if snapshot.BytesDownloaded != int64(len("hello world")) {
+		t.Errorf("Expected %d bytes, got %d", len("hello world"), snapshot.BytesDownloaded)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	testCases := map[int64]string{
+		512:         "512 B",
+		2048:        "2.0 KB",
+		5 * 1 << 20: "5.0 MB",
+	}
+
+	for input, expected := range testCases {
+		if result := FormatBytes(input); result != expected {
+			t.Errorf("Expected %s for %d, got %s", expected, input, result)
+		}
+	}
+}
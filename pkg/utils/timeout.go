@@ -2,17 +2,22 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // TimeoutConfig holds configuration for timeout operations
 type TimeoutConfig struct {
-	OperationTimeout time.Duration // Timeout for individual operations
-	GlobalTimeout    time.Duration // Global timeout for all operations
+	OperationTimeout  time.Duration // Timeout for individual operations
+	GlobalTimeout     time.Duration // Global timeout for all operations
 	HeartbeatInterval time.Duration // Interval for heartbeat checks
-	GracePeriod      time.Duration // Grace period before force termination
+	GracePeriod       time.Duration // Grace period before force termination
+	MaxOperations     int           // Operations tracked at once; 0 uses DefaultMaxOperations
 }
 
 // DefaultTimeoutConfig returns a default timeout configuration
@@ -45,26 +50,130 @@ func CrawlerTimeoutConfig() *TimeoutConfig {
 	}
 }
 
+// ScannerTimeoutConfig returns a timeout configuration for downloading and
+// scanning JavaScript files. There is no global deadline unless one is set;
+// callers size the operation timeout from their request timeout with
+// WithRequestTimeout.
+func ScannerTimeoutConfig() *TimeoutConfig {
+	return &TimeoutConfig{
+		OperationTimeout:  60 * time.Second,
+		HeartbeatInterval: 10 * time.Second,
+		GracePeriod:       10 * time.Second,
+	}
+}
+
+// DiscoveryTimeoutConfig returns a timeout configuration for endpoint probes.
+// There is no global deadline unless one is set; callers size the operation
+// timeout from their request timeout with WithRequestTimeout.
+func DiscoveryTimeoutConfig() *TimeoutConfig {
+	return &TimeoutConfig{
+		OperationTimeout:  30 * time.Second,
+		HeartbeatInterval: 5 * time.Second,
+		GracePeriod:       5 * time.Second,
+	}
+}
+
+// WithRequestTimeout sizes the operation timeout from the request timeout of
+// the run's HTTP client, so an operation making up to attempts requests is
+// never cut off before its requests are. A zero request timeout keeps the
+// configured operation timeout.
+func (c *TimeoutConfig) WithRequestTimeout(timeout time.Duration, attempts int) *TimeoutConfig {
+	if timeout <= 0 {
+		return c
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	c.OperationTimeout = timeout * time.Duration(attempts)
+	return c
+}
+
+// DefaultMaxOperations caps how many operations a TimeoutManager tracks at
+// once when the config does not set MaxOperations
+const DefaultMaxOperations = 1024
+
+// maxTargetLength bounds the target shown in operation log lines
+const maxTargetLength = 120
+
 // TimeoutManager manages timeouts for operations
 type TimeoutManager struct {
-	config    *TimeoutConfig
-	logger    *Logger
+	config     *TimeoutConfig
+	logger     *Logger
 	operations map[string]*OperationContext
-	mutex     sync.RWMutex
-	globalCtx context.Context
-	cancel    context.CancelFunc
-	startTime time.Time
+	slots      chan struct{}
+	mutex      sync.RWMutex
+	globalCtx  context.Context
+	cancel     context.CancelFunc
+	startTime  time.Time
+	sequence   int64
+	metrics    OperationMetrics
 }
 
-// OperationContext holds context for a single operation
+// OperationContext holds context for a single operation. ID is an opaque
+// identifier; Kind and Target describe the operation for log lines.
 type OperationContext struct {
 	ID        string
+	Kind      string
+	Target    string
 	Ctx       context.Context
 	Cancel    context.CancelFunc
 	StartTime time.Time
 	Timeout   time.Duration
 	Heartbeat chan struct{}
 	Done      chan struct{}
+	slot      bool // Counted against MaxOperations; nested operations are not
+}
+
+// String describes the operation with its target shortened for logging
+func (op *OperationContext) String() string {
+	target := op.Target
+	if len(target) > maxTargetLength {
+		target = target[:maxTargetLength] + "..."
+	}
+	if target == "" {
+		return op.ID
+	}
+	return fmt.Sprintf("%s (%s)", op.ID, target)
+}
+
+// OperationMetrics counts the operations tracked by a TimeoutManager
+type OperationMetrics struct {
+	Active    int64 `json:"active"`
+	Peak      int64 `json:"peak"`
+	Started   int64 `json:"started"`
+	Completed int64 `json:"completed"`
+	TimedOut  int64 `json:"timed_out"`
+	Cancelled int64 `json:"cancelled"`
+	Waited    int64 `json:"waited"` // starts held back by the concurrency cap
+}
+
+// add accumulates other into m, keeping the highest peak
+func (m *OperationMetrics) add(other OperationMetrics) {
+	m.Active += other.Active
+	m.Started += other.Started
+	m.Completed += other.Completed
+	m.TimedOut += other.TimedOut
+	m.Cancelled += other.Cancelled
+	m.Waited += other.Waited
+	if other.Peak > m.Peak {
+		m.Peak = other.Peak
+	}
+}
+
+// String formats the metrics for the run summary
+func (m OperationMetrics) String() string {
+	parts := []string{
+		fmt.Sprintf("%d started", m.Started),
+		fmt.Sprintf("%d timed out", m.TimedOut),
+	}
+	if m.Cancelled > 0 {
+		parts = append(parts, fmt.Sprintf("%d cancelled", m.Cancelled))
+	}
+	parts = append(parts, fmt.Sprintf("peak %d active", m.Peak))
+	if m.Waited > 0 {
+		parts = append(parts, fmt.Sprintf("%d waited for a slot", m.Waited))
+	}
+	return strings.Join(parts, ", ")
 }
 
 // NewTimeoutManager creates a new timeout manager
@@ -72,87 +181,153 @@ func NewTimeoutManager(config *TimeoutConfig, logger *Logger) *TimeoutManager {
 	if config == nil {
 		config = DefaultTimeoutConfig()
 	}
-	
+
 	if logger == nil {
 		logger = defaultLogger
 	}
-	
-	ctx, cancel := context.WithTimeout(context.Background(), config.GlobalTimeout)
-	
+
+	maxOperations := config.MaxOperations
+	if maxOperations <= 0 {
+		maxOperations = DefaultMaxOperations
+	}
+
+	// A zero global timeout leaves the run unbounded; operations still time out
+	ctx, cancel := context.WithCancel(context.Background())
+	if config.GlobalTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), config.GlobalTimeout)
+	}
+
 	tm := &TimeoutManager{
 		config:     config,
 		logger:     logger,
 		operations: make(map[string]*OperationContext),
+		slots:      make(chan struct{}, maxOperations),
 		globalCtx:  ctx,
 		cancel:     cancel,
 		startTime:  time.Now(),
 	}
-	
+
 	// Start global timeout monitor
 	go tm.monitorGlobalTimeout()
-	
+
 	return tm
 }
 
-// CreateOperation creates a new operation with timeout
-func (tm *TimeoutManager) CreateOperation(id string, timeout time.Duration) *OperationContext {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
-	
-	// Use operation timeout if not specified
-	if timeout == 0 {
-		timeout = tm.config.OperationTimeout
-	}
-	
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(tm.globalCtx, timeout)
-	
+// StartOperation registers an operation of the given kind against target
+// with the configured operation timeout. The returned ID is opaque and short;
+// the target is kept only as metadata for log lines. When MaxOperations are
+// already tracked it waits for one to finish, and once the global deadline
+// has passed it returns an operation whose context is already done.
+func (tm *TimeoutManager) StartOperation(kind, target string) *OperationContext {
+	return tm.start(kind, target, true)
+}
+
+// StartNestedOperation registers an operation started while the caller
+// already holds one, such as a baseline request made in the middle of a
+// probe. It times out like any other but is not counted against
+// MaxOperations, so a full set of workers waiting on nested work cannot
+// deadlock on the cap.
+func (tm *TimeoutManager) StartNestedOperation(kind, target string) *OperationContext {
+	return tm.start(kind, target, false)
+}
+
+func (tm *TimeoutManager) start(kind, target string, slot bool) *OperationContext {
+	timeout := tm.config.OperationTimeout
 	opCtx := &OperationContext{
-		ID:        id,
-		Ctx:       ctx,
-		Cancel:    cancel,
+		ID:        fmt.Sprintf("%s-%d", kind, atomic.AddInt64(&tm.sequence, 1)),
+		Kind:      kind,
+		Target:    target,
 		StartTime: time.Now(),
 		Timeout:   timeout,
 		Heartbeat: make(chan struct{}, 1),
 		Done:      make(chan struct{}),
+		slot:      slot,
 	}
-	
-	tm.operations[id] = opCtx
-	
+
+	if slot && !tm.acquire() {
+		opCtx.Ctx, opCtx.Cancel = context.WithCancel(tm.globalCtx)
+		opCtx.Cancel()
+		close(opCtx.Done)
+		return opCtx
+	}
+
+	opCtx.Ctx, opCtx.Cancel = context.WithTimeout(tm.globalCtx, timeout)
+
+	tm.mutex.Lock()
+	tm.operations[opCtx.ID] = opCtx
+	tm.metrics.Started++
+	if active := int64(len(tm.operations)); active > tm.metrics.Peak {
+		tm.metrics.Peak = active
+	}
+	tm.mutex.Unlock()
+
 	// Start operation monitor
 	go tm.monitorOperation(opCtx)
-	
-	tm.logger.Debug(fmt.Sprintf("Created operation %s with timeout %v", id, timeout))
+
+	tm.logger.Debug(fmt.Sprintf("Created operation %s with timeout %v", opCtx, timeout))
 	return opCtx
 }
 
+// acquire takes an operation slot, waiting for one while MaxOperations are
+// tracked. It reports false when the global deadline passes first.
+func (tm *TimeoutManager) acquire() bool {
+	select {
+	case tm.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	tm.mutex.Lock()
+	tm.metrics.Waited++
+	tm.mutex.Unlock()
+	select {
+	case tm.slots <- struct{}{}:
+		return true
+	case <-tm.globalCtx.Done():
+		return false
+	}
+}
+
 // CompleteOperation marks an operation as completed
 func (tm *TimeoutManager) CompleteOperation(id string) {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
-	
-	if opCtx, exists := tm.operations[id]; exists {
-		close(opCtx.Done)
-		opCtx.Cancel()
-		delete(tm.operations, id)
-		
-		duration := time.Since(opCtx.StartTime)
-		tm.logger.Debug(fmt.Sprintf("Completed operation %s in %v", id, duration))
+	if opCtx, ok := tm.finish(id, false); ok {
+		tm.logger.Debug(fmt.Sprintf("Completed operation %s in %v", opCtx, time.Since(opCtx.StartTime)))
 	}
 }
 
 // CancelOperation cancels a specific operation
 func (tm *TimeoutManager) CancelOperation(id string) {
+	if opCtx, ok := tm.finish(id, true); ok {
+		tm.logger.Warn(fmt.Sprintf("Cancelled operation %s", opCtx))
+	}
+}
+
+// finish stops tracking an operation and frees its slot. It reports false
+// when the operation already finished.
+func (tm *TimeoutManager) finish(id string, cancelled bool) (*OperationContext, bool) {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
-	
-	if opCtx, exists := tm.operations[id]; exists {
-		opCtx.Cancel()
-		close(opCtx.Done)
-		delete(tm.operations, id)
-		
-		tm.logger.Warn(fmt.Sprintf("Cancelled operation %s", id))
+
+	opCtx, exists := tm.operations[id]
+	if !exists {
+		return nil, false
+	}
+	opCtx.Cancel()
+	close(opCtx.Done)
+	delete(tm.operations, id)
+	if opCtx.slot {
+		<-tm.slots
 	}
+
+	switch {
+	case errors.Is(opCtx.Ctx.Err(), context.DeadlineExceeded):
+		tm.metrics.TimedOut++
+	case cancelled:
+		tm.metrics.Cancelled++
+	default:
+		tm.metrics.Completed++
+	}
+	return opCtx, true
 }
 
 // SendHeartbeat sends a heartbeat for an operation
@@ -160,7 +335,7 @@ func (tm *TimeoutManager) SendHeartbeat(id string) {
 	tm.mutex.RLock()
 	opCtx, exists := tm.operations[id]
 	tm.mutex.RUnlock()
-	
+
 	if exists {
 		select {
 		case opCtx.Heartbeat <- struct{}{}:
@@ -175,13 +350,49 @@ func (tm *TimeoutManager) SendHeartbeat(id string) {
 func (tm *TimeoutManager) GetOperationContext(id string) (context.Context, bool) {
 	tm.mutex.RLock()
 	defer tm.mutex.RUnlock()
-	
+
 	if opCtx, exists := tm.operations[id]; exists {
 		return opCtx.Ctx, true
 	}
 	return nil, false
 }
 
+// Context returns the global context, done once the global timeout passes
+func (tm *TimeoutManager) Context() context.Context {
+	return tm.globalCtx
+}
+
+// Expired reports whether the global timeout has passed, after which no new
+// work should be started
+func (tm *TimeoutManager) Expired() bool {
+	return tm.globalCtx.Err() == context.DeadlineExceeded
+}
+
+// ExpiredReason describes the global timeout for the run summary
+func (tm *TimeoutManager) ExpiredReason() string {
+	return fmt.Sprintf("global timeout of %v exceeded", tm.config.GlobalTimeout)
+}
+
+// HeartbeatReader wraps a response body so every read sends a heartbeat for
+// the operation, telling a slow but progressing download from a stuck one
+func (tm *TimeoutManager) HeartbeatReader(id string, reader io.Reader) io.Reader {
+	return &heartbeatReader{tm: tm, id: id, reader: reader}
+}
+
+type heartbeatReader struct {
+	tm     *TimeoutManager
+	id     string
+	reader io.Reader
+}
+
+func (r *heartbeatReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.tm.SendHeartbeat(r.id)
+	}
+	return n, err
+}
+
 // GetActiveOperations returns the number of active operations
 func (tm *TimeoutManager) GetActiveOperations() int {
 	tm.mutex.RLock()
@@ -189,34 +400,45 @@ func (tm *TimeoutManager) GetActiveOperations() int {
 	return len(tm.operations)
 }
 
+// Metrics returns the operation counters so far
+func (tm *TimeoutManager) Metrics() OperationMetrics {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	metrics := tm.metrics
+	metrics.Active = int64(len(tm.operations))
+	return metrics
+}
+
 // Shutdown gracefully shuts down the timeout manager
 func (tm *TimeoutManager) Shutdown() {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
-	
+
 	// Cancel all operations
 	for id, opCtx := range tm.operations {
 		opCtx.Cancel()
 		close(opCtx.Done)
-		tm.logger.Debug(fmt.Sprintf("Shutdown operation %s", id))
+		delete(tm.operations, id)
+		if opCtx.slot {
+			<-tm.slots
+		}
+		tm.metrics.Cancelled++
+		tm.logger.Debug(fmt.Sprintf("Shutdown operation %s", opCtx))
 	}
-	
-	// Clear operations
-	tm.operations = make(map[string]*OperationContext)
-	
+
 	// Cancel global context
 	tm.cancel()
-	
+
 	tm.logger.Info("Timeout manager shutdown completed")
 }
 
 // monitorGlobalTimeout monitors the global timeout
 func (tm *TimeoutManager) monitorGlobalTimeout() {
 	<-tm.globalCtx.Done()
-	
+
 	if tm.globalCtx.Err() == context.DeadlineExceeded {
 		tm.logger.Error(fmt.Sprintf("Global timeout exceeded after %v", tm.config.GlobalTimeout))
-		
+
 		// Cancel all operations
 		tm.mutex.RLock()
 		operations := make([]*OperationContext, 0, len(tm.operations))
@@ -224,7 +446,7 @@ func (tm *TimeoutManager) monitorGlobalTimeout() {
 			operations = append(operations, opCtx)
 		}
 		tm.mutex.RUnlock()
-		
+
 		for _, opCtx := range operations {
 			opCtx.Cancel()
 		}
@@ -235,35 +457,35 @@ func (tm *TimeoutManager) monitorGlobalTimeout() {
 func (tm *TimeoutManager) monitorOperation(opCtx *OperationContext) {
 	heartbeatTicker := time.NewTicker(tm.config.HeartbeatInterval)
 	defer heartbeatTicker.Stop()
-	
+
 	lastHeartbeat := time.Now()
-	
+
 	for {
 		select {
 		case <-opCtx.Done:
 			// Operation completed normally
 			return
-		
+
 		case <-opCtx.Ctx.Done():
 			// Operation timed out or was cancelled
 			if opCtx.Ctx.Err() == context.DeadlineExceeded {
-				duration := time.Since(opCtx.StartTime)
-				tm.logger.Warn(fmt.Sprintf("Operation %s timed out after %v (timeout: %v)", 
-					opCtx.ID, duration, opCtx.Timeout))
+				tm.logger.Warn(fmt.Sprintf("Operation %s timed out after %v (timeout: %v)",
+					opCtx, time.Since(opCtx.StartTime), opCtx.Timeout))
+				tm.finish(opCtx.ID, false)
+			} else {
+				tm.CancelOperation(opCtx.ID)
 			}
-			tm.CancelOperation(opCtx.ID)
 			return
-		
+
 		case <-opCtx.Heartbeat:
 			// Received heartbeat
 			lastHeartbeat = time.Now()
-			tm.logger.Debug(fmt.Sprintf("Received heartbeat for operation %s", opCtx.ID))
-		
+
 		case <-heartbeatTicker.C:
 			// Check for heartbeat timeout
 			if time.Since(lastHeartbeat) > tm.config.HeartbeatInterval*2 {
-				tm.logger.Warn(fmt.Sprintf("No heartbeat received for operation %s in %v", 
-					opCtx.ID, time.Since(lastHeartbeat)))
+				tm.logger.Warn(fmt.Sprintf("No heartbeat received for operation %s in %v",
+					opCtx, time.Since(lastHeartbeat)))
 			}
 		}
 	}
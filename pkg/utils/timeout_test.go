@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutManager_StartOperation(t *testing.T) {
+	tm := NewTimeoutManager(&TimeoutConfig{
+		OperationTimeout:  time.Second,
+		HeartbeatInterval: time.Second,
+	}, nil)
+	defer tm.Shutdown()
+
+	target := "https://example.com/" + strings.Repeat("a", 500)
+	op := tm.StartOperation("probe", target)
+	if op.ID != "probe-1" {
+		t.Errorf("Expected opaque ID probe-1, got %s", op.ID)
+	}
+	if op.Target != target || op.Kind != "probe" {
+		t.Errorf("Expected kind and target as metadata, got %s %s", op.Kind, op.Target)
+	}
+	if len(op.String()) > maxTargetLength+len(op.ID)+10 {
+		t.Errorf("Expected log description to be bounded, got %d bytes", len(op.String()))
+	}
+	if tm.GetActiveOperations() != 1 {
+		t.Errorf("Expected 1 active operation, got %d", tm.GetActiveOperations())
+	}
+
+	tm.CompleteOperation(op.ID)
+	if op.Ctx.Err() == nil {
+		t.Error("Expected context to be cancelled after completion")
+	}
+
+	metrics := tm.Metrics()
+	if metrics.Started != 1 || metrics.Completed != 1 || metrics.Active != 0 || metrics.Peak != 1 {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+}
+
+func TestTimeoutManager_MaxOperations(t *testing.T) {
+	tm := NewTimeoutManager(&TimeoutConfig{
+		OperationTimeout:  time.Second,
+		HeartbeatInterval: time.Second,
+		MaxOperations:     1,
+	}, nil)
+	defer tm.Shutdown()
+
+	first := tm.StartOperation("scan", "https://example.com/a.js")
+
+	started := make(chan *OperationContext)
+	go func() {
+		started <- tm.StartOperation("scan", "https://example.com/b.js")
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("Expected second operation to wait for a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tm.CompleteOperation(first.ID)
+	second := <-started
+	tm.CompleteOperation(second.ID)
+
+	if metrics := tm.Metrics(); metrics.Waited != 1 || metrics.Peak != 1 {
+		t.Errorf("Expected one waited start and a peak of 1, got %+v", metrics)
+	}
+}
+
+func TestTimeoutManager_StartNestedOperation(t *testing.T) {
+	tm := NewTimeoutManager(&TimeoutConfig{
+		OperationTimeout:  time.Second,
+		HeartbeatInterval: time.Second,
+		MaxOperations:     1,
+	}, nil)
+	defer tm.Shutdown()
+
+	probe := tm.StartOperation("probe", "https://example.com/api")
+
+	started := make(chan *OperationContext)
+	go func() {
+		started <- tm.StartNestedOperation("session", "https://example.com")
+	}()
+
+	var session *OperationContext
+	select {
+	case session = <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Expected nested operation to start while every slot is held")
+	}
+	if session.Ctx.Err() != nil {
+		t.Fatalf("Expected nested operation to be live, got %v", session.Ctx.Err())
+	}
+	tm.CompleteOperation(session.ID)
+	tm.CompleteOperation(probe.ID)
+
+	// The nested operation must not have freed the probe's slot twice
+	next := tm.StartOperation("probe", "https://example.com/other")
+	defer tm.CompleteOperation(next.ID)
+	if metrics := tm.Metrics(); metrics.Waited != 0 || metrics.Completed != 2 {
+		t.Errorf("Expected two completions and no waits, got %+v", metrics)
+	}
+}
+
+func TestTimeoutManager_timedOut(t *testing.T) {
+	tm := NewTimeoutManager(&TimeoutConfig{
+		OperationTimeout:  20 * time.Millisecond,
+		HeartbeatInterval: time.Second,
+	}, nil)
+	defer tm.Shutdown()
+
+	stats := NewRunStats()
+	stats.TrackOperations(tm)
+
+	op := tm.StartOperation("crawl", "https://example.com/")
+	<-op.Ctx.Done()
+	deadline := time.Now().Add(time.Second)
+	for tm.GetActiveOperations() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	tm.CompleteOperation(op.ID)
+
+	snapshot := stats.Snapshot()
+	if snapshot.Operations == nil || snapshot.Operations.TimedOut != 1 || snapshot.Operations.Completed != 0 {
+		t.Fatalf("Expected one timed-out operation, got %+v", snapshot.Operations)
+	}
+
+	var summary strings.Builder
+	if err := stats.WriteSummary(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(summary.String(), "Operations:       1 started, 1 timed out") {
+		t.Errorf("Expected operations line in summary, got:\n%s", summary.String())
+	}
+}
+
+func TestTimeoutConfig_WithRequestTimeout(t *testing.T) {
+	config := ScannerTimeoutConfig().WithRequestTimeout(120*time.Second, 5)
+	if config.OperationTimeout != 10*time.Minute {
+		t.Errorf("Expected room for 5 attempts of 120s, got %v", config.OperationTimeout)
+	}
+
+	config = DiscoveryTimeoutConfig().WithRequestTimeout(0, 1)
+	if config.OperationTimeout != DiscoveryTimeoutConfig().OperationTimeout {
+		t.Errorf("Expected the default kept without a request timeout, got %v", config.OperationTimeout)
+	}
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// browserProfile is a browser-like header set
+type browserProfile struct {
+	userAgent string
+	accept    string
+}
+
+// browserProfiles are the browser-like header sets tried when a host blocks the default client
+var browserProfiles = []browserProfile{
+	{
+		userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		accept:    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+	},
+	{
+		userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:125.0) Gecko/20100101 Firefox/125.0",
+		accept:    "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+	},
+}
+
+// blockThreshold is how many blocked responses in a row a host must send
+// before the browser profiles are tried, so one forbidden path does not
+// switch the whole host
+const blockThreshold = 2
+
+// UAFallback retries requests blocked with 403/406 using browser-like headers.
+// The fallback is attempted once per host, after blockThreshold blocked
+// responses in a row; when a profile gets a successful response it is used
+// for every later request to that host. A nil *UAFallback is disabled.
+type UAFallback struct {
+	mutex    sync.Mutex
+	blocks   map[string]int
+	tried    map[string]bool
+	profiles map[string]browserProfile
+	logger   *Logger
+}
+
+// NewUAFallback creates an empty per-host fallback tracker
+func NewUAFallback() *UAFallback {
+	return &UAFallback{
+		blocks:   make(map[string]int),
+		tried:    make(map[string]bool),
+		profiles: make(map[string]browserProfile),
+		logger:   defaultLogger,
+	}
+}
+
+// Results returns the User-Agent that succeeded for each host that needed one
+func (f *UAFallback) Results() map[string]string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	results := make(map[string]string, len(f.profiles))
+	for host, profile := range f.profiles {
+		results[host] = profile.userAgent
+	}
+	return results
+}
+
+// lookup returns the recorded profile for host, if any, and whether the
+// fallback has been tried
+func (f *UAFallback) lookup(host string) (browserProfile, bool, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	profile, found := f.profiles[host]
+	return profile, found, f.tried[host]
+}
+
+// claim counts a response from host and marks the host as tried once it has
+// been blocked blockThreshold times in a row. It returns true for the request
+// that should try the browser profiles, false otherwise.
+func (f *UAFallback) claim(host string, blocked bool) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.tried[host] {
+		return false
+	}
+	if !blocked {
+		delete(f.blocks, host)
+		return false
+	}
+	f.blocks[host]++
+	if f.blocks[host] < blockThreshold {
+		return false
+	}
+	delete(f.blocks, host)
+	f.tried[host] = true
+	return true
+}
+
+// record stores the profile that got through for host
+func (f *UAFallback) record(host string, profile browserProfile) {
+	f.mutex.Lock()
+	f.profiles[host] = profile
+	f.mutex.Unlock()
+
+	f.logger.WithField("target", host).Infof("Blocked with default client, continuing with User-Agent %q", profile.userAgent)
+}
+
+// isBlocked reports whether a status code looks like naive bot blocking
+func isBlocked(statusCode int) bool {
+	return statusCode == http.StatusForbidden || statusCode == http.StatusNotAcceptable
+}
+
+// withBrowserProfile clones req with the User-Agent and Accept headers of profile
+func withBrowserProfile(req *http.Request, profile browserProfile) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("request body cannot be replayed")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+
+	clone.Header.Set("User-Agent", profile.userAgent)
+	clone.Header.Set("Accept", profile.accept)
+	clone.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	return clone, nil
+}
+
+// uaFallbackTransport applies UAFallback to every request
+type uaFallbackTransport struct {
+	base     http.RoundTripper
+	fallback *UAFallback
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *uaFallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	profile, found, tried := t.fallback.lookup(host)
+	if found {
+		profiled, err := withBrowserProfile(req, profile)
+		if err != nil {
+			return t.base.RoundTrip(req)
+		}
+		return t.base.RoundTrip(profiled)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || tried || !t.fallback.claim(host, isBlocked(resp.StatusCode)) {
+		return resp, err
+	}
+
+	for _, profile := range browserProfiles {
+		retry, err := withBrowserProfile(req, profile)
+		if err != nil {
+			break
+		}
+
+		retryResp, err := t.base.RoundTrip(retry)
+		if err != nil {
+			continue
+		}
+		// Only a successful response shows the profile gets through
+		if retryResp.StatusCode < http.StatusBadRequest {
+			resp.Body.Close()
+			t.fallback.record(host, profile)
+			return retryResp, nil
+		}
+		retryResp.Body.Close()
+	}
+
+	return resp, nil
+}
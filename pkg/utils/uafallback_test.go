@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUAFallback(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// Checks the Accept header too, so the whole profile must be reapplied
+		if !strings.Contains(r.Header.Get("User-Agent"), "Mozilla") || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	fallback := NewUAFallback()
+	fallback.logger = NewLogger(ERROR, io.Discard)
+	client := NewHTTPClient(&ClientOptions{UAFallback: fallback})
+
+	get := func() int {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header.Set("User-Agent", "jsfinder/1.0")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// A single block is passed through as-is
+	if status := get(); status != http.StatusForbidden || requests != 1 {
+		t.Fatalf("Expected the first block to be returned, got status %d after %d requests", status, requests)
+	}
+	if status := get(); status != http.StatusOK {
+		t.Fatalf("Expected fallback to get through, got %d", status)
+	}
+	if requests != 3 {
+		t.Errorf("Expected two blocked requests plus one retry, got %d requests", requests)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if agent := fallback.Results()[host]; !strings.Contains(agent, "Mozilla") {
+		t.Errorf("Expected winning User-Agent to be recorded, got %q", agent)
+	}
+
+	// Later requests use the recorded User-Agent directly
+	if status := get(); status != http.StatusOK || requests != 4 {
+		t.Errorf("Expected single request with recorded User-Agent, got status %d after %d requests", status, requests)
+	}
+}
+
+func TestUAFallback_OncePerHost(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotAcceptable)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&ClientOptions{UAFallback: NewUAFallback()})
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Two blocked requests, one retry per browser profile, then two plain requests
+	if expected := int32(2 + len(browserProfiles) + 2); requests != expected {
+		t.Errorf("Expected %d requests, got %d", expected, requests)
+	}
+}
+
+func TestUAFallback_RequiresConsistentBlocks(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch {
+		case r.URL.Path == "/admin":
+			w.WriteHeader(http.StatusForbidden)
+		case strings.Contains(r.Header.Get("User-Agent"), "Mozilla"):
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/blocked":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	fallback := NewUAFallback()
+	client := NewHTTPClient(&ClientOptions{UAFallback: fallback})
+	get := func(path string) int {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Blocks separated by a successful response are not retried
+	for _, path := range []string{"/admin", "/", "/admin", "/"} {
+		get(path)
+	}
+	if requests != 4 {
+		t.Errorf("Expected no fallback retries for isolated blocks, got %d requests", requests)
+	}
+
+	// Browser profiles answered with an error do not switch the host
+	get("/blocked")
+	if status := get("/blocked"); status != http.StatusForbidden {
+		t.Errorf("Expected the original block to be returned, got %d", status)
+	}
+	if len(fallback.Results()) != 0 {
+		t.Errorf("Expected no User-Agent to be recorded, got %v", fallback.Results())
+	}
+	if status := get("/"); status != http.StatusOK {
+		t.Errorf("Expected the default client to be kept, got %d", status)
+	}
+}
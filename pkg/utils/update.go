@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultReleasesAPI is where the latest published jsfinder release is looked up
+const DefaultReleasesAPI = "https://api.github.com/repos/devthedeveloper/jsfinder/releases/latest"
+
+// Release is the subset of a GitHub release the update check reports
+type Release struct {
+	Version   string    `json:"tag_name"`
+	URL       string    `json:"html_url"`
+	Published time.Time `json:"published_at"`
+}
+
+// LatestRelease fetches the newest published release from a GitHub releases API URL
+func LatestRelease(client *http.Client, apiURL string) (*Release, error) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check for updates: HTTP %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("invalid release response: %w", err)
+	}
+	return &release, nil
+}
+
+// NewerVersion reports whether latest is a higher release than current. Both
+// are dotted versions with an optional leading "v"; a current version that
+// is not one, such as a "dev" build, is never considered outdated.
+func NewerVersion(current, latest string) bool {
+	currentParts, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	latestParts, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < len(currentParts) || i < len(latestParts); i++ {
+		var c, l int
+		if i < len(currentParts) {
+			c = currentParts[i]
+		}
+		if i < len(latestParts) {
+			l = latestParts[i]
+		}
+		if c != l {
+			return l > c
+		}
+	}
+	return false
+}
+
+// parseVersion splits v1.2.3 into its numeric parts, ignoring any
+// pre-release or build suffix
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
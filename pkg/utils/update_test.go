@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		expected        bool
+	}{
+		{"v1.0.0", "v1.0.1", true},
+		{"1.2.0", "v1.10.0", true},
+		{"v1.2", "v1.2.0", false},
+		{"v2.0.0", "v1.9.9", false},
+		{"v1.0.0-rc1", "v1.0.0", false},
+		{"dev", "v9.0.0", false},
+		{"v1.0.0", "nightly", false},
+	}
+
+	for _, tt := range tests {
+		if got := NewerVersion(tt.current, tt.latest); got != tt.expected {
+			t.Errorf("NewerVersion(%q, %q) = %v, expected %v", tt.current, tt.latest, got, tt.expected)
+		}
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"tag_name": "v1.4.0", "html_url": "https://github.com/devthedeveloper/jsfinder/releases/tag/v1.4.0", "published_at": "2024-05-01T12:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	release, err := LatestRelease(server.Client(), server.URL+"/latest")
+	if err != nil {
+		t.Fatalf("Failed to fetch release: %v", err)
+	}
+	if release.Version != "v1.4.0" || release.Published.Year() != 2024 {
+		t.Errorf("Unexpected release %+v", release)
+	}
+
+	if _, err := LatestRelease(server.Client(), server.URL+"/missing"); err == nil {
+		t.Error("Expected error for missing release")
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultPorts are left out of canonical hosts so https://example.com and
+// https://example.com:443 are treated as the same origin
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// CanonicalHost returns a URL's host in a comparable form: lowercased, without
+// the scheme's default port, and with IPv6 literals bracketed. Credentials
+// (user:pass@) are never part of it. It returns "" when the URL has no host or
+// an invalid port.
+func CanonicalHost(u *url.URL) string {
+	hostname := strings.ToLower(u.Hostname())
+	if hostname == "" {
+		return ""
+	}
+
+	port := u.Port()
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return ""
+		}
+		if port == defaultPorts[strings.ToLower(u.Scheme)] {
+			port = ""
+		}
+	}
+
+	if port != "" {
+		return net.JoinHostPort(hostname, port)
+	}
+	if strings.Contains(hostname, ":") {
+		return "[" + hostname + "]"
+	}
+	return hostname
+}
+
+// Origin returns scheme://host for a URL, with the host canonicalized and any
+// embedded credentials dropped, or "" when the URL has no scheme or host
+func Origin(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" {
+		return ""
+	}
+
+	host := CanonicalHost(parsed)
+	if host == "" {
+		return ""
+	}
+	return strings.ToLower(parsed.Scheme) + "://" + host
+}
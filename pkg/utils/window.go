@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunWindow restricts traffic to a daily time-of-day window such as "22:00-06:00".
+// A nil *RunWindow is always open.
+type RunWindow struct {
+	spec        string
+	start       time.Duration // Offset from local midnight when the window opens
+	end         time.Duration // Offset from local midnight when the window closes
+	mutex       sync.Mutex
+	pausedUntil time.Time
+	logger      *Logger
+}
+
+// ParseRunWindow parses a "HH:MM-HH:MM" window in local time; windows may wrap midnight
+func ParseRunWindow(spec string) (*RunWindow, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(spec, "-")
+	if len(parts) != 2 {
+		return nil, NewValidationError(fmt.Sprintf("invalid run window %q, expected HH:MM-HH:MM", spec), nil)
+	}
+
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid run window start %q", parts[0]), err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid run window end %q", parts[1]), err)
+	}
+
+	return &RunWindow{
+		spec:   spec,
+		start:  start,
+		end:    end,
+		logger: defaultLogger,
+	}, nil
+}
+
+// String returns the window specification
+func (w *RunWindow) String() string {
+	if w == nil {
+		return "always"
+	}
+	return w.spec
+}
+
+// Contains reports whether t falls inside the window
+func (w *RunWindow) Contains(t time.Time) bool {
+	if w == nil || w.start == w.end {
+		return true
+	}
+
+	offset := t.Sub(midnight(t))
+	if w.start < w.end {
+		return offset >= w.start && offset < w.end
+	}
+	// Window wraps midnight
+	return offset >= w.start || offset < w.end
+}
+
+// NextOpen returns the earliest time at or after t when the window is open
+func (w *RunWindow) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	next := midnight(t).Add(w.start)
+	if next.Before(t) {
+		next = midnight(t.AddDate(0, 0, 1)).Add(w.start)
+	}
+	return next
+}
+
+// Wait blocks until the window is open or the context is cancelled
+func (w *RunWindow) Wait(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+
+	for {
+		now := time.Now()
+		if w.Contains(now) {
+			return nil
+		}
+
+		next := w.NextOpen(now)
+		w.logPause(next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return NewTimeoutError("cancelled while waiting for run window", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// logPause logs a single message per pause period rather than one per worker
+func (w *RunWindow) logPause(next time.Time) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.pausedUntil.Equal(next) {
+		return
+	}
+	w.pausedUntil = next
+	w.logger.Info(fmt.Sprintf("Outside run window %s, pausing until %s", w.spec, next.Format("2006-01-02 15:04")))
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// midnight returns the start of the local day containing t
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRunWindow(t *testing.T) {
+	if _, err := ParseRunWindow("22:00"); err == nil {
+		t.Error("Expected error for window without end")
+	}
+	if _, err := ParseRunWindow("25:00-06:00"); err == nil {
+		t.Error("Expected error for invalid hour")
+	}
+
+	window, err := ParseRunWindow("")
+	if err != nil || window != nil {
+		t.Errorf("Expected empty spec to yield no window, got %v, %v", window, err)
+	}
+}
+
+func TestRunWindow_Contains(t *testing.T) {
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)
+
+	testCases := []struct {
+		name     string
+		spec     string
+		at       time.Duration
+		expected bool
+	}{
+		{"Inside daytime window", "09:00-17:00", 12 * time.Hour, true},
+		{"Before daytime window", "09:00-17:00", 8 * time.Hour, false},
+		{"End is exclusive", "09:00-17:00", 17 * time.Hour, false},
+		{"Overnight late evening", "22:00-06:00", 23 * time.Hour, true},
+		{"Overnight early morning", "22:00-06:00", 5 * time.Hour, true},
+		{"Overnight midday", "22:00-06:00", 12 * time.Hour, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			window, err := ParseRunWindow(tc.spec)
+			if err != nil {
+				t.Fatalf("Failed to parse window: %v", err)
+			}
+			if result := window.Contains(day.Add(tc.at)); result != tc.expected {
+				t.Errorf("Expected %v for %s at %v, got %v", tc.expected, tc.spec, tc.at, result)
+			}
+		})
+	}
+}
+
+func TestRunWindow_NextOpen(t *testing.T) {
+	window, _ := ParseRunWindow("22:00-06:00")
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)
+
+	next := window.NextOpen(day.Add(12 * time.Hour))
+	if expected := day.Add(22 * time.Hour); !next.Equal(expected) {
+		t.Errorf("Expected next open at %v, got %v", expected, next)
+	}
+
+	inside := day.Add(23 * time.Hour)
+	if next := window.NextOpen(inside); !next.Equal(inside) {
+		t.Errorf("Expected open window to return the same time, got %v", next)
+	}
+}
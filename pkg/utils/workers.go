@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Task is a unit of work run by a WorkerPool. The context ends at the pool's
+// task timeout or when the pool's parent context is done.
+type Task func(ctx context.Context, workerID int)
+
+// PoolOptions configures a WorkerPool
+type PoolOptions struct {
+	Workers     int           // Concurrent workers; values below 1 mean 1
+	QueueSize   int           // Tasks accepted ahead of a free worker
+	TaskTimeout time.Duration // Deadline for each task's context; 0 for none
+	Logger      *Logger       // Receives recovered panics
+}
+
+// WorkerPool runs tasks on a fixed set of workers with stable identifiers,
+// so log lines can be attributed to the slot that produced them. Submit
+// blocks once the workers and queue are full, which keeps callers from
+// reading further input than the pool can take.
+type WorkerPool struct {
+	ctx     context.Context
+	options PoolOptions
+	tasks   chan queuedTask
+	wg      sync.WaitGroup
+	start   time.Time
+	end     time.Time
+	mutex   sync.Mutex
+	stats   PoolStats
+}
+
+type queuedTask struct {
+	run      Task
+	queuedAt time.Time
+}
+
+// PoolStats describes how busy a worker pool was and how long tasks waited
+// for a worker. Stats of several pools add up in RunStats.
+type PoolStats struct {
+	Tasks          int64   `json:"tasks"`
+	Panics         int64   `json:"panics,omitempty"`
+	TimedOut       int64   `json:"timed_out,omitempty"`
+	Utilization    float64 `json:"utilization"` // share of worker time spent running tasks
+	AvgQueueWaitMs float64 `json:"avg_queue_wait_ms"`
+	MaxQueueWaitMs int64   `json:"max_queue_wait_ms"`
+
+	busy         time.Duration
+	capacity     time.Duration // workers x pool lifetime
+	queueWait    time.Duration
+	maxQueueWait time.Duration
+}
+
+// NewWorkerPool starts the workers of a pool whose tasks run under ctx
+func NewWorkerPool(ctx context.Context, options PoolOptions) *WorkerPool {
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+	if options.Logger == nil {
+		options.Logger = defaultLogger
+	}
+
+	p := &WorkerPool{
+		ctx:     ctx,
+		options: options,
+		tasks:   make(chan queuedTask, max(options.QueueSize, 0)),
+		start:   time.Now(),
+	}
+	for id := 1; id <= options.Workers; id++ {
+		p.wg.Add(1)
+		go p.work(id)
+	}
+	return p
+}
+
+// Submit queues a task, blocking while the workers and queue are full.
+// It must not be called after Wait.
+func (p *WorkerPool) Submit(task Task) {
+	p.tasks <- queuedTask{run: task, queuedAt: time.Now()}
+}
+
+// Wait stops accepting tasks and returns once every queued task has run
+func (p *WorkerPool) Wait() {
+	close(p.tasks)
+	p.wg.Wait()
+
+	p.mutex.Lock()
+	p.end = time.Now()
+	p.mutex.Unlock()
+}
+
+// Stats returns the pool's counters so far
+func (p *WorkerPool) Stats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	end := p.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	stats := p.stats
+	stats.capacity = time.Duration(p.options.Workers) * end.Sub(p.start)
+	stats.derive()
+	return stats
+}
+
+func (p *WorkerPool) work(workerID int) {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		p.run(task, workerID)
+	}
+}
+
+// run executes one task, recovering a panic so the worker keeps going
+func (p *WorkerPool) run(task queuedTask, workerID int) {
+	started := time.Now()
+	wait := started.Sub(task.queuedAt)
+
+	ctx, cancel := p.ctx, context.CancelFunc(func() {})
+	if p.options.TaskTimeout > 0 {
+		ctx, cancel = context.WithTimeout(p.ctx, p.options.TaskTimeout)
+	}
+
+	panicked := false
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panicked = true
+				p.options.Logger.Errorf("Worker %d recovered from panic: %v\n%s", workerID, r, debug.Stack())
+			}
+		}()
+		task.run(ctx, workerID)
+	}()
+	timedOut := p.options.TaskTimeout > 0 && ctx.Err() == context.DeadlineExceeded && p.ctx.Err() == nil
+	cancel()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.stats.Tasks++
+	p.stats.busy += time.Since(started)
+	p.stats.queueWait += wait
+	if wait > p.stats.maxQueueWait {
+		p.stats.maxQueueWait = wait
+	}
+	if panicked {
+		p.stats.Panics++
+	}
+	if timedOut {
+		p.stats.TimedOut++
+	}
+}
+
+// add accumulates other into s
+func (s *PoolStats) add(other PoolStats) {
+	s.Tasks += other.Tasks
+	s.Panics += other.Panics
+	s.TimedOut += other.TimedOut
+	s.busy += other.busy
+	s.capacity += other.capacity
+	s.queueWait += other.queueWait
+	if other.maxQueueWait > s.maxQueueWait {
+		s.maxQueueWait = other.maxQueueWait
+	}
+	s.derive()
+}
+
+// derive fills the exported figures from the raw durations
+func (s *PoolStats) derive() {
+	s.Utilization, s.AvgQueueWaitMs = 0, 0
+	if s.capacity > 0 {
+		s.Utilization = min(float64(s.busy)/float64(s.capacity), 1)
+	}
+	if s.Tasks > 0 {
+		s.AvgQueueWaitMs = float64(s.queueWait.Microseconds()) / float64(s.Tasks) / 1000
+	}
+	s.MaxQueueWaitMs = s.maxQueueWait.Milliseconds()
+}
+
+// String formats the stats for the run summary
+func (s PoolStats) String() string {
+	summary := fmt.Sprintf("%d tasks, %.0f%% busy, queue wait avg %v max %v", s.Tasks, s.Utilization*100,
+		time.Duration(s.AvgQueueWaitMs*float64(time.Millisecond)).Round(time.Millisecond),
+		s.maxQueueWait.Round(time.Millisecond))
+	if s.TimedOut > 0 {
+		summary += fmt.Sprintf(", %d timed out", s.TimedOut)
+	}
+	if s.Panics > 0 {
+		summary += fmt.Sprintf(", %d panics recovered", s.Panics)
+	}
+	return summary
+}
+
+// WorkerFields returns the standard log fields for a unit of work
+func WorkerFields(target string, workerID int) map[string]string {
+	return map[string]string{
+		"target":    target,
+		"worker_id": strconv.Itoa(workerID),
+	}
+}
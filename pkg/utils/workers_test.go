@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_bounded(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), PoolOptions{Workers: 2})
+
+	var running, peak int64
+	ids := make(map[int]bool)
+	var mutex sync.Mutex
+	for i := 0; i < 10; i++ {
+		pool.Submit(func(ctx context.Context, workerID int) {
+			now := atomic.AddInt64(&running, 1)
+			for {
+				old := atomic.LoadInt64(&peak)
+				if now <= old || atomic.CompareAndSwapInt64(&peak, old, now) {
+					break
+				}
+			}
+			mutex.Lock()
+			ids[workerID] = true
+			mutex.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+		})
+	}
+	pool.Wait()
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 tasks at once, got %d", peak)
+	}
+	for id := range ids {
+		if id < 1 || id > 2 {
+			t.Errorf("Expected worker IDs 1-2, got %d", id)
+		}
+	}
+
+	stats := pool.Stats()
+	if stats.Tasks != 10 {
+		t.Errorf("Expected 10 tasks, got %d", stats.Tasks)
+	}
+	if stats.Utilization <= 0 || stats.Utilization > 1 {
+		t.Errorf("Expected utilization between 0 and 1, got %f", stats.Utilization)
+	}
+	if stats.MaxQueueWaitMs < 1 {
+		t.Errorf("Expected queued tasks to wait for a worker, got max %dms", stats.MaxQueueWaitMs)
+	}
+}
+
+func TestWorkerPool_panicAndTimeout(t *testing.T) {
+	var logs bytes.Buffer
+	logger := NewLogger(INFO, &logs)
+
+	pool := NewWorkerPool(context.Background(), PoolOptions{Workers: 1, TaskTimeout: 10 * time.Millisecond, Logger: logger})
+	pool.Submit(func(ctx context.Context, workerID int) {
+		panic("boom")
+	})
+	pool.Submit(func(ctx context.Context, workerID int) {
+		<-ctx.Done()
+	})
+	ran := false
+	pool.Submit(func(ctx context.Context, workerID int) {
+		ran = true
+	})
+	pool.Wait()
+
+	if !ran {
+		t.Error("Expected the worker to keep running tasks after a panic")
+	}
+	stats := pool.Stats()
+	if stats.Panics != 1 || stats.TimedOut != 1 || stats.Tasks != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if !strings.Contains(logs.String(), "recovered from panic: boom") {
+		t.Errorf("Expected panic to be logged, got %q", logs.String())
+	}
+
+	runStats := NewRunStats()
+	runStats.AddWorkerPool(pool)
+	runStats.AddWorkerPool(pool)
+	if snapshot := runStats.Snapshot(); snapshot.Workers == nil || snapshot.Workers.Tasks != 6 || snapshot.Workers.Panics != 2 {
+		t.Errorf("Expected pool stats to add up, got %+v", snapshot.Workers)
+	}
+}
@@ -0,0 +1,395 @@
+package view
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"jsfinder/pkg/merge"
+)
+
+// DefaultPageSize is how many results a page lists
+const DefaultPageSize = 20
+
+// Fields that results can be filtered and sorted by
+var (
+	FilterFields = []string{"type", "severity", "url", "status", "kind"}
+	SortFields   = []string{"url", "type", "severity", "status"}
+)
+
+// severityRank orders confidences from most to least severe
+var severityRank = map[string]int{"HIGH": 0, "MEDIUM": 1, "LOW": 2}
+
+// entry is a finding or an endpoint in the browser
+type entry struct {
+	finding  *merge.Finding
+	endpoint *merge.Endpoint
+}
+
+func (e entry) kind() string {
+	if e.finding != nil {
+		return "finding"
+	}
+	return "endpoint"
+}
+
+func (e entry) url() string {
+	if e.finding != nil {
+		return e.finding.URL
+	}
+	return e.endpoint.URL
+}
+
+// typ is the finding type, or the content type of an endpoint
+func (e entry) typ() string {
+	if e.finding != nil {
+		return e.finding.Type
+	}
+	return e.endpoint.ContentType
+}
+
+// severity is the finding confidence; endpoints have none
+func (e entry) severity() string {
+	if e.finding != nil {
+		return strings.ToUpper(e.finding.Confidence)
+	}
+	return ""
+}
+
+// status is the endpoint status code; findings have none
+func (e entry) status() int {
+	if e.endpoint != nil {
+		return e.endpoint.StatusCode
+	}
+	return 0
+}
+
+// field returns the value a filter on name compares
+func (e entry) field(name string) string {
+	switch name {
+	case "type":
+		return e.typ()
+	case "severity":
+		return e.severity()
+	case "status":
+		if e.endpoint == nil {
+			return ""
+		}
+		return strconv.Itoa(e.status())
+	case "kind":
+		return e.kind()
+	}
+	return e.url()
+}
+
+// Browser pages through saved findings and endpoints with filters and a sort
+// order, driven by commands read one per line
+type Browser struct {
+	PageSize int
+	entries  []entry
+	filters  map[string]string
+	sortBy   string
+	desc     bool
+	visible  []int // Indexes of the entries passing the filters, in sort order
+	page     int
+}
+
+// New creates a browser over findings and endpoints in their given order
+func New(findings []merge.Finding, endpoints []merge.Endpoint) *Browser {
+	b := &Browser{PageSize: DefaultPageSize, filters: make(map[string]string)}
+	for i := range findings {
+		b.entries = append(b.entries, entry{finding: &findings[i]})
+	}
+	for i := range endpoints {
+		b.entries = append(b.entries, entry{endpoint: &endpoints[i]})
+	}
+	b.refresh()
+	return b
+}
+
+// Filter keeps the results whose field matches value: a case-insensitive
+// substring for type and url, an exact value for severity, status and kind.
+// An empty value removes the filter on field.
+func (b *Browser) Filter(field, value string) error {
+	field = strings.ToLower(field)
+	if !slices.Contains(FilterFields, field) {
+		return fmt.Errorf("unknown filter %q (want one of %s)", field, strings.Join(FilterFields, ", "))
+	}
+	if value == "" {
+		delete(b.filters, field)
+	} else {
+		b.filters[field] = value
+	}
+	b.refresh()
+	return nil
+}
+
+// Sort orders the results by field, descending with a leading "-"
+func (b *Browser) Sort(field string) error {
+	desc := strings.HasPrefix(field, "-")
+	field = strings.ToLower(strings.TrimPrefix(field, "-"))
+	if !slices.Contains(SortFields, field) {
+		return fmt.Errorf("unknown sort field %q (want one of %s)", field, strings.Join(SortFields, ", "))
+	}
+	b.sortBy, b.desc = field, desc
+	b.refresh()
+	return nil
+}
+
+// Len returns the number of results passing the filters
+func (b *Browser) Len() int {
+	return len(b.visible)
+}
+
+// refresh applies the filters and sort order and goes back to the first page
+func (b *Browser) refresh() {
+	b.visible = b.visible[:0]
+	for i, e := range b.entries {
+		if b.matches(e) {
+			b.visible = append(b.visible, i)
+		}
+	}
+	if b.sortBy != "" {
+		sort.SliceStable(b.visible, func(i, j int) bool {
+			x, y := b.entries[b.visible[i]], b.entries[b.visible[j]]
+			if b.desc {
+				x, y = y, x
+			}
+			return b.less(x, y)
+		})
+	}
+	b.page = 0
+}
+
+func (b *Browser) matches(e entry) bool {
+	for field, value := range b.filters {
+		actual := e.field(field)
+		switch field {
+		case "type", "url":
+			if !strings.Contains(strings.ToLower(actual), strings.ToLower(value)) {
+				return false
+			}
+		default:
+			if !strings.EqualFold(actual, value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// less orders two results by the sort field, most severe first for severity
+func (b *Browser) less(x, y entry) bool {
+	switch b.sortBy {
+	case "type":
+		return x.typ() < y.typ()
+	case "severity":
+		return rank(x) < rank(y)
+	case "status":
+		return x.status() < y.status()
+	}
+	return x.url() < y.url()
+}
+
+// rank places results without a known severity after LOW
+func rank(e entry) int {
+	if r, known := severityRank[e.severity()]; known {
+		return r
+	}
+	return len(severityRank)
+}
+
+// Run reads commands from in until "quit" or the end of input, writing the
+// pages and details to out
+func (b *Browser) Run(in io.Reader, out io.Writer) error {
+	b.WritePage(out)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "view> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		if quit := b.Execute(strings.TrimSpace(scanner.Text()), out); quit {
+			return nil
+		}
+	}
+}
+
+// Execute runs one command, reporting whether it asked to quit
+func (b *Browser) Execute(line string, out io.Writer) bool {
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command = strings.ToLower(command); {
+	case command == "q" || command == "quit" || command == "exit":
+		return true
+	case command == "" || command == "n" || command == "next":
+		if (b.page+1)*b.PageSize < len(b.visible) {
+			b.page++
+		}
+		b.WritePage(out)
+	case command == "p" || command == "prev":
+		if b.page > 0 {
+			b.page--
+		}
+		b.WritePage(out)
+	case command == "clear":
+		b.filters = make(map[string]string)
+		b.refresh()
+		b.WritePage(out)
+	case command == "sort":
+		if err := b.Sort(arg); err != nil {
+			fmt.Fprintln(out, err)
+			return false
+		}
+		b.WritePage(out)
+	case command == "open" || isNumber(command):
+		if isNumber(command) {
+			arg = command
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(b.visible) {
+			fmt.Fprintf(out, "No result %q; pick a number from the list\n", arg)
+			return false
+		}
+		b.WriteDetail(out, n)
+	case slices.Contains(FilterFields, command):
+		if err := b.Filter(command, arg); err != nil {
+			fmt.Fprintln(out, err)
+			return false
+		}
+		b.WritePage(out)
+	case command == "help" || command == "?":
+		writeHelp(out)
+	default:
+		fmt.Fprintf(out, "Unknown command %q; type help for the list\n", command)
+	}
+	return false
+}
+
+// WritePage lists the current page of results, numbered from 1 across pages
+func (b *Browser) WritePage(out io.Writer) {
+	start := b.page * b.PageSize
+	end := min(start+b.PageSize, len(b.visible))
+
+	var state []string
+	for _, field := range FilterFields {
+		if value, exists := b.filters[field]; exists {
+			state = append(state, field+"="+value)
+		}
+	}
+	if b.sortBy != "" {
+		order := b.sortBy
+		if b.desc {
+			order = "-" + order
+		}
+		state = append(state, "sort "+order)
+	}
+	if len(b.visible) == 0 {
+		fmt.Fprintf(out, "No results of %d match %s\n", len(b.entries), strings.Join(state, ", "))
+		return
+	}
+	fmt.Fprintf(out, "Results %d-%d of %d", start+1, end, len(b.visible))
+	if len(state) > 0 {
+		fmt.Fprintf(out, " (%s)", strings.Join(state, ", "))
+	}
+	fmt.Fprintln(out)
+
+	for i := start; i < end; i++ {
+		e := b.entries[b.visible[i]]
+		if e.finding != nil {
+			fmt.Fprintf(out, "%4d  %-6s  %-28s  %s:%d\n", i+1, e.severity(), e.finding.Type, e.finding.URL, e.finding.LineNumber)
+		} else {
+			fmt.Fprintf(out, "%4d  %-6d  %-28s  %s\n", i+1, e.endpoint.StatusCode, e.endpoint.Method, e.endpoint.URL)
+		}
+	}
+}
+
+// WriteDetail shows every field of result n of the list, with the source
+// context of a finding
+func (b *Browser) WriteDetail(out io.Writer, n int) {
+	e := b.entries[b.visible[n-1]]
+	var fields [][2]string
+	if f := e.finding; f != nil {
+		fields = [][2]string{
+			{"URL", f.URL},
+			{"Source", f.Source},
+			{"Type", f.Type},
+			{"Severity", e.severity()},
+			{"Description", f.Description},
+			{"Position", fmt.Sprintf("line %d, column %d", f.LineNumber, f.Column)},
+			{"Location", f.Location},
+			{"Match", f.Match},
+			{"Secret", f.Secret},
+			{"Handshake", f.Handshake},
+			{"Commit", f.Commit},
+			{"Introduced", f.Introduced},
+			{"Labels", f.Labels.String()},
+			{"Occurrences", fmt.Sprintf("%d (last seen %s)", f.Occurrences, f.LastSeen.Format("2006-01-02 15:04"))},
+		}
+		if f.Remediation != nil {
+			fields = append(fields, [2]string{"Remediation", f.Remediation.Summary}, [2]string{"Rotation", f.Remediation.Rotation})
+		}
+	} else {
+		ep := e.endpoint
+		fields = [][2]string{
+			{"URL", ep.URL},
+			{"Method", ep.Method},
+			{"Status", strconv.Itoa(ep.StatusCode)},
+			{"Content type", ep.ContentType},
+			{"Length", strconv.FormatInt(ep.ContentLength, 10)},
+			{"Time", fmt.Sprintf("%dms %s", ep.ResponseTime, ep.LatencyAnomaly)},
+			{"Route", ep.Route},
+			{"Virtual host", ep.VirtualHost},
+			{"Source", ep.Source},
+			{"Redirects", ep.RedirectChain},
+			{"Auth", strings.TrimSpace(ep.AuthScheme + " " + ep.AuthParam)},
+			{"Allowed", ep.AllowedMethods},
+			{"CORS origin", ep.CORSOrigin},
+			{"Tags", strings.Join(ep.Tags, ", ")},
+			{"Schema", ep.Schema},
+			{"Labels", ep.Labels.String()},
+			{"Occurrences", fmt.Sprintf("%d (last seen %s)", ep.Occurrences, ep.LastSeen.Format("2006-01-02 15:04"))},
+		}
+	}
+
+	fmt.Fprintf(out, "--- %s %d of %d ---\n", e.kind(), n, len(b.visible))
+	for _, field := range fields {
+		if value := strings.TrimSpace(field[1]); value != "" {
+			fmt.Fprintf(out, "%-13s %s\n", field[0]+":", value)
+		}
+	}
+	if e.finding != nil && e.finding.Context != "" {
+		fmt.Fprintln(out, "Context:")
+		for _, line := range strings.Split(e.finding.Context, "\n") {
+			fmt.Fprintf(out, "    %s\n", line)
+		}
+	}
+}
+
+func writeHelp(out io.Writer) {
+	fmt.Fprint(out, `Commands:
+  <enter>, n, next      Next page
+  p, prev               Previous page
+  <number>, open N      Show result N with its context
+  type TEXT             Findings of a type / endpoints of a content type containing TEXT
+  url TEXT              Results whose URL contains TEXT
+  severity LEVEL        Findings of severity HIGH, MEDIUM or LOW
+  status CODE           Endpoints answering with CODE
+  kind finding|endpoint Only findings or only endpoints
+  FIELD                 A filter command without a value removes that filter
+  clear                 Remove all filters
+  sort FIELD            Sort by url, type, severity or status; -FIELD reverses
+  q, quit               Leave
+`)
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
@@ -0,0 +1,98 @@
+package view
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"jsfinder/pkg/discovery"
+	"jsfinder/pkg/merge"
+	"jsfinder/pkg/scanner"
+)
+
+func testBrowser() *Browser {
+	findings := []merge.Finding{
+		{Finding: scanner.Finding{URL: "https://app.example.com/main.js", Type: "AWS_ACCESS_KEY", Confidence: "HIGH", LineNumber: 12, Context: "const key = \"AKIA\""}},
+		{Finding: scanner.Finding{URL: "https://cdn.example.com/vendor.js", Type: "API_ENDPOINT", Confidence: "LOW"}},
+		{Finding: scanner.Finding{URL: "https://app.example.com/admin.js", Type: "GENERIC_SECRET", Confidence: "MEDIUM"}},
+	}
+	endpoints := []merge.Endpoint{
+		{Endpoint: discovery.Endpoint{URL: "https://app.example.com/api/users", Method: "GET", StatusCode: 200}},
+		{Endpoint: discovery.Endpoint{URL: "https://app.example.com/admin", Method: "GET", StatusCode: 403}},
+	}
+	return New(findings, endpoints)
+}
+
+func TestBrowser_filterAndSort(t *testing.T) {
+	b := testBrowser()
+	if b.Len() != 5 {
+		t.Fatalf("Expected every result listed, got %d", b.Len())
+	}
+
+	b.Filter("url", "ADMIN")
+	if b.Len() != 2 {
+		t.Errorf("Expected a case-insensitive URL filter, got %d results", b.Len())
+	}
+	b.Filter("kind", "endpoint")
+	if b.Len() != 1 || b.entries[b.visible[0]].status() != 403 {
+		t.Errorf("Expected filters to combine, got %d results", b.Len())
+	}
+	b.Filter("url", "")
+	b.Filter("kind", "")
+	b.Filter("severity", "high")
+	if b.Len() != 1 {
+		t.Errorf("Expected one HIGH finding, got %d", b.Len())
+	}
+	if err := b.Filter("color", "red"); err == nil {
+		t.Error("Expected an unknown filter to fail")
+	}
+
+	b.Filter("severity", "")
+	b.Filter("kind", "finding")
+	b.Sort("severity")
+	var order []string
+	for _, i := range b.visible {
+		order = append(order, b.entries[i].severity())
+	}
+	if strings.Join(order, ",") != "HIGH,MEDIUM,LOW" {
+		t.Errorf("Expected the most severe first, got %v", order)
+	}
+	b.Sort("-severity")
+	if b.entries[b.visible[0]].severity() != "LOW" {
+		t.Error("Expected a leading - to reverse the order")
+	}
+	if err := b.Sort("size"); err == nil {
+		t.Error("Expected an unknown sort field to fail")
+	}
+}
+
+func TestBrowser_Run(t *testing.T) {
+	b := testBrowser()
+	b.PageSize = 2
+
+	out := &bytes.Buffer{}
+	input := "n\nn\np\ntype aws\n1\n9\nclear\nstatus 403\nbogus\nquit\nn\n"
+	if err := b.Run(strings.NewReader(input), out); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	output := out.String()
+
+	for _, expected := range []string{
+		"Results 1-2 of 5",
+		"Results 3-4 of 5",
+		"Results 5-5 of 5",
+		"Results 1-1 of 1 (type=aws)",
+		"Position:     line 12",
+		"    const key = \"AKIA\"",
+		"No result \"9\"",
+		"403     GET",
+		"Unknown command \"bogus\"",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Count(output, "view> ") != 10 {
+		t.Errorf("Expected commands after quit to be ignored, got:\n%s", output)
+	}
+}
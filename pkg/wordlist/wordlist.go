@@ -0,0 +1,445 @@
+package wordlist
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"jsfinder/pkg/input"
+	"jsfinder/pkg/scope"
+	"jsfinder/pkg/utils"
+)
+
+// DefaultMinLength is the shortest identifier or key kept as a word; path
+// segments are kept at any length since the target really serves them
+const DefaultMinLength = 3
+
+// maxWordLength drops long generated names that are never endpoint paths
+const maxWordLength = 64
+
+// maxDocumentSize bounds how much of a page or JS file is read, like the
+// scanner's limit on archive entries; the rest of a larger file is ignored
+const maxDocumentSize = 64 << 20
+
+// Config holds the configuration for wordlist generation
+type Config struct {
+	InputFile     string
+	OutputFile    string
+	Threads       int
+	Timeout       int
+	MinLength     int // Shortest identifier or JSON key kept, DefaultMinLength if zero
+	MaxWords      int // Keep only the most frequent words; 0 keeps all
+	Verbose       bool
+	Stats         *utils.RunStats
+	Budget        *utils.Budget
+	Window        *utils.RunWindow
+	Shard         *utils.Shard
+	Scope         *scope.Scope
+	Identity      *utils.Identity
+	Auth          *utils.Auth
+	UAFallback    *utils.UAFallback
+	Timeouts      *utils.ClientTimeouts
+	Dialer        *utils.Dialer
+	Errors        *utils.ErrorLog
+	GlobalTimeout time.Duration // Stop starting new downloads and cancel stuck ones after this long; 0 for no limit
+}
+
+// Generator builds a target-specific wordlist from the pages and JavaScript
+// files of a target: the paths they reference, the keys of their objects and
+// JSON, and the names they declare, split at camelCase and snake_case
+type Generator struct {
+	config     *Config
+	client     *http.Client
+	prober     *input.SchemeProber
+	counts     map[string]int
+	mutex      sync.Mutex
+	stats      *utils.RunStats
+	logger     *utils.Logger
+	timeoutMgr *utils.TimeoutManager
+}
+
+var (
+	// pathPattern matches quoted and attribute paths such as "/api/users" or
+	// href=/account, but not protocol-relative //host URLs
+	pathPattern = regexp.MustCompile("[\"'`=](/[A-Za-z0-9_~.{}:$-][A-Za-z0-9_~./{}:$-]*)")
+	urlPattern  = regexp.MustCompile(`https?://[A-Za-z0-9.-]+(?::\d+)?(/[A-Za-z0-9_~./-]*)`)
+
+	// keyPattern matches quoted keys ("userId":) and unquoted object keys ({userId: or ,userId:)
+	keyPattern = regexp.MustCompile(`["']([A-Za-z_$][\w$-]*)["']\s*:|[{,]\s*([A-Za-z_$][\w$]*)\s*:`)
+
+	// namePattern matches declared names and called methods
+	namePattern = regexp.MustCompile(`\b(?:function|const|let|var|class)\s+([A-Za-z_$][\w$]*)|\.([A-Za-z_$][\w$]*)\s*\(`)
+)
+
+// stopWords are language keywords and browser API names common to every
+// script, which would crowd the target's own words out of the list
+var stopWords = map[string]bool{
+	"function": true, "return": true, "const": true, "this": true, "prototype": true,
+	"length": true, "undefined": true, "null": true, "true": true, "false": true,
+	"document": true, "window": true, "console": true, "push": true, "call": true,
+	"apply": true, "bind": true, "then": true, "catch": true, "default": true,
+	"exports": true, "module": true, "require": true, "object": true, "string": true,
+	"number": true, "typeof": true, "instanceof": true, "new": true, "var": true,
+	"let": true, "for": true, "while": true, "else": true, "case": true, "break": true,
+	"continue": true, "switch": true, "throw": true, "try": true, "finally": true,
+	"class": true, "extends": true, "super": true, "static": true, "async": true,
+	"await": true, "yield": true, "void": true, "with": true, "import": true,
+	"export": true, "from": true, "the": true, "and": true, "use": true, "strict": true,
+	"http": true, "https": true, "www": true, "com": true, "html": true, "div": true,
+	"span": true, "style": true, "script": true, "tostring": true, "valueof": true,
+	"hasownproperty": true, "addeventlistener": true, "removeeventlistener": true,
+	"queryselector": true, "queryselectorall": true, "getelementbyid": true,
+	"createelement": true, "appendchild": true, "setattribute": true, "getattribute": true,
+	"foreach": true, "indexof": true, "slice": true, "splice": true, "concat": true,
+	"join": true, "split": true, "replace": true, "keys": true, "values": true,
+	"entries": true, "assign": true, "defineproperty": true, "math": true, "date": true,
+	"json": true, "parse": true, "stringify": true, "promise": true, "resolve": true,
+	"reject": true, "settimeout": true, "cleartimeout": true, "setinterval": true,
+}
+
+// staticExtensions mark paths to assets, which are not worth probing for
+// endpoints
+var staticExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".map": true, ".css": true, ".png": true, ".jpg": true,
+	".jpeg": true, ".gif": true, ".svg": true, ".ico": true, ".webp": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".mp4": true,
+}
+
+// New creates a new wordlist generator
+func New(config *Config) *Generator {
+	stats := config.Stats
+	if stats == nil {
+		stats = utils.NewRunStats()
+	}
+	if config.MinLength <= 0 {
+		config.MinLength = DefaultMinLength
+	}
+
+	client := utils.NewHTTPClient(&utils.ClientOptions{
+		Timeout:    time.Duration(config.Timeout) * time.Second,
+		Stats:      stats,
+		Budget:     config.Budget,
+		Scope:      config.Scope,
+		Identity:   config.Identity,
+		Auth:       config.Auth,
+		UAFallback: config.UAFallback,
+		Timeouts:   config.Timeouts,
+		Dialer:     config.Dialer,
+	})
+
+	logger := utils.NewModuleLogger("wordlist")
+	timeoutConfig := utils.ScannerTimeoutConfig().
+		WithRequestTimeout(time.Duration(config.Timeout)*time.Second, 1)
+	timeoutConfig.GlobalTimeout = config.GlobalTimeout
+	timeoutMgr := utils.NewTimeoutManager(timeoutConfig, logger)
+	stats.TrackOperations(timeoutMgr)
+
+	return &Generator{
+		config:     config,
+		client:     client,
+		prober:     input.NewSchemeProber(client),
+		counts:     make(map[string]int),
+		stats:      stats,
+		logger:     logger,
+		timeoutMgr: timeoutMgr,
+	}
+}
+
+// Stats returns the run statistics collected by the generator
+func (g *Generator) Stats() *utils.RunStats {
+	return g.stats
+}
+
+// GenerateFromFile builds the wordlist from the page and JS URLs in the input file
+func (g *Generator) GenerateFromFile(inputFile string) error {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	return g.generateFromReader(file)
+}
+
+// GenerateFromStdin builds the wordlist from the page and JS URLs on stdin
+func (g *Generator) GenerateFromStdin() error {
+	return g.generateFromReader(os.Stdin)
+}
+
+func (g *Generator) generateFromReader(reader io.Reader) error {
+	pool := utils.NewWorkerPool(g.timeoutMgr.Context(), utils.PoolOptions{Workers: g.config.Threads, Logger: g.logger})
+
+	scanner := input.NewScanner(reader)
+	for scanner.Scan() {
+		target := scanner.Target().URL
+		if !g.config.Shard.Includes(target) || !g.config.Scope.AllowsURL(target) {
+			continue
+		}
+		g.stats.AddQueued(1)
+
+		pending := scanner.Target()
+		pool.Submit(func(ctx context.Context, workerID int) {
+			if g.config.Budget.Exceeded() {
+				g.stats.SetStopReason(g.config.Budget.Reason())
+				return
+			}
+			if err := g.config.Window.Wait(ctx); err != nil {
+				return
+			}
+			if g.timeoutMgr.Expired() {
+				g.stats.SetStopReason(g.timeoutMgr.ExpiredReason())
+				return
+			}
+
+			url := g.prober.Resolve(ctx, pending).URL
+			if err := g.fetch(url); err != nil {
+				g.config.Errors.Record(url, err)
+				if g.config.Verbose {
+					g.logger.WithFields(utils.WorkerFields(url, workerID)).Warnf("Error fetching: %v", err)
+				}
+			}
+			g.stats.AddProcessed()
+		})
+	}
+
+	pool.Wait()
+	g.stats.AddWorkerPool(pool)
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return g.outputWords()
+}
+
+// Plan builds the request plan for the URLs read from reader without sending traffic
+func (g *Generator) Plan(reader io.Reader) (*utils.RequestPlan, error) {
+	plan := utils.NewRequestPlan("wordlist gen", g.config.Threads, time.Duration(g.config.Timeout)*time.Second)
+	plan.AddSetting("Min length", g.config.MinLength)
+
+	skipped := 0
+	scanner := input.NewScanner(reader)
+	for scanner.Scan() {
+		target := scanner.Target().URL
+		if !g.config.Shard.Includes(target) {
+			continue
+		}
+		if !g.config.Scope.AllowsURL(target) {
+			skipped++
+			continue
+		}
+		plan.Add(target, 1)
+	}
+
+	if skipped > 0 {
+		plan.AddNote("%d out-of-scope URLs skipped", skipped)
+	}
+	return plan, scanner.Err()
+}
+
+// fetch downloads a page or JS file and adds its words
+func (g *Generator) fetch(target string) error {
+	op := g.timeoutMgr.StartOperation("wordlist", target)
+	defer g.timeoutMgr.CompleteOperation(op.ID)
+
+	req, err := http.NewRequestWithContext(op.Ctx, "GET", target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return utils.NewHTTPError(fmt.Sprintf("HTTP %d: %s", resp.StatusCode, target), resp.StatusCode, nil)
+	}
+	if !isText(resp.Header.Get("Content-Type")) {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(g.timeoutMgr.HeartbeatReader(op.ID, resp.Body), maxDocumentSize))
+	if err != nil {
+		return err
+	}
+
+	g.addDocument(string(body))
+	return nil
+}
+
+// isText reports whether a response may hold paths and identifiers
+func isText(contentType string) bool {
+	for _, prefix := range []string{"image/", "font/", "audio/", "video/", "application/octet-stream", "application/pdf"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// addDocument adds the words of a page or script
+func (g *Generator) addDocument(content string) {
+	words := make(map[string]int)
+
+	var paths []string
+	for _, match := range pathPattern.FindAllStringSubmatch(content, -1) {
+		paths = append(paths, match[1])
+	}
+	for _, match := range urlPattern.FindAllStringSubmatch(content, -1) {
+		paths = append(paths, match[1])
+	}
+	for _, path := range paths {
+		path = strings.Trim(path, "/")
+		if path == "" || staticExtensions[strings.ToLower(pathpkg.Ext(path))] {
+			continue
+		}
+		if !strings.ContainsAny(path, "{}:$") && strings.Count(path, "/") > 0 && strings.Count(path, "/") < 3 {
+			words[path]++
+		}
+		for _, segment := range strings.Split(path, "/") {
+			if segment != "" && segment != "." && segment != ".." && !strings.ContainsAny(segment, "{}:$") &&
+				!looksLikeHash(segment) && len(segment) <= maxWordLength {
+				words[segment]++
+			}
+		}
+	}
+
+	var names []string
+	for _, match := range keyPattern.FindAllStringSubmatch(content, -1) {
+		names = append(names, match[1]+match[2])
+	}
+	for _, match := range namePattern.FindAllStringSubmatch(content, -1) {
+		names = append(names, match[1]+match[2])
+	}
+	for _, name := range names {
+		name = strings.Trim(name, "$_")
+		if g.keep(name) {
+			words[name]++
+		}
+		for _, part := range splitIdentifier(name) {
+			if g.keep(part) {
+				words[part]++
+			}
+		}
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for word, count := range words {
+		g.counts[word] += count
+	}
+}
+
+// keep reports whether an identifier, key or part of one is worth a probe
+func (g *Generator) keep(word string) bool {
+	return len(word) >= g.config.MinLength && len(word) <= maxWordLength &&
+		!stopWords[strings.ToLower(word)] && !looksLikeHash(word)
+}
+
+// splitIdentifier breaks camelCase, PascalCase, snake_case and kebab-case
+// names into lowercase words: getUserProfile, parseHTTPResponse and
+// user_account_id become get user profile, parse http response and user
+// account id
+func splitIdentifier(name string) []string {
+	var parts []string
+	for _, piece := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '$' }) {
+		runes := []rune(piece)
+		start := 0
+		for i := 1; i <= len(runes); i++ {
+			if i < len(runes) && !wordBoundary(runes, i) {
+				continue
+			}
+			if part := strings.ToLower(string(runes[start:i])); part != strings.ToLower(name) {
+				parts = append(parts, part)
+			}
+			start = i
+		}
+	}
+	return parts
+}
+
+// wordBoundary reports whether a new word starts at runes[i]: a lowercase
+// letter followed by an uppercase one, the last capital of an acronym
+// followed by lowercase (HTTPResponse), or a change between letters and digits
+func wordBoundary(runes []rune, i int) bool {
+	prev, cur := runes[i-1], runes[i]
+	switch {
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+		return true
+	default:
+		return unicode.IsDigit(prev) != unicode.IsDigit(cur)
+	}
+}
+
+// looksLikeHash reports whether a word is a build hash or ID rather than a
+// name: mostly digits, or a long run of hex
+func looksLikeHash(word string) bool {
+	letters, digits, hex := 0, 0, true
+	for _, r := range word {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case unicode.IsLetter(r):
+			letters++
+			if !strings.ContainsRune("abcdefABCDEF", r) {
+				hex = false
+			}
+		default:
+			hex = false
+		}
+	}
+	return digits > letters || (hex && len(word) >= 8)
+}
+
+// Words returns the generated words, most frequent first
+func (g *Generator) Words() []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	words := make([]string, 0, len(g.counts))
+	for word := range g.counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if g.counts[words[i]] != g.counts[words[j]] {
+			return g.counts[words[i]] > g.counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	if g.config.MaxWords > 0 && len(words) > g.config.MaxWords {
+		words = words[:g.config.MaxWords]
+	}
+	return words
+}
+
+func (g *Generator) outputWords() error {
+	var output io.Writer = os.Stdout
+	if g.config.OutputFile != "" {
+		file, err := os.Create(g.config.OutputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	writer := bufio.NewWriter(output)
+	for _, word := range g.Words() {
+		fmt.Fprintln(writer, word)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,97 @@
+package wordlist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitIdentifier(t *testing.T) {
+	tests := map[string][]string{
+		"getUserProfile":    {"get", "user", "profile"},
+		"parseHTTPResponse": {"parse", "http", "response"},
+		"user_account_id":   {"user", "account", "id"},
+		"api-v2":            {"api", "v", "2"},
+		"orders":            nil,
+	}
+
+	for name, expected := range tests {
+		if parts := splitIdentifier(name); !reflect.DeepEqual(parts, expected) {
+			t.Errorf("splitIdentifier(%q) = %v, expected %v", name, parts, expected)
+		}
+	}
+}
+
+func TestGenerator_addDocument(t *testing.T) {
+	g := New(&Config{})
+	g.addDocument(`
+		<a href="/account/settings">Settings</a>
+		<script src="/static/js/main.3f9a2b1c.js"></script>
+		fetch("/api/v2/orders/" + id);
+		fetch("https://api.example.com/internal/reports?x=1");
+		const route = "/users/:userId/invoices";
+		var payload = {"billingAddress": 1, shippingMethod: "fast"};
+		function loadCustomerNotes() { return this.push(1); }
+	`)
+
+	words := make(map[string]bool)
+	for _, word := range g.Words() {
+		words[word] = true
+	}
+
+	for _, want := range []string{
+		"account", "settings", "account/settings", "api", "v2", "orders", "api/v2/orders",
+		"internal", "reports", "users", "invoices", "billingAddress", "billing", "address",
+		"shippingMethod", "shipping", "method", "loadCustomerNotes", "customer", "notes",
+	} {
+		if !words[want] {
+			t.Errorf("Expected %q in %v", want, g.Words())
+		}
+	}
+	for _, unwanted := range []string{"static/js/main.3f9a2b1c.js", "main.3f9a2b1c.js", ":userId", "push", "this", "id"} {
+		if words[unwanted] {
+			t.Errorf("Did not expect %q in %v", unwanted, g.Words())
+		}
+	}
+}
+
+func TestGenerator_GenerateFromFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/dashboard">x</a><a href="/dashboard">y</a>`))
+		case "/app.js":
+			w.Write([]byte(`fetch("/api/orders"); fetch("/api/orders"); fetch("/api/orders");`))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(`"/not/a/word"`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "urls.txt")
+	outputFile := filepath.Join(dir, "words.txt")
+	os.WriteFile(inputFile, []byte(strings.Join([]string{server.URL + "/", server.URL + "/app.js", server.URL + "/logo.png", server.URL + "/missing"}, "\n")), 0644)
+
+	g := New(&Config{OutputFile: outputFile, Threads: 2, Timeout: 5, MaxWords: 3})
+	if err := g.GenerateFromFile(inputFile); err != nil {
+		t.Fatalf("GenerateFromFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	expected := "api\napi/orders\norders\n"
+	if string(data) != expected {
+		t.Errorf("Expected the 3 most frequent words %q, got %q", expected, string(data))
+	}
+}